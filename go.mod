@@ -10,7 +10,7 @@ require (
 	github.com/grpc-ecosystem/go-grpc-middleware v1.2.0
 	github.com/matryer/is v1.4.0
 	github.com/opentracing/opentracing-go v1.2.0
-	github.com/prometheus/client_golang v1.9.0 // indirect
+	github.com/prometheus/client_golang v1.9.0
 	github.com/stretchr/testify v1.6.1
 	github.com/uber/jaeger-client-go v2.25.0+incompatible
 	github.com/uber/jaeger-lib v2.4.0+incompatible
@@ -20,6 +20,7 @@ require (
 	golang.org/dl v0.0.0-20210506185525-b8dea299038d // indirect
 	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
 	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.23.0
 	gotest.tools v2.1.0+incompatible // indirect
 	gotest.tools/gotestsum v1.6.4 // indirect
 )
@@ -0,0 +1,241 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Builder collects the annotations for an error so they can be applied in a single chain,
+// instead of stacking several wrapper calls:
+//
+//	err := errors.Build("call not found").
+//	        WithGrpcCode(codes.NotFound).
+//	        WithHTTPStatus(http.StatusNotFound).
+//	        WithField("callID", id).
+//	        WithCause(err).
+//	        Err()
+//
+// The annotations can be read back with GrpcCode, HTTPStatus and Fields.
+type Builder struct {
+	msg        string
+	cause      error
+	grpcCode   *codes.Code
+	httpStatus int
+	safeMsg    string
+	fields     map[string]interface{}
+}
+
+// Build starts building an error with the supplied message
+func Build(message string) *Builder {
+	return &Builder{msg: message}
+}
+
+// WithGrpcCode sets the gRPC code of the error
+func (b *Builder) WithGrpcCode(code codes.Code) *Builder {
+	b.grpcCode = &code
+	return b
+}
+
+// WithHTTPStatus sets the http status of the error
+func (b *Builder) WithHTTPStatus(code int) *Builder {
+	b.httpStatus = code
+	return b
+}
+
+// WithSafeMessage sets the message that is safe to show to clients, see WithSafeMessage
+func (b *Builder) WithSafeMessage(message string) *Builder {
+	b.safeMsg = message
+	return b
+}
+
+// WithField adds a key value pair to the error, e.g. the ID of the entity the error relates to
+func (b *Builder) WithField(key string, value interface{}) *Builder {
+	if b.fields == nil {
+		b.fields = make(map[string]interface{})
+	}
+	b.fields[key] = value
+	return b
+}
+
+// WithCause sets the error that caused this one, the message is then used to annotate the cause
+func (b *Builder) WithCause(err error) *Builder {
+	b.cause = err
+	return b
+}
+
+// Err returns the built error, recording the stack trace at the point it was called unless the
+// cause already has one
+func (b *Builder) Err() error {
+	var err error
+	if b.cause == nil {
+		err = &fundamental{
+			msg:   b.msg,
+			stack: callers(),
+		}
+	} else if needsStack(b.cause) {
+		err = &withStack{
+			&withMessage{
+				cause: b.cause,
+				msg:   b.msg,
+			},
+			callers(),
+		}
+	} else {
+		err = &withMessage{
+			cause: b.cause,
+			msg:   b.msg,
+		}
+	}
+
+	if len(b.fields) > 0 {
+		fields := make(map[string]interface{}, len(b.fields))
+		for k, v := range b.fields {
+			fields[k] = v
+		}
+		err = &withFields{
+			cause:  err,
+			fields: fields,
+		}
+	}
+
+	if b.safeMsg != "" {
+		err = &withSafeMessage{
+			cause: err,
+			msg:   b.safeMsg,
+		}
+	}
+
+	// the status message is taken before the http annotation so it only holds the error message
+	msg, ok := SafeMessage(err)
+	if !ok {
+		msg = err.Error()
+	}
+
+	if b.httpStatus != 0 {
+		err = &withhttpCode{
+			cause:    err,
+			httpCode: b.httpStatus,
+		}
+	}
+
+	// the gRPC status is kept outermost so the grpc status package recognises the error
+	if b.grpcCode != nil {
+		st := status.New(*b.grpcCode, msg)
+		if d, ok := RetryAfter(err); ok {
+			st = statusWithRetryInfo(st, d)
+		}
+		st = statusWithFingerprint(st, err)
+		err = &withGrpcStatus{
+			cause:      err,
+			grpcCode:   *b.grpcCode,
+			grpcStatus: st,
+		}
+	}
+
+	if b.grpcCode != nil || b.httpStatus != 0 {
+		emitCode(err)
+	}
+
+	return err
+}
+
+// GrpcCode returns the gRPC code of the first error in err's chain that has one.
+// It returns codes.OK if err is nil, and codes.Unknown if no error in the chain has a code.
+func GrpcCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	var g interface{ GRPCStatus() *status.Status }
+	if As(err, &g) {
+		return g.GRPCStatus().Code()
+	}
+
+	return codes.Unknown
+}
+
+// HTTPStatus returns the http status of the first error in err's chain that has one.
+// If there is none, the status is converted from the gRPC code of the chain, and if that is
+// unknown too http.StatusInternalServerError is returned. It returns 0 if err is nil.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var h *withhttpCode
+	if As(err, &h) {
+		return h.httpCode
+	}
+
+	if code := GrpcCode(err); code != codes.Unknown {
+		return HTTPFromGrpc(code)
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Fields returns all of the fields added to err's chain. Where a key was added more than
+// once, the value closest to the top of the chain wins.
+func Fields(err error) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	for err != nil {
+		if f, ok := err.(*withFields); ok {
+			for k, v := range f.fields {
+				if _, exists := fields[k]; !exists {
+					fields[k] = v
+				}
+			}
+		}
+		err = Unwrap(err)
+	}
+
+	return fields
+}
+
+type withFields struct {
+	cause  error
+	fields map[string]interface{}
+}
+
+func (w *withFields) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withFields) Cause() error {
+	return w.cause
+}
+
+func (w *withFields) Unwrap() error {
+	return w.cause
+}
+
+func (w *withFields) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\n", w.Cause())
+
+			keys := make([]string, 0, len(w.fields))
+			for k := range w.fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for i, k := range keys {
+				if i > 0 {
+					io.WriteString(s, " ")
+				}
+				fmt.Fprintf(s, "%s=%v", k, w.fields[k])
+			}
+			return
+		}
+		fallthrough
+	case 's', 'q':
+		io.WriteString(s, w.Error())
+	}
+}
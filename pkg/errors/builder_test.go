@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBuild(t *testing.T) {
+	err := Build("call not found").
+		WithGrpcCode(codes.NotFound).
+		WithHTTPStatus(http.StatusGone).
+		WithField("callID", 42).
+		Err()
+
+	assert.Equal(t, codes.NotFound, GrpcCode(err))
+	assert.Equal(t, http.StatusGone, HTTPStatus(err), "Expected the http status rather than the converted code")
+	assert.Equal(t, map[string]interface{}{"callID": 42}, Fields(err))
+	assert.Contains(t, err.Error(), "call not found")
+	assert.NotEmpty(t, StackTraceOf(err), "Expected the stack recorded by Err")
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "Expected a status error")
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "call not found", st.Message(), "Expected the status message without the http annotation")
+	assert.Equal(t, Fingerprint(err), FingerprintFromStatus(st), "Expected the fingerprint in the DebugInfo details")
+}
+
+func TestBuildWithoutCode(t *testing.T) {
+	err := Build("call not found").Err()
+
+	assert.Equal(t, "call not found", err.Error())
+	assert.Equal(t, codes.Unknown, GrpcCode(err))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(err))
+	assert.Empty(t, Fields(err))
+
+	_, ok := status.FromError(err)
+	assert.False(t, ok, "Expected no status without a gRPC code")
+
+	assert.Equal(t, codes.OK, GrpcCode(nil))
+	assert.Equal(t, 0, HTTPStatus(nil))
+}
+
+func TestBuildWithCause(t *testing.T) {
+	cause := Build("row missing").WithField("table", "calls").WithField("callID", 1).Err()
+	err := Build("call not found").WithCause(cause).WithField("callID", 42).WithSafeMessage("the call could not be found").
+		WithGrpcCode(codes.NotFound).Err()
+
+	assert.Contains(t, err.Error(), "call not found: row missing", "Expected the message to annotate the cause")
+	assert.Equal(t, StackTraceOf(cause), StackTraceOf(err), "Expected the stack of the cause to be kept")
+	assert.Equal(t, map[string]interface{}{"callID": 42, "table": "calls"}, Fields(err),
+		"Expected the fields of the whole chain, the outermost winning")
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "Expected a status error")
+	assert.Equal(t, "the call could not be found", st.Message(), "Expected the safe message as the status message")
+}
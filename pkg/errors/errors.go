@@ -53,15 +53,20 @@ func Errorf(format string, args ...interface{}) error {
 
 // Wrap returns an error annotating err with a stack trace
 // at the point Wrap is called, and the supplied message.
+// The stack trace is skipped if err already has one, see SetCaptureDuplicateStacks.
 // If err is nil, Wrap returns nil.
 func Wrap(err error, message string) error {
 	if err == nil {
 		return nil
 	}
+	capture := needsStack(err)
 	err = &withMessage{
 		cause: err,
 		msg:   message,
 	}
+	if !capture {
+		return err
+	}
 	return &withStack{
 		err,
 		callers(),
@@ -70,15 +75,20 @@ func Wrap(err error, message string) error {
 
 // Wrapf returns an error annotating err with a stack trace
 // at the point Wrapf is called, and the format specifier.
+// The stack trace is skipped if err already has one, see SetCaptureDuplicateStacks.
 // If err is nil, Wrapf returns nil.
 func Wrapf(err error, format string, args ...interface{}) error {
 	if err == nil {
 		return nil
 	}
+	capture := needsStack(err)
 	err = &withMessage{
 		cause: err,
 		msg:   fmt.Sprintf(format, args...),
 	}
+	if !capture {
+		return err
+	}
 	return &withStack{
 		err,
 		callers(),
@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// fingerprintFrames is how many of the innermost stack frames a fingerprint is taken from
+const fingerprintFrames = 5
+
+// fingerprintPrefix prefixes the fingerprint in the DebugInfo details of a gRPC status
+const fingerprintPrefix = "fingerprint:"
+
+// packagePath is the import path of this package, whose wrappers are left out of fingerprints
+var packagePath = reflect.TypeOf(fundamental{}).PkgPath()
+
+// Fingerprint returns a stable hash of err, so log aggregation can group the occurrences of a recurring error. It
+// is taken from the types of the errors in err's chain, and the functions of the innermost frames of its deepest
+// stack trace with the line the error was created on. Messages are left out, so errors that only differ in the
+// IDs or values they quote share a fingerprint, and so are the lines of the calling frames, so it is kept across
+// releases unless the function creating the error changes. The wrappers of this package are left out of the
+// types, so annotating an error with a code or fields doesn't change its fingerprint.
+// It returns an empty string if err is nil.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	for e := err; e != nil; e = next(e) {
+		t := reflect.TypeOf(e)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.PkgPath() == packagePath {
+			continue
+		}
+		h.Write([]byte(t.PkgPath() + "." + t.Name() + "\n"))
+	}
+
+	st := StackTraceOf(err)
+	for i := 0; i < len(st) && i < fingerprintFrames; i++ {
+		h.Write([]byte(st[i].Function()))
+		// the line the error was created on tells apart the errors of a function
+		if i == 0 {
+			h.Write([]byte(":" + strconv.Itoa(st[i].Line())))
+		}
+		h.Write([]byte("\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// FingerprintFromStatus returns the fingerprint of the error a gRPC status was created from, which the errors of
+// this package with a gRPC code carry in the DebugInfo details of their status. It returns an empty string if the
+// status has none, e.g. when it was created by another library
+func FingerprintFromStatus(st *status.Status) string {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.DebugInfo); ok && strings.HasPrefix(info.Detail, fingerprintPrefix) {
+			return strings.TrimPrefix(info.Detail, fingerprintPrefix)
+		}
+	}
+	return ""
+}
+
+// statusWithFingerprint returns a copy of st with DebugInfo details holding the fingerprint of err. If the
+// details can't be added st is returned as it is.
+func statusWithFingerprint(st *status.Status, err error) *status.Status {
+	withInfo, detailsErr := st.WithDetails(&errdetails.DebugInfo{
+		Detail: fingerprintPrefix + Fingerprint(err),
+	})
+	if detailsErr != nil {
+		return st
+	}
+	return withInfo
+}
+
+// next returns the error err wraps, through Unwrap or Cause, or nil
+func next(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	if c, ok := err.(interface{ Cause() error }); ok {
+		return c.Cause()
+	}
+	return nil
+}
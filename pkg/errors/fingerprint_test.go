@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fingerprintTestError is an error type of its own, which the fingerprint tells apart
+type fingerprintTestError struct{ msg string }
+
+func (e *fingerprintTestError) Error() string { return e.msg }
+
+// newNotFound creates its errors on a single line, so every call shares the call site
+func newNotFound(id int) error {
+	return Errorf("call %d not found", id)
+}
+
+// newOtherNotFound creates the same errors as newNotFound, from another call site
+func newOtherNotFound(id int) error {
+	return Errorf("call %d not found", id)
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint(newNotFound(1))
+	assert.NotEmpty(t, fp)
+	// created from another line of this test, so the line of the calling frame differs too
+	assert.Equal(t, fp, Fingerprint(newNotFound(2)), "Expected the same fingerprint across messages and calling lines")
+
+	assert.Equal(t, fp, Fingerprint(WithGrpcStatus(Build("lookup failed").WithCause(newNotFound(4)).WithField("callID", 4).Err(), codes.NotFound)),
+		"Expected the wrappers of this package not to change the fingerprint")
+	assert.NotEqual(t, fp, Fingerprint(newOtherNotFound(1)), "Expected different call sites to differ")
+	assert.NotEqual(t, fp, Fingerprint(Wrap(&fingerprintTestError{msg: "call 1 not found"}, "lookup failed")),
+		"Expected different error types to differ")
+	assert.NotEqual(t, fp, Fingerprint(fmt.Errorf("lookup failed: %w", newNotFound(1))),
+		"Expected errors wrapped in other types to differ")
+
+	assert.Empty(t, Fingerprint(nil))
+}
+
+func TestFingerprintFromStatus(t *testing.T) {
+	err := WithGrpcStatus(newNotFound(1), codes.NotFound)
+	st, _ := status.FromError(err)
+	assert.Equal(t, Fingerprint(err), FingerprintFromStatus(st), "Expected the fingerprint of the error the status was created from")
+
+	assert.Empty(t, FingerprintFromStatus(status.New(codes.NotFound, "not found")), "Expected none on statuses of other libraries")
+}
@@ -0,0 +1,129 @@
+package errors
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Function returns the fully qualified name of the function of the frame, or "unknown"
+func (f Frame) Function() string { return f.name() }
+
+// File returns the full path of the source file of the frame, or "unknown"
+func (f Frame) File() string { return f.file() }
+
+// Line returns the line of the source file of the frame, or 0 if it is unknown
+func (f Frame) Line() int { return f.line() }
+
+// StackFrame is a resolved stack frame, for crash reporting integrations that need the parts of each
+// frame rather than the %+v text of the error
+type StackFrame struct {
+	// The fully qualified name of the function, e.g. github.com/caring/svc/calls.(*Server).Dial
+	Function string
+	// The full path of the source file
+	File string
+	Line int
+}
+
+// Frames resolves the frames of the stack trace, from innermost (newest) to outermost (oldest)
+func (st StackTrace) Frames() []StackFrame {
+	frames := make([]StackFrame, len(st))
+	for i, f := range st {
+		frames[i] = StackFrame{Function: f.Function(), File: f.File(), Line: f.Line()}
+	}
+	return frames
+}
+
+// Frames returns the resolved frames of the deepest stack trace in err's chain, see StackTraceOf, from
+// innermost (newest) to outermost (oldest). It returns nil if no error in the chain recorded a stack trace
+func Frames(err error) []StackFrame {
+	st := StackTraceOf(err)
+	if st == nil {
+		return nil
+	}
+	return st.Frames()
+}
+
+// Callers returns the program counters of the deepest stack trace in err's chain, innermost first, the form
+// integrations such as bugsnag-go read stack traces in. It returns nil if no error in the chain recorded one
+func Callers(err error) []uintptr {
+	st := StackTraceOf(err)
+	if st == nil {
+		return nil
+	}
+	pcs := make([]uintptr, len(st))
+	for i, f := range st {
+		pcs[i] = uintptr(f)
+	}
+	return pcs
+}
+
+// SentryStacktrace is a stack trace in the shape of the Sentry event protocol, which sentry-go's Stacktrace
+// type shares, so it can be converted field by field or through its JSON encoding
+type SentryStacktrace struct {
+	// The frames from outermost (oldest) to innermost (newest), the order Sentry expects
+	Frames []SentryFrame `json:"frames"`
+}
+
+// SentryFrame is a frame of a SentryStacktrace
+type SentryFrame struct {
+	// The name of the function, without its package
+	Function string `json:"function,omitempty"`
+	// The import path of the package of the function
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	// Whether the frame is in the application, rather than the standard library or a dependency
+	InApp bool `json:"in_app"`
+}
+
+// NewSentryStacktrace returns the deepest stack trace in err's chain in the shape of the Sentry event protocol,
+// or nil if no error in the chain recorded one
+func NewSentryStacktrace(err error) *SentryStacktrace {
+	frames := Frames(err)
+	if frames == nil {
+		return nil
+	}
+
+	st := &SentryStacktrace{Frames: make([]SentryFrame, len(frames))}
+	for i, f := range frames {
+		module, function := splitFunctionName(f.Function)
+		st.Frames[len(frames)-1-i] = SentryFrame{
+			Function: function,
+			Module:   module,
+			Filename: fileBase(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    inApp(module, f.File),
+		}
+	}
+	return st
+}
+
+// splitFunctionName splits a fully qualified function name into the import path of its package and the
+// rest of the name, e.g. github.com/caring/svc/calls and (*Server).Dial
+func splitFunctionName(name string) (string, string) {
+	start := strings.LastIndex(name, "/") + 1
+	i := strings.Index(name[start:], ".")
+	if i < 0 {
+		return "", name
+	}
+	return name[:start+i], name[start+i+1:]
+}
+
+// fileBase returns the last element of a source file path
+func fileBase(file string) string {
+	return file[strings.LastIndex(file, "/")+1:]
+}
+
+// inApp reports whether a frame is in the application, rather than the standard library or a dependency
+func inApp(module, file string) bool {
+	if module == "" || module == "main" {
+		return true
+	}
+	if strings.HasPrefix(file, runtime.GOROOT()) || strings.Contains(file, "/vendor/") || strings.Contains(file, "/pkg/mod/") {
+		return false
+	}
+	// standard library packages have no domain in their import path
+	return strings.Contains(strings.SplitN(module, "/", 2)[0], ".")
+}
@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFramesTestError returns the line it records the stack of the error it returns on, and the error
+func newFramesTestError() (int, error) {
+	_, _, line, _ := runtime.Caller(0)
+	return line + 1, New("connection refused")
+}
+
+func TestFrames(t *testing.T) {
+	line, err := newFramesTestError()
+	frames := Frames(Wrap(err, "dial failed"))
+	require.True(t, len(frames) >= 2, "Expected the frames of the stack recorded by New")
+
+	assert.Equal(t, packagePath+".newFramesTestError", frames[0].Function, "Expected the innermost frame first")
+	assert.True(t, strings.HasSuffix(frames[0].File, "/frames_test.go"), "Expected the full path of the file, got %s", frames[0].File)
+	assert.Equal(t, line, frames[0].Line)
+	assert.Equal(t, packagePath+".TestFrames", frames[1].Function, "Expected the caller next")
+
+	assert.Equal(t, len(frames), len(Callers(err)))
+	assert.Equal(t, frames[0].Function, runtime.FuncForPC(Callers(err)[0]-1).Name(), "Expected the program counters in the same order")
+
+	assert.Nil(t, Frames(fmt.Errorf("no stack")), "Expected no frames when no stack was recorded")
+	assert.Nil(t, Callers(fmt.Errorf("no stack")))
+}
+
+func TestNewSentryStacktrace(t *testing.T) {
+	line, err := newFramesTestError()
+	st := NewSentryStacktrace(err)
+	require.NotNil(t, st)
+	require.Len(t, st.Frames, len(Frames(err)))
+
+	innermost := st.Frames[len(st.Frames)-1]
+	assert.Equal(t, "newFramesTestError", innermost.Function, "Expected the innermost frame last")
+	assert.Equal(t, packagePath, innermost.Module)
+	assert.Equal(t, "frames_test.go", innermost.Filename)
+	assert.True(t, strings.HasSuffix(innermost.AbsPath, "/frames_test.go"))
+	assert.Equal(t, line, innermost.Lineno)
+	assert.True(t, innermost.InApp, "Expected the frames of the application in app")
+
+	outermost := st.Frames[0]
+	assert.False(t, outermost.InApp, "Expected the frames of the standard library not in app, got %s", outermost.AbsPath)
+
+	b, jerr := json.Marshal(st)
+	require.NoError(t, jerr)
+	var decoded map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.NotEmpty(t, decoded["frames"], "Expected the frames under the key of the Sentry protocol")
+	for _, k := range []string{"function", "module", "filename", "abs_path", "lineno", "in_app"} {
+		assert.Contains(t, decoded["frames"][len(decoded["frames"])-1], k)
+	}
+
+	assert.Nil(t, NewSentryStacktrace(fmt.Errorf("no stack")))
+}
+
+func TestSplitFunctionName(t *testing.T) {
+	for name, want := range map[string][2]string{
+		"github.com/caring/svc/calls.(*Server).Dial": {"github.com/caring/svc/calls", "(*Server).Dial"},
+		"github.com/caring/svc/calls.Dial.func1":     {"github.com/caring/svc/calls", "Dial.func1"},
+		"main.main":                                  {"main", "main"},
+		"runtime.goexit":                             {"runtime", "goexit"},
+		"unknown":                                    {"", "unknown"},
+	} {
+		module, function := splitFunctionName(name)
+		assert.Equal(t, want, [2]string{module, function}, name)
+	}
+}
@@ -185,16 +185,30 @@ func FromGrpcError(origErr error) error {
 }
 
 // WithGrpcStatus annotates err with the grpc code and a status.
+// If err has a retry after, it is added to the status as RetryInfo details, and if it has a
+// safe message that is used as the status message, rather than the error's own. The Fingerprint
+// of err is added as DebugInfo details, see FingerprintFromStatus.
 // If err is nil, WithMessage returns nil.
 func WithGrpcStatus(err error, code codes.Code) error {
 	if err == nil {
 		return nil
 	}
-	return &withGrpcStatus{
+	msg, ok := SafeMessage(err)
+	if !ok {
+		msg = err.Error()
+	}
+	st := status.New(code, msg)
+	if d, ok := RetryAfter(err); ok {
+		st = statusWithRetryInfo(st, d)
+	}
+	st = statusWithFingerprint(st, err)
+	err = &withGrpcStatus{
 		cause:      err,
 		grpcCode:   code,
-		grpcStatus: status.New(code, err.Error()),
+		grpcStatus: st,
 	}
+	emitCode(err)
+	return err
 }
 
 type withGrpcStatus struct {
@@ -208,6 +222,10 @@ func (w *withGrpcStatus) GRPCStatus() *status.Status {
 }
 
 func (w *withGrpcStatus) Error() string {
+	// the status message of an error with a safe message is only that, the full message is kept for logs
+	if _, ok := SafeMessage(w.cause); ok {
+		return fmt.Sprintf("rpc error: code = %s desc = %s", w.grpcCode, w.cause.Error())
+	}
 	return fmt.Sprintf("rpc error: code = %s desc = %s", w.grpcCode, w.grpcStatus.Message())
 }
 
@@ -155,8 +155,16 @@ func HTTPFromGrpc(grpcCode codes.Code) int {
 }
 
 // ToHTTP writes the error to the http response.
+// If the error has a retry after, it is written as the Retry-After header.
+// If it has a safe message, only that is written, with the error's http status.
 func ToHTTP(in error, w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
+	setRetryAfterHeader(in, w)
+
+	if msg, ok := SafeMessage(in); ok {
+		w.WriteHeader(HTTPStatus(in))
+		return json.NewEncoder(w).Encode(safeBody{Message: msg})
+	}
 
 	// If the error was of *a specific type?* we can find
 	// a specific satus code to write to the header
@@ -172,7 +180,8 @@ func ToHTTP(in error, w http.ResponseWriter) error {
 // FromHTTP reads an error from the http response.
 // this assumes that errors are coming in as JSON
 // formatted the way that can be marshaled into a
-// WithHttpStatus error type
+// WithHttpStatus error type. A Retry-After header
+// is read into the error's retry after
 func FromHTTP(resp *http.Response) error {
 	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
 		return nil
@@ -180,9 +189,14 @@ func FromHTTP(resp *http.Response) error {
 	defer resp.Body.Close()
 	var err error
 	if decErr := json.NewDecoder(resp.Body).Decode(&err); decErr != nil {
-		return WithHTTPStatus(decErr, resp.StatusCode)
+		err = withHTTPStatus(decErr, resp.StatusCode)
+	} else {
+		err = withHTTPStatus(New("Unknown error"), resp.StatusCode)
+	}
+	if d, ok := retryAfterFromHeader(resp); ok {
+		err = WithRetryAfter(err, d)
 	}
-	return WithHTTPStatus(New("Unknown error"), resp.StatusCode)
+	return err
 }
 
 // WithHTTPStatus annotates an error with an http code
@@ -190,6 +204,13 @@ func WithHTTPStatus(err error, code int) error {
 	if err == nil {
 		return nil
 	}
+	err = withHTTPStatus(err, code)
+	emitCode(err)
+	return err
+}
+
+// withHTTPStatus annotates an error with an http code without calling the code hook
+func withHTTPStatus(err error, code int) error {
 	return &withhttpCode{
 		cause:    err,
 		httpCode: code,
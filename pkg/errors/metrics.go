@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// CodeHook is called with the code of every coded error as it is created, the gRPC code name,
+// e.g. "NotFound", or the HTTP status, e.g. "404"
+type CodeHook func(code string)
+
+// codeHook holds the CodeHook set with SetCodeHook, if any
+var codeHook atomic.Value
+
+// SetCodeHook sets the process-wide hook called each time a coded error is created with WithGrpcStatus,
+// WithHTTPStatus or a Builder given a code, so error budgets can be tracked from metrics instead of logs.
+// Errors read from another service with FromGrpcError or FromHTTP aren't counted, so each error is only counted
+// by the service that created it. A nil hook removes the current one.
+func SetCodeHook(hook CodeHook) {
+	codeHook.Store(hook)
+}
+
+// CodeCounter returns a CodeHook that increments the errors_created counter of the factory,
+// tagged with the code of the error and the service name
+func CodeCounter(factory metrics.Factory, service string) CodeHook {
+	var counters sync.Map
+
+	return func(code string) {
+		c, ok := counters.Load(code)
+		if !ok {
+			c, _ = counters.LoadOrStore(code, factory.Counter(metrics.Options{
+				Name: "errors_created",
+				Tags: map[string]string{"code": code, "service": service},
+				Help: "Number of coded errors created, by code",
+			}))
+		}
+		c.(metrics.Counter).Inc(1)
+	}
+}
+
+// emitCode calls the code hook, if one is set, with the code of err
+func emitCode(err error) {
+	if hook, _ := codeHook.Load().(CodeHook); hook != nil {
+		hook(codeOf(err))
+	}
+}
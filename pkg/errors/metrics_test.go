@@ -0,0 +1,59 @@
+package errors
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSetCodeHook(t *testing.T) {
+	var emitted []string
+	SetCodeHook(func(code string) {
+		emitted = append(emitted, code)
+	})
+	defer SetCodeHook(nil)
+
+	New("not coded")
+	Build("not coded").WithField("callID", 42).Err()
+	WithGrpcStatus(New("no such call"), codes.NotFound)
+	WithHTTPStatus(New("rate limited"), http.StatusTooManyRequests)
+	Build("no such call").WithGrpcCode(codes.NotFound).WithHTTPStatus(http.StatusGone).Err()
+	WithRetryAfter(WithGrpcStatus(New("unavailable"), codes.Unavailable), time.Second)
+	assert.Equal(t, []string{"NotFound", "429", "NotFound", "Unavailable"}, emitted,
+		"Expected the code of each coded error once")
+
+	emitted = nil
+	st := status.New(codes.NotFound, "no such call")
+	FromGrpcError(st.Err())
+	FromHTTP(&http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Header: http.Header{},
+		Body: ioutil.NopCloser(strings.NewReader(`{}`))})
+	assert.Empty(t, emitted, "Expected errors read from another service not to be counted")
+
+	SetCodeHook(nil)
+	WithGrpcStatus(New("no such call"), codes.NotFound)
+	assert.Empty(t, emitted, "Expected no calls once the hook is removed")
+}
+
+func TestCodeCounter(t *testing.T) {
+	factory := metricstest.NewFactory(0)
+	defer factory.Stop()
+
+	SetCodeHook(CodeCounter(factory, "calls"))
+	defer SetCodeHook(nil)
+
+	WithGrpcStatus(New("no such call"), codes.NotFound)
+	WithGrpcStatus(New("no such call"), codes.NotFound)
+	WithHTTPStatus(New("rate limited"), http.StatusTooManyRequests)
+
+	factory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "errors_created", Tags: map[string]string{"code": "NotFound", "service": "calls"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "errors_created", Tags: map[string]string{"code": "429", "service": "calls"}, Value: 1},
+	)
+}
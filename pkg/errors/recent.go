@@ -0,0 +1,141 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRecentErrorsSize is the number of errors kept by the recent error recorder
+// unless changed with SetRecentErrorsSize.
+const DefaultRecentErrorsSize = 100
+
+// RecentError is a single error held by the recent error recorder
+type RecentError struct {
+	Time    time.Time `json:"time"`
+	Code    string    `json:"code"`
+	Message string    `json:"message"`
+}
+
+// RecentErrors is a snapshot of the recent error recorder. Errors are ordered
+// newest first, and the counts cover every error recorded since the process
+// started, not only the ones still held in the buffer.
+type RecentErrors struct {
+	Errors       []RecentError    `json:"errors"`
+	CountsByCode map[string]int64 `json:"counts_by_code"`
+}
+
+// recentRecorder is a capped ring buffer of errors plus a running count by code
+type recentRecorder struct {
+	mu     sync.Mutex
+	buf    []RecentError
+	next   int
+	full   bool
+	counts map[string]int64
+}
+
+var recent = newRecentRecorder(DefaultRecentErrorsSize)
+
+func newRecentRecorder(size int) *recentRecorder {
+	if size < 1 {
+		size = DefaultRecentErrorsSize
+	}
+	return &recentRecorder{
+		buf:    make([]RecentError, size),
+		counts: map[string]int64{},
+	}
+}
+
+// SetRecentErrorsSize changes the capacity of the process-wide recent error recorder.
+// Any errors already recorded are discarded, the counts by code are kept.
+func SetRecentErrorsSize(size int) {
+	r := newRecentRecorder(size)
+
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+
+	recent.buf, recent.next, recent.full = r.buf, 0, false
+}
+
+// RecordRecent adds err to the process-wide recent error recorder, so that an instance can
+// report what has been failing on it without access to its logs. If err is nil nothing is recorded.
+func RecordRecent(err error) {
+	if err == nil {
+		return
+	}
+	recent.record(RecentError{
+		Time:    time.Now(),
+		Code:    codeOf(err),
+		Message: err.Error(),
+	})
+}
+
+// Recent returns a snapshot of the process-wide recent error recorder
+func Recent() RecentErrors {
+	return recent.snapshot()
+}
+
+// RecentErrorsHandler returns an http.Handler that writes the recent error snapshot as JSON,
+// intended to be mounted on a health or admin mux, e.g. under /healthz/errors.
+func RecentErrorsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Recent())
+	})
+}
+
+func (r *recentRecorder) record(e RecentError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf[r.next] = e
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.counts[e.Code]++
+}
+
+func (r *recentRecorder) snapshot() RecentErrors {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.buf)
+	}
+
+	s := RecentErrors{
+		Errors:       make([]RecentError, 0, n),
+		CountsByCode: make(map[string]int64, len(r.counts)),
+	}
+	for i := 1; i <= n; i++ {
+		s.Errors = append(s.Errors, r.buf[(r.next-i+len(r.buf))%len(r.buf)])
+	}
+	for k, v := range r.counts {
+		s.CountsByCode[k] = v
+	}
+
+	return s
+}
+
+// codeOf returns the gRPC code name or HTTP status attached to err, falling back to the
+// Unknown gRPC code name when err carries neither
+func codeOf(err error) string {
+	var g interface{ GRPCStatus() *status.Status }
+	if As(err, &g) {
+		return g.GRPCStatus().Code().String()
+	}
+
+	var h *withhttpCode
+	if As(err, &h) {
+		return strconv.Itoa(h.httpCode)
+	}
+
+	return codes.Unknown.String()
+}
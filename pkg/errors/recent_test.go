@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// recentMessages returns the messages of the errors in s, in the order they are held
+func recentMessages(s RecentErrors) []string {
+	var messages []string
+	for _, e := range s.Errors {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+func TestRecentRecorderOrdering(t *testing.T) {
+	r := newRecentRecorder(3)
+	assert.Empty(t, r.snapshot().Errors, "Expected no errors before any is recorded")
+
+	r.record(RecentError{Code: "NotFound", Message: "first"})
+	r.record(RecentError{Code: "NotFound", Message: "second"})
+
+	assert.Equal(t, []string{"second", "first"}, recentMessages(r.snapshot()), "Expected the newest error first")
+}
+
+func TestRecentRecorderWraparound(t *testing.T) {
+	r := newRecentRecorder(3)
+	for _, m := range []string{"first", "second", "third", "fourth", "fifth"} {
+		r.record(RecentError{Code: "Internal", Message: m})
+	}
+	r.record(RecentError{Code: "NotFound", Message: "sixth"})
+
+	s := r.snapshot()
+	assert.Equal(t, []string{"sixth", "fifth", "fourth"}, recentMessages(s), "Expected only the newest errors the buffer holds")
+	assert.Equal(t, map[string]int64{"Internal": 5, "NotFound": 1}, s.CountsByCode, "Expected the counts to cover every recorded error")
+}
+
+func TestRecentErrorsHandler(t *testing.T) {
+	SetRecentErrorsSize(2)
+	defer SetRecentErrorsSize(DefaultRecentErrorsSize)
+
+	RecordRecent(nil)
+	RecordRecent(New("plain failure"))
+	RecordRecent(WithHTTPStatus(New("rate limited"), http.StatusTooManyRequests))
+	RecordRecent(WithGrpcStatus(New("no such appointment"), codes.NotFound))
+
+	rec := httptest.NewRecorder()
+	RecentErrorsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz/errors", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var s RecentErrors
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s), "Expected the snapshot as JSON")
+	require.Len(t, s.Errors, 2, "Expected only as many errors as the buffer holds")
+	assert.Contains(t, s.Errors[0].Message, "no such appointment", "Expected the newest error first")
+	assert.Equal(t, codes.NotFound.String(), s.Errors[0].Code, "Expected the gRPC code name")
+	assert.Contains(t, s.Errors[1].Message, "rate limited")
+	assert.Equal(t, "429", s.Errors[1].Code, "Expected the HTTP status")
+	assert.False(t, s.Errors[0].Time.IsZero(), "Expected the time the error was recorded")
+	assert.NotZero(t, s.CountsByCode[codes.Unknown.String()], "Expected errors without a code counted as Unknown")
+}
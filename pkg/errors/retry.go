@@ -0,0 +1,141 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// WithRetryAfter annotates err with how long the caller should wait before retrying, e.g. when
+// it has been rate limited. It is written as the Retry-After header by ToHTTP, and as RetryInfo
+// details on the gRPC status of the error.
+// If err is nil, WithRetryAfter returns nil.
+func WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	w := &withRetryAfter{
+		cause:      err,
+		retryAfter: d,
+	}
+
+	// if the error already has a gRPC status, the retry info is added to it here, otherwise
+	// it is added when WithGrpcStatus is applied
+	var g interface{ GRPCStatus() *status.Status }
+	if As(err, &g) {
+		return &withRetryAfterStatus{
+			withRetryAfter: w,
+			grpcStatus:     statusWithRetryInfo(g.GRPCStatus(), d),
+		}
+	}
+
+	return w
+}
+
+// RetryAfter returns how long the caller should wait before retrying err, and whether it was set.
+// It is read from WithRetryAfter annotations and from the RetryInfo details of gRPC statuses.
+func RetryAfter(err error) (time.Duration, bool) {
+	var w *withRetryAfter
+	if As(err, &w) {
+		return w.retryAfter, true
+	}
+
+	var g interface{ GRPCStatus() *status.Status }
+	if As(err, &g) {
+		for _, detail := range g.GRPCStatus().Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok {
+				d, err := ptypes.Duration(info.GetRetryDelay())
+				if err == nil {
+					return d, true
+				}
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// setRetryAfterHeader sets the Retry-After header in whole seconds, rounded up, if err has a retry after
+func setRetryAfterHeader(err error, w http.ResponseWriter) {
+	if d, ok := RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+}
+
+// retryAfterFromHeader reads the Retry-After header of the response, which may be a number of
+// seconds or a date
+func retryAfterFromHeader(resp *http.Response) (time.Duration, bool) {
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(h); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// statusWithRetryInfo returns a copy of st with RetryInfo details for d. If the details can't be
+// added st is returned as it is.
+func statusWithRetryInfo(st *status.Status, d time.Duration) *status.Status {
+	withInfo, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(d),
+	})
+	if err != nil {
+		return st
+	}
+	return withInfo
+}
+
+type withRetryAfter struct {
+	cause      error
+	retryAfter time.Duration
+}
+
+func (w *withRetryAfter) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withRetryAfter) Cause() error {
+	return w.cause
+}
+
+func (w *withRetryAfter) Unwrap() error {
+	return w.cause
+}
+
+func (w *withRetryAfter) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\n", w.Cause())
+			fmt.Fprintf(s, "retry after %s", w.retryAfter)
+			return
+		}
+		fallthrough
+	case 's', 'q':
+		io.WriteString(s, w.Error())
+	}
+}
+
+// withRetryAfterStatus is a retry after annotation on an error that has a gRPC status,
+// it keeps the status recognisable with the retry info added
+type withRetryAfterStatus struct {
+	*withRetryAfter
+	grpcStatus *status.Status
+}
+
+func (w *withRetryAfterStatus) GRPCStatus() *status.Status {
+	return w.grpcStatus
+}
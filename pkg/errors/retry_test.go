@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryInfoOf returns the retry delay in the RetryInfo details of the status of err
+func retryInfoOf(t *testing.T, err error) (time.Duration, bool) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "Expected a status error")
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			d, err := ptypes.Duration(info.GetRetryDelay())
+			require.NoError(t, err)
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := WithRetryAfter(New("rate limited"), 1500*time.Millisecond)
+	d, ok := RetryAfter(err)
+	assert.True(t, ok, "Expected the retry after to be set")
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	d, ok = RetryAfter(Wrap(err, "call failed"))
+	assert.True(t, ok, "Expected the retry after of a wrapped error")
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	_, ok = RetryAfter(New("rate limited"))
+	assert.False(t, ok, "Expected no retry after when none was set")
+	assert.Nil(t, WithRetryAfter(nil, time.Second))
+}
+
+func TestRetryAfterRetryInfo(t *testing.T) {
+	before := WithGrpcStatus(WithRetryAfter(New("rate limited"), 3*time.Second), codes.ResourceExhausted)
+	d, ok := retryInfoOf(t, before)
+	assert.True(t, ok, "Expected RetryInfo details when the status is added after the retry after")
+	assert.Equal(t, 3*time.Second, d)
+
+	after := WithRetryAfter(WithGrpcStatus(New("rate limited"), codes.ResourceExhausted), 4*time.Second)
+	d, ok = retryInfoOf(t, after)
+	assert.True(t, ok, "Expected RetryInfo details when the retry after is added to a status")
+	assert.Equal(t, 4*time.Second, d)
+	assert.Equal(t, codes.ResourceExhausted, GrpcCode(after), "Expected the code of the status to be kept")
+
+	// the retry after is read back from a status that crossed the wire
+	st, _ := status.FromError(after)
+	d, ok = RetryAfter(FromGrpcError(st.Err()))
+	assert.True(t, ok, "Expected the retry after from the RetryInfo details")
+	assert.Equal(t, 4*time.Second, d)
+}
+
+func TestSetRetryAfterHeader(t *testing.T) {
+	rec := httptest.NewRecorder()
+	setRetryAfterHeader(WithRetryAfter(New("rate limited"), 1500*time.Millisecond), rec)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"), "Expected whole seconds, rounded up")
+
+	rec = httptest.NewRecorder()
+	setRetryAfterHeader(New("rate limited"), rec)
+	assert.Empty(t, rec.Header().Get("Retry-After"), "Expected no header without a retry after")
+}
+
+func TestRetryAfterFromHeader(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := retryAfterFromHeader(resp)
+	assert.False(t, ok, "Expected no retry after without the header")
+
+	resp.Header.Set("Retry-After", "120")
+	d, ok := retryAfterFromHeader(resp)
+	assert.True(t, ok, "Expected the retry after in seconds")
+	assert.Equal(t, 2*time.Minute, d)
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	d, ok = retryAfterFromHeader(resp)
+	assert.True(t, ok, "Expected the retry after as an HTTP date")
+	assert.InDelta(t, time.Hour.Seconds(), d.Seconds(), 2, "Expected the time until the date")
+
+	resp.Header.Set("Retry-After", "soon")
+	_, ok = retryAfterFromHeader(resp)
+	assert.False(t, ok, "Expected no retry after from an invalid header")
+}
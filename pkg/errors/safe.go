@@ -0,0 +1,101 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/status"
+)
+
+// genericMessage is sent to clients in place of the message of errors that have no safe message
+const genericMessage = "internal error"
+
+// WithSafeMessage annotates err with a message that is safe to show to clients, such as
+// "the appointment could not be found". ToGrpcError and ToHTTP send only the safe message,
+// while the error's own message and cause chain are kept for logs.
+// If err is nil, WithSafeMessage returns nil.
+func WithSafeMessage(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &withSafeMessage{
+		cause: err,
+		msg:   message,
+	}
+}
+
+// SafeMessage returns the safe message of the first error in err's chain that has one,
+// and whether there was one.
+func SafeMessage(err error) (string, bool) {
+	var w *withSafeMessage
+	if As(err, &w) {
+		return w.msg, true
+	}
+	return "", false
+}
+
+// ToGrpcError returns the error to send to a gRPC client for err. It is a status with the
+// code of err's chain and its safe message. If err has no safe message, the message is replaced
+// by a generic one, so internal details never reach clients: a status err already has keeps its
+// code and details, and any other error is replaced by a status with the code of its chain.
+// Retry info is kept.
+// If err is nil, ToGrpcError returns nil.
+func ToGrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg, ok := SafeMessage(err)
+	if !ok {
+		var g interface{ GRPCStatus() *status.Status }
+		if As(err, &g) {
+			// the status message of WithGrpcStatus is the error's own, with its cause chain
+			p := g.GRPCStatus().Proto()
+			p.Message = genericMessage
+			return status.FromProto(p).Err()
+		}
+		msg = genericMessage
+	}
+
+	st := status.New(GrpcCode(err), msg)
+	if d, ok := RetryAfter(err); ok {
+		st = statusWithRetryInfo(st, d)
+	}
+	return st.Err()
+}
+
+type withSafeMessage struct {
+	cause error
+	msg   string
+}
+
+func (w *withSafeMessage) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withSafeMessage) Cause() error {
+	return w.cause
+}
+
+func (w *withSafeMessage) Unwrap() error {
+	return w.cause
+}
+
+func (w *withSafeMessage) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\n", w.Cause())
+			fmt.Fprintf(s, "safe message: %s", w.msg)
+			return
+		}
+		fallthrough
+	case 's', 'q':
+		io.WriteString(s, w.Error())
+	}
+}
+
+// safeBody is the http response body of errors with a safe message
+type safeBody struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,45 @@
+package errors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToGrpcErrorSafeMessage(t *testing.T) {
+	err := WithGrpcStatus(WithSafeMessage(New("appointment 42 not in table appts"), "the appointment could not be found"), codes.NotFound)
+
+	st, ok := status.FromError(ToGrpcError(err))
+	require.True(t, ok, "Expected a status error")
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "the appointment could not be found", st.Message(), "Expected only the safe message")
+}
+
+func TestToGrpcErrorWrappedStatus(t *testing.T) {
+	cause := New("pq: connection refused to db-internal:5432")
+	err := WithRetryAfter(WithGrpcStatus(cause, codes.Unavailable), 5*time.Second)
+
+	st, ok := status.FromError(ToGrpcError(err))
+	require.True(t, ok, "Expected a status error")
+	assert.Equal(t, codes.Unavailable, st.Code(), "Expected the code of the status to be kept")
+	assert.Equal(t, genericMessage, st.Message(), "Expected the internal message to be replaced")
+	assert.NotContains(t, st.Message(), "db-internal")
+
+	d, ok := RetryAfter(st.Err())
+	assert.True(t, ok, "Expected the retry info to be kept")
+	assert.Equal(t, 5*time.Second, d)
+	assert.Equal(t, Fingerprint(cause), FingerprintFromStatus(st), "Expected the details to be kept")
+}
+
+func TestToGrpcErrorPlainError(t *testing.T) {
+	st, ok := status.FromError(ToGrpcError(New("open /etc/secrets/db.json: permission denied")))
+	require.True(t, ok, "Expected a status error")
+	assert.Equal(t, codes.Unknown, st.Code())
+	assert.Equal(t, genericMessage, st.Message(), "Expected a generic message")
+
+	assert.Nil(t, ToGrpcError(nil))
+}
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
 // Frame represents a program counter inside a stack frame.
@@ -160,6 +161,45 @@ func (s *stack) StackTrace() StackTrace {
 	return f
 }
 
+// stackTracer is implemented by errors that recorded a stack trace
+type stackTracer interface {
+	StackTrace() StackTrace
+}
+
+// captureDuplicateStacks is 1 when Wrap and WithStack record a stack trace even though the
+// error they annotate already has one
+var captureDuplicateStacks int32
+
+// SetCaptureDuplicateStacks changes whether Wrap, Wrapf, WithStack and Builder.Err record a stack trace
+// when the error they annotate already has one in its chain. By default they don't, since the stack recorded
+// closest to the origin of the error is the most useful, and every extra stack adds its full size to the
+// formatted error.
+func SetCaptureDuplicateStacks(capture bool) {
+	var v int32
+	if capture {
+		v = 1
+	}
+	atomic.StoreInt32(&captureDuplicateStacks, v)
+}
+
+// needsStack reports whether a stack trace should be recorded when annotating err
+func needsStack(err error) bool {
+	return atomic.LoadInt32(&captureDuplicateStacks) == 1 || StackTraceOf(err) == nil
+}
+
+// StackTraceOf returns the deepest stack trace in err's chain, the one recorded closest to where the
+// error originated, or nil if no error in the chain recorded one. The chain is followed through both
+// Unwrap and Cause, so stacks wrapped with fmt.Errorf's %w are found too.
+func StackTraceOf(err error) StackTrace {
+	var st StackTrace
+	for ; err != nil; err = next(err) {
+		if s, ok := err.(stackTracer); ok {
+			st = s.StackTrace()
+		}
+	}
+	return st
+}
+
 func callers() *stack {
 	const depth = 32
 	var pcs [depth]uintptr
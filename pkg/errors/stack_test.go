@@ -0,0 +1,22 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackTraceOf(t *testing.T) {
+	origin := New("connection refused")
+	st := StackTraceOf(origin)
+	require.NotEmpty(t, st, "Expected the stack recorded by New")
+
+	assert.Equal(t, st, StackTraceOf(Wrap(origin, "dial failed")), "Expected the deepest stack through Cause")
+	assert.Equal(t, st, StackTraceOf(fmt.Errorf("dial failed: %w", origin)), "Expected the deepest stack through Unwrap")
+	assert.Equal(t, st, StackTraceOf(Wrap(fmt.Errorf("dial failed: %w", origin), "call failed")),
+		"Expected the deepest stack through Cause and Unwrap")
+	assert.Nil(t, StackTraceOf(fmt.Errorf("no stack")), "Expected no stack when none was recorded")
+	assert.Nil(t, StackTraceOf(nil))
+}
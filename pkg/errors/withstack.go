@@ -6,11 +6,15 @@ import (
 )
 
 // WithStack annotates err with a stack trace at the point WithStack was called.
+// If err already has a stack trace it is returned as it is, see SetCaptureDuplicateStacks.
 // If err is nil, WithStack returns nil.
 func WithStack(err error) error {
 	if err == nil {
 		return nil
 	}
+	if !needsStack(err) {
+		return err
+	}
 	return &withStack{
 		err,
 		callers(),
@@ -0,0 +1,85 @@
+package dialer
+
+import (
+	"context"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/uber/jaeger-lib/metrics"
+	"google.golang.org/grpc"
+)
+
+// deadlineMetrics are the deadline hygiene metrics reported for each dialed target
+type deadlineMetrics struct {
+	Missing     metrics.Counter `metric:"calls_without_deadline" help:"Number of gRPC client calls made without a context deadline"`
+	OverCeiling metrics.Counter `metric:"calls_over_deadline_ceiling" help:"Number of gRPC client calls made with a deadline further away than the configured ceiling"`
+}
+
+// deadlineAudit is the configuration of the deadline audit
+type deadlineAudit struct {
+	ceiling time.Duration
+	logger  *logging.Logger
+}
+
+// WithDeadlineAudit logs a warning for every call made on the connection without a context deadline, or with
+// a deadline further away than the ceiling, and counts them in the metrics set with WithMetrics, tagged by
+// target. Calls without a deadline can hang forever on a stuck server, so this surfaces them across services.
+// A ceiling of 0 only audits calls without a deadline. The audit runs after every other interceptor, so a
+// deadline set by one of them counts.
+func (b *Builder) WithDeadlineAudit(ceiling time.Duration, logger *logging.Logger) {
+	if logger == nil {
+		logger = logging.NewNopLogger()
+	}
+	b.deadlineAudit = &deadlineAudit{
+		ceiling: ceiling,
+		logger:  logger,
+	}
+}
+
+// GetDeadlineAudit returns the ceiling of the deadline audit, and whether the audit is enabled
+func (b *Builder) GetDeadlineAudit() (time.Duration, bool) {
+	if b.deadlineAudit == nil {
+		return 0, false
+	}
+	return b.deadlineAudit.ceiling, true
+}
+
+// auditInterceptors returns the interceptors auditing the deadlines of calls to addr
+func (b *Builder) auditInterceptors(addr string) (grpc.UnaryClientInterceptor, grpc.StreamClientInterceptor) {
+	a := b.deadlineAudit
+	logger := a.logger.NewChild(nil, logging.String("grpc.target", addr))
+
+	var m deadlineMetrics
+	metrics.Init(&m, b.GetMetrics(), map[string]string{"target": addr})
+
+	audit := func(ctx context.Context, method string) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			m.Missing.Inc(1)
+			logger.Warn("grpc call made without a deadline", logging.String("grpc.method", method))
+			return
+		}
+
+		if remaining := time.Until(deadline); a.ceiling > 0 && remaining > a.ceiling {
+			m.OverCeiling.Inc(1)
+			logger.Warn(
+				"grpc call made with a deadline over the ceiling",
+				logging.String("grpc.method", method),
+				logging.Duration("grpc.deadline", remaining),
+				logging.Duration("grpc.deadlineCeiling", a.ceiling),
+			)
+		}
+	}
+
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		audit(ctx, method)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		audit(ctx, method)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	return unary, stream
+}
@@ -0,0 +1,56 @@
+package dialer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/matryer/is"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestDeadlineAudit(t *testing.T) {
+	is := is.New(t)
+
+	logger, logs := logging.NewObservedLogger(logging.InfoLevel)
+	factory := metricstest.NewFactory(0)
+	defer factory.Stop()
+
+	b := &Builder{}
+	b.WithMetrics(factory)
+	b.WithDeadlineAudit(time.Second, logger)
+	is.NoErr(b.SetConnInfo("localhost", "1234", false))
+
+	ceiling, ok := b.Clone().GetDeadlineAudit()
+	is.True(ok)
+	is.Equal(ceiling, time.Second)
+
+	cc, err := b.Dial(context.Background())
+	is.NoErr(err)
+	defer cc.Close()
+	client := grpc_health_v1.NewHealthClient(cc)
+
+	// Nothing is listening, so the calls fail, but only after they are audited
+	client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+
+	is.Equal(logs.FilterMessage("grpc call made without a deadline").Len(), 1)
+	over := logs.FilterMessage("grpc call made with a deadline over the ceiling").All()
+	is.Equal(len(over), 1)
+	is.Equal(over[0].ContextMap()["grpc.method"], "/grpc.health.v1.Health/Check")
+	is.Equal(over[0].ContextMap()["grpc.target"], "localhost:1234")
+
+	factory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "calls_without_deadline", Tags: map[string]string{"target": "localhost:1234"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "calls_over_deadline_ceiling", Tags: map[string]string{"target": "localhost:1234"}, Value: 1},
+	)
+}
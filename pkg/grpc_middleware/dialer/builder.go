@@ -151,6 +151,35 @@ func (b *Builder) GetClientTransportCredentials() []tls.Certificate {
 	return b.tlsConfig.Certificates
 }
 
+// WithServerNameOverride sets the name used to verify the server certificate. Use this when
+// dialing through a load balancer or IP target whose certificate SANs differ from the dialed host,
+// rather than skipping verification altogether.
+func (b *Builder) WithServerNameOverride(serverName string) {
+	if b.tlsConfig == nil {
+		b.tlsConfig = &tls.Config{}
+	}
+	b.tlsConfig.ServerName = serverName
+}
+
+// GetServerNameOverride returns the server name used to verify the server certificate, if set
+func (b *Builder) GetServerNameOverride() string {
+	if b.tlsConfig == nil {
+		return ""
+	}
+	return b.tlsConfig.ServerName
+}
+
+// WithAuthority sets the :authority pseudo-header sent with every call on the connection,
+// in place of the dialed host.
+func (b *Builder) WithAuthority(authority string) {
+	b.authority = authority
+}
+
+// GetAuthority returns the configured authority, if set
+func (b *Builder) GetAuthority() string {
+	return b.authority
+}
+
 // WithClientCredentials builds transport credentials for a gRPC client
 func (b *Builder) WithClientCredentials(c credentials.PerRPCCredentials) {
 	b.credentials = c
@@ -166,24 +195,31 @@ func (b *Builder) GetClientCredentials() credentials.PerRPCCredentials {
 func (b *Builder) SetConnInfo(dns, port string, tls bool) error {
 	u, err := url.Parse(dns)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("invalid dns: %s", dns))
+		return newDialError(ErrInvalidTarget, err, fmt.Sprintf("invalid dns: %s", dns))
 	}
 	if u.Scheme != "" {
 		// This will cause the connection to fail silently with a cryptic "context.Deadline exceeded" so we validate for it here.
-		return errors.New(fmt.Sprintf("grpc connection dns must not contain a scheme/protocol: %s", dns))
+		return newDialError(ErrInvalidTarget, nil, fmt.Sprintf("grpc connection dns must not contain a scheme/protocol: %s", dns))
 	}
 	b.dns = &dns
 
 	i, err := strconv.ParseUint(port, 10, 16)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("invalid port number: %s", port))
+		return newDialError(ErrInvalidTarget, err, fmt.Sprintf("invalid port number: %s", port))
 	}
 	p := uint16(i)
 	b.port = &p
+	b.discovery = nil
 
 	return nil
 }
 
+// setDiscoveryTarget sets the service whose discovered instances are dialed, in place of the dns and port
+func (b *Builder) setDiscoveryTarget(t *discoveryTarget) {
+	b.discovery = t
+	b.dns, b.port = nil, nil
+}
+
 // GetConnInfo returns the dns and port that were set, and errors if either
 // were null, allowing verfication prior to attempting the connection
 func (b *Builder) GetConnInfo() (dns string, port uint16, err error) {
@@ -199,35 +235,48 @@ func (b *Builder) GetConnInfo() (dns string, port uint16, err error) {
 func (b *Builder) SetConnectionAddress(addr string) error {
 	cfg, err := ReadConnectionAddress(addr)
 	if err != nil {
-		return err
-	}
-	cfg.ApplyToBuilder(b)
-	if err != nil {
-		return err
+		if errors.Is(err, ErrInvalidTarget) {
+			return err
+		}
+		return newDialError(ErrInvalidTarget, err, "invalid connection address")
 	}
-	return nil
+	return cfg.ApplyToBuilder(b)
 }
 
 // Dial returns the client connection to the server.
 // context is ignored unless builder is set to block using WithBlock(true)
+// The error returned matches ErrInvalidTarget, ErrTLSConfig, ErrTimeout or ErrUnavailable with errors.Is
 func (b *Builder) Dial(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	dns, port, err := b.GetConnInfo()
-	if err != nil {
-		return nil, fmt.Errorf("target connection parameter missing: dns and/or port not set")
-	}
+	var addr string
+	if b.discovery != nil {
+		addr = b.discovery.dialTarget()
 
-	addr := net.JoinHostPort(dns, strconv.Itoa(int(port)))
+		discoveryOpts, err := b.discoveryDialOptions(b.discovery)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(discoveryOpts, opts...)
+	} else {
+		dns, port, err := b.GetConnInfo()
+		if err != nil {
+			return nil, newDialError(ErrInvalidTarget, err, "target connection parameter missing: dns and/or port not set")
+		}
+
+		addr = net.JoinHostPort(dns, strconv.Itoa(int(port)))
+	}
 
-	options := b.joinOptions(opts...)
+	options := b.joinOptions(addr, opts...)
 
+	ctx, finish := b.startDial(ctx, addr)
 	cc, err := grpc.DialContext(ctx, addr, options...)
+	finish(err)
 
 	if err != nil {
-		return nil, fmt.Errorf("unable to connect to client %s. error = %+v", addr, err)
+		return nil, connectError(ctx, addr, err)
 	}
 	return cc, nil
 }
@@ -245,13 +294,19 @@ func (b *Builder) DialAddr(ctx context.Context, addr string, opts ...grpc.DialOp
 // Clone the builder and return copy
 func (b *Builder) Clone() *Builder {
 	c := &Builder{
-		options:         make([]grpc.DialOption, len(b.options)),
-		enabledBlocking: b.enabledBlocking,
-		connectParams:   b.connectParams,
-		credentials:     b.credentials,
-		keepAliveParams: b.keepAliveParams,
-		uinterceptors:   make([]grpc.UnaryClientInterceptor, len(b.uinterceptors)),
-		sinterceptors:   make([]grpc.StreamClientInterceptor, len(b.sinterceptors)),
+		options:          make([]grpc.DialOption, len(b.options)),
+		enabledBlocking:  b.enabledBlocking,
+		connectParams:    b.connectParams,
+		credentials:      b.credentials,
+		keepAliveParams:  b.keepAliveParams,
+		authority:        b.authority,
+		tracer:           b.tracer,
+		metricsFactory:   b.metricsFactory,
+		deadlineAudit:    b.deadlineAudit,
+		discovery:        b.discovery,
+		discoveryOptions: b.discoveryOptions,
+		uinterceptors:    make([]grpc.UnaryClientInterceptor, len(b.uinterceptors)),
+		sinterceptors:    make([]grpc.StreamClientInterceptor, len(b.sinterceptors)),
 	}
 	copy(c.options, b.options)
 	copy(c.uinterceptors, b.uinterceptors)
@@ -269,20 +324,32 @@ func (b *Builder) Clone() *Builder {
 		c.tlsConfig = &tls.Config{
 			Certificates: make([]tls.Certificate, len(b.tlsConfig.Certificates)),
 			RootCAs:      b.tlsConfig.RootCAs,
+			ServerName:   b.tlsConfig.ServerName,
 		}
 		copy(c.tlsConfig.Certificates, b.tlsConfig.Certificates)
 	}
 	return c
 }
 
-func (b *Builder) joinOptions(opts ...grpc.DialOption) []grpc.DialOption {
+func (b *Builder) joinOptions(addr string, opts ...grpc.DialOption) []grpc.DialOption {
 	var options []grpc.DialOption
 	if b.enabledBlocking {
 		options = append(options, grpc.WithBlock())
 	}
 	options = append(options, grpc.WithKeepaliveParams(b.keepAliveParams))
-	options = append(options, grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(b.uinterceptors...)))
-	options = append(options, grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(b.sinterceptors...)))
+
+	uinterceptors, sinterceptors := b.uinterceptors, b.sinterceptors
+	if b.deadlineAudit != nil {
+		unary, stream := b.auditInterceptors(addr)
+		uinterceptors = append(uinterceptors[:len(uinterceptors):len(uinterceptors)], unary)
+		sinterceptors = append(sinterceptors[:len(sinterceptors):len(sinterceptors)], stream)
+	}
+	options = append(options, grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(uinterceptors...)))
+	options = append(options, grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(sinterceptors...)))
+
+	if b.authority != "" {
+		options = append(options, grpc.WithAuthority(b.authority))
+	}
 
 	if b.tlsConfig != nil {
 		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(b.tlsConfig)))
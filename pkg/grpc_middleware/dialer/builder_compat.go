@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-lib/metrics"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
@@ -18,16 +20,22 @@ const (
 )
 
 type Builder struct {
-	options         []grpc.DialOption
-	enabledBlocking bool
-	connectParams   grpc.ConnectParams
-	keepAliveParams keepalive.ClientParameters
-	credentials     credentials.PerRPCCredentials
-	uinterceptors   []grpc.UnaryClientInterceptor
-	sinterceptors   []grpc.StreamClientInterceptor
-	tlsConfig       *tls.Config
-	dns             *string
-	port            *uint16
+	options          []grpc.DialOption
+	enabledBlocking  bool
+	connectParams    grpc.ConnectParams
+	keepAliveParams  keepalive.ClientParameters
+	credentials      credentials.PerRPCCredentials
+	uinterceptors    []grpc.UnaryClientInterceptor
+	sinterceptors    []grpc.StreamClientInterceptor
+	tlsConfig        *tls.Config
+	authority        string
+	tracer           opentracing.Tracer
+	metricsFactory   metrics.Factory
+	deadlineAudit    *deadlineAudit
+	dns              *string
+	port             *uint16
+	discovery        *discoveryTarget
+	discoveryOptions DiscoveryOptions
 }
 
 func (b *Builder) WithFS(fs interface{}) {
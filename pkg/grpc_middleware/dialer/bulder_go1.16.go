@@ -10,6 +10,8 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-lib/metrics"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
@@ -20,17 +22,23 @@ const (
 )
 
 type Builder struct {
-	options         []grpc.DialOption
-	enabledBlocking bool
-	connectParams   grpc.ConnectParams
-	keepAliveParams keepalive.ClientParameters
-	credentials     credentials.PerRPCCredentials
-	uinterceptors   []grpc.UnaryClientInterceptor
-	sinterceptors   []grpc.StreamClientInterceptor
-	tlsConfig       *tls.Config
-	dns             *string
-	port            *uint16
-	fs              fs.FS
+	options          []grpc.DialOption
+	enabledBlocking  bool
+	connectParams    grpc.ConnectParams
+	keepAliveParams  keepalive.ClientParameters
+	credentials      credentials.PerRPCCredentials
+	uinterceptors    []grpc.UnaryClientInterceptor
+	sinterceptors    []grpc.StreamClientInterceptor
+	tlsConfig        *tls.Config
+	authority        string
+	tracer           opentracing.Tracer
+	metricsFactory   metrics.Factory
+	deadlineAudit    *deadlineAudit
+	dns              *string
+	port             *uint16
+	discovery        *discoveryTarget
+	discoveryOptions DiscoveryOptions
+	fs               fs.FS
 }
 
 // WithFS will set the filesystem to use when loading resouces. If not set will fallback to using os.Open
@@ -62,4 +70,4 @@ func readFile(b *Builder, filename string) ([]byte, error) {
 
 func dirFS(path string) fs.FS {
 	return os.DirFS(path)
-}
\ No newline at end of file
+}
@@ -86,6 +86,18 @@ func TestApplyBuilder(t *testing.T) {
 	tlsConfig, err = cfg.loadTLS(nil)
 	is.NoErr(err)
 	is.Equal(tlsConfig.InsecureSkipVerify, true)
+
+	// tls string with server_name should parse and apply to the builder
+	cfg, err = ReadConnectionAddress("tls://10.0.0.12:1234?server_name=example.dev.caring.com")
+	is.NoErr(err)
+	is.Equal(cfg.String(), "tls://10.0.0.12:1234?server_name=example.dev.caring.com")
+	tlsConfig, err = cfg.loadTLS(nil)
+	is.NoErr(err)
+	is.Equal(tlsConfig.ServerName, "example.dev.caring.com")
+	b := &Builder{}
+	is.NoErr(cfg.ApplyToBuilder(b))
+	is.Equal(b.GetServerNameOverride(), "example.dev.caring.com")
+	is.Equal(b.Clone().GetServerNameOverride(), "example.dev.caring.com")
 }
 
 func TestLoadCerts(t *testing.T) {
@@ -21,20 +21,28 @@ type ConnectionAddress struct {
 	clientKey  string
 	tokenAuth  string
 	basicAuth  string
+	serverName string
 	disableTLS bool
 	skipVerify bool
+	discovery  *discoveryTarget
 }
 
 // ReadConnectionAddress reads connection string from provided env variable name and returns config.
 // If there are tls options the ConnectionAddress value can be used to generate the tls.ConnectionAddress
 // the format is tls://hostname:port (or tcp://hostname:port if developing locally without TLS)
 // Optional TLS parameters:
-//   skip_verify=true             ignore server CA verification.
-//   ca_file=./filename.pem       CA of service for verification.
-//   client_cert=./filename.pem   Client certificate to use for authentication.
-//   client_key=./filename.pem    Client private key to use for authentication.
-//   basic_auth=user:pass         Username and Password for basic authentication.
-//   token_auth=token             Bearer token for token authentication.
+//
+//	skip_verify=true             ignore server CA verification.
+//	ca_file=./filename.pem       CA of service for verification.
+//	client_cert=./filename.pem   Client certificate to use for authentication.
+//	client_key=./filename.pem    Client private key to use for authentication.
+//	basic_auth=user:pass         Username and Password for basic authentication.
+//	token_auth=token             Bearer token for token authentication.
+//	server_name=name             Name used to verify the server certificate when it differs from the dialed host.
+//
+// The instances of a service can be discovered rather than dialed through a load balancer, with
+// awssd://namespace/service for AWS Cloud Map or consul://service for Consul. The connection follows instances
+// as they change, see DiscoveryOptions. These use TLS with the same options unless disable_tls=true is set.
 // NOTE: The connection process is non-blocking so a timeout is not needed. To force a blocking add grpc.Blocking() to the opts
 func ReadConnectionAddress(addr string) (*ConnectionAddress, error) {
 	var c ConnectionAddress
@@ -62,20 +70,27 @@ func ReadConnectionAddress(addr string) (*ConnectionAddress, error) {
 		return nil, errors.Wrap(err, "connection string is malformed")
 	}
 
-	c.host = u.Hostname()
-	if c.host == "" {
-		return nil, errors.Wrap(err, "connection string has no hostname")
-	}
+	if u.Scheme == SchemeCloudMap || u.Scheme == SchemeConsul {
+		if c.discovery, err = readDiscoveryTarget(u); err != nil {
+			return nil, err
+		}
+		c.disableTLS = u.Query().Get("disable_tls") == "true"
+	} else {
+		c.host = u.Hostname()
+		if c.host == "" {
+			return nil, errors.New("connection string has no hostname")
+		}
 
-	switch u.Scheme {
-	case "tcp", "http":
-		c.port, c.disableTLS = "80", true
-	case "tls", "https":
-		c.port, c.disableTLS = "443", false
-	}
+		switch u.Scheme {
+		case "tcp", "http":
+			c.port, c.disableTLS = "80", true
+		case "tls", "https":
+			c.port, c.disableTLS = "443", false
+		}
 
-	if port := u.Port(); port != "" {
-		c.port = port
+		if port := u.Port(); port != "" {
+			c.port = port
+		}
 	}
 
 	if !c.disableTLS {
@@ -84,6 +99,7 @@ func ReadConnectionAddress(addr string) (*ConnectionAddress, error) {
 		} else {
 			c.caFile = u.Query().Get("ca_file")
 		}
+		c.serverName = u.Query().Get("server_name")
 	}
 
 	return &c, nil
@@ -99,6 +115,9 @@ func (c *ConnectionAddress) String() string {
 		scheme = "tcp"
 	}
 	query := make(url.Values)
+	if c.discovery != nil && c.disableTLS {
+		query.Set("disable_tls", "true")
+	}
 	if c.skipVerify {
 		query.Set("skip_verify", "true")
 	}
@@ -117,12 +136,18 @@ func (c *ConnectionAddress) String() string {
 	if c.tokenAuth != "" {
 		query.Set("token_auth", c.tokenAuth)
 	}
+	if c.serverName != "" {
+		query.Set("server_name", c.serverName)
+	}
 
 	qry := ""
 	if len(query) > 0 {
 		qry = "?" + query.Encode()
 	}
 
+	if c.discovery != nil {
+		return c.discovery.String() + qry
+	}
 	return fmt.Sprintf("%s://%s:%s%s", scheme, c.host, c.port, qry)
 }
 
@@ -134,7 +159,10 @@ func (c *ConnectionAddress) loadTLS(b *Builder) (*tls.Config, error) {
 		return nil, errors.Errorf("TLS is not enabled")
 	}
 
-	tlsConnectionAddress := tls.Config{InsecureSkipVerify: c.skipVerify}
+	tlsConnectionAddress := tls.Config{
+		InsecureSkipVerify: c.skipVerify,
+		ServerName:         c.serverName,
+	}
 
 	if c.caFile != "" {
 		pemServerCA, err := readFile(b, c.caFile)
@@ -174,17 +202,21 @@ func (c *ConnectionAddress) ApplyToBuilder(cb *Builder) error {
 		return errors.New("connection builder is nil")
 	}
 
-	err := cb.SetConnInfo(c.host, c.port, !c.disableTLS)
-	if err != nil {
+	if c.discovery != nil {
+		cb.setDiscoveryTarget(c.discovery)
+	} else if err := cb.SetConnInfo(c.host, c.port, !c.disableTLS); err != nil {
 		return errors.Wrap(err, "unable to set connect options")
 	}
 	if !c.disableTLS {
 		tlsConnectionAddress, err := c.loadTLS(cb)
 		if err != nil {
-			return errors.Wrap(err, "unable to read tls options")
+			return newDialError(ErrTLSConfig, err, "unable to read tls options")
 		}
 		cb.WithServerTransportCredentials(tlsConnectionAddress.InsecureSkipVerify, tlsConnectionAddress.RootCAs)
 		cb.WithClientTransportCredentials(tlsConnectionAddress.Certificates...)
+		if tlsConnectionAddress.ServerName != "" {
+			cb.WithServerNameOverride(tlsConnectionAddress.ServerName)
+		}
 	}
 	if c.basicAuth != "" {
 		cb.WithClientCredentials(authorization{authType: basicAuth, content: c.basicAuth})
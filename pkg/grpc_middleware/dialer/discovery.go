@@ -0,0 +1,345 @@
+package dialer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	"github.com/aws/aws-sdk-go/service/servicediscovery/servicediscoveryiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// The connection address schemes of the service discovery backends
+const (
+	// SchemeCloudMap discovers the instances of a Cloud Map service, awssd://namespace/service
+	SchemeCloudMap = "awssd"
+	// SchemeConsul discovers the healthy instances of a Consul service, consul://service
+	SchemeConsul = "consul"
+)
+
+// DefaultDiscoveryInterval is how often the instances of a discovered service are looked up
+const DefaultDiscoveryInterval = 30 * time.Second
+
+// DiscoveryOptions configures how the instances of services dialed with an awssd:// or consul:// connection
+// address are looked up
+type DiscoveryOptions struct {
+	// How often the instances are looked up, so the connection follows instances being replaced. Defaults to 30s
+	Interval time.Duration
+	// The Cloud Map client. Defaults to one configured from the environment
+	CloudMap servicediscoveryiface.ServiceDiscoveryAPI
+	// The address of the Consul agent. Defaults to CONSUL_HTTP_ADDR, or 127.0.0.1:8500 if it isn't set
+	ConsulAddr string
+	// The ACL token sent to the Consul agent. Defaults to CONSUL_HTTP_TOKEN
+	ConsulToken string
+	// The client requests to the Consul agent are sent with. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// WithDiscoveryOptions sets how the instances of discovered services are looked up
+func (b *Builder) WithDiscoveryOptions(opts DiscoveryOptions) {
+	b.discoveryOptions = opts
+}
+
+// GetDiscoveryOptions returns the current discovery options
+func (b *Builder) GetDiscoveryOptions() DiscoveryOptions {
+	return b.discoveryOptions
+}
+
+// discoveryTarget is a service whose instances are discovered rather than dialed by hostname
+type discoveryTarget struct {
+	scheme    string
+	namespace string
+	service   string
+}
+
+// readDiscoveryTarget reads the service of an awssd:// or consul:// connection address
+func readDiscoveryTarget(u *url.URL) (*discoveryTarget, error) {
+	switch u.Scheme {
+	case SchemeCloudMap:
+		service := strings.Trim(u.Path, "/")
+		if u.Host == "" || service == "" || strings.Contains(service, "/") {
+			return nil, errors.Errorf("cloud map connection string must be awssd://namespace/service")
+		}
+		return &discoveryTarget{scheme: SchemeCloudMap, namespace: u.Host, service: service}, nil
+	case SchemeConsul:
+		if u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return nil, errors.Errorf("consul connection string must be consul://service")
+		}
+		return &discoveryTarget{scheme: SchemeConsul, service: u.Host}, nil
+	}
+	return nil, errors.Errorf("unknown service discovery scheme: %s", u.Scheme)
+}
+
+func (t *discoveryTarget) String() string {
+	if t.scheme == SchemeCloudMap {
+		return fmt.Sprintf("%s://%s/%s", t.scheme, t.namespace, t.service)
+	}
+	return fmt.Sprintf("%s://%s", t.scheme, t.service)
+}
+
+// dialTarget is the target the connection is dialed with, resolved by the discovery resolver
+func (t *discoveryTarget) dialTarget() string {
+	if t.scheme == SchemeCloudMap {
+		return fmt.Sprintf("%s:///%s/%s", t.scheme, t.namespace, t.service)
+	}
+	return fmt.Sprintf("%s:///%s", t.scheme, t.service)
+}
+
+// serverName is the DNS name of the service, sent as the :authority of calls to its instances and verified
+// against their certificates
+func (t *discoveryTarget) serverName() string {
+	if t.scheme == SchemeCloudMap {
+		return t.service + "." + t.namespace
+	}
+	return t.service + ".service.consul"
+}
+
+// discoveryDialOptions returns the options that resolve the target's instances
+func (b *Builder) discoveryDialOptions(t *discoveryTarget) ([]grpc.DialOption, error) {
+	opts := b.discoveryOptions
+	if opts.Interval == 0 {
+		opts.Interval = DefaultDiscoveryInterval
+	}
+
+	var lookup lookupFunc
+	switch t.scheme {
+	case SchemeCloudMap:
+		if opts.CloudMap == nil {
+			ses, err := session.NewSession(&aws.Config{
+				CredentialsChainVerboseErrors: aws.Bool(true),
+			})
+			if err != nil {
+				return nil, errors.Wrap(err, "unable to create cloud map client")
+			}
+			opts.CloudMap = servicediscovery.New(ses)
+		}
+		lookup = cloudMapLookup(opts.CloudMap, t.namespace, t.service)
+	case SchemeConsul:
+		if opts.ConsulAddr == "" {
+			opts.ConsulAddr = os.Getenv("CONSUL_HTTP_ADDR")
+		}
+		if opts.ConsulAddr == "" {
+			opts.ConsulAddr = "127.0.0.1:8500"
+		}
+		if opts.ConsulToken == "" {
+			opts.ConsulToken = os.Getenv("CONSUL_HTTP_TOKEN")
+		}
+		if opts.HTTPClient == nil {
+			opts.HTTPClient = http.DefaultClient
+		}
+		lookup = consulLookup(opts.HTTPClient, opts.ConsulAddr, opts.ConsulToken, t.service)
+	}
+
+	builder := &discoveryBuilder{
+		scheme:   t.scheme,
+		lookup:   lookup,
+		interval: opts.Interval,
+	}
+	// an authority or server name that was set takes the place of the service's
+	if b.authority == "" && b.GetServerNameOverride() == "" {
+		builder.serverName = t.serverName()
+	}
+
+	return []grpc.DialOption{grpc.WithResolvers(builder)}, nil
+}
+
+// lookupFunc looks up the addresses of the healthy instances of a service
+type lookupFunc func(ctx context.Context) ([]string, error)
+
+// cloudMapLookup looks up the healthy instances of a Cloud Map service, by their IPv4 address and port attributes
+func cloudMapLookup(client servicediscoveryiface.ServiceDiscoveryAPI, namespace, service string) lookupFunc {
+	return func(ctx context.Context) ([]string, error) {
+		out, err := client.DiscoverInstancesWithContext(ctx, &servicediscovery.DiscoverInstancesInput{
+			NamespaceName: aws.String(namespace),
+			ServiceName:   aws.String(service),
+			HealthStatus:  aws.String(servicediscovery.HealthStatusFilterHealthy),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to discover cloud map instances")
+		}
+
+		var addrs []string
+		for _, inst := range out.Instances {
+			ip, port := inst.Attributes["AWS_INSTANCE_IPV4"], inst.Attributes["AWS_INSTANCE_PORT"]
+			if ip == nil || port == nil {
+				continue
+			}
+			addrs = append(addrs, net.JoinHostPort(*ip, *port))
+		}
+		return addrs, nil
+	}
+}
+
+// consulServiceEntry is the part of a Consul health endpoint entry the address of the instance is read from
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// consulLookup looks up the instances of a Consul service that pass their health checks
+func consulLookup(client *http.Client, addr, token, service string) lookupFunc {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	endpoint := strings.TrimSuffix(addr, "/") + "/v1/health/service/" + url.PathEscape(service) + "?passing=true"
+
+	return func(ctx context.Context) ([]string, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create consul request")
+		}
+		req = req.WithContext(ctx)
+		if token != "" {
+			req.Header.Set("X-Consul-Token", token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to discover consul instances")
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("unable to discover consul instances: %s", resp.Status)
+		}
+
+		var entries []consulServiceEntry
+		if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+			return nil, errors.Wrap(err, "unable to read consul instances")
+		}
+
+		addrs := make([]string, 0, len(entries))
+		for _, e := range entries {
+			host := e.Service.Address
+			if host == "" {
+				host = e.Node.Address
+			}
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(e.Service.Port)))
+		}
+		return addrs, nil
+	}
+}
+
+// discoveryBuilder builds the resolvers of connections to a discovered service
+type discoveryBuilder struct {
+	scheme     string
+	lookup     lookupFunc
+	interval   time.Duration
+	serverName string
+}
+
+func (b *discoveryBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discoveryResolver{
+		service:    target.Endpoint,
+		lookup:     b.lookup,
+		interval:   b.interval,
+		serverName: b.serverName,
+		cc:         cc,
+		ctx:        ctx,
+		cancel:     cancel,
+		resolveNow: make(chan struct{}, 1),
+	}
+
+	r.wg.Add(1)
+	go r.watch()
+
+	return r, nil
+}
+
+func (b *discoveryBuilder) Scheme() string {
+	return b.scheme
+}
+
+// discoveryResolver looks up the instances of a service every interval, updating the connection when they change
+type discoveryResolver struct {
+	service    string
+	lookup     lookupFunc
+	interval   time.Duration
+	serverName string
+	cc         resolver.ClientConn
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	resolveNow chan struct{}
+	wg         sync.WaitGroup
+}
+
+func (r *discoveryResolver) watch() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var current []string
+	for {
+		addrs, err := r.lookup(r.ctx)
+		switch {
+		case r.ctx.Err() != nil:
+			return
+		case err != nil:
+			r.cc.ReportError(err)
+		case len(addrs) == 0:
+			r.cc.ReportError(errors.Errorf("no healthy instances of %s", r.service))
+		case !sameAddresses(addrs, current):
+			current = addrs
+			state := resolver.State{Addresses: make([]resolver.Address, len(addrs))}
+			for i, addr := range addrs {
+				state.Addresses[i] = resolver.Address{Addr: addr, ServerName: r.serverName}
+			}
+			r.cc.UpdateState(state)
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.resolveNow:
+		}
+	}
+}
+
+// ResolveNow looks the instances up straight away, e.g. once connecting to one of them has failed
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// sameAddresses reports whether a and b hold the same addresses, whatever their order. a is sorted
+func sameAddresses(a, b []string) bool {
+	sort.Strings(a)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
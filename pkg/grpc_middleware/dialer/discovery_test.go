@@ -0,0 +1,184 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	"github.com/aws/aws-sdk-go/service/servicediscovery/servicediscoveryiface"
+	"github.com/matryer/is"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+func TestReadDiscoveryAddress(t *testing.T) {
+	is := is.New(t)
+
+	// cloud map addresses name the namespace and service
+	cfg, err := ReadConnectionAddress("awssd://prod.local/calls")
+	is.NoErr(err)
+	is.Equal(cfg.String(), "awssd://prod.local/calls")
+	is.Equal(cfg.discovery.dialTarget(), "awssd:///prod.local/calls")
+	is.Equal(cfg.discovery.serverName(), "calls.prod.local")
+
+	// consul addresses name the service, and can disable tls
+	cfg, err = ReadConnectionAddress("consul://calls?disable_tls=true")
+	is.NoErr(err)
+	is.Equal(cfg.String(), "consul://calls?disable_tls=true")
+	is.Equal(cfg.discovery.serverName(), "calls.service.consul")
+
+	// the service is dialed in place of a host and port
+	b := &Builder{}
+	is.NoErr(b.SetConnectionAddress("consul://calls?disable_tls=true"))
+	_, _, err = b.GetConnInfo()
+	is.True(err != nil)
+	is.Equal(b.Clone().discovery.service, "calls")
+	is.NoErr(b.SetConnectionAddress("tcp://localhost:1234"))
+	is.True(b.discovery == nil)
+
+	// malformed addresses are rejected
+	_, err = ReadConnectionAddress("awssd://prod.local")
+	is.True(err != nil)
+	_, err = ReadConnectionAddress("consul://calls/extra")
+	is.True(err != nil)
+}
+
+type fakeCloudMap struct {
+	servicediscoveryiface.ServiceDiscoveryAPI
+	instances []*servicediscovery.HttpInstanceSummary
+}
+
+func (f *fakeCloudMap) DiscoverInstancesWithContext(ctx aws.Context, in *servicediscovery.DiscoverInstancesInput, opts ...request.Option) (*servicediscovery.DiscoverInstancesOutput, error) {
+	return &servicediscovery.DiscoverInstancesOutput{Instances: f.instances}, nil
+}
+
+func TestCloudMapLookup(t *testing.T) {
+	is := is.New(t)
+
+	client := &fakeCloudMap{instances: []*servicediscovery.HttpInstanceSummary{
+		{Attributes: map[string]*string{"AWS_INSTANCE_IPV4": aws.String("10.0.0.1"), "AWS_INSTANCE_PORT": aws.String("8443")}},
+		// instances without an address are skipped
+		{Attributes: map[string]*string{"AWS_INSTANCE_CNAME": aws.String("calls.example.com")}},
+	}}
+
+	addrs, err := cloudMapLookup(client, "prod.local", "calls")(context.Background())
+	is.NoErr(err)
+	is.Equal(addrs, []string{"10.0.0.1:8443"})
+}
+
+func TestConsulLookup(t *testing.T) {
+	is := is.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/v1/health/service/calls")
+		is.Equal(r.URL.Query().Get("passing"), "true")
+		is.Equal(r.Header.Get("X-Consul-Token"), "token")
+		w.Write([]byte(`[
+			{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8443}},
+			{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "10.0.1.2", "Port": 8443}}
+		]`))
+	}))
+	defer srv.Close()
+
+	addrs, err := consulLookup(srv.Client(), srv.URL, "token", "calls")(context.Background())
+	is.NoErr(err)
+	is.Equal(addrs, []string{"10.0.0.1:8443", "10.0.1.2:8443"})
+}
+
+// fakeClientConn records the states and errors the resolver reports
+type fakeClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errs   chan error
+}
+
+func (f *fakeClientConn) UpdateState(s resolver.State) {
+	f.states <- s
+}
+
+func (f *fakeClientConn) ReportError(err error) {
+	f.errs <- err
+}
+
+func TestDiscoveryResolver(t *testing.T) {
+	is := is.New(t)
+
+	lookups := make(chan []string, 3)
+	lookups <- []string{"10.0.0.2:8443", "10.0.0.1:8443"}
+	lookups <- []string{"10.0.0.1:8443", "10.0.0.2:8443"}
+	lookups <- []string{}
+
+	cc := &fakeClientConn{states: make(chan resolver.State, 3), errs: make(chan error, 3)}
+	b := &discoveryBuilder{
+		scheme: SchemeConsul,
+		lookup: func(ctx context.Context) ([]string, error) {
+			select {
+			case addrs := <-lookups:
+				return addrs, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+		interval:   time.Hour,
+		serverName: "calls.service.consul",
+	}
+	r, err := b.Build(resolver.Target{Scheme: SchemeConsul, Endpoint: "calls"}, cc, resolver.BuildOptions{})
+	is.NoErr(err)
+	defer r.Close()
+
+	// the first lookup updates the connection
+	state := <-cc.states
+	is.Equal(len(state.Addresses), 2)
+	is.Equal(state.Addresses[0], resolver.Address{Addr: "10.0.0.1:8443", ServerName: "calls.service.consul"})
+
+	// unchanged instances don't update it, and no instances are reported as an error
+	deadline := time.After(time.Second)
+	for reported := false; !reported; {
+		r.ResolveNow(resolver.ResolveNowOptions{})
+		select {
+		case err := <-cc.errs:
+			is.Equal(err.Error(), "no healthy instances of calls")
+			reported = true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("expected an error without instances")
+		}
+	}
+	is.Equal(len(cc.states), 0)
+}
+
+func TestDialDiscovered(t *testing.T) {
+	is := is.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoErr(err)
+	server := grpc.NewServer()
+	go server.Serve(lis)
+	defer server.Stop()
+
+	port := lis.Addr().(*net.TCPAddr).Port
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Node": {"Address": "127.0.0.1"}, "Service": {"Port": ` + strconv.Itoa(port) + `}}]`))
+	}))
+	defer consul.Close()
+
+	b := &Builder{}
+	b.WithBlock(true)
+	b.WithDiscoveryOptions(DiscoveryOptions{ConsulAddr: consul.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the discovered instance is connected to
+	cc, err := b.DialAddr(ctx, "consul://calls?disable_tls=true")
+	is.NoErr(err)
+	defer cc.Close()
+	is.Equal(cc.Target(), "consul:///calls")
+}
@@ -0,0 +1,80 @@
+package dialer
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The causes of a failed dial. The errors Dial, DialAddr and SetConnectionAddress return match one of them
+// with errors.Is, and carry its gRPC code, so callers and health checks can branch on why a dial failed:
+//
+//	cc, err := b.DialAddr(ctx, addr)
+//	if errors.Is(err, dialer.ErrTimeout) {
+//		// the service did not come up in time, try again later
+//	}
+var (
+	// ErrInvalidTarget is returned when the connection address, dns or port is missing or malformed
+	ErrInvalidTarget = errors.Build("invalid dial target").WithGrpcCode(codes.InvalidArgument).Err()
+	// ErrTLSConfig is returned when the CA or client certificate files cannot be read or parsed
+	ErrTLSConfig = errors.Build("invalid tls configuration").WithGrpcCode(codes.FailedPrecondition).Err()
+	// ErrTimeout is returned when a blocking dial does not connect before the context's deadline
+	ErrTimeout = errors.Build("timed out dialing").WithGrpcCode(codes.DeadlineExceeded).Err()
+	// ErrUnavailable is returned for any other failure to connect to the service
+	ErrUnavailable = errors.Build("service unavailable").WithGrpcCode(codes.Unavailable).Err()
+)
+
+// dialError is an error that matches the sentinel of its cause with errors.Is, while keeping the
+// error that caused it in its chain
+type dialError struct {
+	kind error
+	err  error
+}
+
+// newDialError annotates err with the message and the gRPC code of kind, so it matches kind with errors.Is
+func newDialError(kind, err error, message string) error {
+	return &dialError{
+		kind: kind,
+		err: errors.Build(message).
+			WithGrpcCode(errors.GrpcCode(kind)).
+			WithCause(err).
+			Err(),
+	}
+}
+
+// connectError returns the error of a dial that failed to connect, telling a timeout from other failures
+func connectError(ctx context.Context, addr string, err error) error {
+	kind := ErrUnavailable
+	if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+		kind = ErrTimeout
+	}
+	return newDialError(kind, err, "unable to connect to client "+addr)
+}
+
+func (e *dialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *dialError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *dialError) Unwrap() error {
+	return e.err
+}
+
+func (e *dialError) GRPCStatus() *status.Status {
+	return status.Convert(e.err)
+}
+
+func (e *dialError) Format(s fmt.State, verb rune) {
+	if f, ok := e.err.(fmt.Formatter); ok {
+		f.Format(s, verb)
+		return
+	}
+	io.WriteString(s, e.Error())
+}
@@ -0,0 +1,52 @@
+package dialer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/matryer/is"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDialErrors(t *testing.T) {
+	is := is.New(t)
+
+	// a missing dns and port is an invalid target
+	_, err := (&Builder{}).Dial(context.Background())
+	is.True(errors.Is(err, ErrInvalidTarget))
+	is.Equal(errors.GrpcCode(err), codes.InvalidArgument)
+	is.Equal(status.Code(err), codes.InvalidArgument)
+
+	// so is a malformed connection address
+	err = (&Builder{}).SetConnectionAddress("tcp://localhost:port")
+	is.True(errors.Is(err, ErrInvalidTarget))
+	is.True(!errors.Is(err, ErrTLSConfig))
+	err = (&Builder{}).SetConnectionAddress("tcp://:1234")
+	is.True(errors.Is(err, ErrInvalidTarget))
+
+	// a missing CA file is a tls error
+	err = (&Builder{}).SetConnectionAddress("tls://localhost:1234?ca_file=missing.pem")
+	is.True(errors.Is(err, ErrTLSConfig))
+	is.Equal(errors.GrpcCode(err), codes.FailedPrecondition)
+
+	// a blocking dial to nothing times out
+	b := &Builder{}
+	b.WithBlock(true)
+	is.NoErr(b.SetConnInfo("localhost", "1234", false))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = b.Dial(ctx)
+	is.True(errors.Is(err, ErrTimeout))
+	is.True(errors.Is(err, context.DeadlineExceeded))
+	is.Equal(status.Code(err), codes.DeadlineExceeded)
+
+	// a dial that is cancelled is unavailable
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = b.Dial(ctx)
+	is.True(errors.Is(err, ErrUnavailable))
+	is.Equal(errors.GrpcCode(err), codes.Unavailable)
+}
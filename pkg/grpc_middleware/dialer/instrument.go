@@ -0,0 +1,77 @@
+package dialer
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// dialMetrics are the connection establishment metrics reported for each dialed target
+type dialMetrics struct {
+	Attempts metrics.Counter `metric:"dial_attempts" help:"Number of attempts to establish a gRPC client connection"`
+	Failures metrics.Counter `metric:"dial_failures" help:"Number of failed attempts to establish a gRPC client connection"`
+	Latency  metrics.Timer   `metric:"dial_latency" help:"Time taken to establish a gRPC client connection"`
+}
+
+// WithTracer sets the tracer used to create a span around each Dial. If not set the
+// global tracer is used, which is set up by the tracing package.
+func (b *Builder) WithTracer(tracer opentracing.Tracer) {
+	b.tracer = tracer
+}
+
+// GetTracer returns the tracer used to create a span around each Dial
+func (b *Builder) GetTracer() opentracing.Tracer {
+	if b.tracer == nil {
+		return opentracing.GlobalTracer()
+	}
+	return b.tracer
+}
+
+// WithMetrics sets the factory used to report connection attempts, failures and latency,
+// tagged by target. Latency only reflects establishing the connection when the builder is
+// set to block, otherwise Dial returns before connecting.
+func (b *Builder) WithMetrics(factory metrics.Factory) {
+	b.metricsFactory = factory
+}
+
+// GetMetrics returns the factory used to report connection metrics
+func (b *Builder) GetMetrics() metrics.Factory {
+	if b.metricsFactory == nil {
+		return metrics.NullFactory
+	}
+	return b.metricsFactory
+}
+
+// startDial starts a span for dialing addr as a child of any span in ctx, returning the
+// func to call with the outcome once the dial completes
+func (b *Builder) startDial(ctx context.Context, addr string) (context.Context, func(error)) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, b.GetTracer(), "grpc.dial")
+	ext.SpanKindRPCClient.Set(span)
+	ext.PeerAddress.Set(span, addr)
+	span.SetTag("dial.tls", b.tlsConfig != nil)
+	span.SetTag("dial.blocking", b.enabledBlocking)
+
+	var m dialMetrics
+	metrics.Init(&m, b.GetMetrics(), map[string]string{"target": addr})
+
+	start := time.Now()
+
+	return ctx, func(err error) {
+		m.Attempts.Inc(1)
+		m.Latency.Record(time.Since(start))
+
+		if err != nil {
+			m.Failures.Inc(1)
+			ext.Error.Set(span, true)
+			span.SetTag("dial.outcome", "failure")
+			span.LogFields(log.Error(err))
+		} else {
+			span.SetTag("dial.outcome", "success")
+		}
+		span.Finish()
+	}
+}
@@ -0,0 +1,53 @@
+package dialer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+)
+
+func TestDialInstrumentation(t *testing.T) {
+	is := is.New(t)
+
+	tracer := mocktracer.New()
+	factory := metricstest.NewFactory(0)
+	defer factory.Stop()
+
+	b := &Builder{}
+	b.WithTracer(tracer)
+	b.WithMetrics(factory)
+	is.NoErr(b.SetConnInfo("localhost", "1234", false))
+
+	// A non blocking dial succeeds without connecting
+	cc, err := b.Dial(context.Background())
+	is.NoErr(err)
+	cc.Close()
+
+	spans := tracer.FinishedSpans()
+	is.Equal(len(spans), 1)
+	is.Equal(spans[0].OperationName, "grpc.dial")
+	is.Equal(spans[0].Tag("peer.address"), "localhost:1234")
+	is.Equal(spans[0].Tag("dial.tls"), false)
+	is.Equal(spans[0].Tag("dial.outcome"), "success")
+
+	// A blocking dial to nothing fails once the context is done
+	b.WithBlock(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = b.Clone().Dial(ctx)
+	is.True(err != nil)
+
+	spans = tracer.FinishedSpans()
+	is.Equal(len(spans), 2)
+	is.Equal(spans[1].Tag("error"), true)
+	is.Equal(spans[1].Tag("dial.outcome"), "failure")
+
+	factory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "dial_attempts", Tags: map[string]string{"target": "localhost:1234"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "dial_failures", Tags: map[string]string{"target": "localhost:1234"}, Value: 1},
+	)
+}
@@ -0,0 +1,202 @@
+package dialer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// AddressSource reads the current connection address, see ReadConnectionAddress for its format
+type AddressSource func(ctx context.Context) (string, error)
+
+// EnvSource reads the connection address from an environment variable
+func EnvSource(name string) AddressSource {
+	return func(ctx context.Context) (string, error) {
+		addr := os.Getenv(name)
+		if addr == "" {
+			return "", errors.Errorf("environment variable %s is not set", name)
+		}
+		return addr, nil
+	}
+}
+
+// FileSource reads the connection address from a file, e.g. a mounted config map or secret
+func FileSource(path string) AddressSource {
+	return func(ctx context.Context) (string, error) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", errors.Wrap(err, "unable to read connection address file")
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+}
+
+// SSMSource reads the connection address from an SSM parameter, decrypting it if it is a secure string
+func SSMSource(client ssmiface.SSMAPI, name string) AddressSource {
+	return func(ctx context.Context) (string, error) {
+		out, err := client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "unable to read connection address parameter")
+		}
+		return aws.StringValue(out.Parameter.Value), nil
+	}
+}
+
+// ReloadOptions configures how DialReloading watches for connection address changes
+type ReloadOptions struct {
+	// How often the address is read. If 0 it is only read on SIGHUP or Reload
+	Interval time.Duration
+	// How long a replaced connection is kept open so calls already using it can finish. Defaults to 30s
+	GracePeriod time.Duration
+	// Called with errors reading the address or re-dialing in the background, the current connection
+	// is kept when either fails
+	OnError func(error)
+}
+
+// ReloadingConn is a client connection that re-dials when its connection address changes, so endpoint
+// migrations and credential rotations don't require a restart. Clients are created with it in place of
+// a *grpc.ClientConn, and keep working across re-dials.
+type ReloadingConn struct {
+	builder *Builder
+	source  AddressSource
+	opts    ReloadOptions
+	dialOps []grpc.DialOption
+
+	mu   sync.RWMutex
+	addr string
+	cc   *grpc.ClientConn
+
+	// serializes reloads, so the same change isn't dialed twice
+	reloadMu sync.Mutex
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// DialReloading dials the address read from source, then watches it on the interval in opts and on SIGHUP,
+// re-dialing with a clone of the builder whenever it changes. Address changes include changes to the TLS and
+// authentication options it carries.
+func (b *Builder) DialReloading(ctx context.Context, source AddressSource, opts ReloadOptions, dialOpts ...grpc.DialOption) (*ReloadingConn, error) {
+	if opts.GracePeriod == 0 {
+		opts.GracePeriod = 30 * time.Second
+	}
+
+	r := &ReloadingConn{
+		builder: b.Clone(),
+		source:  source,
+		opts:    opts,
+		dialOps: dialOpts,
+		stop:    make(chan struct{}),
+	}
+
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	go r.watch()
+
+	return r, nil
+}
+
+// Reload reads the address and re-dials if it has changed since the last dial. Calls using the
+// previous connection are given the grace period to finish before it is closed.
+func (r *ReloadingConn) Reload(ctx context.Context) error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	addr, err := r.source(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cc != nil && addr == r.addr
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cc, err := r.builder.Clone().DialAddr(ctx, addr, r.dialOps...)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.cc
+	r.addr, r.cc = addr, cc
+	r.mu.Unlock()
+
+	if old != nil {
+		time.AfterFunc(r.opts.GracePeriod, func() { old.Close() })
+	}
+
+	return nil
+}
+
+// watch reloads on every interval and SIGHUP until the connection is closed
+func (r *ReloadingConn) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if r.opts.Interval > 0 {
+		ticker := time.NewTicker(r.opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+		case <-hup:
+		case <-r.stop:
+			return
+		}
+
+		if err := r.Reload(context.Background()); err != nil && r.opts.OnError != nil {
+			r.opts.OnError(err)
+		}
+	}
+}
+
+// Conn returns the current connection
+func (r *ReloadingConn) Conn() *grpc.ClientConn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cc
+}
+
+// Invoke implements grpc.ClientConnInterface on the current connection
+func (r *ReloadingConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	return r.Conn().Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream implements grpc.ClientConnInterface on the current connection
+func (r *ReloadingConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return r.Conn().NewStream(ctx, desc, method, opts...)
+}
+
+// Close stops watching the address and closes the current connection
+func (r *ReloadingConn) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		err = r.Conn().Close()
+	})
+	return err
+}
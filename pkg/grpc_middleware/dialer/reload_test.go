@@ -0,0 +1,44 @@
+package dialer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestDialReloading(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "reload")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "address")
+	is.NoErr(ioutil.WriteFile(path, []byte("tcp://localhost:1234\n"), 0600))
+
+	// A non blocking dial succeeds without connecting
+	r, err := (&Builder{}).DialReloading(context.Background(), FileSource(path), ReloadOptions{})
+	is.NoErr(err)
+	defer r.Close()
+
+	first := r.Conn()
+	is.Equal(first.Target(), "localhost:1234")
+
+	// An unchanged address keeps the connection
+	is.NoErr(r.Reload(context.Background()))
+	is.True(r.Conn() == first)
+
+	// A changed address re-dials
+	is.NoErr(ioutil.WriteFile(path, []byte("tcp://localhost:5678\n"), 0600))
+	is.NoErr(r.Reload(context.Background()))
+	is.Equal(r.Conn().Target(), "localhost:5678")
+
+	// A bad address keeps the current connection
+	is.NoErr(ioutil.WriteFile(path, []byte("tcp://:5678\n"), 0600))
+	is.True(r.Reload(context.Background()) != nil)
+	is.Equal(r.Conn().Target(), "localhost:5678")
+}
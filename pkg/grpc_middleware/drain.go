@@ -0,0 +1,101 @@
+package grpc_middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// DrainOptions configures how connections to a gRPC server are drained, so clients move to other
+// instances before this one goes away instead of seeing a burst of Unavailable errors
+type DrainOptions struct {
+	// The logger draining is reported to
+	Logger *logging.Logger
+	// Connections older than this are sent a GOAWAY, so clients regularly reconnect and spread
+	// across new instances. Defaults to five minutes
+	MaxConnectionAge time.Duration
+	// The time requests on a connection sent a GOAWAY are given to complete before it is closed.
+	// Defaults to thirty seconds
+	MaxConnectionAgeGrace time.Duration
+	// The time to keep serving after shutdown starts and before GOAWAYs are sent, giving load
+	// balancers and service discovery time to stop sending new connections to the instance
+	ShutdownDelay time.Duration
+}
+
+func (o DrainOptions) withDefaults() DrainOptions {
+	if o.Logger == nil {
+		o.Logger = logging.NewNopLogger()
+	}
+	if o.MaxConnectionAge == 0 {
+		o.MaxConnectionAge = 5 * time.Minute
+	}
+	if o.MaxConnectionAgeGrace == 0 {
+		o.MaxConnectionAgeGrace = 30 * time.Second
+	}
+	return o
+}
+
+// NewDrainServerOption returns the keepalive server option that regularly sends long lived
+// connections a GOAWAY, as configured by the options
+func NewDrainServerOption(opts DrainOptions) grpc.ServerOption {
+	opts = opts.withDefaults()
+
+	return grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      opts.MaxConnectionAge,
+		MaxConnectionAgeGrace: opts.MaxConnectionAgeGrace,
+	})
+}
+
+// Drainer drains a gRPC server when the service shuts down
+type Drainer struct {
+	server *grpc.Server
+	opts   DrainOptions
+}
+
+// NewDrainer returns a drainer for the server. The server should be created with the option
+// from NewDrainServerOption using the same options.
+func NewDrainer(server *grpc.Server, opts DrainOptions) *Drainer {
+	return &Drainer{
+		server: server,
+		opts:   opts.withDefaults(),
+	}
+}
+
+// Shutdown waits for the shutdown delay, then sends every connection a GOAWAY and waits for
+// in flight requests to complete. If ctx is done first the server is stopped immediately, and
+// the context's error is returned. It matches the signature of http.Server's Shutdown, so both
+// can be shut down the same way, e.g. when the pod receives SIGTERM.
+func (d *Drainer) Shutdown(ctx context.Context) error {
+	if d.opts.ShutdownDelay > 0 {
+		d.opts.Logger.Info("delaying grpc server drain", logging.Int64("delayMs", d.opts.ShutdownDelay.Milliseconds()))
+
+		select {
+		case <-time.After(d.opts.ShutdownDelay):
+		case <-ctx.Done():
+			d.server.Stop()
+			return ctx.Err()
+		}
+	}
+
+	d.opts.Logger.Info("draining grpc server")
+
+	done := make(chan struct{})
+	go func() {
+		d.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.opts.Logger.Info("grpc server drained")
+		return nil
+	case <-ctx.Done():
+		d.opts.Logger.Warn("grpc server drain timed out, stopping", logging.String("error", ctx.Err().Error()))
+		d.server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+package grpctest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// AssertLogged fails the test unless an entry with the message was logged, carrying every field in fields
+// with the value given. Values are compared by how they print, so an int matches the int64 it is recorded as
+func (h *Harness) AssertLogged(t testing.TB, message string, fields map[string]interface{}) observer.LoggedEntry {
+	t.Helper()
+
+	entries := h.Logs.FilterMessage(message).All()
+	if len(entries) == 0 {
+		t.Fatalf("no entry was logged with the message %q, logged: %v", message, messages(h.Logs.All()))
+	}
+
+	var mismatch string
+	for _, e := range entries {
+		if mismatch = fieldsMismatch(e.ContextMap(), fields); mismatch == "" {
+			return e
+		}
+	}
+
+	t.Fatalf("no entry with the message %q has the fields %v: %s", message, fields, mismatch)
+	return observer.LoggedEntry{}
+}
+
+// AssertNotLogged fails the test if an entry with the message was logged
+func (h *Harness) AssertNotLogged(t testing.TB, message string) {
+	t.Helper()
+
+	if n := h.Logs.FilterMessage(message).Len(); n > 0 {
+		t.Fatalf("%d entries were logged with the message %q", n, message)
+	}
+}
+
+// AssertSpan fails the test unless a span with the operation name has finished, carrying every tag in tags
+// with the value given, compared like AssertLogged. gRPC spans are named after the full method,
+// e.g. /grpc.health.v1.Health/Check
+func (h *Harness) AssertSpan(t testing.TB, operationName string, tags map[string]interface{}) *mocktracer.MockSpan {
+	t.Helper()
+
+	var names []string
+	var mismatch string
+	for _, span := range h.Tracer.FinishedSpans() {
+		names = append(names, span.OperationName)
+		if span.OperationName != operationName {
+			continue
+		}
+		if mismatch = fieldsMismatch(span.Tags(), tags); mismatch == "" {
+			return span
+		}
+	}
+
+	if mismatch == "" {
+		t.Fatalf("no span named %q has finished, finished: %v", operationName, names)
+	} else {
+		t.Fatalf("no span named %q has the tags %v: %s", operationName, tags, mismatch)
+	}
+	return nil
+}
+
+// AssertMetadata fails the test unless the server received the key with the last call, with the values
+// in want if any are given
+func (h *Harness) AssertMetadata(t testing.TB, key string, want ...string) {
+	t.Helper()
+
+	got := h.Metadata().Get(key)
+	if len(got) == 0 {
+		t.Fatalf("the server received no metadata %q, received: %v", key, h.Metadata())
+	}
+	if len(want) > 0 && !reflect.DeepEqual(got, want) {
+		t.Fatalf("the server received the metadata %q with %v, want %v", key, got, want)
+	}
+}
+
+// fieldsMismatch describes the first of the fields that got doesn't carry, or carries with another value,
+// and is empty if it carries them all
+func fieldsMismatch(got map[string]interface{}, want map[string]interface{}) string {
+	for k, v := range want {
+		g, ok := got[k]
+		if !ok {
+			return fmt.Sprintf("%s is missing", k)
+		}
+		if !reflect.DeepEqual(g, v) && fmt.Sprint(g) != fmt.Sprint(v) {
+			return fmt.Sprintf("%s is %v (%T), want %v (%T)", k, g, g, v, v)
+		}
+	}
+	return ""
+}
+
+func messages(entries []observer.LoggedEntry) []string {
+	m := make([]string, len(entries))
+	for i, e := range entries {
+		m[i] = e.Message
+	}
+	return m
+}
@@ -0,0 +1,122 @@
+package grpctest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/grpc_middleware"
+	"github.com/matryer/is"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// hold is an interceptor that signals started when a call is handled, and holds it until release is closed
+// or the call's context is done
+func hold(started chan<- struct{}, release <-chan struct{}) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// drainHarness starts a harness whose calls are held by hold, with a drainer for its server
+func drainHarness(t *testing.T, started chan<- struct{}, release <-chan struct{}, opts grpc_middleware.DrainOptions) (*Harness, *grpc_middleware.Drainer) {
+	h := New(t, nil, Options{
+		Unary: grpc_middleware.UnaryOptions{
+			Interceptors: []grpc.UnaryServerInterceptor{hold(started, release)},
+		},
+		ServerOptions: []grpc.ServerOption{grpc_middleware.NewDrainServerOption(opts)},
+	})
+	opts.Logger = h.Logger
+	return h, grpc_middleware.NewDrainer(h.Server, opts)
+}
+
+func TestDrainerWaitsForInFlightRequests(t *testing.T) {
+	is := is.New(t)
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	h, drainer := drainHarness(t, started, release, grpc_middleware.DrainOptions{})
+	defer h.Close()
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		called <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- drainer.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned before the in flight request completed: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	is.NoErr(<-called)   // the in flight request completes
+	is.NoErr(<-shutdown) // and the server is drained once it has
+	h.AssertLogged(t, "draining grpc server", nil)
+	h.AssertLogged(t, "grpc server drained", nil)
+}
+
+func TestDrainerTimeout(t *testing.T) {
+	is := is.New(t)
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	h, drainer := drainHarness(t, started, release, grpc_middleware.DrainOptions{})
+	defer h.Close()
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		called <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	is.Equal(drainer.Shutdown(ctx), context.DeadlineExceeded) // the request never completes
+
+	err := <-called
+	is.True(err != nil) // the request is cut off when the server is stopped
+	is.True(status.Code(err) != codes.OK)
+	h.AssertLogged(t, "grpc server drain timed out, stopping", map[string]interface{}{
+		"error": context.DeadlineExceeded.Error(),
+	})
+	h.AssertNotLogged(t, "grpc server drained")
+}
+
+func TestDrainerShutdownDelay(t *testing.T) {
+	is := is.New(t)
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	close(release)
+	h, drainer := drainHarness(t, started, release, grpc_middleware.DrainOptions{ShutdownDelay: time.Hour})
+	defer h.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	is.Equal(drainer.Shutdown(ctx), context.DeadlineExceeded) // the context is done before the delay is up
+
+	h.AssertLogged(t, "delaying grpc server drain", map[string]interface{}{
+		"delayMs": time.Hour.Milliseconds(),
+	})
+	h.AssertNotLogged(t, "draining grpc server")
+
+	_, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	is.True(err != nil) // the server was stopped
+}
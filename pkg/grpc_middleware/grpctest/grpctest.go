@@ -0,0 +1,194 @@
+// Package grpctest runs a gRPC server and client in process, over an in memory listener, with the
+// standard interceptor chains of this library wired in, so services can integration test their
+// interceptor configuration in milliseconds without opening a port.
+package grpctest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/grpc_middleware"
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/caring/go-packages/v2/pkg/tracing"
+	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// bufSize is the size of the in memory listener's buffer
+const bufSize = 1024 * 1024
+
+// Options configures the server and client of a Harness
+type Options struct {
+	// The options of the server's unary chain. If the logger or tracer is nil, the harness's observed
+	// logger and mock tracer are used
+	Unary grpc_middleware.UnaryOptions
+	// The options of the server's stream chain. If the logger or tracer is nil, the harness's observed
+	// logger and mock tracer are used
+	Stream grpc_middleware.StreamOptions
+	// Interceptors the client runs on unary calls, after the tracing interceptor
+	UnaryClientInterceptors []grpc.UnaryClientInterceptor
+	// Interceptors the client runs on stream calls, after the tracing interceptor
+	StreamClientInterceptors []grpc.StreamClientInterceptor
+	// Additional server options, e.g. a drain option
+	ServerOptions []grpc.ServerOption
+	// Additional dial options for the client
+	DialOptions []grpc.DialOption
+	// The level the observed logger records at. Defaults to debug, recording everything
+	Level *logging.Level
+}
+
+// Harness is a running in process server and a client connected to it
+type Harness struct {
+	// The server, with the grpc health service and the services passed to New registered
+	Server *grpc.Server
+	// The client connection to the server
+	Conn *grpc.ClientConn
+	// The entries logged by the harness's logger
+	Logs *observer.ObservedLogs
+	// The tracer the client and, unless the options set another, the server trace with
+	Tracer *mocktracer.MockTracer
+	// The logger the server logs with, unless the options set another
+	Logger *logging.Logger
+	// The health service, to change the status the server reports
+	Health *health.Server
+
+	listener *bufconn.Listener
+
+	mu sync.Mutex
+	// the metadata the server received with the last call
+	metadata metadata.MD
+}
+
+// New starts a server with the standard chains built from opts, registers the services with register, which
+// may be nil, and dials it. The grpc health service is always registered, so a chain can be tested without
+// generating any protos. The harness must be closed once the test is done.
+func New(t testing.TB, register func(*grpc.Server), opts Options) *Harness {
+	t.Helper()
+
+	level := logging.DebugLevel
+	if opts.Level != nil {
+		level = *opts.Level
+	}
+	logger, logs := logging.NewObservedLogger(level)
+
+	h := &Harness{
+		Logs:     logs,
+		Tracer:   mocktracer.New(),
+		Logger:   logger,
+		Health:   health.NewServer(),
+		listener: bufconn.Listen(bufSize),
+	}
+	tracer := tracing.NewTracerFrom(h.Tracer)
+
+	unary := opts.Unary
+	if unary.Logger == nil {
+		unary.Logger = logger
+	}
+	if unary.Tracer == nil {
+		unary.Tracer = tracer
+	}
+	// the metadata is recorded last, so it includes anything added by the interceptors before it
+	unary.Interceptors = append(unary.Interceptors[:len(unary.Interceptors):len(unary.Interceptors)], h.recordUnaryMetadata)
+
+	stream := opts.Stream
+	if stream.Logger == nil {
+		stream.Logger = logger
+	}
+	if stream.Tracer == nil {
+		stream.Tracer = tracer
+	}
+	stream.Interceptors = append(stream.Interceptors[:len(stream.Interceptors):len(stream.Interceptors)], h.recordStreamMetadata)
+
+	serverOpts := append([]grpc.ServerOption{
+		grpc_middleware.NewGRPCChainedUnaryInterceptor(unary),
+		grpc_middleware.NewGRPCChainedStreamInterceptor(stream),
+	}, opts.ServerOptions...)
+
+	h.Server = grpc.NewServer(serverOpts...)
+	grpc_health_v1.RegisterHealthServer(h.Server, h.Health)
+	if register != nil {
+		register(h.Server)
+	}
+
+	go h.Server.Serve(h.listener)
+
+	unaryClient := append([]grpc.UnaryClientInterceptor{
+		grpc_opentracing.UnaryClientInterceptor(grpc_opentracing.WithTracer(h.Tracer)),
+	}, opts.UnaryClientInterceptors...)
+	streamClient := append([]grpc.StreamClientInterceptor{
+		grpc_opentracing.StreamClientInterceptor(grpc_opentracing.WithTracer(h.Tracer)),
+	}, opts.StreamClientInterceptors...)
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return h.listener.Dial()
+		}),
+		grpc.WithInsecure(),
+		grpc.WithChainUnaryInterceptor(unaryClient...),
+		grpc.WithChainStreamInterceptor(streamClient...),
+	}, opts.DialOptions...)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", dialOpts...)
+	if err != nil {
+		h.Server.Stop()
+		t.Fatalf("unable to dial the test server: %s", err.Error())
+	}
+	h.Conn = conn
+
+	return h
+}
+
+// Close closes the client connection and stops the server
+func (h *Harness) Close() {
+	h.Conn.Close()
+	h.Server.Stop()
+}
+
+// HealthClient returns a client of the grpc health service, to make calls through the chains
+// without a service of your own
+func (h *Harness) HealthClient() grpc_health_v1.HealthClient {
+	return grpc_health_v1.NewHealthClient(h.Conn)
+}
+
+// Metadata returns the incoming metadata the server received with the last call
+func (h *Harness) Metadata() metadata.MD {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.metadata.Copy()
+}
+
+// Reset clears the recorded logs, spans and metadata, e.g. between the cases of a table test
+func (h *Harness) Reset() {
+	h.Logs.TakeAll()
+	h.Tracer.Reset()
+
+	h.mu.Lock()
+	h.metadata = nil
+	h.mu.Unlock()
+}
+
+func (h *Harness) recordUnaryMetadata(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	h.record(ctx)
+	return handler(ctx, req)
+}
+
+func (h *Harness) recordStreamMetadata(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	h.record(ss.Context())
+	return handler(srv, ss)
+}
+
+func (h *Harness) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	h.mu.Lock()
+	h.metadata = md
+	h.mu.Unlock()
+}
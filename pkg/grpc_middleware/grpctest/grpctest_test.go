@@ -0,0 +1,129 @@
+package grpctest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/grpc_middleware"
+	"github.com/matryer/is"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestHarness(t *testing.T) {
+	is := is.New(t)
+
+	addHeader := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, "x-team", "care"), method, req, reply, cc, opts...)
+	}
+
+	h := New(t, nil, Options{
+		UnaryClientInterceptors: []grpc.UnaryClientInterceptor{addHeader},
+	})
+	defer h.Close()
+
+	res, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	is.NoErr(err)
+	is.Equal(res.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	h.AssertLogged(t, "finished unary call with code OK", map[string]interface{}{
+		"grpc.service": "grpc.health.v1.Health",
+		"grpc.method":  "Check",
+	})
+	h.AssertMetadata(t, "x-team", "care")
+
+	server := h.AssertSpan(t, "/grpc.health.v1.Health/Check", map[string]interface{}{
+		"span.kind": "server",
+	})
+	client := h.AssertSpan(t, "/grpc.health.v1.Health/Check", map[string]interface{}{
+		"span.kind": "client",
+	})
+	is.Equal(server.ParentID, client.SpanContext.SpanID) // the trace is propagated to the server
+
+	h.Reset()
+	is.Equal(h.Logs.Len(), 0)
+	is.Equal(len(h.Tracer.FinishedSpans()), 0)
+	is.Equal(len(h.Metadata()), 0)
+}
+
+func TestRequestID(t *testing.T) {
+	is := is.New(t)
+
+	h := New(t, nil, Options{
+		Unary: grpc_middleware.UnaryOptions{RequestID: true},
+	})
+	defer h.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpc_middleware.RequestIDHeader, "req-1")
+	var header, trailer metadata.MD
+	_, err := h.HealthClient().Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header), grpc.Trailer(&trailer))
+	is.NoErr(err)
+	is.Equal(header.Get(grpc_middleware.RequestIDHeader), []string{"req-1"})  // the client's ID is echoed in the header
+	is.Equal(trailer.Get(grpc_middleware.RequestIDHeader), []string{"req-1"}) // and the trailer
+
+	h.AssertLogged(t, "finished unary call with code OK", map[string]interface{}{
+		"grpc.request_id": "req-1",
+	})
+	h.AssertSpan(t, "/grpc.health.v1.Health/Check", map[string]interface{}{
+		"span.kind":  "server",
+		"request.id": "req-1",
+	})
+
+	h.Reset()
+	_, err = h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+	is.NoErr(err)
+	ids := header.Get(grpc_middleware.RequestIDHeader)
+	is.Equal(len(ids), 1)
+	is.True(ids[0] != "") // an ID is generated when the client sends none
+
+	for _, invalid := range []string{strings.Repeat("a", 129), "req 1", "req-1;drop"} {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), grpc_middleware.RequestIDHeader, invalid)
+		_, err = h.HealthClient().Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+		is.NoErr(err)
+		ids := header.Get(grpc_middleware.RequestIDHeader)
+		is.Equal(len(ids), 1)
+		is.True(ids[0] != "" && ids[0] != invalid) // an ID is generated when the client's is oversized or invalid
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	is := is.New(t)
+
+	h := New(t, nil, Options{
+		Unary: grpc_middleware.UnaryOptions{SizeLimit: &grpc_middleware.SizeLimitOptions{
+			MaxMetadataBytes: 1024,
+			MaxRequestBytes:  1024,
+			MethodMaxRequestBytes: map[string]int{
+				"/grpc.health.v1.Health/Check": 16,
+			},
+		}},
+	})
+	defer h.Close()
+
+	_, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	is.NoErr(err)
+
+	// the limit of the method applies in place of the default
+	_, err = h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("a", 32)})
+	st := status.Convert(err)
+	is.Equal(st.Code(), codes.ResourceExhausted)
+	is.Equal(st.Message(), "request message is 34 bytes, over the limit of 16 bytes")
+	is.Equal(len(st.Details()), 1)
+	quota, ok := st.Details()[0].(*errdetails.QuotaFailure)
+	is.True(ok)
+	is.Equal(quota.Violations[0].Subject, "request message of /grpc.health.v1.Health/Check")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-large", strings.Repeat("a", 2048))
+	_, err = h.HealthClient().Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	st = status.Convert(err)
+	is.Equal(st.Code(), codes.InvalidArgument)
+	is.Equal(len(st.Details()), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	is.True(ok)
+	is.Equal(badRequest.FieldViolations[0].Field, "metadata")
+}
@@ -0,0 +1,64 @@
+package grpctest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/grpc_middleware"
+	"github.com/matryer/is"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// delay is an interceptor that holds each call for d before handling it
+func delay(d time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		time.Sleep(d)
+		return handler(ctx, req)
+	}
+}
+
+func TestSlowRequest(t *testing.T) {
+	is := is.New(t)
+
+	h := New(t, nil, Options{
+		Unary: grpc_middleware.UnaryOptions{
+			SlowRequest:  &grpc_middleware.SlowRequestOptions{Threshold: 10 * time.Millisecond},
+			Interceptors: []grpc.UnaryServerInterceptor{delay(50 * time.Millisecond)},
+		},
+	})
+	defer h.Close()
+
+	_, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	is.NoErr(err)
+
+	h.AssertLogged(t, "slow request still running", map[string]interface{}{
+		"method":      "/grpc.health.v1.Health/Check",
+		"thresholdMs": 10,
+	})
+	h.AssertLogged(t, "slow request completed", map[string]interface{}{
+		"method": "/grpc.health.v1.Health/Check",
+	})
+}
+
+func TestSlowRequestDefaultThreshold(t *testing.T) {
+	is := is.New(t)
+
+	// a threshold that isn't positive falls back to the default, rather than reporting every request
+	for _, threshold := range []time.Duration{0, -time.Second} {
+		h := New(t, nil, Options{
+			Unary: grpc_middleware.UnaryOptions{
+				SlowRequest:  &grpc_middleware.SlowRequestOptions{Threshold: threshold},
+				Interceptors: []grpc.UnaryServerInterceptor{delay(20 * time.Millisecond)},
+			},
+		})
+
+		_, err := h.HealthClient().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		is.NoErr(err)
+
+		h.AssertNotLogged(t, "slow request still running")
+		h.AssertNotLogged(t, "slow request completed")
+		h.Close()
+	}
+}
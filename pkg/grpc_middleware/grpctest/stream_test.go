@@ -0,0 +1,193 @@
+package grpctest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/grpc_middleware"
+	"github.com/matryer/is"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// echoStreamDesc is a bidirectional stream that sends a response for each request until the client half-closes
+var echoStreamDesc = grpc.StreamDesc{StreamName: "Echo", ServerStreams: true, ClientStreams: true}
+
+// registerEcho registers a test service with the echo stream, and a stream that receives a single request and then
+// waits for another one, which reports on returned when its handler returns
+func registerEcho(returned chan<- error) func(*grpc.Server) {
+	return func(s *grpc.Server) {
+		s.RegisterService(&grpc.ServiceDesc{
+			ServiceName: "grpctest.Echo",
+			HandlerType: (*interface{})(nil),
+			Streams: []grpc.StreamDesc{
+				{
+					StreamName:    "Echo",
+					ServerStreams: true,
+					ClientStreams: true,
+					Handler: func(srv interface{}, ss grpc.ServerStream) error {
+						for {
+							if err := ss.RecvMsg(&grpc_health_v1.HealthCheckRequest{}); err == io.EOF {
+								return nil
+							} else if err != nil {
+								return err
+							}
+							if err := ss.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+								return err
+							}
+						}
+					},
+				},
+				{
+					StreamName:    "Wait",
+					ServerStreams: true,
+					ClientStreams: true,
+					Handler: func(srv interface{}, ss grpc.ServerStream) error {
+						err := ss.RecvMsg(&grpc_health_v1.HealthCheckRequest{})
+						if err == nil {
+							err = ss.RecvMsg(&grpc_health_v1.HealthCheckRequest{})
+						}
+						returned <- err
+						return err
+					},
+				},
+			},
+		}, struct{}{})
+	}
+}
+
+// echo sends n requests on a new stream of the method, half-closes it and receives until it ends
+func echo(h *Harness, method string, n int) (int, error) {
+	cs, err := h.Conn.NewStream(context.Background(), &echoStreamDesc, method)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < n; i++ {
+		if err := cs.SendMsg(&grpc_health_v1.HealthCheckRequest{Service: "calls"}); err != nil {
+			break
+		}
+	}
+	if err := cs.CloseSend(); err != nil {
+		return 0, err
+	}
+
+	received := 0
+	for {
+		err := cs.RecvMsg(&grpc_health_v1.HealthCheckResponse{})
+		if err == io.EOF {
+			return received, nil
+		}
+		if err != nil {
+			return received, err
+		}
+		received++
+	}
+}
+
+func TestStreamObserver(t *testing.T) {
+	is := is.New(t)
+
+	h := New(t, registerEcho(nil), Options{
+		Stream: grpc_middleware.StreamOptions{StreamObserver: &grpc_middleware.StreamObserverOptions{
+			MaxRecvMessages: 3,
+			MaxSendMessages: 3,
+			IdleTimeout:     time.Second,
+		}},
+	})
+	defer h.Close()
+
+	received, err := echo(h, "/grpctest.Echo/Echo", 3)
+	is.NoErr(err)
+	is.Equal(received, 3)
+
+	h.AssertLogged(t, "stream opened", map[string]interface{}{
+		"grpc.method": "/grpctest.Echo/Echo",
+	})
+	h.AssertLogged(t, "stream half-closed", map[string]interface{}{
+		"messagesReceived": int64(3),
+	})
+	h.AssertLogged(t, "stream closed", map[string]interface{}{
+		"grpc.code":        "OK",
+		"messagesReceived": int64(3),
+		"messagesSent":     int64(3),
+		"bytesReceived":    int64(21),
+		"bytesSent":        int64(6),
+	})
+}
+
+func TestStreamObserverQuotas(t *testing.T) {
+	is := is.New(t)
+
+	h := New(t, registerEcho(nil), Options{
+		Stream: grpc_middleware.StreamOptions{StreamObserver: &grpc_middleware.StreamObserverOptions{
+			MaxRecvMessages: 2,
+		}},
+	})
+	defer h.Close()
+
+	received, err := echo(h, "/grpctest.Echo/Echo", 3)
+	is.Equal(received, 2) // the third message is rejected
+	is.Equal(status.Code(err), codes.ResourceExhausted)
+	is.Equal(status.Convert(err).Message(), "stream exceeded 2 received messages")
+	h.AssertLogged(t, "stream closed", map[string]interface{}{
+		"grpc.code":        "ResourceExhausted",
+		"messagesReceived": int64(3),
+		"messagesSent":     int64(2),
+	})
+
+	h = New(t, registerEcho(nil), Options{
+		Stream: grpc_middleware.StreamOptions{StreamObserver: &grpc_middleware.StreamObserverOptions{
+			MaxSendBytes: 4,
+		}},
+	})
+	defer h.Close()
+
+	received, err = echo(h, "/grpctest.Echo/Echo", 3)
+	is.Equal(received, 2) // the third response would exceed the bytes sent
+	is.Equal(status.Code(err), codes.ResourceExhausted)
+	is.Equal(status.Convert(err).Message(), "stream exceeded 4 sent bytes")
+	h.AssertLogged(t, "stream closed", map[string]interface{}{
+		"grpc.code":    "ResourceExhausted",
+		"messagesSent": int64(2),
+		"bytesSent":    int64(4),
+	})
+}
+
+func TestStreamObserverIdleTimeout(t *testing.T) {
+	is := is.New(t)
+
+	returned := make(chan error, 1)
+	h := New(t, registerEcho(returned), Options{
+		Stream: grpc_middleware.StreamOptions{StreamObserver: &grpc_middleware.StreamObserverOptions{
+			IdleTimeout: 50 * time.Millisecond,
+		}},
+	})
+	defer h.Close()
+
+	cs, err := h.Conn.NewStream(context.Background(), &echoStreamDesc, "/grpctest.Echo/Wait")
+	is.NoErr(err)
+	is.NoErr(cs.SendMsg(&grpc_health_v1.HealthCheckRequest{}))
+
+	// the stream is ended while the handler waits for the next request
+	err = cs.RecvMsg(&grpc_health_v1.HealthCheckResponse{})
+	is.Equal(status.Code(err), codes.DeadlineExceeded)
+	is.Equal(status.Convert(err).Message(), "stream idle for 50ms")
+
+	// and the handler returns once the stream is closed, rather than outliving it
+	select {
+	case err := <-returned:
+		is.True(err != nil)
+	case <-time.After(time.Second):
+		t.Fatal("the handler didn't return after the stream went idle")
+	}
+
+	h.AssertLogged(t, "stream closed", map[string]interface{}{
+		"grpc.method":      "/grpctest.Echo/Wait",
+		"grpc.code":        "DeadlineExceeded",
+		"messagesReceived": int64(1),
+	})
+}
@@ -0,0 +1,121 @@
+package grpc_middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// SizeLimitOptions configures the size limit interceptors, on top of the limits of the transport. Limits are
+// disabled when 0
+type SizeLimitOptions struct {
+	// The most bytes of metadata a request may carry, the sum of the lengths of its keys and values
+	MaxMetadataBytes int
+	// The most bytes of a request message, measured as encoded protobuf, for the methods without a limit of their own
+	MaxRequestBytes int
+	// The most bytes of a request message of each full method, e.g. /calls.Calls/UploadRecording, in place
+	// of MaxRequestBytes
+	MethodMaxRequestBytes map[string]int
+}
+
+// NewSizeLimitUnaryInterceptor returns a unary interceptor that rejects requests whose metadata or message
+// exceeds the limits of the options. Oversized metadata gets InvalidArgument with BadRequest details, and an
+// oversized message gets ResourceExhausted with QuotaFailure details, both naming the limit, so clients get an
+// actionable error rather than a reset stream
+func NewSizeLimitUnaryInterceptor(opts SizeLimitOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := opts.checkMetadata(ctx); err != nil {
+			return nil, err
+		}
+		if err := opts.checkMessage(info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewSizeLimitStreamInterceptor returns a stream interceptor that rejects streams whose metadata exceeds the
+// limit of the options, and each message received that exceeds the limit of the method, like
+// NewSizeLimitUnaryInterceptor
+func NewSizeLimitStreamInterceptor(opts SizeLimitOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := opts.checkMetadata(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, &sizeLimitedStream{ServerStream: ss, opts: &opts, method: info.FullMethod})
+	}
+}
+
+// checkMetadata returns an InvalidArgument error if the incoming metadata of ctx is over the limit
+func (o *SizeLimitOptions) checkMetadata(ctx context.Context) error {
+	if o.MaxMetadataBytes <= 0 {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	size := 0
+	for k, vs := range md {
+		for _, v := range vs {
+			size += len(k) + len(v)
+		}
+	}
+	if size <= o.MaxMetadataBytes {
+		return nil
+	}
+
+	desc := fmt.Sprintf("metadata is %d bytes, over the limit of %d bytes", size, o.MaxMetadataBytes)
+	return statusWithDetails(status.New(codes.InvalidArgument, desc), &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "metadata", Description: desc}},
+	})
+}
+
+// checkMessage returns a ResourceExhausted error if the message is over the limit of the method
+func (o *SizeLimitOptions) checkMessage(method string, m interface{}) error {
+	limit, ok := o.MethodMaxRequestBytes[method]
+	if !ok {
+		limit = o.MaxRequestBytes
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	size := messageSize(m)
+	if size <= int64(limit) {
+		return nil
+	}
+
+	desc := fmt.Sprintf("request message is %d bytes, over the limit of %d bytes", size, limit)
+	return statusWithDetails(status.New(codes.ResourceExhausted, desc), &errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: "request message of " + method, Description: desc}},
+	})
+}
+
+// sizeLimitedStream is a server stream that rejects the messages it receives over the limit of its method
+type sizeLimitedStream struct {
+	grpc.ServerStream
+	opts   *SizeLimitOptions
+	method string
+}
+
+func (s *sizeLimitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.opts.checkMessage(s.method, m)
+}
+
+// statusWithDetails returns the error of st with the details added. If the details can't be added the error
+// of st is returned as it is
+func statusWithDetails(st *status.Status, details proto.Message) error {
+	withDetails, err := st.WithDetails(details)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -12,6 +12,17 @@ type StreamOptions struct {
 	Logger       *logging.Logger
 	Tracer       *tracing.Tracer
 	Interceptors []grpc.StreamServerInterceptor
+	// If set, requests running past the threshold are reported. The options logger is used
+	// when the detector has no logger of its own
+	SlowRequest *SlowRequestOptions
+	// If set, every request is given a request ID, which is logged, tagged on the span and echoed
+	// to the client. It runs after the logger and tracer so both carry the ID
+	RequestID bool
+	// If set, requests whose metadata or messages exceed the limits are rejected with a descriptive error
+	SizeLimit *SizeLimitOptions
+	// If set, the messages of each stream are counted and limited, and its lifecycle is logged. The
+	// options logger is used when the observer has no logger of its own
+	StreamObserver *StreamObserverOptions
 }
 
 // NewGRPCChainedStreamInterceptor creates new stream interceptors from each package in this library
@@ -34,6 +45,26 @@ func NewGRPCChainedStreamInterceptor(opts StreamOptions) grpc.ServerOption {
 	if opts.Tracer != nil {
 		chain = append(chain, opts.Tracer.NewGRPCStreamServerInterceptor())
 	}
+	if opts.RequestID {
+		chain = append(chain, NewRequestIDStreamInterceptor())
+	}
+	if opts.SizeLimit != nil {
+		chain = append(chain, NewSizeLimitStreamInterceptor(*opts.SizeLimit))
+	}
+	if opts.SlowRequest != nil {
+		slow := *opts.SlowRequest
+		if slow.Logger == nil {
+			slow.Logger = opts.Logger
+		}
+		chain = append(chain, NewSlowRequestStreamInterceptor(slow))
+	}
+	if opts.StreamObserver != nil {
+		observer := *opts.StreamObserver
+		if observer.Logger == nil {
+			observer.Logger = opts.Logger
+		}
+		chain = append(chain, NewStreamObserverInterceptor(observer))
+	}
 	if opts.Interceptors != nil {
 		chain = append(chain, opts.Interceptors...)
 	}
@@ -50,6 +81,14 @@ type UnaryOptions struct {
 	Logger       *logging.Logger
 	Tracer       *tracing.Tracer
 	Interceptors []grpc.UnaryServerInterceptor
+	// If set, requests running past the threshold are reported. The options logger is used
+	// when the detector has no logger of its own
+	SlowRequest *SlowRequestOptions
+	// If set, every request is given a request ID, which is logged, tagged on the span and echoed
+	// to the client. It runs after the logger and tracer so both carry the ID
+	RequestID bool
+	// If set, requests whose metadata or messages exceed the limits are rejected with a descriptive error
+	SizeLimit *SizeLimitOptions
 }
 
 // NewGRPCChainedUnaryInterceptor creates new unary interceptors from each package in this library
@@ -72,6 +111,19 @@ func NewGRPCChainedUnaryInterceptor(opts UnaryOptions) grpc.ServerOption {
 	if opts.Tracer != nil {
 		chain = append(chain, opts.Tracer.NewGRPCUnaryServerInterceptor())
 	}
+	if opts.RequestID {
+		chain = append(chain, NewRequestIDUnaryInterceptor())
+	}
+	if opts.SizeLimit != nil {
+		chain = append(chain, NewSizeLimitUnaryInterceptor(*opts.SizeLimit))
+	}
+	if opts.SlowRequest != nil {
+		slow := *opts.SlowRequest
+		if slow.Logger == nil {
+			slow.Logger = opts.Logger
+		}
+		chain = append(chain, NewSlowRequestUnaryInterceptor(slow))
+	}
 	if opts.Interceptors != nil {
 		chain = append(chain, opts.Interceptors...)
 	}
@@ -0,0 +1,109 @@
+package grpc_middleware
+
+import (
+	"context"
+
+	"github.com/caring/go-packages/v2/pkg/uuid"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the metadata key the request ID is read from and echoed in
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLength is the longest request ID accepted from a client
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID ctx carries, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestIDUnaryInterceptor returns a unary interceptor that gives every request a request ID, the one
+// the client sent in the x-request-id header or a new uuid if it sent none, or one that is longer than 128
+// characters or has characters other than letters, digits, '.', '_' and '-'. The ID is put on the context,
+// added to the request's log entries and span, and echoed in the response headers and trailers, so clients
+// can quote it in support tickets. It should run after the logging and tracing interceptors
+func NewRequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, id := withRequestID(ctx)
+
+		md := metadata.Pairs(RequestIDHeader, id)
+		grpc.SetHeader(ctx, md)
+		grpc.SetTrailer(ctx, md)
+
+		return handler(ctx, req)
+	}
+}
+
+// NewRequestIDStreamInterceptor returns a stream interceptor that gives every stream a request ID, like
+// NewRequestIDUnaryInterceptor
+func NewRequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withRequestID(ss.Context())
+
+		md := metadata.Pairs(RequestIDHeader, id)
+		ss.SetHeader(md)
+		ss.SetTrailer(md)
+
+		return handler(srv, &requestIDStream{ss, ctx})
+	}
+}
+
+// withRequestID reads the request ID from the incoming metadata, generating one if there is none or it
+// isn't valid, and returns a copy of ctx carrying it. The ID is added to the call's log fields and span
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDHeader); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if !validRequestID(id) {
+		id = uuid.New().String()
+	}
+
+	ctxzap.AddFields(ctx, zap.String("grpc.request_id", id))
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetTag("request.id", id)
+	}
+
+	return ContextWithRequestID(ctx, id), id
+}
+
+// validRequestID reports whether id can be trusted in logs, spans and response headers: it isn't empty or
+// longer than maxRequestIDLength, and only has letters, digits, '.', '_' and '-'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDStream is a server stream with a context carrying the request ID
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDStream) Context() context.Context {
+	return s.ctx
+}
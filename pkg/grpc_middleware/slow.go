@@ -0,0 +1,139 @@
+package grpc_middleware
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"google.golang.org/grpc"
+)
+
+// SlowRequestOptions configures the slow request detector
+type SlowRequestOptions struct {
+	// The logger slow requests are reported to
+	Logger *logging.Logger
+	// Requests running longer than this are reported as slow. Defaults to five seconds, which is
+	// also used when it isn't positive, as every request would be reported otherwise
+	Threshold time.Duration
+	// If set, a goroutine dump is written into this directory when a request becomes slow,
+	// and the path of the dump is included in the warning
+	GoroutineDumpDir string
+	// The minimum time between two goroutine dumps, so that a burst of slow requests doesn't
+	// fill the disk. Defaults to one minute
+	GoroutineDumpInterval time.Duration
+}
+
+// slowRequestDetector reports requests that run past the threshold while they are still
+// running, so stuck handlers are reported even if they never return
+type slowRequestDetector struct {
+	opts SlowRequestOptions
+
+	mu       sync.Mutex
+	lastDump time.Time
+}
+
+func newSlowRequestDetector(opts SlowRequestOptions) *slowRequestDetector {
+	if opts.Logger == nil {
+		opts.Logger = logging.NewNopLogger()
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5 * time.Second
+	}
+	if opts.GoroutineDumpInterval == 0 {
+		opts.GoroutineDumpInterval = time.Minute
+	}
+	return &slowRequestDetector{opts: opts}
+}
+
+// NewSlowRequestUnaryInterceptor returns a unary interceptor that logs a warning with the method and elapsed
+// time whenever a handler runs past the configured threshold
+func NewSlowRequestUnaryInterceptor(opts SlowRequestOptions) grpc.UnaryServerInterceptor {
+	d := newSlowRequestDetector(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done := d.watch(info.FullMethod)
+		defer done()
+
+		return handler(ctx, req)
+	}
+}
+
+// NewSlowRequestStreamInterceptor returns a stream interceptor that logs a warning with the method and elapsed
+// time whenever a stream runs past the configured threshold
+func NewSlowRequestStreamInterceptor(opts SlowRequestOptions) grpc.StreamServerInterceptor {
+	d := newSlowRequestDetector(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done := d.watch(info.FullMethod)
+		defer done()
+
+		return handler(srv, ss)
+	}
+}
+
+// watch starts timing a request, and returns the func to call once it completes
+func (d *slowRequestDetector) watch(method string) func() {
+	start := time.Now()
+
+	timer := time.AfterFunc(d.opts.Threshold, func() {
+		fields := []logging.DataField{
+			logging.String("method", method),
+			logging.Int64("thresholdMs", d.opts.Threshold.Milliseconds()),
+		}
+		if path := d.dumpGoroutines(method); path != "" {
+			fields = append(fields, logging.String("goroutineDump", path))
+		}
+		d.opts.Logger.Warn("slow request still running", fields...)
+	})
+
+	return func() {
+		if timer.Stop() {
+			return
+		}
+		d.opts.Logger.Warn(
+			"slow request completed",
+			logging.String("method", method),
+			logging.Int64("elapsedMs", time.Since(start).Milliseconds()),
+		)
+	}
+}
+
+// dumpGoroutines writes a goroutine dump and returns its path, or an empty string if dumps are
+// disabled, one was written too recently, or the dump failed
+func (d *slowRequestDetector) dumpGoroutines(method string) string {
+	if d.opts.GoroutineDumpDir == "" {
+		return ""
+	}
+
+	d.mu.Lock()
+	now := time.Now()
+	if now.Sub(d.lastDump) < d.opts.GoroutineDumpInterval {
+		d.mu.Unlock()
+		return ""
+	}
+	d.lastDump = now
+	d.mu.Unlock()
+
+	name := fmt.Sprintf("goroutines-%s-%d.txt", strings.Replace(strings.Trim(method, "/"), "/", ".", -1), now.UnixNano())
+	path := filepath.Join(d.opts.GoroutineDumpDir, name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		d.opts.Logger.Error("unable to create goroutine dump", logging.String("error", err.Error()))
+		return ""
+	}
+	defer f.Close()
+
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		d.opts.Logger.Error("unable to write goroutine dump", logging.String("error", err.Error()))
+		return ""
+	}
+
+	return path
+}
@@ -0,0 +1,199 @@
+package grpc_middleware
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamObserverOptions configures the stream observer. Quotas and the idle timeout are disabled when 0
+type StreamObserverOptions struct {
+	// The logger the lifecycle of each stream is reported to
+	Logger *logging.Logger
+	// The most messages a stream may receive from the client
+	MaxRecvMessages int64
+	// The most messages a stream may send to the client
+	MaxSendMessages int64
+	// The most bytes of messages a stream may receive from the client, measured as encoded protobuf
+	MaxRecvBytes int64
+	// The most bytes of messages a stream may send to the client, measured as encoded protobuf
+	MaxSendBytes int64
+	// Streams that neither send nor receive a message for this long are ended with DeadlineExceeded
+	IdleTimeout time.Duration
+}
+
+// streamStats counts the messages a stream sent and received. They are updated atomically
+type streamStats struct {
+	recvMessages int64
+	sentMessages int64
+	recvBytes    int64
+	sentBytes    int64
+}
+
+// NewStreamObserverInterceptor returns a stream interceptor that counts the messages and bytes each stream
+// sends and receives, enforces the configured quotas and idle timeout, and logs when the stream opens, when the
+// client half-closes it and when it ends, with its final code, duration and counts.
+//
+// A stream that exceeds a quota gets ResourceExhausted from the RecvMsg or SendMsg call that exceeded it, and
+// the message is not sent. A stream that goes idle is ended straight away with DeadlineExceeded and its context
+// is cancelled, while the handler returns in its own time.
+func NewStreamObserverInterceptor(opts StreamObserverOptions) grpc.StreamServerInterceptor {
+	if opts.Logger == nil {
+		opts.Logger = logging.NewNopLogger()
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		logger := opts.Logger.NewChild(nil, logging.String("grpc.method", info.FullMethod))
+		logger.Info("stream opened")
+
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+
+		s := &observedStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			opts:         &opts,
+			logger:       logger,
+			activity:     make(chan struct{}, 1),
+		}
+
+		err := s.run(srv, handler)
+
+		code := status.Code(err)
+		fields := []logging.DataField{
+			logging.String("grpc.code", code.String()),
+			logging.Int64("durationMs", time.Since(start).Milliseconds()),
+			logging.Int64("messagesReceived", atomic.LoadInt64(&s.stats.recvMessages)),
+			logging.Int64("messagesSent", atomic.LoadInt64(&s.stats.sentMessages)),
+			logging.Int64("bytesReceived", atomic.LoadInt64(&s.stats.recvBytes)),
+			logging.Int64("bytesSent", atomic.LoadInt64(&s.stats.sentBytes)),
+		}
+		if err != nil {
+			fields = append(fields, logging.Error(err))
+		}
+		logger.Info("stream closed", fields...)
+
+		return err
+	}
+}
+
+// observedStream is a server stream that counts its messages and enforces the quotas of the options
+type observedStream struct {
+	// updated atomically, so it comes first to stay 64 bit aligned
+	stats streamStats
+	grpc.ServerStream
+
+	ctx    context.Context
+	opts   *StreamObserverOptions
+	logger *logging.Logger
+	// signalled whenever a message is sent or received, resetting the idle timeout
+	activity   chan struct{}
+	halfClosed sync.Once
+}
+
+// run calls the handler, ending the stream early if it goes idle.
+//
+// The handler isn't waited for once the stream goes idle, as it is usually blocked in RecvMsg, which only returns
+// once gRPC has closed the stream, after the interceptor returns. The context the handler is given is cancelled when
+// the interceptor returns, and closing the stream makes every RecvMsg and SendMsg call fail, so a handler using
+// either returns straight after, rather than outliving the stream.
+func (s *observedStream) run(srv interface{}, handler grpc.StreamHandler) error {
+	if s.opts.IdleTimeout <= 0 {
+		return handler(srv, s)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(srv, s)
+	}()
+
+	idle := time.NewTimer(s.opts.IdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-s.activity:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(s.opts.IdleTimeout)
+		case <-idle.C:
+			return status.Errorf(codes.DeadlineExceeded, "stream idle for %s", s.opts.IdleTimeout)
+		}
+	}
+}
+
+func (s *observedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *observedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == io.EOF {
+		s.halfClosed.Do(func() {
+			s.logger.Info("stream half-closed", logging.Int64("messagesReceived", atomic.LoadInt64(&s.stats.recvMessages)))
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	s.touch()
+
+	n := atomic.AddInt64(&s.stats.recvMessages, 1)
+	if s.opts.MaxRecvMessages > 0 && n > s.opts.MaxRecvMessages {
+		return status.Errorf(codes.ResourceExhausted, "stream exceeded %d received messages", s.opts.MaxRecvMessages)
+	}
+	b := atomic.AddInt64(&s.stats.recvBytes, messageSize(m))
+	if s.opts.MaxRecvBytes > 0 && b > s.opts.MaxRecvBytes {
+		return status.Errorf(codes.ResourceExhausted, "stream exceeded %d received bytes", s.opts.MaxRecvBytes)
+	}
+
+	return nil
+}
+
+func (s *observedStream) SendMsg(m interface{}) error {
+	size := messageSize(m)
+	if s.opts.MaxSendMessages > 0 && atomic.LoadInt64(&s.stats.sentMessages) >= s.opts.MaxSendMessages {
+		return status.Errorf(codes.ResourceExhausted, "stream exceeded %d sent messages", s.opts.MaxSendMessages)
+	}
+	if s.opts.MaxSendBytes > 0 && atomic.LoadInt64(&s.stats.sentBytes)+size > s.opts.MaxSendBytes {
+		return status.Errorf(codes.ResourceExhausted, "stream exceeded %d sent bytes", s.opts.MaxSendBytes)
+	}
+
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.touch()
+
+	atomic.AddInt64(&s.stats.sentMessages, 1)
+	atomic.AddInt64(&s.stats.sentBytes, size)
+	return nil
+}
+
+// touch resets the idle timeout
+func (s *observedStream) touch() {
+	select {
+	case s.activity <- struct{}{}:
+	default:
+	}
+}
+
+// messageSize returns the encoded size of a protobuf message, or 0 for other messages
+func messageSize(m interface{}) int64 {
+	if pm, ok := m.(proto.Message); ok {
+		return int64(proto.Size(pm))
+	}
+	return 0
+}
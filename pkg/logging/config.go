@@ -1,12 +1,15 @@
 package logging
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/caring/go-packages/v2/pkg/logging/internal/writer"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -15,9 +18,43 @@ import (
 // to help avoid messy multiliners when configuring the logger
 type ReportFlag *bool
 
+// The kinesis services the monitoring and reporting streams can be hosted on
+const (
+	// KinesisBackendFirehose writes to Kinesis Data Firehose delivery streams
+	KinesisBackendFirehose = "firehose"
+	// KinesisBackendStreams writes to Kinesis Data Streams, putting each record with a partition key
+	KinesisBackendStreams = "streams"
+)
+
+// How the partition key of each record put to Kinesis Data Streams is chosen
+const (
+	// PartitionRandom puts each record with a random key, spreading records across shards
+	PartitionRandom = "random"
+	// PartitionFixed puts every record with KinesisPartitionKey, so they are read in order from one shard
+	PartitionFixed = "fixed"
+	// PartitionCorrelationID puts records with the correlation ID of their entry, so the entries of a request
+	// land on the same shard. Records without one get a random key
+	PartitionCorrelationID = "correlation-id"
+	// PartitionCustom puts records with the key KinesisPartitionKeyFunc returns for them
+	PartitionCustom = "custom"
+)
+
+// What is done with entries written to kinesis while its queue is full
+const (
+	// QueueBlock blocks the write until there is room in the queue, so no entry is dropped
+	QueueBlock = writer.QueueBlock
+	// QueueDropOldest drops the oldest queued entry to make room for the new one
+	QueueDropOldest = writer.QueueDropOldest
+	// QueueDropNewest drops the entry being written
+	QueueDropNewest = writer.QueueDropNewest
+)
+
 var (
 	trueVar  = true
 	falseVar = false
+
+	errorLevelVar = ErrorLevel
+	warnLevelVar  = WarnLevel
 )
 
 // Config encapsulates the various settings that may be applied to a logger
@@ -26,37 +63,270 @@ type Config struct {
 	LoggerName string
 	// The service name
 	ServiceName string
-	// All levels above this will be logged to output and to kinesis (if enabled)
+	// Sets the defaults of the level, sampling, dev logging, kinesis and stacktrace settings for the environment
+	// the service runs in, ProfileProduction, ProfileStaging or ProfileDevelopment. Each setting can still be
+	// overridden, by the config or the environment. The defaults are left as they are when empty
+	Profile string
+	// All levels above this will be logged to output and to kinesis (if enabled). As InfoLevel is the zero
+	// value, set LogLevelSet to override a profile's level or LOG_LEVEL with it
 	LogLevel Level
+	// Marks LogLevel as set, so it is used even when it is InfoLevel
+	LogLevelSet bool
+	// The minimum level of the entries a stacktrace is added to. Defaults to error, or warn with dev logging
+	StacktraceLevel *Level
+	// The minimum level of the entries written to stdout. When set, stdout is written to alongside the other
+	// monitoring outputs, otherwise only when none is enabled. Entries must also be at or above LogLevel
+	StdoutLevel *Level
+	// Writes the entries at error and above to stderr instead of stdout, for log routers that tell severity apart
+	// by stream. Applies wherever stdout is written to, reports included
+	SplitStderr *bool
+	// The minimum level of the entries written to the kinesis monitoring stream, LogLevel when nil
+	KinesisLevel *Level
+	// The minimum level of the entries written to CloudWatch, LogLevel when nil
+	CloudWatchLevel *Level
+	// The URL each entry at or above WebhookLevel is posted to as JSON, in addition to the other outputs,
+	// e.g. an alerting service's events endpoint. The webhook is disabled when empty
+	WebhookURL string
+	// The minimum level of the entries posted to the webhook
+	WebhookLevel *Level
 	// Dev logging out puts in a format to be consumed by the console pretty-printer
 	EnableDevLogging *bool
 	// The name of the kinesis stream where developer monitoring logs are piped through
 	KinesisStreamMonitoring string
 	// The name of the kinesis stream where business insight lgs are piped through
 	KinesisStreamReporting string
+	// Gzips each record written to the reporting stream, to cut data transfer for chatty reports. Each record is a
+	// gzip member of its own, which Firehose concatenates into S3 objects that decompress as one stream
+	ReportingGzip *bool
 	// Flag to disable kinesis
 	DisableKinesis *bool
+	// The kinesis service the streams are hosted on, KinesisBackendFirehose or KinesisBackendStreams
+	KinesisBackend string
+	// The partition key records are put to a Kinesis Data Stream with. When empty each record gets a random key,
+	// spreading them across the shards of the stream
+	KinesisPartitionKey string
+	// How the partition key of each record is chosen, PartitionRandom, PartitionFixed, PartitionCorrelationID or
+	// PartitionCustom. Defaults to PartitionCustom when KinesisPartitionKeyFunc is set, PartitionFixed when
+	// KinesisPartitionKey is set and PartitionRandom otherwise. Entries are only put as a record each when
+	// KinesisBatching is enabled, otherwise the key is chosen from the first entry of each buffered record
+	KinesisPartitionStrategy string
+	// Returns the partition key of a record from the record as encoded, for the PartitionCustom strategy.
+	// Records given an empty key get a random one, keys longer than 256 characters are hashed. Only set from the config
+	KinesisPartitionKeyFunc func(record []byte) string
+	// The region of the kinesis streams, when they are not in the region of the runtime
+	KinesisRegion string
+	// A role assumed to write to the kinesis streams, e.g. a role of the centralized logging account. When empty
+	// the streams are written to with the default credential chain
+	KinesisRoleArn string
+	// The external ID the trust policy of KinesisRoleArn requires, if any
+	KinesisExternalID string
 	// If kinesis is enabled, this sets the time between each buffer flush
 	// of each core that writes to kinesis
 	FlushInterval time.Duration
 	// If kinesis is enabled this sets the byte size of the buffer for both kinesis cores.
 	BufferSize int64
-	// This value is used to help filter logs by environment. Expected values are caring-prod, caring-stg, & caring-dev
+	// Sends each log entry as its own kinesis record, batched with PutRecordBatch, instead of buffering
+	// entries and sending each flush of the buffer as one record. Batches are sent every FlushInterval,
+	// or sooner once BatchMaxRecords or BatchMaxBytes are pending
+	KinesisBatching *bool
+	// Packs the entries of each batch into KPL aggregated records, so many small entries are put to Kinesis Data
+	// Streams as one record, cutting per record costs and throttling. Consumers must deaggregate the records, as
+	// the KCL does. Requires KinesisBackendStreams and KinesisBatching
+	KinesisAggregation *bool
+	// The number of records that are sent as soon as they are pending, at most 500
+	BatchMaxRecords int
+	// The byte size of records that are sent as soon as they are pending, at most 4MiB
+	BatchMaxBytes int64
+	// The number of times each write to kinesis is attempted before it is given up on
+	DeliveryAttempts int
+	// The wait before the first retry of a failed write to kinesis, doubled before each retry after it
+	DeliveryBackoff time.Duration
+	// Where log data is written when every attempt to write it to kinesis fails, so it isn't lost.
+	// Either "stderr" or the path of a file that is appended to. The data is dropped when empty
+	DeliveryFallback string
+	// The number of entries queued for each kinesis stream. Entries are written to kinesis from the queue in the
+	// background, so slow responses don't block the goroutines logging
+	QueueSize int
+	// What is done with entries written while the queue is full, QueueBlock, QueueDropOldest or QueueDropNewest.
+	// Dropped entries are counted in the pipeline metrics
+	QueuePolicy string
+	// Samples the entries written to the monitoring outputs, so bursts of the same entry don't flood the
+	// kinesis buffers. Each second, the first SampleInitial entries with the same level and message are
+	// written, then every SampleThereafter-th entry after them. Reports are never sampled
+	EnableSampling *bool
+	// The number of entries with the same level and message written each second before sampling starts
+	SampleInitial int
+	// Once sampling starts, one in every SampleThereafter entries with the same level and message is written
+	SampleThereafter int
+	// Collapses repeats of a monitoring entry, e.g. from an error logged in a tight loop. Once an entry is written,
+	// entries with the same level and message are dropped for this long, and their number is logged in the
+	// repeated field of the next one written. Disabled when 0. Reports are never collapsed
+	RepeatWindow time.Duration
+	// This value is used to help filter logs by environment. Expected values are caring-prod, caring-stg, & caring-dev,
+	// or local when run on a developer's machine. It is added to every entry. Dev logging is enabled when it is local
+	// and no profile is set
 	Env string
+	// Field keys whose values are replaced with a salted SHA-256 digest before being logged, whatever their case,
+	// so quasi-identifiers can still be joined on in analytics without the raw value leaving the service
+	HashedFieldKeys []string
+	// The salt mixed into hashed field values. This should differ per environment
+	HashSalt string
+	// Field keys whose values are PII and are replaced with "[redacted]" before being logged, whatever their case,
+	// in every output including reports, e.g. "ssn", "phone" and "email"
+	RedactedFieldKeys []string
+	// Field keys reports are limited to, so bulky debug fields don't reach the BI stream. The standard fields,
+	// e.g. service and correlationID, and the reportID are always kept. Every field is kept when empty
+	ReportingFields []string
+	// Field keys dropped from reports. The standard fields are always kept
+	ReportingExcludedFields []string
+	// Field keys dropped from the entries written to stdout and the monitoring outputs. The standard fields are
+	// always kept
+	MonitoringExcludedFields []string
+	// Called for every field not in RedactedFieldKeys, to redact PII that can't be recognised by its key alone.
+	// Only set from the config, it can't be read from the environment
+	RedactFunc RedactFunc
+	// The format entries are written to stdout and the monitoring outputs in, FormatJSON or FormatLogfmt.
+	// Reports are always written as JSON, for the BI pipeline
+	LogFormat string
+	// The key names, time format and level encoding of the entries written to stdout and the monitoring outputs.
+	// The zap production defaults, or the development ones when dev logging is enabled, when nil. Only set from
+	// the config
+	MonitoringEncoderConfig *zapcore.EncoderConfig
+	// The key names, time format and level encoding of the reports written to the reporting stream, e.g.
+	// WarehouseEncoderConfig() for the warehouse loader. The monitoring encoder config when nil. Reports written
+	// to stdout, when there is no reporting stream, use the monitoring encoder config. Only set from the config
+	ReportingEncoderConfig *zapcore.EncoderConfig
+	// Writes the fields of each entry in sorted key order, so snapshot and diff based
+	// log assertions don't churn when the order fields are added in changes
+	SortFields *bool
+	// Generates a traceability ID for child loggers created without one, so every log line
+	// in a request chain can be grouped even when an upstream service didn't set one
+	GenerateTraceabilityID *bool
+	// Logs a compact entry with the report ID of each report to the monitoring output, so the reports a
+	// service emitted can be confirmed from its operational logs. Disabled by default, as it adds an entry
+	// for every report
+	LogReportEvents *bool
+	// Rewrites the caller of each entry into a link to the source, so a log line can be followed
+	// straight to the code that wrote it. Links are always written when dev logging is enabled
+	LinkCallers *bool
+	// The template callers are rewritten with. {revision}, {file} and {line} are replaced with the
+	// source revision, the caller's file relative to SourceRoot, and the caller's line, e.g.
+	// https://github.com/caring/my-service/blob/{revision}/{file}#L{line}. Callers are left as they are when empty
+	SourceURLTemplate string
+	// The revision of the source the service was built from, usually the commit SHA
+	SourceRevision string
+	// The path prefix trimmed from caller file paths, usually the directory the service was built in
+	SourceRoot string
+	// The name of the CloudWatch Logs group monitoring logs are shipped to, instead of or as well as kinesis.
+	// CloudWatch is disabled when empty. The group must already exist
+	CloudWatchLogGroup string
+	// The name of the CloudWatch Logs stream monitoring logs are written to, created if it doesn't exist.
+	// Defaults to the hostname, so each instance writes to its own stream
+	CloudWatchLogStream string
+	// The path of a file monitoring logs are written to, instead of or as well as kinesis, for deployments where
+	// log files are tailed. The file and its directory are created if they don't exist. Disabled when empty
+	FilePath string
+	// The byte size the file is rotated at
+	FileMaxSize int64
+	// How long the file is written to before it is rotated
+	FileMaxAge time.Duration
+	// The number of rotated files kept, the oldest are removed
+	FileMaxBackups int
+	// The minimum level of the entries written to the file, LogLevel when nil
+	FileLevel *Level
+	// The host and port of an OpenTelemetry collector monitoring logs are exported to over OTLP/gRPC, instead of
+	// or as well as kinesis, e.g. localhost:4317. OTLP is disabled when empty
+	OTLPEndpoint string
+	// The headers sent with every export as gRPC metadata, e.g. the API key of a hosted collector
+	OTLPHeaders map[string]string
+	// Connects to the collector without TLS, e.g. to a collector running as a sidecar
+	OTLPInsecure *bool
+	// The minimum level of the entries exported over OTLP, LogLevel when nil
+	OTLPLevel *Level
+	// Adds the hostname, pid, container ID, ECS task ARN or Kubernetes pod name, and availability zone to every
+	// entry, detected once when the logger is created. Metadata that can't be detected is left out
+	EnableHostMetadata *bool
+	// Where the metrics of the kinesis and CloudWatch writers are registered, e.g. prometheus.DefaultRegisterer,
+	// so degraded log delivery can be alerted on. The Count and Gauge fields are recorded there too. No metrics
+	// are kept when nil. Only set from the config
+	MetricsRegisterer prometheus.Registerer
+	// Called after each entry is written to the monitoring outputs, so side effects such as error counters can
+	// be wired up without wrapping every log call. Reports don't call the hooks. Only set from the config
+	OnLog []LogHook
 }
 
 func newDefaultConfig() *Config {
 	return &Config{
-		LoggerName:              "",
-		ServiceName:             "",
-		LogLevel:                InfoLevel,
-		EnableDevLogging:        &falseVar,
-		KinesisStreamMonitoring: "",
-		KinesisStreamReporting:  "",
-		DisableKinesis:          &trueVar,
-		FlushInterval:           10 * time.Second,
-		BufferSize:              writer.DefaultBufferSize,
-		Env:                     "",
+		LoggerName:               "",
+		ServiceName:              "",
+		Profile:                  "",
+		LogLevel:                 InfoLevel,
+		StacktraceLevel:          nil,
+		StdoutLevel:              nil,
+		SplitStderr:              &falseVar,
+		KinesisLevel:             nil,
+		CloudWatchLevel:          nil,
+		WebhookURL:               "",
+		WebhookLevel:             &errorLevelVar,
+		EnableDevLogging:         &falseVar,
+		KinesisStreamMonitoring:  "",
+		KinesisStreamReporting:   "",
+		ReportingGzip:            &falseVar,
+		DisableKinesis:           &trueVar,
+		KinesisBackend:           KinesisBackendFirehose,
+		KinesisPartitionKey:      "",
+		KinesisPartitionStrategy: "",
+		KinesisPartitionKeyFunc:  nil,
+		KinesisRegion:            "",
+		KinesisRoleArn:           "",
+		KinesisExternalID:        "",
+		FlushInterval:            10 * time.Second,
+		BufferSize:               writer.DefaultBufferSize,
+		KinesisBatching:          &falseVar,
+		KinesisAggregation:       &falseVar,
+		BatchMaxRecords:          writer.MaxBatchRecords,
+		BatchMaxBytes:            writer.MaxBatchBytes,
+		DeliveryAttempts:         3,
+		DeliveryBackoff:          200 * time.Millisecond,
+		DeliveryFallback:         "",
+		QueueSize:                writer.DefaultQueueSize,
+		QueuePolicy:              QueueBlock,
+		EnableSampling:           &falseVar,
+		SampleInitial:            100,
+		SampleThereafter:         100,
+		RepeatWindow:             0,
+		Env:                      "",
+		HashedFieldKeys:          []string{},
+		HashSalt:                 "",
+		RedactedFieldKeys:        []string{},
+		ReportingFields:          []string{},
+		ReportingExcludedFields:  []string{},
+		MonitoringExcludedFields: []string{},
+		RedactFunc:               nil,
+		LogFormat:                FormatJSON,
+		MonitoringEncoderConfig:  nil,
+		ReportingEncoderConfig:   nil,
+		SortFields:               &falseVar,
+		GenerateTraceabilityID:   &falseVar,
+		LogReportEvents:          &falseVar,
+		LinkCallers:              &falseVar,
+		SourceURLTemplate:        "",
+		SourceRevision:           "",
+		SourceRoot:               "",
+		CloudWatchLogGroup:       "",
+		CloudWatchLogStream:      "",
+		FilePath:                 "",
+		FileMaxSize:              100 * 1024 * 1024,
+		FileMaxAge:               24 * time.Hour,
+		FileMaxBackups:           7,
+		FileLevel:                nil,
+		OTLPEndpoint:             "",
+		OTLPHeaders:              map[string]string{},
+		OTLPInsecure:             &falseVar,
+		OTLPLevel:                nil,
+		EnableHostMetadata:       &falseVar,
+		MetricsRegisterer:        nil,
+		OnLog:                    nil,
 	}
 }
 
@@ -70,6 +340,27 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 	if c == nil {
 		c = &Config{}
 	}
+
+	// the profile replaces the defaults, so it is applied before any other setting is read
+	profile := c.Profile
+	if profile == "" {
+		profile = os.Getenv("LOG_PROFILE")
+	}
+	if err := applyProfile(final, profile); err != nil {
+		return nil, err
+	}
+	// services set ENV long before profiles existed, so it never picks one, as sampling and levels would change
+	// without an opt in. Only a developer's machine gets different defaults, logging in the dev format
+	if profile == "" {
+		env := c.Env
+		if env == "" {
+			env = os.Getenv("ENV")
+		}
+		if devLoggingEnvs[strings.ToLower(env)] {
+			final.EnableDevLogging = &trueVar
+		}
+	}
+
 	if c.LoggerName != "" {
 		final.LoggerName = c.LoggerName
 	} else if s := os.Getenv("LOG_NAME"); s != "" {
@@ -82,7 +373,7 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.ServiceName = s
 	}
 
-	if c.LogLevel != 0 {
+	if logLevelSet(c) {
 		final.LogLevel = c.LogLevel
 	} else if s := os.Getenv("LOG_LEVEL"); s != "" {
 		err := final.LogLevel.Set(s)
@@ -91,6 +382,72 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		}
 	}
 
+	if c.StacktraceLevel != nil {
+		final.StacktraceLevel = c.StacktraceLevel
+	} else if s := os.Getenv("LOG_STACKTRACE_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.StacktraceLevel = lvl
+	}
+
+	if c.StdoutLevel != nil {
+		final.StdoutLevel = c.StdoutLevel
+	} else if s := os.Getenv("LOG_STDOUT_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.StdoutLevel = lvl
+	}
+
+	if c.SplitStderr != nil {
+		final.SplitStderr = c.SplitStderr
+	} else if s := os.Getenv("LOG_SPLIT_STDERR"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.SplitStderr = &b
+	}
+
+	if c.KinesisLevel != nil {
+		final.KinesisLevel = c.KinesisLevel
+	} else if s := os.Getenv("LOG_KINESIS_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.KinesisLevel = lvl
+	}
+
+	if c.CloudWatchLevel != nil {
+		final.CloudWatchLevel = c.CloudWatchLevel
+	} else if s := os.Getenv("LOG_CLOUDWATCH_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.CloudWatchLevel = lvl
+	}
+
+	if c.WebhookURL != "" {
+		final.WebhookURL = c.WebhookURL
+	} else if s := os.Getenv("LOG_WEBHOOK_URL"); s != "" {
+		final.WebhookURL = s
+	}
+
+	if c.WebhookLevel != nil {
+		final.WebhookLevel = c.WebhookLevel
+	} else if s := os.Getenv("LOG_WEBHOOK_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.WebhookLevel = lvl
+	}
+
 	if c.EnableDevLogging != nil {
 		final.EnableDevLogging = c.EnableDevLogging
 	} else if s := os.Getenv("LOG_ENABLE_DEV"); s != "" {
@@ -113,6 +470,16 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.KinesisStreamReporting = s
 	}
 
+	if c.ReportingGzip != nil {
+		final.ReportingGzip = c.ReportingGzip
+	} else if s := os.Getenv("LOG_REPORTING_GZIP"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.ReportingGzip = &b
+	}
+
 	if c.DisableKinesis != nil {
 		final.DisableKinesis = c.DisableKinesis
 	} else if s := os.Getenv("LOG_DISABLE_KINESIS"); s != "" {
@@ -123,6 +490,68 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.DisableKinesis = &b
 	}
 
+	if c.KinesisBackend != "" {
+		final.KinesisBackend = c.KinesisBackend
+	} else if s := os.Getenv("LOG_KINESIS_BACKEND"); s != "" {
+		final.KinesisBackend = strings.ToLower(s)
+	}
+	if final.KinesisBackend != KinesisBackendFirehose && final.KinesisBackend != KinesisBackendStreams {
+		return nil, fmt.Errorf("unrecognized kinesis backend: %q", final.KinesisBackend)
+	}
+
+	if c.KinesisPartitionKey != "" {
+		final.KinesisPartitionKey = c.KinesisPartitionKey
+	} else if s := os.Getenv("LOG_KINESIS_PARTITION_KEY"); s != "" {
+		final.KinesisPartitionKey = s
+	}
+
+	final.KinesisPartitionKeyFunc = c.KinesisPartitionKeyFunc
+	if c.KinesisPartitionStrategy != "" {
+		final.KinesisPartitionStrategy = c.KinesisPartitionStrategy
+	} else if s := os.Getenv("LOG_KINESIS_PARTITION_STRATEGY"); s != "" {
+		final.KinesisPartitionStrategy = strings.ToLower(s)
+	} else if final.KinesisPartitionKeyFunc != nil {
+		final.KinesisPartitionStrategy = PartitionCustom
+	} else if final.KinesisPartitionKey != "" {
+		final.KinesisPartitionStrategy = PartitionFixed
+	} else {
+		final.KinesisPartitionStrategy = PartitionRandom
+	}
+	switch final.KinesisPartitionStrategy {
+	case PartitionRandom, PartitionCorrelationID:
+	case PartitionFixed:
+		if final.KinesisPartitionKey == "" {
+			return nil, fmt.Errorf("partition strategy %q requires a partition key", final.KinesisPartitionStrategy)
+		}
+	case PartitionCustom:
+		if final.KinesisPartitionKeyFunc == nil {
+			return nil, fmt.Errorf("partition strategy %q requires a partition key func", final.KinesisPartitionStrategy)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized partition strategy: %q", final.KinesisPartitionStrategy)
+	}
+
+	if c.KinesisRegion != "" {
+		final.KinesisRegion = c.KinesisRegion
+	} else if s := os.Getenv("LOG_KINESIS_REGION"); s != "" {
+		final.KinesisRegion = s
+	}
+
+	if c.KinesisRoleArn != "" {
+		final.KinesisRoleArn = c.KinesisRoleArn
+	} else if s := os.Getenv("LOG_KINESIS_ROLE_ARN"); s != "" {
+		final.KinesisRoleArn = s
+	}
+
+	if c.KinesisExternalID != "" {
+		final.KinesisExternalID = c.KinesisExternalID
+	} else if s := os.Getenv("LOG_KINESIS_EXTERNAL_ID"); s != "" {
+		final.KinesisExternalID = s
+	}
+	if final.KinesisExternalID != "" && final.KinesisRoleArn == "" {
+		return nil, fmt.Errorf("a kinesis external ID requires a kinesis role")
+	}
+
 	if c.BufferSize != 0 {
 		final.BufferSize = c.BufferSize
 	} else if s := os.Getenv("LOG_BUFFER_SIZE"); s != "" {
@@ -133,6 +562,134 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.BufferSize = i
 	}
 
+	if c.KinesisBatching != nil {
+		final.KinesisBatching = c.KinesisBatching
+	} else if s := os.Getenv("LOG_KINESIS_BATCHING"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.KinesisBatching = &b
+	}
+
+	if c.KinesisAggregation != nil {
+		final.KinesisAggregation = c.KinesisAggregation
+	} else if s := os.Getenv("LOG_KINESIS_AGGREGATION"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.KinesisAggregation = &b
+	}
+	if *final.KinesisAggregation && (final.KinesisBackend != KinesisBackendStreams || !*final.KinesisBatching) {
+		return nil, fmt.Errorf("kinesis aggregation requires the %q backend with batching", KinesisBackendStreams)
+	}
+
+	if c.BatchMaxRecords != 0 {
+		final.BatchMaxRecords = c.BatchMaxRecords
+	} else if s := os.Getenv("LOG_BATCH_MAX_RECORDS"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.BatchMaxRecords = i
+	}
+
+	if c.BatchMaxBytes != 0 {
+		final.BatchMaxBytes = c.BatchMaxBytes
+	} else if s := os.Getenv("LOG_BATCH_MAX_BYTES"); s != "" {
+		i, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		final.BatchMaxBytes = i
+	}
+
+	if c.DeliveryAttempts != 0 {
+		final.DeliveryAttempts = c.DeliveryAttempts
+	} else if s := os.Getenv("LOG_DELIVERY_ATTEMPTS"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.DeliveryAttempts = i
+	}
+
+	if c.DeliveryBackoff != 0 {
+		final.DeliveryBackoff = c.DeliveryBackoff
+	} else if s := os.Getenv("LOG_DELIVERY_BACKOFF"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		final.DeliveryBackoff = d
+	}
+
+	if c.DeliveryFallback != "" {
+		final.DeliveryFallback = c.DeliveryFallback
+	} else if s := os.Getenv("LOG_DELIVERY_FALLBACK"); s != "" {
+		final.DeliveryFallback = s
+	}
+
+	if c.QueueSize != 0 {
+		final.QueueSize = c.QueueSize
+	} else if s := os.Getenv("LOG_QUEUE_SIZE"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.QueueSize = i
+	}
+
+	if c.QueuePolicy != "" {
+		final.QueuePolicy = c.QueuePolicy
+	} else if s := os.Getenv("LOG_QUEUE_POLICY"); s != "" {
+		final.QueuePolicy = strings.ToLower(s)
+	}
+	if final.QueuePolicy != QueueBlock && final.QueuePolicy != QueueDropOldest && final.QueuePolicy != QueueDropNewest {
+		return nil, fmt.Errorf("unrecognized queue policy: %q", final.QueuePolicy)
+	}
+
+	if c.EnableSampling != nil {
+		final.EnableSampling = c.EnableSampling
+	} else if s := os.Getenv("LOG_SAMPLING"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.EnableSampling = &b
+	}
+
+	if c.SampleInitial != 0 {
+		final.SampleInitial = c.SampleInitial
+	} else if s := os.Getenv("LOG_SAMPLE_INITIAL"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.SampleInitial = i
+	}
+
+	if c.SampleThereafter != 0 {
+		final.SampleThereafter = c.SampleThereafter
+	} else if s := os.Getenv("LOG_SAMPLE_THEREAFTER"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.SampleThereafter = i
+	}
+
+	if c.RepeatWindow != 0 {
+		final.RepeatWindow = c.RepeatWindow
+	} else if s := os.Getenv("LOG_REPEAT_WINDOW"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		final.RepeatWindow = d
+	}
+
 	if c.FlushInterval != 0 {
 		final.FlushInterval = c.FlushInterval
 	} else if s := os.Getenv("LOG_FLUSH_INTERVAL"); s != "" {
@@ -149,9 +706,262 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.Env = s
 	}
 
+	if len(c.HashedFieldKeys) != 0 {
+		final.HashedFieldKeys = c.HashedFieldKeys
+	} else if s := os.Getenv("LOG_HASH_FIELDS"); s != "" {
+		final.HashedFieldKeys = splitList(s)
+	}
+
+	if c.HashSalt != "" {
+		final.HashSalt = c.HashSalt
+	} else if s := os.Getenv("LOG_HASH_SALT"); s != "" {
+		final.HashSalt = s
+	}
+
+	if len(c.RedactedFieldKeys) != 0 {
+		final.RedactedFieldKeys = c.RedactedFieldKeys
+	} else if s := os.Getenv("LOG_REDACT_FIELDS"); s != "" {
+		final.RedactedFieldKeys = splitList(s)
+	}
+
+	if len(c.ReportingFields) != 0 {
+		final.ReportingFields = c.ReportingFields
+	} else if s := os.Getenv("LOG_REPORTING_FIELDS"); s != "" {
+		final.ReportingFields = splitList(s)
+	}
+
+	if len(c.ReportingExcludedFields) != 0 {
+		final.ReportingExcludedFields = c.ReportingExcludedFields
+	} else if s := os.Getenv("LOG_REPORTING_EXCLUDED_FIELDS"); s != "" {
+		final.ReportingExcludedFields = splitList(s)
+	}
+
+	if len(c.MonitoringExcludedFields) != 0 {
+		final.MonitoringExcludedFields = c.MonitoringExcludedFields
+	} else if s := os.Getenv("LOG_MONITORING_EXCLUDED_FIELDS"); s != "" {
+		final.MonitoringExcludedFields = splitList(s)
+	}
+
+	final.RedactFunc = c.RedactFunc
+	final.MonitoringEncoderConfig = c.MonitoringEncoderConfig
+	final.ReportingEncoderConfig = c.ReportingEncoderConfig
+
+	if c.LogFormat != "" {
+		final.LogFormat = c.LogFormat
+	} else if s := os.Getenv("LOG_FORMAT"); s != "" {
+		final.LogFormat = strings.ToLower(s)
+	}
+	if final.LogFormat != FormatJSON && final.LogFormat != FormatLogfmt {
+		return nil, fmt.Errorf("unrecognized log format: %q", final.LogFormat)
+	}
+
+	if c.SortFields != nil {
+		final.SortFields = c.SortFields
+	} else if s := os.Getenv("LOG_SORT_FIELDS"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.SortFields = &b
+	}
+
+	if c.GenerateTraceabilityID != nil {
+		final.GenerateTraceabilityID = c.GenerateTraceabilityID
+	} else if s := os.Getenv("LOG_GENERATE_TRACEABILITY_ID"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.GenerateTraceabilityID = &b
+	}
+
+	if c.LogReportEvents != nil {
+		final.LogReportEvents = c.LogReportEvents
+	} else if s := os.Getenv("LOG_REPORT_EVENTS"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.LogReportEvents = &b
+	}
+
+	if c.LinkCallers != nil {
+		final.LinkCallers = c.LinkCallers
+	} else if s := os.Getenv("LOG_LINK_CALLERS"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.LinkCallers = &b
+	}
+
+	if c.SourceURLTemplate != "" {
+		final.SourceURLTemplate = c.SourceURLTemplate
+	} else if s := os.Getenv("LOG_SOURCE_URL_TEMPLATE"); s != "" {
+		final.SourceURLTemplate = s
+	}
+
+	if c.SourceRevision != "" {
+		final.SourceRevision = c.SourceRevision
+	} else if s := os.Getenv("LOG_SOURCE_REVISION"); s != "" {
+		final.SourceRevision = s
+	}
+
+	if c.SourceRoot != "" {
+		final.SourceRoot = c.SourceRoot
+	} else if s := os.Getenv("LOG_SOURCE_ROOT"); s != "" {
+		final.SourceRoot = s
+	}
+
+	if c.CloudWatchLogGroup != "" {
+		final.CloudWatchLogGroup = c.CloudWatchLogGroup
+	} else if s := os.Getenv("LOG_CLOUDWATCH_GROUP"); s != "" {
+		final.CloudWatchLogGroup = s
+	}
+
+	if c.CloudWatchLogStream != "" {
+		final.CloudWatchLogStream = c.CloudWatchLogStream
+	} else if s := os.Getenv("LOG_CLOUDWATCH_STREAM"); s != "" {
+		final.CloudWatchLogStream = s
+	}
+
+	if c.FilePath != "" {
+		final.FilePath = c.FilePath
+	} else if s := os.Getenv("LOG_FILE"); s != "" {
+		final.FilePath = s
+	}
+
+	if c.FileMaxSize != 0 {
+		final.FileMaxSize = c.FileMaxSize
+	} else if s := os.Getenv("LOG_FILE_MAX_SIZE"); s != "" {
+		i, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		final.FileMaxSize = i
+	}
+
+	if c.FileMaxAge != 0 {
+		final.FileMaxAge = c.FileMaxAge
+	} else if s := os.Getenv("LOG_FILE_MAX_AGE"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		final.FileMaxAge = d
+	}
+
+	if c.FileMaxBackups != 0 {
+		final.FileMaxBackups = c.FileMaxBackups
+	} else if s := os.Getenv("LOG_FILE_MAX_BACKUPS"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.FileMaxBackups = i
+	}
+
+	if c.FileLevel != nil {
+		final.FileLevel = c.FileLevel
+	} else if s := os.Getenv("LOG_FILE_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.FileLevel = lvl
+	}
+
+	if c.OTLPEndpoint != "" {
+		final.OTLPEndpoint = c.OTLPEndpoint
+	} else if s := os.Getenv("LOG_OTLP_ENDPOINT"); s != "" {
+		final.OTLPEndpoint = s
+	}
+
+	if len(c.OTLPHeaders) != 0 {
+		final.OTLPHeaders = c.OTLPHeaders
+	} else if s := os.Getenv("LOG_OTLP_HEADERS"); s != "" {
+		headers, err := splitPairs(s)
+		if err != nil {
+			return nil, err
+		}
+		final.OTLPHeaders = headers
+	}
+
+	if c.OTLPInsecure != nil {
+		final.OTLPInsecure = c.OTLPInsecure
+	} else if s := os.Getenv("LOG_OTLP_INSECURE"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.OTLPInsecure = &b
+	}
+
+	if c.OTLPLevel != nil {
+		final.OTLPLevel = c.OTLPLevel
+	} else if s := os.Getenv("LOG_OTLP_LEVEL"); s != "" {
+		lvl, err := parseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		final.OTLPLevel = lvl
+	}
+
+	if c.EnableHostMetadata != nil {
+		final.EnableHostMetadata = c.EnableHostMetadata
+	} else if s := os.Getenv("LOG_HOST_METADATA"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.EnableHostMetadata = &b
+	}
+
+	final.MetricsRegisterer = c.MetricsRegisterer
+	final.OnLog = c.OnLog
+
 	return final, nil
 }
 
+// splitList splits a comma separated environment value into its trimmed, non empty parts
+func splitList(s string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// splitPairs splits a comma separated environment value of key=value pairs into a map
+func splitPairs(s string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, p := range splitList(s) {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("unrecognized key=value pair: %q", p)
+		}
+		pairs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return pairs, nil
+}
+
+// logLevelSet reports whether the config sets LogLevel, which can't be told from its value alone as
+// InfoLevel is the zero value
+func logLevelSet(c *Config) bool {
+	return c.LogLevelSet || c.LogLevel != InfoLevel
+}
+
+// parseLevel parses the level of an output from the environment
+func parseLevel(s string) (*Level, error) {
+	var lvl Level
+	if err := lvl.Set(s); err != nil {
+		return nil, err
+	}
+	return &lvl, nil
+}
+
 // spits out a zap config that has been tuned to play nicely with
 // the zap-pretty pretty printing util and easy development
 func newZapDevelopmentConfig() zap.Config {
@@ -173,17 +983,91 @@ func newZapDevelopmentConfig() zap.Config {
 	return c
 }
 
-// builds a zap core configured at info log level. The underlying io stream that writes to kinesis is wrapped in a buffer
-func buildReportingCore(streamName string, enc zapcore.EncoderConfig, bufSize int64, flushInterval time.Duration) (zapcore.Core, io.Closer, error) {
-	w, err := writer.NewKinesisWriter(streamName)
+// builds how failed writes to kinesis are retried, and the fallback they are written to once every attempt fails.
+// The closer closes the fallback file, if there is one, and must be closed after the kinesis cores
+func buildDelivery(c *Config) (writer.Delivery, io.Closer, error) {
+	d := writer.Delivery{
+		Attempts: c.DeliveryAttempts,
+		Backoff:  c.DeliveryBackoff,
+	}
+
+	switch c.DeliveryFallback {
+	case "":
+		return d, nil, nil
+	case "stderr":
+		d.Fallback = zapcore.Lock(os.Stderr)
+		return d, nil, nil
+	}
+
+	f, err := os.OpenFile(c.DeliveryFallback, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return d, nil, err
+	}
+	d.Fallback = zapcore.Lock(f)
+
+	return d, f, nil
+}
+
+// builds the sink of a kinesis core, writing to a Firehose delivery stream or a Kinesis Data Stream as the backend
+// configures. Unless batching is enabled, the underlying io stream that writes to kinesis is wrapped in a buffer,
+// and each flush of the buffer is sent as one record. Either way entries are queued in front of the sink, so
+// slow responses from kinesis don't block the goroutines logging. If compress is set each record is gzipped
+func buildKinesisSink(streamName string, c *Config, compress bool, delivery writer.Delivery, metrics writer.Metrics) (zapcore.WriteSyncer, io.Closer, error) {
+	ws, closer, err := buildKinesisWriter(streamName, c, compress, delivery, metrics)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ws, closer = writer.Queue(ws, closer, c.QueueSize, c.QueuePolicy, metrics)
+	return ws, closer, nil
+}
+
+// builds the writer to a kinesis stream, batched or buffered
+func buildKinesisWriter(streamName string, c *Config, compress bool, delivery writer.Delivery, metrics writer.Metrics) (zapcore.WriteSyncer, io.Closer, error) {
+	streams := c.KinesisBackend == KinesisBackendStreams
+	sc := writer.SessionConfig{
+		Region:     c.KinesisRegion,
+		RoleArn:    c.KinesisRoleArn,
+		ExternalID: c.KinesisExternalID,
+	}
+
+	if *c.KinesisBatching {
+		if streams {
+			return writer.NewKinesisStreamBatchWriter(sc, streamName, partitionKeyFunc(c), *c.KinesisAggregation, compress, c.BatchMaxRecords, int(c.BatchMaxBytes), c.FlushInterval, delivery, metrics)
+		}
+		return writer.NewKinesisBatchWriter(sc, streamName, compress, c.BatchMaxRecords, int(c.BatchMaxBytes), c.FlushInterval, delivery, metrics)
+	}
+
+	var (
+		w   io.Writer
+		err error
+	)
+	if streams {
+		// the stream writer compresses each record itself, once its partition key is chosen from the record
+		w, err = writer.NewKinesisStreamWriter(sc, streamName, partitionKeyFunc(c), compress)
+	} else {
+		w, err = writer.NewKinesisWriter(sc, streamName)
+		if err == nil && compress {
+			w = writer.Gzip(w)
+		}
+	}
 	if err != nil {
 		return nil, nil, err
 	}
 
-	buf, closer := writer.Buffer(zapcore.AddSync(w), int(bufSize), flushInterval)
+	buf, closer := writer.Buffer(zapcore.AddSync(writer.Retry(w, delivery, metrics)), int(c.BufferSize), c.FlushInterval, metrics)
+	return buf, closer, nil
+}
+
+// builds a zap core configured at info log level that writes to kinesis
+func buildReportingCore(streamName string, enc zapcore.Encoder, c *Config, delivery writer.Delivery, metrics writer.Metrics) (zapcore.Core, io.Closer, error) {
+	buf, closer, err := buildKinesisSink(streamName, c, *c.ReportingGzip, delivery, metrics)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(enc),
+		enc,
 		buf,
 		zapcore.InfoLevel,
 	)
@@ -191,20 +1075,101 @@ func buildReportingCore(streamName string, enc zapcore.EncoderConfig, bufSize in
 	return core, closer, nil
 }
 
-// builds a zap core configured at the provided log level. The underlying io stream that writes to kinesis is wrapped in a buffer
-func buildMonitoringCore(streamName string, enc zapcore.EncoderConfig, bufSize int64, flushInterval time.Duration, lvl zapcore.Level) (zapcore.Core, io.Closer, error) {
-	w, err := writer.NewKinesisWriter(streamName)
+// builds a zap core configured at the provided log level that writes to kinesis
+func buildMonitoringCore(streamName string, enc zapcore.Encoder, c *Config, delivery writer.Delivery, metrics writer.Metrics, lvl zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	buf, closer, err := buildKinesisSink(streamName, c, false, delivery, metrics)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	buf, closer := writer.Buffer(zapcore.AddSync(w), int(bufSize), flushInterval)
+	core := zapcore.NewCore(
+		enc,
+		buf,
+		lvl,
+	)
+
+	return core, closer, nil
+}
+
+// builds a zap core configured at the provided log level that writes to CloudWatch Logs. If no stream name is given the
+// hostname is used. The underlying io stream that writes to CloudWatch is wrapped in a buffer
+func buildCloudWatchCore(groupName, streamName string, enc zapcore.Encoder, bufSize int64, flushInterval time.Duration, metrics writer.Metrics, lvl zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	if streamName == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, nil, err
+		}
+		streamName = host
+	}
+
+	w, err := writer.NewCloudWatchWriter(groupName, streamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buf, closer := writer.Buffer(zapcore.AddSync(w), int(bufSize), flushInterval, metrics)
 
 	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(enc),
+		enc,
 		buf,
 		lvl,
 	)
 
 	return core, closer, nil
 }
+
+// builds a zap core that posts each entry routed to it to a webhook as JSON. Failed posts are retried as
+// configured for kinesis, and dropped once every attempt fails
+func buildWebhookCore(url string, enc zapcore.Encoder, c *Config, lvl zapcore.LevelEnabler) (zapcore.Core, io.Closer) {
+	w, closer := writer.NewWebhookWriter(url, writer.Delivery{
+		Attempts: c.DeliveryAttempts,
+		Backoff:  c.DeliveryBackoff,
+	})
+
+	core := zapcore.NewCore(
+		enc,
+		w,
+		lvl,
+	)
+
+	return newRoutedCore(core, lvl), closer
+}
+
+// builds a zap core configured at the provided log level that appends to a file, rotated once it reaches
+// the configured size or age
+func buildFileCore(enc zapcore.Encoder, c *Config, lvl zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	w, closer, err := writer.NewRotatingFile(c.FilePath, c.FileMaxSize, c.FileMaxAge, c.FileMaxBackups)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	core := zapcore.NewCore(
+		enc,
+		w,
+		lvl,
+	)
+
+	return newRoutedCore(core, lvl), closer, nil
+}
+
+// builds a zap core configured at the provided log level that exports entries to an OpenTelemetry collector
+// over OTLP/gRPC, describing the service and environment they came from with resource attributes
+func buildOTLPCore(enc zapcore.EncoderConfig, c *Config, lvl zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	resource := map[string]string{}
+	if c.ServiceName != "" {
+		resource["service.name"] = c.ServiceName
+	}
+	if c.Env != "" {
+		resource["deployment.environment"] = c.Env
+	}
+
+	w, closer, err := writer.NewOTLPWriter(c.OTLPEndpoint, c.OTLPHeaders, resource, *c.OTLPInsecure, c.FlushInterval, writer.Delivery{
+		Attempts: c.DeliveryAttempts,
+		Backoff:  c.DeliveryBackoff,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newRoutedCore(newOTLPCore(enc, w, lvl), lvl), closer, nil
+}
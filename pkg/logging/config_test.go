@@ -18,10 +18,67 @@ func Test_newDefaultConfig(t *testing.T) {
 	assert.Equal(t, false, *c.EnableDevLogging, "Expected dev logging to be disabled")
 	assert.Equal(t, "", c.KinesisStreamMonitoring, "Expected blank kinesis stream")
 	assert.Equal(t, "", c.KinesisStreamReporting, "Expected blank kinesis stream")
+	assert.Equal(t, false, *c.ReportingGzip, "Expected reporting records not to be gzipped")
 	assert.Equal(t, true, *c.DisableKinesis, "Expected kinesis to be disabled")
+	assert.Equal(t, KinesisBackendFirehose, c.KinesisBackend, "Expected the firehose kinesis backend")
+	assert.Equal(t, "", c.KinesisPartitionKey, "Expected random partition keys")
+	assert.Equal(t, "", c.KinesisPartitionStrategy, "Expected the partition strategy to follow the partition key")
+	assert.Equal(t, "", c.KinesisRegion, "Expected the region of the runtime")
+	assert.Equal(t, "", c.KinesisRoleArn, "Expected the default credential chain")
+	assert.Equal(t, "", c.KinesisExternalID, "Expected no external ID")
 	assert.Equal(t, 10*time.Second, c.FlushInterval, "Expected flush interval to be 10 seconds")
 	assert.Equal(t, int64(256*1024), c.BufferSize, "Expected buffer size to be 262_144 bytes")
+	assert.Equal(t, false, *c.KinesisBatching, "Expected kinesis batching to be disabled")
+	assert.Equal(t, false, *c.KinesisAggregation, "Expected kinesis aggregation to be disabled")
+	assert.Equal(t, 500, c.BatchMaxRecords, "Expected batches of at most 500 records")
+	assert.Equal(t, int64(4*1024*1024), c.BatchMaxBytes, "Expected batches of at most 4MiB")
+	assert.Equal(t, 3, c.DeliveryAttempts, "Expected 3 delivery attempts")
+	assert.Equal(t, 200*time.Millisecond, c.DeliveryBackoff, "Expected a delivery backoff of 200ms")
+	assert.Equal(t, "", c.DeliveryFallback, "Expected no delivery fallback")
+	assert.Equal(t, 1024, c.QueueSize, "Expected queues of 1024 entries")
+	assert.Equal(t, QueueBlock, c.QueuePolicy, "Expected full queues to block")
+	assert.Equal(t, false, *c.EnableSampling, "Expected sampling to be disabled")
+	assert.Equal(t, 100, c.SampleInitial, "Expected 100 entries before sampling starts")
+	assert.Equal(t, 100, c.SampleThereafter, "Expected 1 in 100 entries to be sampled")
+	assert.Equal(t, time.Duration(0), c.RepeatWindow, "Expected repeats not to be collapsed")
 	assert.Equal(t, "", c.Env, "Expected an empty environment name")
+	assert.Empty(t, c.HashedFieldKeys, "Expected no hashed field keys")
+	assert.Equal(t, "", c.HashSalt, "Expected an empty hash salt")
+	assert.Empty(t, c.RedactedFieldKeys, "Expected no redacted field keys")
+	assert.Empty(t, c.ReportingFields, "Expected every field to be reported")
+	assert.Empty(t, c.ReportingExcludedFields, "Expected no fields to be excluded from reports")
+	assert.Empty(t, c.MonitoringExcludedFields, "Expected no fields to be excluded from monitoring")
+	assert.Nil(t, c.RedactFunc, "Expected no redact func")
+	assert.Nil(t, c.MonitoringEncoderConfig, "Expected the default monitoring encoder config")
+	assert.Nil(t, c.ReportingEncoderConfig, "Expected the default reporting encoder config")
+	assert.Equal(t, "", c.Profile, "Expected no profile")
+	assert.Nil(t, c.StacktraceLevel, "Expected no stacktrace level")
+	assert.Nil(t, c.StdoutLevel, "Expected no stdout level")
+	assert.Equal(t, false, *c.SplitStderr, "Expected errors to be written to stdout")
+	assert.Nil(t, c.KinesisLevel, "Expected no kinesis level")
+	assert.Nil(t, c.CloudWatchLevel, "Expected no cloudwatch level")
+	assert.Equal(t, "", c.WebhookURL, "Expected an empty webhook url")
+	assert.Equal(t, ErrorLevel, *c.WebhookLevel, "Expected an ERROR webhook level")
+	assert.Equal(t, FormatJSON, c.LogFormat, "Expected the JSON log format")
+	assert.Equal(t, false, *c.SortFields, "Expected field sorting to be disabled")
+	assert.Equal(t, false, *c.GenerateTraceabilityID, "Expected traceability ID generation to be disabled")
+	assert.Equal(t, false, *c.LogReportEvents, "Expected report events not to be logged")
+	assert.Equal(t, false, *c.LinkCallers, "Expected caller links to be disabled")
+	assert.Equal(t, "", c.SourceURLTemplate, "Expected an empty source url template")
+	assert.Equal(t, "", c.SourceRevision, "Expected an empty source revision")
+	assert.Equal(t, "", c.SourceRoot, "Expected an empty source root")
+	assert.Equal(t, "", c.CloudWatchLogGroup, "Expected an empty cloudwatch log group")
+	assert.Equal(t, "", c.CloudWatchLogStream, "Expected an empty cloudwatch log stream")
+	assert.Equal(t, "", c.FilePath, "Expected no log file")
+	assert.Equal(t, int64(100*1024*1024), c.FileMaxSize, "Expected files to be rotated at 100MiB")
+	assert.Equal(t, 24*time.Hour, c.FileMaxAge, "Expected files to be rotated daily")
+	assert.Equal(t, 7, c.FileMaxBackups, "Expected 7 rotated files to be kept")
+	assert.Nil(t, c.FileLevel, "Expected no file level")
+	assert.Equal(t, "", c.OTLPEndpoint, "Expected no OTLP endpoint")
+	assert.Empty(t, c.OTLPHeaders, "Expected no OTLP headers")
+	assert.False(t, *c.OTLPInsecure, "Expected OTLP to use TLS")
+	assert.Nil(t, c.OTLPLevel, "Expected no OTLP level")
+	assert.False(t, *c.EnableHostMetadata, "Expected host metadata to be disabled")
 }
 
 func Test_mergeAndPopulateConfig(t *testing.T) {
@@ -48,10 +105,62 @@ func Test_mergeAndPopulateConfig(t *testing.T) {
 	os.Setenv("LOG_ENABLE_DEV", "TRUE")
 	os.Setenv("LOG_STREAM_MONITORING", "monitoringstream2")
 	os.Setenv("LOG_STREAM_REPORTING", "reportingstream2")
+	os.Setenv("LOG_REPORTING_GZIP", "true")
 	os.Setenv("LOG_DISABLE_KINESIS", "FALSE")
+	os.Setenv("LOG_KINESIS_BACKEND", "STREAMS")
+	os.Setenv("LOG_KINESIS_PARTITION_KEY", "fooservice")
+	os.Setenv("LOG_KINESIS_PARTITION_STRATEGY", "Correlation-ID")
+	os.Setenv("LOG_KINESIS_REGION", "us-west-2")
+	os.Setenv("LOG_KINESIS_ROLE_ARN", "arn:aws:iam::123456789012:role/logging-writer")
+	os.Setenv("LOG_KINESIS_EXTERNAL_ID", "fooservice-logs")
 	os.Setenv("LOG_FLUSH_INTERVAL", "7")
 	os.Setenv("LOG_BUFFER_SIZE", "1024")
+	os.Setenv("LOG_KINESIS_BATCHING", "true")
+	os.Setenv("LOG_KINESIS_AGGREGATION", "true")
+	os.Setenv("LOG_BATCH_MAX_RECORDS", "100")
+	os.Setenv("LOG_BATCH_MAX_BYTES", "65536")
+	os.Setenv("LOG_DELIVERY_ATTEMPTS", "5")
+	os.Setenv("LOG_DELIVERY_BACKOFF", "1s")
+	os.Setenv("LOG_DELIVERY_FALLBACK", "stderr")
+	os.Setenv("LOG_QUEUE_SIZE", "64")
+	os.Setenv("LOG_QUEUE_POLICY", "DROP-OLDEST")
+	os.Setenv("LOG_SAMPLING", "true")
+	os.Setenv("LOG_SAMPLE_INITIAL", "10")
+	os.Setenv("LOG_SAMPLE_THEREAFTER", "50")
+	os.Setenv("LOG_REPEAT_WINDOW", "2s")
+	os.Setenv("LOG_REPORT_EVENTS", "true")
 	os.Setenv("ENV", "caring-dev")
+	os.Setenv("LOG_HASH_FIELDS", "userID, phone")
+	os.Setenv("LOG_HASH_SALT", "devsalt")
+	os.Setenv("LOG_REDACT_FIELDS", "ssn, email")
+	os.Setenv("LOG_REPORTING_FIELDS", "callID, duration")
+	os.Setenv("LOG_REPORTING_EXCLUDED_FIELDS", "request")
+	os.Setenv("LOG_MONITORING_EXCLUDED_FIELDS", "payload")
+	os.Setenv("LOG_FORMAT", "LOGFMT")
+	os.Setenv("LOG_PROFILE", "Staging")
+	os.Setenv("LOG_STACKTRACE_LEVEL", "DPANIC")
+	os.Setenv("LOG_STDOUT_LEVEL", "DEBUG")
+	os.Setenv("LOG_SPLIT_STDERR", "true")
+	os.Setenv("LOG_KINESIS_LEVEL", "WARN")
+	os.Setenv("LOG_CLOUDWATCH_LEVEL", "INFO")
+	os.Setenv("LOG_WEBHOOK_URL", "https://alerts.example.com/events")
+	os.Setenv("LOG_WEBHOOK_LEVEL", "FATAL")
+	os.Setenv("LOG_FILE", "/var/log/fooservice.log")
+	os.Setenv("LOG_FILE_MAX_SIZE", "1024")
+	os.Setenv("LOG_FILE_MAX_AGE", "1h")
+	os.Setenv("LOG_FILE_MAX_BACKUPS", "3")
+	os.Setenv("LOG_FILE_LEVEL", "ERROR")
+	os.Setenv("LOG_OTLP_ENDPOINT", "localhost:4317")
+	os.Setenv("LOG_OTLP_HEADERS", "api-key=secret, tenant=caring")
+	os.Setenv("LOG_OTLP_INSECURE", "true")
+	os.Setenv("LOG_OTLP_LEVEL", "WARN")
+	os.Setenv("LOG_HOST_METADATA", "true")
+	os.Setenv("LOG_LINK_CALLERS", "TRUE")
+	os.Setenv("LOG_SOURCE_URL_TEMPLATE", "https://example.com/{revision}/{file}#L{line}")
+	os.Setenv("LOG_SOURCE_REVISION", "abc123")
+	os.Setenv("LOG_SOURCE_ROOT", "/build")
+	os.Setenv("LOG_CLOUDWATCH_GROUP", "/caring/dev")
+	os.Setenv("LOG_CLOUDWATCH_STREAM", "fooservice")
 
 	t.Run("Initializes all config from environment correctly when given an empty config object", func(t *testing.T) {
 		c := &Config{}
@@ -64,10 +173,62 @@ func Test_mergeAndPopulateConfig(t *testing.T) {
 		assert.Equal(t, true, *result.EnableDevLogging, "Expected dev logging to be enabled")
 		assert.Equal(t, "monitoringstream2", result.KinesisStreamMonitoring, "Expected stream name to  kinesisstream2")
 		assert.Equal(t, "reportingstream2", result.KinesisStreamReporting, "Expected blank kinesis shard to be shard1")
+		assert.Equal(t, true, *result.ReportingGzip, "Expected reporting records to be gzipped")
 		assert.Equal(t, false, *result.DisableKinesis, "Expected kinesis to be enabled")
+		assert.Equal(t, KinesisBackendStreams, result.KinesisBackend, "Expected the data streams kinesis backend")
+		assert.Equal(t, "fooservice", result.KinesisPartitionKey, "Expected the partition key from the environment")
+		assert.Equal(t, PartitionCorrelationID, result.KinesisPartitionStrategy, "Expected the correlation ID partition strategy")
+		assert.Equal(t, "us-west-2", result.KinesisRegion, "Expected the region from the environment")
+		assert.Equal(t, "arn:aws:iam::123456789012:role/logging-writer", result.KinesisRoleArn, "Expected the role from the environment")
+		assert.Equal(t, "fooservice-logs", result.KinesisExternalID, "Expected the external ID from the environment")
 		assert.Equal(t, 7*time.Second, result.FlushInterval, "Expected flush interval to be 7 seconds")
 		assert.Equal(t, int64(1024), result.BufferSize, "Expected buffer size to be 1024 bytes")
+		assert.Equal(t, true, *result.KinesisBatching, "Expected kinesis batching to be enabled")
+		assert.Equal(t, true, *result.KinesisAggregation, "Expected kinesis aggregation to be enabled")
+		assert.Equal(t, 100, result.BatchMaxRecords, "Expected batches of at most 100 records")
+		assert.Equal(t, int64(65536), result.BatchMaxBytes, "Expected batches of at most 65536 bytes")
+		assert.Equal(t, 5, result.DeliveryAttempts, "Expected 5 delivery attempts")
+		assert.Equal(t, time.Second, result.DeliveryBackoff, "Expected a delivery backoff of 1s")
+		assert.Equal(t, "stderr", result.DeliveryFallback, "Expected the stderr delivery fallback")
+		assert.Equal(t, 64, result.QueueSize, "Expected queues of 64 entries")
+		assert.Equal(t, QueueDropOldest, result.QueuePolicy, "Expected the oldest entries of full queues to be dropped")
+		assert.Equal(t, true, *result.EnableSampling, "Expected sampling to be enabled")
+		assert.Equal(t, 10, result.SampleInitial, "Expected 10 entries before sampling starts")
+		assert.Equal(t, 50, result.SampleThereafter, "Expected 1 in 50 entries to be sampled")
+		assert.Equal(t, 2*time.Second, result.RepeatWindow, "Expected repeats to be collapsed for 2s")
+		assert.Equal(t, true, *result.LogReportEvents, "Expected report events to be logged")
 		assert.Equal(t, "caring-dev", result.Env, "Expected environment to be caring-dev")
+		assert.Equal(t, []string{"userID", "phone"}, result.HashedFieldKeys, "Expected hashed keys to be userID and phone")
+		assert.Equal(t, "devsalt", result.HashSalt, "Expected hash salt to be devsalt")
+		assert.Equal(t, []string{"ssn", "email"}, result.RedactedFieldKeys, "Expected redacted keys to be ssn and email")
+		assert.Equal(t, []string{"callID", "duration"}, result.ReportingFields, "Expected reports to be limited to callID and duration")
+		assert.Equal(t, []string{"request"}, result.ReportingExcludedFields, "Expected request to be excluded from reports")
+		assert.Equal(t, []string{"payload"}, result.MonitoringExcludedFields, "Expected payload to be excluded from monitoring")
+		assert.Equal(t, FormatLogfmt, result.LogFormat, "Expected the logfmt log format")
+		assert.Equal(t, ProfileStaging, result.Profile, "Expected the staging profile")
+		assert.Equal(t, DPanicLevel, *result.StacktraceLevel, "Expected a DPANIC stacktrace level")
+		assert.Equal(t, DebugLevel, *result.StdoutLevel, "Expected a DEBUG stdout level")
+		assert.Equal(t, true, *result.SplitStderr, "Expected errors to be written to stderr")
+		assert.Equal(t, WarnLevel, *result.KinesisLevel, "Expected a WARN kinesis level")
+		assert.Equal(t, InfoLevel, *result.CloudWatchLevel, "Expected an INFO cloudwatch level")
+		assert.Equal(t, "https://alerts.example.com/events", result.WebhookURL, "Expected the webhook url from the environment")
+		assert.Equal(t, FatalLevel, *result.WebhookLevel, "Expected a FATAL webhook level")
+		assert.Equal(t, "/var/log/fooservice.log", result.FilePath, "Expected the log file from the environment")
+		assert.Equal(t, int64(1024), result.FileMaxSize, "Expected files to be rotated at 1024 bytes")
+		assert.Equal(t, time.Hour, result.FileMaxAge, "Expected files to be rotated hourly")
+		assert.Equal(t, 3, result.FileMaxBackups, "Expected 3 rotated files to be kept")
+		assert.Equal(t, ErrorLevel, *result.FileLevel, "Expected an ERROR file level")
+		assert.Equal(t, "localhost:4317", result.OTLPEndpoint, "Expected the OTLP endpoint from the environment")
+		assert.Equal(t, map[string]string{"api-key": "secret", "tenant": "caring"}, result.OTLPHeaders, "Expected the OTLP headers to be parsed")
+		assert.True(t, *result.OTLPInsecure, "Expected OTLP to connect without TLS")
+		assert.Equal(t, WarnLevel, *result.OTLPLevel, "Expected a WARN OTLP level")
+		assert.True(t, *result.EnableHostMetadata, "Expected host metadata to be enabled")
+		assert.Equal(t, true, *result.LinkCallers, "Expected caller links to be enabled")
+		assert.Equal(t, "https://example.com/{revision}/{file}#L{line}", result.SourceURLTemplate, "Expected the source url template from the environment")
+		assert.Equal(t, "abc123", result.SourceRevision, "Expected source revision to be abc123")
+		assert.Equal(t, "/build", result.SourceRoot, "Expected source root to be /build")
+		assert.Equal(t, "/caring/dev", result.CloudWatchLogGroup, "Expected cloudwatch log group to be /caring/dev")
+		assert.Equal(t, "fooservice", result.CloudWatchLogStream, "Expected cloudwatch log stream to be fooservice")
 	})
 
 	t.Run("Initializes all config from environment correctly when given a populated config object", func(t *testing.T) {
@@ -103,8 +264,168 @@ func Test_mergeAndPopulateConfig(t *testing.T) {
 	os.Setenv("LOG_ENABLE_DEV", "")
 	os.Setenv("LOG_STREAM_MONITORING", "")
 	os.Setenv("LOG_STREAM_REPORTING", "")
+	os.Setenv("LOG_REPORTING_GZIP", "")
 	os.Setenv("LOG_DISABLE_KINESIS", "")
+	os.Setenv("LOG_KINESIS_BACKEND", "")
+	os.Setenv("LOG_KINESIS_PARTITION_KEY", "")
+	os.Setenv("LOG_KINESIS_PARTITION_STRATEGY", "")
+	os.Setenv("LOG_KINESIS_REGION", "")
+	os.Setenv("LOG_KINESIS_ROLE_ARN", "")
+	os.Setenv("LOG_KINESIS_EXTERNAL_ID", "")
 	os.Setenv("LOG_FLUSH_INTERVAL", "")
 	os.Setenv("LOG_BUFFER_SIZE", "")
+	os.Setenv("LOG_KINESIS_BATCHING", "")
+	os.Setenv("LOG_KINESIS_AGGREGATION", "")
+	os.Setenv("LOG_BATCH_MAX_RECORDS", "")
+	os.Setenv("LOG_BATCH_MAX_BYTES", "")
+	os.Setenv("LOG_DELIVERY_ATTEMPTS", "")
+	os.Setenv("LOG_DELIVERY_BACKOFF", "")
+	os.Setenv("LOG_DELIVERY_FALLBACK", "")
+	os.Setenv("LOG_QUEUE_SIZE", "")
+	os.Setenv("LOG_QUEUE_POLICY", "")
+	os.Setenv("LOG_SAMPLING", "")
+	os.Setenv("LOG_SAMPLE_INITIAL", "")
+	os.Setenv("LOG_SAMPLE_THEREAFTER", "")
+	os.Setenv("LOG_REPEAT_WINDOW", "")
+	os.Setenv("LOG_REPORT_EVENTS", "")
 	os.Setenv("ENV", "")
+	os.Setenv("LOG_HASH_FIELDS", "")
+	os.Setenv("LOG_HASH_SALT", "")
+	os.Setenv("LOG_REDACT_FIELDS", "")
+	os.Setenv("LOG_REPORTING_FIELDS", "")
+	os.Setenv("LOG_REPORTING_EXCLUDED_FIELDS", "")
+	os.Setenv("LOG_MONITORING_EXCLUDED_FIELDS", "")
+	os.Setenv("LOG_FORMAT", "")
+	os.Setenv("LOG_PROFILE", "")
+	os.Setenv("LOG_STACKTRACE_LEVEL", "")
+	os.Setenv("LOG_STDOUT_LEVEL", "")
+	os.Setenv("LOG_SPLIT_STDERR", "")
+	os.Setenv("LOG_KINESIS_LEVEL", "")
+	os.Setenv("LOG_CLOUDWATCH_LEVEL", "")
+	os.Setenv("LOG_WEBHOOK_URL", "")
+	os.Setenv("LOG_WEBHOOK_LEVEL", "")
+	os.Setenv("LOG_FILE", "")
+	os.Setenv("LOG_FILE_MAX_SIZE", "")
+	os.Setenv("LOG_FILE_MAX_AGE", "")
+	os.Setenv("LOG_FILE_MAX_BACKUPS", "")
+	os.Setenv("LOG_FILE_LEVEL", "")
+	os.Setenv("LOG_OTLP_ENDPOINT", "")
+	os.Setenv("LOG_OTLP_HEADERS", "")
+	os.Setenv("LOG_OTLP_INSECURE", "")
+	os.Setenv("LOG_OTLP_LEVEL", "")
+	os.Setenv("LOG_HOST_METADATA", "")
+	os.Setenv("LOG_LINK_CALLERS", "")
+	os.Setenv("LOG_SOURCE_URL_TEMPLATE", "")
+	os.Setenv("LOG_SOURCE_REVISION", "")
+	os.Setenv("LOG_SOURCE_ROOT", "")
+	os.Setenv("LOG_CLOUDWATCH_GROUP", "")
+	os.Setenv("LOG_CLOUDWATCH_STREAM", "")
+}
+
+func Test_mergeAndPopulateConfigKinesisBackend(t *testing.T) {
+	_, err := mergeAndPopulateConfig(&Config{KinesisBackend: "sqs"})
+	assert.EqualError(t, err, `unrecognized kinesis backend: "sqs"`, "Expected an unknown backend to be rejected")
+}
+
+func Test_mergeAndPopulateConfigPartitionStrategy(t *testing.T) {
+	c, err := mergeAndPopulateConfig(&Config{})
+	require.NoError(t, err)
+	assert.Equal(t, PartitionRandom, c.KinesisPartitionStrategy, "Expected random partition keys by default")
+
+	c, err = mergeAndPopulateConfig(&Config{KinesisPartitionKey: "shard-1"})
+	require.NoError(t, err)
+	assert.Equal(t, PartitionFixed, c.KinesisPartitionStrategy, "Expected a partition key to be used for every record")
+
+	c, err = mergeAndPopulateConfig(&Config{KinesisPartitionKeyFunc: func([]byte) string { return "" }})
+	require.NoError(t, err)
+	assert.Equal(t, PartitionCustom, c.KinesisPartitionStrategy, "Expected a partition key func to be used")
+
+	_, err = mergeAndPopulateConfig(&Config{KinesisPartitionStrategy: PartitionFixed})
+	assert.EqualError(t, err, `partition strategy "fixed" requires a partition key`, "Expected a fixed strategy without a key to be rejected")
+	_, err = mergeAndPopulateConfig(&Config{KinesisPartitionStrategy: PartitionCustom})
+	assert.EqualError(t, err, `partition strategy "custom" requires a partition key func`, "Expected a custom strategy without a func to be rejected")
+	_, err = mergeAndPopulateConfig(&Config{KinesisPartitionStrategy: "session"})
+	assert.EqualError(t, err, `unrecognized partition strategy: "session"`, "Expected an unknown strategy to be rejected")
+}
+
+func Test_mergeAndPopulateConfigKinesisRole(t *testing.T) {
+	_, err := mergeAndPopulateConfig(&Config{KinesisExternalID: "fooservice"})
+	assert.EqualError(t, err, "a kinesis external ID requires a kinesis role", "Expected an external ID without a role to be rejected")
+
+	c, err := mergeAndPopulateConfig(&Config{KinesisRoleArn: "arn:aws:iam::123456789012:role/logging-writer", KinesisExternalID: "fooservice"})
+	require.NoError(t, err)
+	assert.Equal(t, "fooservice", c.KinesisExternalID, "Expected the external ID of the config")
+}
+
+func Test_mergeAndPopulateConfigKinesisAggregation(t *testing.T) {
+	_, err := mergeAndPopulateConfig(&Config{KinesisAggregation: &trueVar, KinesisBatching: &trueVar})
+	assert.EqualError(t, err, `kinesis aggregation requires the "streams" backend with batching`, "Expected aggregation to firehose to be rejected")
+
+	_, err = mergeAndPopulateConfig(&Config{KinesisAggregation: &trueVar, KinesisBackend: KinesisBackendStreams})
+	assert.Error(t, err, "Expected aggregation without batching to be rejected")
+
+	c, err := mergeAndPopulateConfig(&Config{KinesisAggregation: &trueVar, KinesisBackend: KinesisBackendStreams, KinesisBatching: &trueVar})
+	require.NoError(t, err)
+	assert.True(t, *c.KinesisAggregation, "Expected aggregation to be enabled")
+}
+
+func Test_mergeAndPopulateConfigQueuePolicy(t *testing.T) {
+	_, err := mergeAndPopulateConfig(&Config{QueuePolicy: "drop-all"})
+	assert.EqualError(t, err, `unrecognized queue policy: "drop-all"`, "Expected an unknown queue policy to be rejected")
+}
+
+func Test_mergeAndPopulateConfigProfile(t *testing.T) {
+	c, err := mergeAndPopulateConfig(&Config{Profile: ProfileDevelopment})
+	require.NoError(t, err)
+	assert.Equal(t, DebugLevel, c.LogLevel, "Expected the development profile to log at DEBUG")
+	assert.True(t, *c.EnableDevLogging, "Expected the development profile to enable dev logging")
+	assert.True(t, *c.DisableKinesis, "Expected the development profile to disable kinesis")
+	assert.False(t, *c.EnableSampling, "Expected the development profile not to sample")
+	assert.Equal(t, WarnLevel, *c.StacktraceLevel, "Expected the development profile to add stacktraces to warnings")
+
+	c, err = mergeAndPopulateConfig(&Config{Profile: ProfileProduction, EnableSampling: &falseVar, LogLevel: WarnLevel})
+	require.NoError(t, err)
+	assert.Equal(t, WarnLevel, c.LogLevel, "Expected the config to override the profile's level")
+	assert.False(t, *c.EnableSampling, "Expected the config to override the profile's sampling")
+	assert.False(t, *c.EnableDevLogging, "Expected the production profile to disable dev logging")
+	assert.False(t, *c.DisableKinesis, "Expected the production profile to enable kinesis")
+	assert.Equal(t, ErrorLevel, *c.StacktraceLevel, "Expected the production profile to add stacktraces to errors")
+
+	c, err = mergeAndPopulateConfig(&Config{Profile: ProfileDevelopment, LogLevel: InfoLevel, LogLevelSet: true})
+	require.NoError(t, err)
+	assert.Equal(t, InfoLevel, c.LogLevel, "Expected the config to override the profile's level with INFO")
+
+	_, err = mergeAndPopulateConfig(&Config{Profile: "prod"})
+	assert.EqualError(t, err, `unrecognized log profile: "prod"`, "Expected an unknown profile to be rejected")
+}
+
+func Test_mergeAndPopulateConfigEnvProfile(t *testing.T) {
+	c, err := mergeAndPopulateConfig(&Config{Env: "local"})
+	require.NoError(t, err)
+	assert.Equal(t, "", c.Profile, "Expected the local environment not to pick a profile")
+	assert.True(t, *c.EnableDevLogging, "Expected dev logging to be enabled locally")
+	assert.Equal(t, InfoLevel, c.LogLevel, "Expected the local environment to keep the level")
+
+	c, err = mergeAndPopulateConfig(&Config{Env: "local", Profile: ProfileStaging})
+	require.NoError(t, err)
+	assert.Equal(t, ProfileStaging, c.Profile, "Expected the profile to be applied")
+	assert.False(t, *c.EnableDevLogging, "Expected the profile to override the environment's dev logging")
+
+	os.Setenv("ENV", "caring-prod")
+	c, err = mergeAndPopulateConfig(&Config{})
+	os.Setenv("ENV", "")
+	require.NoError(t, err)
+	assert.Equal(t, "", c.Profile, "Expected ENV not to pick a profile")
+	assert.False(t, *c.EnableSampling, "Expected ENV not to enable sampling")
+	assert.Nil(t, c.StacktraceLevel, "Expected ENV not to change the stacktrace level")
+	assert.Equal(t, InfoLevel, c.LogLevel, "Expected ENV not to change the level")
+
+	os.Setenv("ENV", "caring-prod")
+	os.Setenv("LOG_PROFILE", ProfileProduction)
+	c, err = mergeAndPopulateConfig(&Config{})
+	os.Setenv("ENV", "")
+	os.Setenv("LOG_PROFILE", "")
+	require.NoError(t, err)
+	assert.Equal(t, ProfileProduction, c.Profile, "Expected the profile from LOG_PROFILE")
+	assert.True(t, *c.EnableSampling, "Expected the production profile to sample")
 }
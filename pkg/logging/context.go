@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	"go.uber.org/zap"
+)
+
+type correlationIDKey struct{}
+
+type traceabilityIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying the correlation ID, which the Ctx log
+// methods add to each entry
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID ctx carries, or an empty string
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// ContextWithTraceabilityID returns a copy of ctx carrying the traceability ID, which the Ctx log
+// methods add to each entry
+func ContextWithTraceabilityID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceabilityIDKey{}, id)
+}
+
+// TraceabilityIDFromContext returns the traceability ID ctx carries, or an empty string
+func TraceabilityIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceabilityIDKey{}).(string)
+	return id
+}
+
+// getZapFieldsCtx aggregates the Logger fields like getZapFields, with the correlation and traceability
+// IDs ctx carries in place of the logger's, and the trace and span IDs of the span in ctx.
+func (l *Logger) getZapFieldsCtx(ctx context.Context, fields ...DataField) []zap.Field {
+	if ctx == nil {
+		return l.getZapFields(fields...)
+	}
+
+	withCtx := *l
+	withCtx.with(&FieldOpts{
+		CorrelationID:  CorrelationIDFromContext(ctx),
+		TraceabilityID: TraceabilityIDFromContext(ctx),
+	})
+
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if sc, ok := span.Context().(jaeger.SpanContext); ok {
+			fields = append(fields[:len(fields):len(fields)],
+				String("traceID", sc.TraceID().String()),
+				String("spanID", sc.SpanID().String()),
+			)
+		}
+	}
+
+	return withCtx.getZapFields(fields...)
+}
+
+// DebugCtx logs the message at debug level output like Debug, adding the correlation and traceability IDs
+// and the tracing span ctx carries.
+func (l *Logger) DebugCtx(ctx context.Context, message string, additionalFields ...DataField) {
+	f := l.getZapFieldsCtx(ctx, additionalFields...)
+	l.monitorLogger.Debug(message, f...)
+}
+
+// InfoCtx logs the message at info level output like Info, adding the correlation and traceability IDs
+// and the tracing span ctx carries.
+func (l *Logger) InfoCtx(ctx context.Context, message string, additionalFields ...DataField) {
+	f := l.getZapFieldsCtx(ctx, additionalFields...)
+	l.monitorLogger.Info(message, f...)
+}
+
+// WarnCtx logs the message at warn level output like Warn, adding the correlation and traceability IDs
+// and the tracing span ctx carries.
+func (l *Logger) WarnCtx(ctx context.Context, message string, additionalFields ...DataField) {
+	f := l.getZapFieldsCtx(ctx, additionalFields...)
+	l.monitorLogger.Warn(message, f...)
+}
+
+// ErrorCtx logs the message at error level output like Error, adding the correlation and traceability IDs
+// and the tracing span ctx carries.
+func (l *Logger) ErrorCtx(ctx context.Context, message string, additionalFields ...DataField) {
+	f := l.getZapFieldsCtx(ctx, additionalFields...)
+	l.monitorLogger.Error(message, f...)
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_LoggerCtxMethods(t *testing.T) {
+	withLogger(config, func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.With(&FieldOpts{CorrelationID: "loggerCorrelationID"})
+
+		ctx := ContextWithCorrelationID(context.Background(), "ctxCorrelationID")
+		ctx = ContextWithTraceabilityID(ctx, "ctxTraceabilityID")
+
+		logger.InfoCtx(ctx, "with ids", String("foo", "bar"))
+		logger.WarnCtx(context.Background(), "without ids")
+
+		require.Equal(t, 2, logs.Len(), "Expected two entries")
+		assert.Equal(t, commonFields(config, FieldOpts{CorrelationID: "ctxCorrelationID", TraceabilityID: "ctxTraceabilityID"}, zap.String("foo", "bar")), logs.All()[0].Context, "Expected the IDs from the context")
+		assert.Equal(t, commonFields(config, FieldOpts{CorrelationID: "loggerCorrelationID"}), logs.All()[1].Context, "Expected the logger's IDs")
+	})
+}
+
+func Test_LoggerCtxMethodsAddSpanIDs(t *testing.T) {
+	withLogger(config, func(logger *Logger, logs *observer.ObservedLogs) {
+		tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+		defer closer.Close()
+
+		span := tracer.StartSpan("op")
+		defer span.Finish()
+		sc := span.Context().(jaeger.SpanContext)
+
+		logger.ErrorCtx(opentracing.ContextWithSpan(context.Background(), span), "in a span")
+
+		require.Equal(t, 1, logs.Len(), "Expected one entry")
+		assert.Equal(t, commonFields(config, FieldOpts{}, zap.String("traceID", sc.TraceID().String()), zap.String("spanID", sc.SpanID().String())), logs.All()[0].Context, "Expected the span's IDs")
+	})
+}
@@ -0,0 +1,285 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelControl holds the level of a logger and its children, which can be changed while
+// the service is running
+type levelControl struct {
+	level zap.AtomicLevel
+
+	mu sync.Mutex
+	// reverts a temporary level change, nil if the level isn't temporary
+	revert *time.Timer
+	// the levels gRPC methods are logged at in place of the shared one, by full method name
+	methods map[string]methodLevel
+}
+
+// methodLevel is the level of a gRPC method, until it expires
+type methodLevel struct {
+	level Level
+	// zero if the level is permanent
+	expires time.Time
+}
+
+func newLevelControl(level zap.AtomicLevel) *levelControl {
+	return &levelControl{level: level, methods: map[string]methodLevel{}}
+}
+
+// set sets the level, cancelling any pending revert. If d is over 0, the previous
+// level is restored once d has passed
+func (lc *levelControl) set(level Level, d time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	previous := lc.level.Level()
+	if lc.revert != nil {
+		lc.revert.Stop()
+		lc.revert = nil
+	}
+
+	lc.level.SetLevel(zapcore.Level(level))
+
+	if d > 0 {
+		var revert *time.Timer
+		revert = time.AfterFunc(d, func() {
+			lc.mu.Lock()
+			defer lc.mu.Unlock()
+			// a later change may have replaced this one
+			if lc.revert == revert {
+				lc.level.SetLevel(previous)
+				lc.revert = nil
+			}
+		})
+		lc.revert = revert
+	}
+}
+
+// setMethod sets the level of the method. If d is over 0, the method's level is removed once d has passed
+func (lc *levelControl) setMethod(method string, level Level, d time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	m := methodLevel{level: level}
+	if d > 0 {
+		m.expires = time.Now().Add(d)
+	}
+	lc.methods[method] = m
+}
+
+// resetMethod removes the level of the method
+func (lc *levelControl) resetMethod(method string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	delete(lc.methods, method)
+}
+
+// method returns the level of the method, if it has one that hasn't expired. Expired levels are removed
+// when they are next looked up, rather than with a timer for each
+func (lc *levelControl) method(method string) (Level, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	m, ok := lc.methods[method]
+	if !ok {
+		return 0, false
+	}
+	if !m.expires.IsZero() && !time.Now().Before(m.expires) {
+		delete(lc.methods, method)
+		return 0, false
+	}
+	return m.level, true
+}
+
+// Level returns the level the logger, and every logger sharing its outputs, is logging at.
+// For a logger whose level is overridden, it returns the override
+func (l *Logger) Level() Level {
+	if l.levelOverride != nil {
+		return *l.levelOverride
+	}
+	if l.levels == nil {
+		return InfoLevel
+	}
+	return Level(l.levels.level.Level())
+}
+
+// SetLevel changes the level of the logger and every logger sharing its outputs, the parent
+// and all of its children, while the service is running. It cancels any temporary level change.
+// Loggers whose level is overridden keep the override.
+func (l *Logger) SetLevel(level Level) {
+	l.SetLevelFor(level, 0)
+}
+
+// SetLevelFor changes the level like SetLevel, and restores the level it replaced once d has
+// passed, e.g. to turn on debug logs in production for a few minutes. This is what an admin
+// gRPC method that changes the level should call. If d is 0 the change is permanent.
+func (l *Logger) SetLevelFor(level Level, d time.Duration) {
+	if l.levels == nil {
+		return
+	}
+
+	l.levels.set(level, d)
+
+	fields := []DataField{String("level", level.String())}
+	if d > 0 {
+		fields = append(fields, String("revertAfter", d.String()))
+	}
+	l.Warn("log level changed", fields...)
+}
+
+// SetMethodLevel logs requests to the gRPC method at their own level, e.g. to turn on debug logs for one
+// endpoint without the noise of the rest of the service. It applies to the loggers ForMethod returns, which
+// the logctx method level interceptors put on the context of each request. method is the full method name,
+// e.g. /calls.v1.Calls/Create. If d is over 0 the method's level is removed once d has passed.
+func (l *Logger) SetMethodLevel(method string, level Level, d time.Duration) {
+	if l.levels == nil {
+		return
+	}
+
+	l.levels.setMethod(method, level, d)
+
+	fields := []DataField{String("method", method), String("level", level.String())}
+	if d > 0 {
+		fields = append(fields, String("revertAfter", d.String()))
+	}
+	l.Warn("method log level changed", fields...)
+}
+
+// ResetMethodLevel removes the level of the gRPC method, so its requests are logged at the logger's level
+func (l *Logger) ResetMethodLevel(method string) {
+	if l.levels == nil {
+		return
+	}
+	l.levels.resetMethod(method)
+}
+
+// ForMethod returns the logger requests to the gRPC method log with, a child at the method's level if
+// SetMethodLevel gave it one, otherwise l
+func (l *Logger) ForMethod(method string) *Logger {
+	if l.levels == nil {
+		return l
+	}
+	level, ok := l.levels.method(method)
+	if !ok {
+		return l
+	}
+	return l.NewChild(&FieldOpts{Level: &level})
+}
+
+// levelPayload is the body of the level handler's requests and responses
+type levelPayload struct {
+	Level *Level `json:"level"`
+	// The full name of the gRPC method whose level is changed, rather than the logger's. Empty for the logger
+	Method string `json:"method,omitempty"`
+	// How long a level change lasts before the previous level is restored, e.g. "15m". Empty if it is permanent
+	Duration string `json:"duration,omitempty"`
+}
+
+// LevelHandler returns a handler that reports the logger's level on GET, and changes it on PUT, to be mounted
+// on an internal admin mux. The PUT body is JSON like {"level":"debug","duration":"15m"}, where the
+// duration is optional and the level is restored once it has passed. A body with a method, like
+// {"level":"debug","method":"/calls.v1.Calls/Create"}, sets the level of that gRPC method, see SetMethodLevel.
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				enc.Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			if req.Level == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				enc.Encode(map[string]string{"error": "level is required"})
+				return
+			}
+
+			var d time.Duration
+			if req.Duration != "" {
+				var err error
+				if d, err = time.ParseDuration(req.Duration); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					enc.Encode(map[string]string{"error": err.Error()})
+					return
+				}
+			}
+
+			if req.Method != "" {
+				l.SetMethodLevel(req.Method, *req.Level, d)
+				enc.Encode(levelPayload{Level: req.Level, Method: req.Method})
+				return
+			}
+			l.SetLevelFor(*req.Level, d)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			enc.Encode(map[string]string{"error": "only GET and PUT are supported"})
+			return
+		}
+
+		level := l.Level()
+		enc.Encode(levelPayload{Level: &level})
+	})
+}
+
+// levelOption returns the option that checks entries against the logger's level, its override or the shared
+// level, replacing the check of the logger it was derived from
+func (l *Logger) levelOption() zap.Option {
+	var enabler zapcore.LevelEnabler = zapcore.DebugLevel
+	if l.levelOverride != nil {
+		enabler = zapcore.Level(*l.levelOverride)
+	} else if l.levels != nil {
+		enabler = l.levels.level
+	}
+
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newLeveledCore(core, enabler)
+	})
+}
+
+// leveledCore only passes on the entries its logger is logging at. It is the outermost core of a logger, so
+// a child can replace it to log at its own level while sharing the outputs of its parent
+type leveledCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+// newLeveledCore wraps core in a leveledCore, replacing the level check of core if it already has one
+func newLeveledCore(core zapcore.Core, enabler zapcore.LevelEnabler) zapcore.Core {
+	if c, ok := core.(*leveledCore); ok {
+		core = c.Core
+	}
+	return &leveledCore{
+		Core:    core,
+		enabler: enabler,
+	}
+}
+
+func (c *leveledCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *leveledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &leveledCore{
+		Core:    c.Core.With(fields),
+		enabler: c.enabler,
+	}
+}
+
+func (c *leveledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,180 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_SetLevel(t *testing.T) {
+	l, err := NewLogger(&Config{})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	fac, logs := observer.New(l.levels.level)
+	l.monitorLogger = zap.New(fac)
+	child := l.NewChild(nil)
+
+	t.Run("Changes the level of the logger and its children", func(t *testing.T) {
+		child.Debug("dropped")
+		assert.Equal(t, 0, logs.Len(), "Expected debug entries to be dropped at info level")
+
+		l.SetLevel(DebugLevel)
+		child.Debug("written")
+		assert.Equal(t, DebugLevel, child.Level(), "Expected the child to share the level")
+		assert.Equal(t, "written", logs.All()[logs.Len()-1].Message, "Expected debug entries to be written")
+	})
+
+	t.Run("Restores the previous level after a temporary change", func(t *testing.T) {
+		l.SetLevelFor(ErrorLevel, 10*time.Millisecond)
+		assert.Equal(t, ErrorLevel, l.Level(), "Expected the temporary level")
+
+		assert.Eventually(t, func() bool { return l.Level() == DebugLevel }, time.Second, 5*time.Millisecond, "Expected the previous level to be restored")
+	})
+
+	t.Run("A later change cancels the revert", func(t *testing.T) {
+		l.SetLevelFor(ErrorLevel, 10*time.Millisecond)
+		l.SetLevel(WarnLevel)
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, WarnLevel, l.Level(), "Expected the later change to be kept")
+	})
+}
+
+func Test_NewChildLevel(t *testing.T) {
+	l, logs := NewObservedLogger(DebugLevel)
+	warn, debug := WarnLevel, DebugLevel
+
+	noisy := l.NewChild(&FieldOpts{Level: &warn, Endpoint: "http-client"})
+	noisy.Info("dropped")
+	noisy.Warn("written")
+	l.Debug("parent written")
+	assert.Equal(t, []string{"written", "parent written"}, messages(logs.TakeAll()), "Expected only the child to log at WARN")
+	assert.Equal(t, WarnLevel, noisy.Level(), "Expected the child's override")
+	assert.Equal(t, DebugLevel, l.Level(), "Expected the parent's level to be kept")
+
+	t.Run("Is kept by the child's children and level changes", func(t *testing.T) {
+		l.SetLevel(ErrorLevel)
+		defer l.SetLevel(DebugLevel)
+		logs.TakeAll()
+
+		noisy.NewChild(nil).Warn("written")
+		l.Warn("dropped")
+		assert.Equal(t, []string{"written"}, messages(logs.TakeAll()), "Expected the override to be kept")
+	})
+
+	t.Run("Can be lower than the parent's level", func(t *testing.T) {
+		l.SetLevel(ErrorLevel)
+		defer l.SetLevel(DebugLevel)
+		logs.TakeAll()
+
+		l.NewChild(&FieldOpts{Level: &debug}).Debug("written")
+		assert.Equal(t, []string{"written"}, messages(logs.TakeAll()), "Expected the child to log at DEBUG")
+	})
+
+	t.Run("Can be reset", func(t *testing.T) {
+		l.SetLevel(ErrorLevel)
+		defer l.SetLevel(DebugLevel)
+		logs.TakeAll()
+
+		reset := noisy.NewChild(&FieldOpts{ResetLevel: true})
+		reset.Warn("dropped")
+		reset.Error("written")
+		assert.Equal(t, []string{"written"}, messages(logs.TakeAll()), "Expected the shared level")
+		assert.Equal(t, ErrorLevel, reset.Level())
+	})
+}
+
+func Test_SetMethodLevel(t *testing.T) {
+	l, logs := NewObservedLogger(InfoLevel)
+	const create, list = "/calls.v1.Calls/Create", "/calls.v1.Calls/List"
+
+	l.SetMethodLevel(create, DebugLevel, 0)
+	logs.TakeAll()
+
+	l.ForMethod(create).Debug("written")
+	l.ForMethod(list).Debug("dropped")
+	l.Debug("dropped")
+	assert.Equal(t, []string{"written"}, messages(logs.TakeAll()), "Expected only the method to log at DEBUG")
+	assert.Equal(t, l, l.ForMethod(list), "Expected the logger itself for methods without a level")
+
+	t.Run("Is shared with children", func(t *testing.T) {
+		child := l.NewChild(nil, String("team", "care"))
+		assert.Equal(t, DebugLevel, child.ForMethod(create).Level())
+	})
+
+	t.Run("Is removed after a temporary change", func(t *testing.T) {
+		l.SetMethodLevel(list, ErrorLevel, 10*time.Millisecond)
+		assert.Equal(t, ErrorLevel, l.ForMethod(list).Level(), "Expected the temporary level")
+
+		assert.Eventually(t, func() bool { return l.ForMethod(list) == l }, time.Second, 5*time.Millisecond, "Expected the method's level to be removed")
+	})
+
+	t.Run("Can be reset", func(t *testing.T) {
+		l.ResetMethodLevel(create)
+		assert.Equal(t, l, l.ForMethod(create))
+	})
+}
+
+func Test_LevelHandler(t *testing.T) {
+	l, err := NewLogger(&Config{})
+	require.NoError(t, err, "Expected no error creating the logger")
+	h := l.LevelHandler()
+
+	t.Run("Reports the level", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code, "Expected OK")
+		assert.JSONEq(t, `{"level":"info"}`, rec.Body.String(), "Expected the info level")
+	})
+
+	t.Run("Changes the level", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"debug","duration":"1m"}`)))
+
+		assert.Equal(t, http.StatusOK, rec.Code, "Expected OK")
+		assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String(), "Expected the new level")
+		assert.Equal(t, DebugLevel, l.Level(), "Expected the logger to be at debug level")
+	})
+
+	t.Run("Changes the level of a method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"warn","method":"/calls.v1.Calls/Create"}`)))
+
+		assert.Equal(t, http.StatusOK, rec.Code, "Expected OK")
+		assert.JSONEq(t, `{"level":"warn","method":"/calls.v1.Calls/Create"}`, rec.Body.String(), "Expected the method's new level")
+		assert.Equal(t, WarnLevel, l.ForMethod("/calls.v1.Calls/Create").Level(), "Expected the method to be at warn level")
+		assert.Equal(t, DebugLevel, l.Level(), "Expected the logger's level to be unchanged")
+	})
+
+	t.Run("Rejects bad requests", func(t *testing.T) {
+		for _, body := range []string{`{"level":"loud"}`, `{}`, `{"level":"info","duration":"soon"}`} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, "Expected bad request for %s", body)
+		}
+		assert.Equal(t, DebugLevel, l.Level(), "Expected the level to be unchanged")
+	})
+
+	t.Run("Rejects other methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, "Expected method not allowed")
+	})
+}
+
+// messages returns the messages of the entries
+func messages(entries []observer.LoggedEntry) []string {
+	msgs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
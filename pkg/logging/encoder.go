@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// sortedJSONEncoding is the name the sorted JSON encoder is registered with in zap,
+// so it can be selected in the zap config used to build the stdout logger
+const sortedJSONEncoding = "caring-sorted-json"
+
+func init() {
+	err := zap.RegisterEncoder(sortedJSONEncoding, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return sortedEncoder{zapcore.NewJSONEncoder(cfg)}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// encoding returns the name of the zap encoding for the given config
+func encoding(c *Config) string {
+	if c.LogFormat == FormatLogfmt {
+		if *c.SortFields {
+			return sortedLogfmtEncoding
+		}
+		return logfmtEncoding
+	}
+	if *c.SortFields {
+		return sortedJSONEncoding
+	}
+	return "json"
+}
+
+// newMonitoringEncoder builds the encoder used by the monitoring cores for the given config
+func newMonitoringEncoder(c *Config, enc zapcore.EncoderConfig) zapcore.Encoder {
+	if c.LogFormat == FormatLogfmt {
+		if *c.SortFields {
+			return sortedEncoder{newLogfmtEncoder(enc)}
+		}
+		return newLogfmtEncoder(enc)
+	}
+	return newEncoder(c, enc)
+}
+
+// reportingEncoderConfig returns the encoder config of the reporting stream, the monitoring one unless the
+// config sets its own
+func reportingEncoderConfig(c *Config, monitoring zapcore.EncoderConfig) zapcore.EncoderConfig {
+	if c.ReportingEncoderConfig != nil {
+		return *c.ReportingEncoderConfig
+	}
+	return monitoring
+}
+
+// newEncoder builds the JSON encoder used by the reporting core for the given config
+func newEncoder(c *Config, enc zapcore.EncoderConfig) zapcore.Encoder {
+	if *c.SortFields {
+		return sortedEncoder{zapcore.NewJSONEncoder(enc)}
+	}
+	return zapcore.NewJSONEncoder(enc)
+}
+
+// WarehouseEncoderConfig returns an encoder config with the snake_case keys, epoch millisecond times and
+// lowercase levels the warehouse loader expects, to be set as the ReportingEncoderConfig
+func WarehouseEncoderConfig() *zapcore.EncoderConfig {
+	return &zapcore.EncoderConfig{
+		TimeKey:        "timestamp_ms",
+		LevelKey:       "level",
+		NameKey:        "logger_name",
+		CallerKey:      "caller",
+		MessageKey:     "message",
+		StacktraceKey:  "stack_trace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.EpochMillisTimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// sortedEncoder wraps an encoder so the fields of every entry are written in key order,
+// keeping output stable regardless of the order fields were added in
+type sortedEncoder struct {
+	zapcore.Encoder
+}
+
+func (e sortedEncoder) Clone() zapcore.Encoder {
+	return sortedEncoder{e.Encoder.Clone()}
+}
+
+func (e sortedEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	sorted := make([]zapcore.Field, len(fields))
+	copy(sorted, fields)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+
+	return e.Encoder.EncodeEntry(ent, sorted)
+}
+
+// linkCallers reports whether callers should be rewritten into source links for the given config
+func linkCallers(c *Config) bool {
+	return c.SourceURLTemplate != "" && (*c.LinkCallers || *c.EnableDevLogging)
+}
+
+// newSourceLinkCallerEncoder returns a caller encoder that writes the caller as a link built from the
+// template, with the root trimmed from the caller's file
+func newSourceLinkCallerEncoder(template, revision, root string) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		if !caller.Defined {
+			enc.AppendString("undefined")
+			return
+		}
+
+		file := strings.TrimPrefix(strings.TrimPrefix(caller.File, root), "/")
+		r := strings.NewReplacer(
+			"{revision}", revision,
+			"{file}", file,
+			"{line}", strconv.Itoa(caller.Line),
+		)
+		enc.AppendString(r.Replace(template))
+	}
+}
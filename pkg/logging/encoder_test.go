@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_sortedEncoder(t *testing.T) {
+	enc := sortedEncoder{zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})}
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{
+		zap.String("zebra", "z"),
+		zap.String("apple", "a"),
+		zap.Int64("mango", 1),
+	})
+
+	require.NoError(t, err, "Expected no error encoding the entry")
+	assert.Equal(t, `{"msg":"hello","apple":"a","mango":1,"zebra":"z"}`+"\n", buf.String(), "Expected fields in sorted key order")
+}
+
+func Test_sortedEncoderClone(t *testing.T) {
+	enc := sortedEncoder{zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})}
+
+	_, ok := enc.Clone().(sortedEncoder)
+	assert.True(t, ok, "Expected a clone to keep sorting fields")
+}
+
+func Test_sourceLinkCallerEncoder(t *testing.T) {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:   "msg",
+		CallerKey:    "caller",
+		EncodeCaller: newSourceLinkCallerEncoder("https://example.com/blob/{revision}/{file}#L{line}", "abc123", "/build"),
+	})
+
+	caller := zapcore.NewEntryCaller(0, "/build/pkg/thing/thing.go", 42, true)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello", Caller: caller}, nil)
+
+	require.NoError(t, err, "Expected no error encoding the entry")
+	assert.Equal(t, `{"caller":"https://example.com/blob/abc123/pkg/thing/thing.go#L42","msg":"hello"}`+"\n", buf.String(), "Expected the caller to be a source link")
+}
+
+func Test_linkCallers(t *testing.T) {
+	c := newDefaultConfig()
+	assert.False(t, linkCallers(c), "Expected no links by default")
+
+	c.SourceURLTemplate = "https://example.com/{file}"
+	assert.False(t, linkCallers(c), "Expected no links without dev logging or the flag")
+
+	c.EnableDevLogging = &trueVar
+	assert.True(t, linkCallers(c), "Expected links with dev logging")
+
+	c.EnableDevLogging = &falseVar
+	c.LinkCallers = &trueVar
+	assert.True(t, linkCallers(c), "Expected links with the flag set")
+}
+
+func Test_WarehouseEncoderConfig(t *testing.T) {
+	enc := zapcore.NewJSONEncoder(*WarehouseEncoderConfig())
+
+	ts := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zap.InfoLevel, Message: "hello", Time: ts}, []zapcore.Field{
+		zap.Duration("elapsed", 1500*time.Millisecond),
+	})
+
+	require.NoError(t, err, "Expected no error encoding the entry")
+	assert.Equal(t, `{"level":"info","timestamp_ms":1591012800000,"message":"hello","elapsed":1500}`+"\n", buf.String(),
+		"Expected snake_case keys and epoch millis")
+}
+
+func Test_reportingEncoderConfig(t *testing.T) {
+	monitoring := zap.NewProductionEncoderConfig()
+
+	c := &Config{}
+	assert.Equal(t, monitoring.MessageKey, reportingEncoderConfig(c, monitoring).MessageKey, "Expected the monitoring config by default")
+
+	c.ReportingEncoderConfig = WarehouseEncoderConfig()
+	assert.Equal(t, "message", reportingEncoderConfig(c, monitoring).MessageKey, "Expected the reporting config when set")
+}
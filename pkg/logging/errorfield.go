@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+)
+
+// Error constructs a field with the key "error" holding the error, see NamedError
+func Error(err error) Field {
+	return NamedError("error", err)
+}
+
+// NamedError constructs a field holding the error as an object with its message, its fingerprint, the deepest
+// stack trace in its chain, any gRPC code and http status attached to it, and any fields added to it with the
+// errors builder.
+// If err is nil the field is skipped.
+func NamedError(k string, err error) Field {
+	if err == nil {
+		return Field{field: zap.Skip()}
+	}
+
+	return Field{field: zap.Object(k, errorObject{err})}
+}
+
+// errorObject encodes an error as its structured sub-fields
+type errorObject struct {
+	err error
+}
+
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.err.Error())
+	enc.AddString("fingerprint", errors.Fingerprint(e.err))
+
+	if code := errors.GrpcCode(e.err); code != codes.Unknown {
+		enc.AddString("grpcCode", code.String())
+	}
+
+	// http statuses are attached by errors that report them as an int error code
+	var h interface{ ErrorCode() int }
+	if errors.As(e.err, &h) {
+		enc.AddInt("httpStatus", h.ErrorCode())
+	}
+
+	if fields := errors.Fields(e.err); len(fields) > 0 {
+		if err := enc.AddReflected("fields", fields); err != nil {
+			return err
+		}
+	}
+
+	if st := errors.StackTraceOf(e.err); len(st) > 0 {
+		return enc.AddArray("stack", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			for _, f := range st {
+				text, _ := f.MarshalText()
+				arr.AppendByteString(text)
+			}
+			return nil
+		}))
+	}
+
+	return nil
+}
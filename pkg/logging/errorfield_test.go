@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func encodeField(f Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	f.getField().AddTo(enc)
+	return enc.Fields
+}
+
+func Test_Error(t *testing.T) {
+	t.Run("Encodes the message and stack of a plain error", func(t *testing.T) {
+		fields := encodeField(Error(errors.New("boom")))
+
+		e, ok := fields["error"].(map[string]interface{})
+		require.True(t, ok, "Expected the error to be encoded as an object")
+		assert.Equal(t, "boom", e["message"])
+		assert.NotEmpty(t, e["stack"], "Expected the stack trace to be encoded")
+		assert.Contains(t, e["stack"].([]interface{})[0], "Test_Error", "Expected the innermost frame first")
+		assert.NotContains(t, e, "grpcCode")
+		assert.NotContains(t, e, "httpStatus")
+	})
+
+	t.Run("Encodes the codes and fields attached to the error", func(t *testing.T) {
+		err := errors.Build("call not found").
+			WithGrpcCode(codes.NotFound).
+			WithHTTPStatus(http.StatusNotFound).
+			WithField("callID", "abc").
+			Err()
+		fields := encodeField(NamedError("cause", err))
+
+		e := fields["cause"].(map[string]interface{})
+		assert.Equal(t, err.Error(), e["message"])
+		assert.Equal(t, "NotFound", e["grpcCode"])
+		assert.Equal(t, http.StatusNotFound, e["httpStatus"])
+		assert.Equal(t, map[string]interface{}{"callID": "abc"}, e["fields"])
+		assert.Equal(t, errors.Fingerprint(err), e["fingerprint"])
+		assert.Equal(t, e["fingerprint"], errors.FingerprintFromStatus(status.Convert(err)), "Expected the fingerprint in the status details")
+	})
+
+	t.Run("Encodes the same fingerprint for messages that differ", func(t *testing.T) {
+		var fingerprints []interface{}
+		for _, id := range []string{"abc", "def"} {
+			e := encodeField(Error(errors.Errorf("call %s not found", id)))["error"].(map[string]interface{})
+			fingerprints = append(fingerprints, e["fingerprint"])
+		}
+		assert.NotEmpty(t, fingerprints[0])
+		assert.Equal(t, fingerprints[0], fingerprints[1])
+		assert.NotEqual(t, fingerprints[0], errors.Fingerprint(errors.New("call not found")), "Expected errors created elsewhere to differ")
+	})
+
+	t.Run("Skips nil errors", func(t *testing.T) {
+		assert.Empty(t, encodeField(Error(nil)), "Expected no field for a nil error")
+	})
+}
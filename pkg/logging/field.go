@@ -1,6 +1,11 @@
 package logging
 
-import "go.uber.org/zap"
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
 
 type DataField interface {
 	getField() zap.Field
@@ -97,3 +102,96 @@ func Bools(k string, vs []bool) Field {
 
 	return f
 }
+
+// Uint64 constructs a field with a uint64 value
+func Uint64(k string, v uint64) Field {
+	f := Field{}
+	u := zap.Uint64(k, v)
+	f.field = u
+
+	return f
+}
+
+// Uint64s constructs a field with a slice of uint64s value
+func Uint64s(k string, vs []uint64) Field {
+	f := Field{}
+	us := zap.Uint64s(k, vs)
+	f.field = us
+
+	return f
+}
+
+// Ints constructs a field with a slice of ints value
+func Ints(k string, vs []int) Field {
+	f := Field{}
+	is := zap.Ints(k, vs)
+	f.field = is
+
+	return f
+}
+
+// Duration constructs a field with a time.Duration value
+func Duration(k string, v time.Duration) Field {
+	f := Field{}
+	d := zap.Duration(k, v)
+	f.field = d
+
+	return f
+}
+
+// Time constructs a field with a time.Time value
+func Time(k string, v time.Time) Field {
+	f := Field{}
+	t := zap.Time(k, v)
+	f.field = t
+
+	return f
+}
+
+// ByteString constructs a field with a UTF-8 encoded byte slice value, written as a string
+func ByteString(k string, v []byte) Field {
+	f := Field{}
+	bs := zap.ByteString(k, v)
+	f.field = bs
+
+	return f
+}
+
+// Binary constructs a field with an opaque binary blob value, written base64 encoded. Use ByteString for UTF-8 text
+func Binary(k string, v []byte) Field {
+	f := Field{}
+	b := zap.Binary(k, v)
+	f.field = b
+
+	return f
+}
+
+// Stringer constructs a field with the value's String method output. The method is only
+// called if the entry is written
+func Stringer(k string, v fmt.Stringer) Field {
+	f := Field{}
+	st := zap.Stringer(k, v)
+	f.field = st
+
+	return f
+}
+
+// metricField is a field whose value is also recorded as a metric when it is logged
+type metricField struct {
+	Field
+	gauge bool
+	value float64
+}
+
+// Count constructs a field with an int64 value that is also added to the logging_field_count_total
+// counter, labeled with the key, so business events can be counted where they are logged. Negative
+// values are logged but not counted. See Config.MetricsRegisterer
+func Count(k string, n int64) DataField {
+	return metricField{Field: Int64(k, n), value: float64(n)}
+}
+
+// Gauge constructs a field with a float64 value that the logging_field_gauge gauge, labeled with the
+// key, is also set to. See Config.MetricsRegisterer
+func Gauge(k string, v float64) DataField {
+	return metricField{Field: Float64(k, v), gauge: true, value: v}
+}
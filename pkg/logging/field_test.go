@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_FieldConstructors(t *testing.T) {
+	now := time.Now()
+	ip := net.IPv4(10, 0, 0, 1)
+
+	tests := []struct {
+		name  string
+		field Field
+		want  zap.Field
+	}{
+		{"Uint64", Uint64("k", 42), zap.Uint64("k", 42)},
+		{"Uint64s", Uint64s("k", []uint64{1, 2}), zap.Uint64s("k", []uint64{1, 2})},
+		{"Ints", Ints("k", []int{1, 2}), zap.Ints("k", []int{1, 2})},
+		{"Duration", Duration("k", time.Second), zap.Duration("k", time.Second)},
+		{"Time", Time("k", now), zap.Time("k", now)},
+		{"ByteString", ByteString("k", []byte("text")), zap.ByteString("k", []byte("text"))},
+		{"Binary", Binary("k", []byte{0x1}), zap.Binary("k", []byte{0x1})},
+		{"Stringer", Stringer("k", ip), zap.Stringer("k", ip)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.field.getField(), "Expected the equivalent zap field")
+		})
+	}
+}
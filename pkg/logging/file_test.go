@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoggerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	require.NoError(t, err, "Expected no error creating a temp dir")
+	defer os.RemoveAll(dir)
+
+	l, err := NewLogger(&Config{
+		LogLevel:       DebugLevel,
+		FilePath:       filepath.Join(dir, "service.log"),
+		FileMaxSize:    512,
+		FileMaxBackups: 2,
+	})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	for i := 0; i < 20; i++ {
+		l.Info("routine", Int64("i", int64(i)))
+		// rotated files are named to the millisecond
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, l.Close(), "Expected no error closing the logger")
+
+	files, err := filepath.Glob(filepath.Join(dir, "service*.log"))
+	require.NoError(t, err)
+	assert.Len(t, files, 3, "Expected the current file and 2 rotated files")
+
+	for _, name := range files {
+		b, err := ioutil.ReadFile(name)
+		require.NoError(t, err)
+		assert.True(t, len(b) <= 512, "Expected %s to be within the max size", name)
+
+		for _, line := range bytes.Split(bytes.TrimSpace(b), []byte("\n")) {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal(line, &entry), "Expected every line to be a whole entry")
+		}
+	}
+}
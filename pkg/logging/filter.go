@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// standardFieldKeys are the fields every entry carries, plus the ID of reports, kept whatever the field filters
+var standardFieldKeys = []string{"service", "endpoint", "traceabilityID", "correlationID", "userID", "clientID", "env", "reportID"}
+
+// fieldFilter decides which fields are written to an output by their key
+type fieldFilter struct {
+	// the only keys kept, every key is kept when nil
+	allow map[string]struct{}
+	// the keys dropped
+	deny map[string]struct{}
+}
+
+// newFieldFilter builds a filter that keeps only the allowed keys, if any are given, and drops the denied
+// keys. The standard fields are always kept. It returns nil if no keys are given, so nothing is filtered
+func newFieldFilter(allowed, denied []string) *fieldFilter {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil
+	}
+
+	f := &fieldFilter{
+		deny: make(map[string]struct{}, len(denied)),
+	}
+	if len(allowed) > 0 {
+		f.allow = make(map[string]struct{}, len(allowed)+len(standardFieldKeys))
+		for _, k := range allowed {
+			f.allow[k] = struct{}{}
+		}
+		for _, k := range standardFieldKeys {
+			f.allow[k] = struct{}{}
+		}
+	}
+	for _, k := range denied {
+		f.deny[k] = struct{}{}
+	}
+	for _, k := range standardFieldKeys {
+		delete(f.deny, k)
+	}
+
+	return f
+}
+
+func (f *fieldFilter) keep(key string) bool {
+	if _, ok := f.deny[key]; ok {
+		return false
+	}
+	if f.allow == nil {
+		return true
+	}
+	_, ok := f.allow[key]
+	return ok
+}
+
+// apply returns the fields the filter keeps. The fields are copied, they may be shared with other outputs
+func (f *fieldFilter) apply(fields []zapcore.Field) []zapcore.Field {
+	kept := make([]zapcore.Field, 0, len(fields))
+	for _, field := range fields {
+		if f.keep(field.Key) {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// filteredCore only writes the fields its filter keeps to the wrapped core. Like routedCore,
+// it also applies to writes that skip Check, such as those of the tee core
+type filteredCore struct {
+	zapcore.Core
+	filter *fieldFilter
+}
+
+func newFilteredCore(core zapcore.Core, filter *fieldFilter) zapcore.Core {
+	return &filteredCore{
+		Core:   core,
+		filter: filter,
+	}
+}
+
+func (c *filteredCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filteredCore{
+		Core:   c.Core.With(c.filter.apply(fields)),
+		filter: c.filter,
+	}
+}
+
+func (c *filteredCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// added itself, rather than the wrapped core, so the fields are filtered when the entry is written
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *filteredCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.filter.apply(fields))
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_newFieldFilter(t *testing.T) {
+	assert.Nil(t, newFieldFilter(nil, nil), "Expected no filter without any keys")
+
+	allow := newFieldFilter([]string{"callID"}, []string{"service", "duration"})
+	assert.True(t, allow.keep("callID"), "Expected allowed keys to be kept")
+	assert.True(t, allow.keep("correlationID"), "Expected standard fields to be kept")
+	assert.True(t, allow.keep("service"), "Expected standard fields to be kept even when denied")
+	assert.False(t, allow.keep("payload"), "Expected keys that aren't allowed to be dropped")
+
+	deny := newFieldFilter(nil, []string{"payload"})
+	assert.True(t, deny.keep("callID"), "Expected every key but the denied ones to be kept")
+	assert.False(t, deny.keep("payload"), "Expected denied keys to be dropped")
+}
+
+func Test_filteredCore(t *testing.T) {
+	fac, logs := observer.New(zap.InfoLevel)
+	filter := newFieldFilter([]string{"callID"}, nil)
+
+	lc := newLifecycle(zap.NewProductionEncoderConfig())
+	zapL := zap.New(newFilteredCore(fac, filter)).WithOptions(lc.wrap()).With(zap.String("payload", "bulky"))
+
+	zapL.Info("call ended", zap.String("callID", "call-1"), zap.String("request", "bulky"), zap.String("reportID", "report-1"))
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, []zap.Field{zap.String("callID", "call-1"), zap.String("reportID", "report-1")}, logs.All()[0].Context,
+		"Expected only the allowed fields and standard fields to be written")
+}
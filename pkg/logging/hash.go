@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldHasher decides which field values the scrubber replaces with a
+// salted SHA-256 digest. The same value and salt always produce the same digest,
+// so hashed identifiers can still be joined on downstream.
+type fieldHasher struct {
+	salt string
+	// lower cased, so keys match whatever their case, like the redactor's
+	keys map[string]struct{}
+}
+
+// newFieldHasher returns a hasher for the given keys, or nil if there are no keys to hash
+func newFieldHasher(salt string, keys []string) *fieldHasher {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	h := &fieldHasher{
+		salt: salt,
+		keys: make(map[string]struct{}, len(keys)),
+	}
+	for _, k := range keys {
+		h.keys[strings.ToLower(k)] = struct{}{}
+	}
+
+	return h
+}
+
+// configured reports whether key is hashed, whatever its case. It is safe to call on a nil hasher
+func (h *fieldHasher) configured(key string) bool {
+	if h == nil {
+		return false
+	}
+
+	_, ok := h.keys[strings.ToLower(key)]
+	return ok
+}
+
+// hash returns the hex encoded SHA-256 digest of the salted value
+func (h *fieldHasher) hash(v string) string {
+	sum := sha256.Sum256([]byte(h.salt + v))
+	return hex.EncodeToString(sum[:])
+}
+
+// fieldValue renders the value of any zap field as a string
+func fieldValue(f zap.Field) string {
+	if f.Type == zapcore.StringType {
+		return f.String
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	v, ok := enc.Fields[f.Key]
+	if !ok || v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
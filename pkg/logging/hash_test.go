@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_fieldHasher(t *testing.T) {
+	t.Run("Returns nil when no keys are configured", func(t *testing.T) {
+		assert.Nil(t, newFieldHasher("salt", nil), "Expected a nil hasher")
+	})
+
+	t.Run("Hashes configured keys with a stable salted digest, whatever their case", func(t *testing.T) {
+		h := newFieldHasher("salt", []string{"userID", "phone"})
+		s := newFieldScrubber(h, nil)
+		fields := s.apply([]zap.Field{
+			zap.String("userID", "abc"),
+			zap.Int64("Phone", 5551234),
+			zap.String("other", "abc"),
+			zap.String("userid", "abc"),
+		})
+
+		assert.Equal(t, zap.String("userID", h.hash("abc")), fields[0], "Expected userID to be hashed")
+		assert.Equal(t, zap.String("Phone", h.hash("5551234")), fields[1], "Expected non string values to be hashed")
+		assert.Equal(t, zap.String("other", "abc"), fields[2], "Expected unconfigured keys to be untouched")
+		assert.Equal(t, fields[0].String, fields[3].String, "Expected keys to match whatever their case")
+		assert.Len(t, fields[0].String, 64, "Expected a hex encoded SHA-256 digest")
+	})
+
+	t.Run("Different salts produce different digests", func(t *testing.T) {
+		a := newFieldHasher("salt-a", []string{"userID"})
+		b := newFieldHasher("salt-b", []string{"userID"})
+
+		assert.NotEqual(t, a.hash("abc"), b.hash("abc"), "Expected the salt to change the digest")
+	})
+
+	t.Run("Leaves empty values untouched", func(t *testing.T) {
+		s := newFieldScrubber(newFieldHasher("salt", []string{"userID"}), nil)
+		fields := s.apply([]zap.Field{zap.String("userID", "")})
+
+		assert.Equal(t, zap.String("userID", ""), fields[0], "Expected empty values not to be hashed")
+	})
+
+	t.Run("Hashes the keys nested in objects", func(t *testing.T) {
+		h := newFieldHasher("salt", []string{"userID"})
+		s := newFieldScrubber(h, nil)
+		f := s.apply([]zap.Field{zap.Object("caller", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("userID", "abc")
+			return nil
+		}))})[0]
+
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		assert.Equal(t, map[string]interface{}{"userID": h.hash("abc")}, enc.Fields["caller"])
+	})
+}
+
+func Test_LoggerHashesFields(t *testing.T) {
+	c := &Config{
+		LogLevel:        DebugLevel,
+		HashedFieldKeys: []string{"userID", "email"},
+		HashSalt:        "caring-dev",
+	}
+
+	var written []map[string]interface{}
+	c.OnLog = []LogHook{func(level Level, message string, fields map[string]interface{}) {
+		written = append(written, fields)
+	}}
+	l, err := NewLogger(c)
+	require.NoError(t, err, "Expected no error creating the logger")
+	defer l.Close()
+	// the entry echoing the config
+	written = nil
+
+	l.With(&FieldOpts{UserID: "someuser"})
+	l.Info("", String("email", "someone@caring.com"))
+	l.GetInternalLogger().Info("", zap.String("email", "someone@caring.com"))
+
+	h := newFieldHasher(c.HashSalt, c.HashedFieldKeys)
+	require.Len(t, written, 2)
+	assert.Equal(t, h.hash("someuser"), written[0]["userID"], "Expected the internal userID field to be hashed")
+	assert.Equal(t, h.hash("someone@caring.com"), written[0]["email"], "Expected the additional email field to be hashed")
+	assert.Equal(t, h.hash("someone@caring.com"), written[1]["email"], "Expected fields added to the internal logger to be hashed")
+}
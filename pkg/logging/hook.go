@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// LogHook is called with the level, message and fields of each entry once it has been written to the monitoring
+// outputs, e.g. to count errors or forward them to Sentry. The fields are the ones written, after redaction,
+// hashing and filtering, decoded into their logged values. Hooks are called synchronously on the logging goroutine,
+// so they should be quick and must not log with the logger that calls them
+type LogHook func(level Level, message string, fields map[string]interface{})
+
+// hookCore calls its hooks after each successful write to the wrapped core. Like routedCore, it also
+// applies to writes that skip Check, such as those of the tee core
+type hookCore struct {
+	zapcore.Core
+	hooks []LogHook
+	// the fields added with With, which the wrapped core has encoded and doesn't pass to Write
+	fields []zapcore.Field
+}
+
+func newHookCore(core zapcore.Core, hooks []LogHook) zapcore.Core {
+	return &hookCore{
+		Core:  core,
+		hooks: hooks,
+	}
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{
+		Core:   c.Core.With(fields),
+		hooks:  c.hooks,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// added itself, rather than the wrapped core, so the hooks are called when the entry is written
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if err := c.Core.Write(ent, fields); err != nil {
+		return err
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	for _, hook := range c.hooks {
+		hook(Level(ent.Level), ent.Message, enc.Fields)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type failingCore struct {
+	zapcore.Core
+}
+
+func (failingCore) Write(zapcore.Entry, []zapcore.Field) error {
+	return errors.New("output unavailable")
+}
+
+func Test_hookCore(t *testing.T) {
+	var calls []string
+	hook := func(level Level, message string, fields map[string]interface{}) {
+		calls = append(calls, message)
+		assert.Equal(t, ErrorLevel, level)
+		assert.Equal(t, map[string]interface{}{"child": "yes", "callID": "call-1"}, fields,
+			"Expected the hook to be given the fields added with With and at the call")
+	}
+
+	fac, _ := observer.New(zap.InfoLevel)
+	lc := newLifecycle(zap.NewProductionEncoderConfig())
+	zapL := zap.New(newHookCore(fac, []LogHook{hook})).WithOptions(lc.wrap()).With(zap.String("child", "yes"))
+
+	zapL.Error("on fire", zap.String("callID", "call-1"))
+	assert.Equal(t, []string{"on fire"}, calls)
+
+	failing := zap.New(newHookCore(failingCore{fac}, []LogHook{hook}))
+	failing.Error("lost")
+	assert.Equal(t, []string{"on fire"}, calls, "Expected hooks not to be called when the write fails")
+}
+
+func Test_LoggerOnLog(t *testing.T) {
+	var errs []string
+	countErrors := func(level Level, message string, fields map[string]interface{}) {
+		if level >= ErrorLevel {
+			errs = append(errs, message)
+		}
+	}
+
+	l, err := NewLogger(&Config{
+		LogLevel:                 DebugLevel,
+		OnLog:                    []LogHook{countErrors},
+		MonitoringExcludedFields: []string{"payload"},
+		RedactedFieldKeys:        []string{"ssn"},
+	})
+	require.NoError(t, err, "Expected no error creating the logger")
+	defer l.Close()
+
+	var fields map[string]interface{}
+	hooked, err := NewLogger(&Config{
+		OnLog: []LogHook{func(level Level, message string, f map[string]interface{}) {
+			fields = f
+		}},
+		MonitoringExcludedFields: []string{"payload"},
+		RedactedFieldKeys:        []string{"ssn"},
+	})
+	require.NoError(t, err, "Expected no error creating the logger")
+	defer hooked.Close()
+
+	hooked.Info("written", String("payload", "bulky"), String("ssn", "123-45-6789"), String("callID", "call-1"))
+	assert.Equal(t, "call-1", fields["callID"])
+	assert.Equal(t, "[redacted]", fields["ssn"], "Expected the hook to be given the redacted value")
+	assert.NotContains(t, fields, "payload", "Expected the hook to be given only the fields that were written")
+
+	l.Info("routine")
+	l.Error("on fire")
+	l.NewChild(nil).Error("child on fire")
+	l.Report("signed up")
+	assert.Equal(t, []string{"on fire", "child on fire"}, errs)
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+)
+
+// HostMetadata describes the host and process a service runs in. Values that couldn't be detected are empty
+type HostMetadata struct {
+	Hostname string
+	PID      int
+	// The ID of the container the process runs in
+	ContainerID string
+	// The ARN of the ECS task the container belongs to, when running on ECS
+	ECSTaskARN string
+	// The name of the Kubernetes pod the container belongs to, when running on Kubernetes
+	PodName string
+	// The availability zone the task runs in, when running on ECS
+	AvailabilityZone string
+}
+
+var (
+	// the file the container ID is read from
+	cgroupPath = "/proc/self/cgroup"
+	// container runtimes name the cgroups of a container after its 64 character ID
+	containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+	// how long the ECS task metadata endpoint is waited on
+	ecsMetadataTimeout = time.Second
+)
+
+// DetectHostMetadata detects the metadata of the host and process, reading the ECS task metadata endpoint when
+// running on ECS. It is detected once per logger with host metadata enabled, and is also used to tag spans
+func DetectHostMetadata() HostMetadata {
+	m := HostMetadata{
+		PID: os.Getpid(),
+	}
+	m.Hostname, _ = os.Hostname()
+
+	if b, err := ioutil.ReadFile(cgroupPath); err == nil {
+		m.ContainerID = containerIDPattern.FindString(string(b))
+	}
+
+	if uri := os.Getenv("ECS_CONTAINER_METADATA_URI_V4"); uri != "" {
+		m.ECSTaskARN, m.AvailabilityZone = ecsTaskMetadata(uri)
+	}
+
+	// the pod name is exposed with the downward API as POD_NAME, pods are otherwise named after their hostname
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		m.PodName = os.Getenv("POD_NAME")
+		if m.PodName == "" {
+			m.PodName = m.Hostname
+		}
+	}
+
+	return m
+}
+
+// ecsTaskMetadata returns the task ARN and availability zone from the ECS task metadata endpoint, or empty
+// strings if it can't be read
+func ecsTaskMetadata(uri string) (string, string) {
+	client := http.Client{Timeout: ecsMetadataTimeout}
+	resp, err := client.Get(uri + "/task")
+	if err != nil {
+		return "", ""
+	}
+	defer resp.Body.Close()
+
+	var task struct {
+		TaskARN          string
+		AvailabilityZone string
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&task) != nil {
+		return "", ""
+	}
+	return task.TaskARN, task.AvailabilityZone
+}
+
+// fields returns the metadata as log fields, leaving out the values that weren't detected
+func (m HostMetadata) fields() []DataField {
+	fields := []DataField{Int64("pid", int64(m.PID))}
+
+	for _, f := range []struct{ key, value string }{
+		{"hostname", m.Hostname},
+		{"containerID", m.ContainerID},
+		{"ecsTaskARN", m.ECSTaskARN},
+		{"podName", m.PodName},
+		{"availabilityZone", m.AvailabilityZone},
+	} {
+		if f.value != "" {
+			fields = append(fields, String(f.key, f.value))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContainerID = "3d2b5e1a9f0c4b7d8e6f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d"
+
+func Test_DetectHostMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "host")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cgroup")
+	require.NoError(t, ioutil.WriteFile(path, []byte("1:name=systemd:/ecs/task-1/"+testContainerID+"\n"), 0644))
+	defer func(p string) { cgroupPath = p }(cgroupPath)
+	cgroupPath = path
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v4/task", r.URL.Path)
+		w.Write([]byte(`{"TaskARN":"arn:aws:ecs:us-east-1:1:task/cluster/task-1","AvailabilityZone":"us-east-1a"}`))
+	}))
+	defer server.Close()
+	os.Setenv("ECS_CONTAINER_METADATA_URI_V4", server.URL+"/v4")
+	defer os.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+
+	m := DetectHostMetadata()
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, m.Hostname)
+	assert.Equal(t, os.Getpid(), m.PID)
+	assert.Equal(t, testContainerID, m.ContainerID, "Expected the container ID from the cgroup")
+	assert.Equal(t, "arn:aws:ecs:us-east-1:1:task/cluster/task-1", m.ECSTaskARN, "Expected the task ARN from the ECS metadata")
+	assert.Equal(t, "us-east-1a", m.AvailabilityZone, "Expected the availability zone from the ECS metadata")
+
+	var fields map[string]interface{}
+	l, err := NewLogger(&Config{
+		EnableHostMetadata: &trueVar,
+		OnLog: []LogHook{func(level Level, message string, f map[string]interface{}) {
+			fields = f
+		}},
+	})
+	require.NoError(t, err, "Expected no error creating the logger")
+	defer l.Close()
+
+	l.NewChild(nil).Info("routine")
+	assert.Equal(t, testContainerID, fields["containerID"], "Expected every entry to carry the host metadata")
+	assert.Equal(t, "us-east-1a", fields["availabilityZone"])
+	assert.NotContains(t, fields, "podName", "Expected metadata that wasn't detected to be left out")
+}
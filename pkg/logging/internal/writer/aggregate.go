@@ -0,0 +1,109 @@
+package writer
+
+import (
+	"crypto/md5"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MaxAggregatedBytes is the most bytes of entries packed into one aggregated record, the default of the KPL
+const MaxAggregatedBytes = 50 * 1024
+
+// kplMagic prefixes every record aggregated in the KPL format, so consumers can tell them from plain records
+var kplMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+// aggregatedRecord is a record packing many entries in the KPL format, and the records of the entries it packs
+type aggregatedRecord struct {
+	data         []byte
+	partitionKey string
+	records      [][]byte
+}
+
+// aggregate packs the records into KPL aggregated records of at most maxBytes of entries each, so many small
+// entries are put as one record. Each entry keeps its own partition key in the record's key table, while the
+// aggregated record is put with the key of its first entry. The KCL and the deaggregation libraries unpack them.
+// If compress is set each entry is gzipped once its key is chosen from the record
+func aggregate(records [][]byte, partitionKey PartitionKeyFunc, compress bool, maxBytes int) ([]aggregatedRecord, error) {
+	var (
+		out     []aggregatedRecord
+		current *aggregator
+	)
+	for _, r := range records {
+		key := partitionKeyOf(r, partitionKey)
+		data := r
+		if compress {
+			var err error
+			if data, err = gzipRecord(r); err != nil {
+				return nil, err
+			}
+		}
+
+		if current != nil && current.size+len(data) > maxBytes {
+			out = append(out, current.record())
+			current = nil
+		}
+		if current == nil {
+			current = &aggregator{keys: map[string]uint64{}}
+		}
+		current.add(r, data, key)
+	}
+	if current != nil {
+		out = append(out, current.record())
+	}
+	return out, nil
+}
+
+// aggregator collects the entries of one aggregated record
+type aggregator struct {
+	keys     map[string]uint64
+	keyTable []string
+	indexes  []uint64
+	records  [][]byte
+	data     [][]byte
+	size     int
+}
+
+// add packs the data of the record with the key
+func (a *aggregator) add(record, data []byte, key string) {
+	i, ok := a.keys[key]
+	if !ok {
+		i = uint64(len(a.keyTable))
+		a.keys[key] = i
+		a.keyTable = append(a.keyTable, key)
+	}
+	a.indexes = append(a.indexes, i)
+	a.records = append(a.records, record)
+	a.data = append(a.data, data)
+	a.size += len(data)
+}
+
+// record encodes the AggregatedRecord message of the KPL, between the magic bytes and the MD5 digest of the message
+func (a *aggregator) record() aggregatedRecord {
+	var msg []byte
+	for _, key := range a.keyTable {
+		// partition_key_table
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, key)
+	}
+	for i, data := range a.data {
+		var r []byte
+		// partition_key_index
+		r = protowire.AppendTag(r, 1, protowire.VarintType)
+		r = protowire.AppendVarint(r, a.indexes[i])
+		// data
+		r = protowire.AppendTag(r, 3, protowire.BytesType)
+		r = protowire.AppendBytes(r, data)
+
+		// records
+		msg = protowire.AppendTag(msg, 3, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, r)
+	}
+
+	sum := md5.Sum(msg)
+	data := make([]byte, 0, len(kplMagic)+len(msg)+len(sum))
+	data = append(data, kplMagic...)
+	data = append(data, msg...)
+	data = append(data, sum[:]...)
+
+	return aggregatedRecord{data: data, partitionKey: a.keyTable[0], records: a.records}
+}
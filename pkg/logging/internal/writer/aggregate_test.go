@@ -0,0 +1,106 @@
+package writer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// kplEntry is an entry decoded from an aggregated record
+type kplEntry struct {
+	key  string
+	data string
+}
+
+// decodeKPL decodes an aggregated record the way the deaggregation libraries do, checking its magic bytes and digest
+func decodeKPL(t *testing.T, data []byte) []kplEntry {
+	t.Helper()
+
+	require.True(t, bytes.HasPrefix(data, kplMagic), "Expected the record to start with the KPL magic bytes")
+	require.True(t, len(data) >= len(kplMagic)+md5.Size, "Expected the record to hold a digest")
+	msg := data[len(kplMagic) : len(data)-md5.Size]
+	sum := md5.Sum(msg)
+	require.Equal(t, sum[:], data[len(data)-md5.Size:], "Expected the record to end with the MD5 digest of the message")
+
+	var (
+		keys    []string
+		entries []kplEntry
+	)
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		require.True(t, n > 0, "Expected a valid tag")
+		msg = msg[n:]
+		require.Equal(t, protowire.BytesType, typ, "Expected field %d to be length delimited", num)
+		v, n := protowire.ConsumeBytes(msg)
+		require.True(t, n > 0, "Expected valid bytes")
+		msg = msg[n:]
+
+		switch num {
+		case 1:
+			keys = append(keys, string(v))
+		case 3:
+			var (
+				index uint64
+				entry kplEntry
+			)
+			for len(v) > 0 {
+				num, typ, n := protowire.ConsumeTag(v)
+				require.True(t, n > 0, "Expected a valid tag in the record")
+				v = v[n:]
+				switch {
+				case num == 1 && typ == protowire.VarintType:
+					index, n = protowire.ConsumeVarint(v)
+				case num == 3 && typ == protowire.BytesType:
+					var b []byte
+					b, n = protowire.ConsumeBytes(v)
+					entry.data = string(b)
+				default:
+					t.Fatalf("unexpected field %d of type %d in the record", num, typ)
+				}
+				require.True(t, n > 0, "Expected a valid value in the record")
+				v = v[n:]
+			}
+			require.True(t, index < uint64(len(keys)), "Expected the key index to be in the key table")
+			entry.key = keys[index]
+			entries = append(entries, entry)
+		default:
+			t.Fatalf("unexpected field %d in the aggregated record", num)
+		}
+	}
+	return entries
+}
+
+func Test_aggregate(t *testing.T) {
+	records := [][]byte{[]byte(`{"callID":"a"}`), []byte(`{"callID":"b"}`), []byte(`{"callID":"a","n":2}`)}
+	keyOf := func(r []byte) string {
+		return string(r[11:12])
+	}
+
+	out, err := aggregate(records, keyOf, false, MaxAggregatedBytes)
+	require.NoError(t, err)
+
+	require.Len(t, out, 1, "Expected the records to be packed into one")
+	assert.Equal(t, []byte{0xF3, 0x89, 0x9A, 0xC2}, out[0].data[:4], "Expected the magic bytes of the KPL")
+	assert.Equal(t, "a", out[0].partitionKey, "Expected the key of the first entry")
+	assert.Equal(t, records, out[0].records)
+	assert.Equal(t, []kplEntry{
+		{key: "a", data: `{"callID":"a"}`},
+		{key: "b", data: `{"callID":"b"}`},
+		{key: "a", data: `{"callID":"a","n":2}`},
+	}, decodeKPL(t, out[0].data), "Expected each entry with its own key")
+}
+
+func Test_aggregateSplitsAtMaxBytes(t *testing.T) {
+	records := [][]byte{[]byte("aaaa"), []byte("bbbb"), []byte("cccc")}
+
+	out, err := aggregate(records, FixedPartitionKey("k"), false, 8)
+	require.NoError(t, err)
+
+	require.Len(t, out, 2, "Expected a new record once the max bytes are reached")
+	assert.Equal(t, []kplEntry{{"k", "aaaa"}, {"k", "bbbb"}}, decodeKPL(t, out[0].data))
+	assert.Equal(t, []kplEntry{{"k", "cccc"}}, decodeKPL(t, out[1].data))
+}
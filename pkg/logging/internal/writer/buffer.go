@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"log"
+	"sync"
 	"time"
 
 	"go.uber.org/zap/zapcore"
@@ -12,7 +13,9 @@ import (
 
 type bufferWriterSyncer struct {
 	bufferWriter *bufio.Writer
+	metrics      Metrics
 	cancel       context.CancelFunc
+	closeOnce    sync.Once
 }
 
 // defaultBufferSize sizes the buffer associated with each WriterSync.
@@ -26,7 +29,8 @@ const (
 // Buffer wraps a WriteSyncer in a buffer to improve performance,
 // if bufferSize = 0, we set it to defaultBufferSize
 // if flushInterval = 0, we set it to defaultFlushInterval
-func Buffer(writer zapcore.WriteSyncer, bufferSize int, flushInterval time.Duration) (zapcore.WriteSyncer, io.Closer) {
+// Entries written, bytes buffered and flushes are counted in the metrics
+func Buffer(writer zapcore.WriteSyncer, bufferSize int, flushInterval time.Duration, metrics Metrics) (zapcore.WriteSyncer, io.Closer) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	if bufferSize == 0 {
@@ -39,6 +43,7 @@ func Buffer(writer zapcore.WriteSyncer, bufferSize int, flushInterval time.Durat
 
 	bw := &bufferWriterSyncer{
 		bufferWriter: bufio.NewWriterSize(writer, bufferSize),
+		metrics:      metrics,
 		cancel:       cancel,
 	}
 
@@ -74,23 +79,45 @@ func (s *bufferWriterSyncer) Write(bs []byte) (int, error) {
 	// this would lead to log spliting, which is not acceptable for log collector
 	// so we need to flush bufferWriter before writing the data into bufferWriter
 	if len(bs) > s.bufferWriter.Available() && s.bufferWriter.Buffered() > 0 {
-		err := s.bufferWriter.Flush()
+		err := s.flush()
 		if err != nil {
 			return 0, err
 		}
 	}
 
-	return s.bufferWriter.Write(bs)
+	n, err := s.bufferWriter.Write(bs)
+	if err == nil {
+		s.metrics.entry()
+	}
+	s.metrics.buffered(s.bufferWriter.Buffered())
+	return n, err
 }
 
 // Sync flushes the underlying buffer into its write destination
 func (s *bufferWriterSyncer) Sync() error {
-	return s.bufferWriter.Flush()
+	if s.bufferWriter.Buffered() == 0 {
+		// still flushed, so an earlier error is returned
+		return s.bufferWriter.Flush()
+	}
+	return s.flush()
+}
+
+// flush flushes the buffer, counting the flush
+func (s *bufferWriterSyncer) flush() error {
+	start := time.Now()
+	err := s.bufferWriter.Flush()
+	s.metrics.flushed(start, err)
+	s.metrics.buffered(s.bufferWriter.Buffered())
+	return err
 }
 
 // Close syncs the buffer and closes the underlying go routines that manage
-// regular flushes
+// regular flushes. Only the first call has any effect
 func (s *bufferWriterSyncer) Close() error {
-	s.cancel()
-	return s.Sync()
+	var err error
+	s.closeOnce.Do(func() {
+		s.cancel()
+		err = s.Sync()
+	})
+	return err
 }
@@ -0,0 +1,147 @@
+package writer
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+)
+
+// CloudWatch Logs limits on a single PutLogEvents call
+const (
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchMaxBatchBytes  = 1024 * 1024
+	cloudWatchMaxEventBytes  = 256 * 1024
+	// each event counts for this many bytes on top of its message
+	cloudWatchEventOverhead = 26
+)
+
+type cloudWatchWriter struct {
+	client        cloudwatchlogsiface.CloudWatchLogsAPI
+	groupName     string
+	streamName    string
+	sequenceToken *string
+}
+
+// NewCloudWatchWriter creates an io.Writer that will write to the given CloudWatch Logs group and stream.
+// The stream is created if it doesn't exist yet, the group must already exist.
+// All other AWS configuration is picked up from the runtime hardware via environnement variables. See AWS docs
+func NewCloudWatchWriter(groupName, streamName string) (io.Writer, error) {
+	ses, err := session.NewSession(&aws.Config{
+		CredentialsChainVerboseErrors: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newCloudWatchWriter(cloudwatchlogs.New(ses), groupName, streamName)
+}
+
+func newCloudWatchWriter(client cloudwatchlogsiface.CloudWatchLogsAPI, groupName, streamName string) (*cloudWatchWriter, error) {
+	_, err := client.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(groupName),
+		LogStreamName: aws.String(streamName),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := &cloudWatchWriter{
+		client:     client,
+		groupName:  groupName,
+		streamName: streamName,
+	}
+
+	// an existing stream needs the sequence token of its last write
+	out, err := client.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName:        aws.String(groupName),
+		LogStreamNamePrefix: aws.String(streamName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range out.LogStreams {
+		if aws.StringValue(s.LogStreamName) == streamName {
+			w.sequenceToken = s.UploadSequenceToken
+		}
+	}
+
+	return w, nil
+}
+
+// Write writes each line of the byte slice as one log event, and blocks until the
+// response is returned. Lines over the CloudWatch event size limit are split.
+func (w *cloudWatchWriter) Write(p []byte) (n int, err error) {
+	now := aws.Int64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	var (
+		batch      []*cloudwatchlogs.InputLogEvent
+		batchBytes int
+	)
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		for len(line) > 0 {
+			event := line
+			if len(event) > cloudWatchMaxEventBytes-cloudWatchEventOverhead {
+				event = event[:cloudWatchMaxEventBytes-cloudWatchEventOverhead]
+			}
+			line = line[len(event):]
+
+			size := len(event) + cloudWatchEventOverhead
+			if len(batch) == cloudWatchMaxBatchEvents || batchBytes+size > cloudWatchMaxBatchBytes {
+				if err := w.put(batch); err != nil {
+					return 0, err
+				}
+				batch, batchBytes = nil, 0
+			}
+
+			batch = append(batch, &cloudwatchlogs.InputLogEvent{
+				Message:   aws.String(string(event)),
+				Timestamp: now,
+			})
+			batchBytes += size
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := w.put(batch); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// put sends a batch of events, retrying once with the expected sequence token if the one held is stale,
+// e.g. because another process wrote to the same stream
+func (w *cloudWatchWriter) put(events []*cloudwatchlogs.InputLogEvent) error {
+	for attempt := 0; ; attempt++ {
+		out, err := w.client.PutLogEvents(&cloudwatchlogs.PutLogEventsInput{
+			LogEvents:     events,
+			LogGroupName:  aws.String(w.groupName),
+			LogStreamName: aws.String(w.streamName),
+			SequenceToken: w.sequenceToken,
+		})
+		switch e := err.(type) {
+		case nil:
+			w.sequenceToken = out.NextSequenceToken
+			return nil
+		case *cloudwatchlogs.DataAlreadyAcceptedException:
+			w.sequenceToken = e.ExpectedSequenceToken
+			return nil
+		case *cloudwatchlogs.InvalidSequenceTokenException:
+			w.sequenceToken = e.ExpectedSequenceToken
+			if attempt == 0 {
+				continue
+			}
+		}
+		return err
+	}
+}
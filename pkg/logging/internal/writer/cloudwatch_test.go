@@ -0,0 +1,182 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloudWatchLogs records the events put to it, handing out a new sequence token with each put. The
+// errors in putErrs are returned by the puts, in order, before any succeeds
+type fakeCloudWatchLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+
+	createErr     error
+	existingToken *string
+	putErrs       []error
+
+	puts  []*cloudwatchlogs.PutLogEventsInput
+	token int
+}
+
+func (f *fakeCloudWatchLogs) CreateLogStream(in *cloudwatchlogs.CreateLogStreamInput) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	return &cloudwatchlogs.CreateLogStreamOutput{}, f.createErr
+}
+
+func (f *fakeCloudWatchLogs) DescribeLogStreams(in *cloudwatchlogs.DescribeLogStreamsInput) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	return &cloudwatchlogs.DescribeLogStreamsOutput{
+		LogStreams: []*cloudwatchlogs.LogStream{
+			{LogStreamName: aws.String(aws.StringValue(in.LogStreamNamePrefix) + "-other"), UploadSequenceToken: aws.String("other-token")},
+			{LogStreamName: in.LogStreamNamePrefix, UploadSequenceToken: f.existingToken},
+		},
+	}, nil
+}
+
+func (f *fakeCloudWatchLogs) PutLogEvents(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	f.puts = append(f.puts, in)
+	if len(f.putErrs) > 0 {
+		err := f.putErrs[0]
+		f.putErrs = f.putErrs[1:]
+		return nil, err
+	}
+	f.token++
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String("token-" + strconv.Itoa(f.token))}, nil
+}
+
+// messages returns the messages of the events of a put
+func messages(in *cloudwatchlogs.PutLogEventsInput) []string {
+	var msgs []string
+	for _, e := range in.LogEvents {
+		msgs = append(msgs, aws.StringValue(e.Message))
+	}
+	return msgs
+}
+
+func Test_newCloudWatchWriter(t *testing.T) {
+	client := &fakeCloudWatchLogs{
+		createErr:     awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "exists", nil),
+		existingToken: aws.String("existing-token"),
+	}
+	w, err := newCloudWatchWriter(client, "services", "calls")
+	require.NoError(t, err, "Expected an existing stream to be written to")
+	assert.Equal(t, "existing-token", aws.StringValue(w.sequenceToken), "Expected the sequence token of the stream's last write")
+
+	client = &fakeCloudWatchLogs{createErr: awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "no group", nil)}
+	_, err = newCloudWatchWriter(client, "services", "calls")
+	assert.Error(t, err, "Expected the stream creation to fail without its group")
+}
+
+func Test_cloudWatchWriterSequencing(t *testing.T) {
+	client := &fakeCloudWatchLogs{}
+	w, err := newCloudWatchWriter(client, "services", "calls")
+	require.NoError(t, err)
+
+	for _, entry := range []string{`{"msg":"one"}`, `{"msg":"two"}`, `{"msg":"three"}`} {
+		n, err := w.Write([]byte(entry + "\n"))
+		require.NoError(t, err)
+		assert.Equal(t, len(entry)+1, n)
+	}
+
+	require.Len(t, client.puts, 3)
+	assert.Nil(t, client.puts[0].SequenceToken, "Expected no token for the first write to a new stream")
+	assert.Equal(t, "token-1", aws.StringValue(client.puts[1].SequenceToken), "Expected the token returned by the last put")
+	assert.Equal(t, "token-2", aws.StringValue(client.puts[2].SequenceToken))
+	for _, in := range client.puts {
+		assert.Equal(t, "services", aws.StringValue(in.LogGroupName))
+		assert.Equal(t, "calls", aws.StringValue(in.LogStreamName))
+	}
+	assert.Equal(t, []string{`{"msg":"two"}`}, messages(client.puts[1]), "Expected each line as an event, without its newline")
+}
+
+func Test_cloudWatchWriterStaleSequenceToken(t *testing.T) {
+	client := &fakeCloudWatchLogs{
+		putErrs: []error{&cloudwatchlogs.InvalidSequenceTokenException{ExpectedSequenceToken: aws.String("expected-token")}},
+	}
+	w, err := newCloudWatchWriter(client, "services", "calls")
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte(`{"msg":"one"}`))
+	require.NoError(t, err, "Expected the put to be retried with the expected token")
+	require.Len(t, client.puts, 2)
+	assert.Equal(t, "expected-token", aws.StringValue(client.puts[1].SequenceToken))
+	assert.Equal(t, "token-1", aws.StringValue(w.sequenceToken))
+
+	client.putErrs = []error{&cloudwatchlogs.DataAlreadyAcceptedException{ExpectedSequenceToken: aws.String("accepted-token")}}
+	_, err = w.Write([]byte(`{"msg":"two"}`))
+	require.NoError(t, err, "Expected events already accepted not to be put again")
+	assert.Len(t, client.puts, 3)
+	assert.Equal(t, "accepted-token", aws.StringValue(w.sequenceToken))
+
+	client.putErrs = []error{
+		&cloudwatchlogs.InvalidSequenceTokenException{ExpectedSequenceToken: aws.String("expected-token")},
+		&cloudwatchlogs.InvalidSequenceTokenException{ExpectedSequenceToken: aws.String("another-token")},
+	}
+	_, err = w.Write([]byte(`{"msg":"three"}`))
+	assert.Error(t, err, "Expected the put to be retried only once")
+	assert.Len(t, client.puts, 5)
+
+	client.putErrs = []error{errors.New("throttled")}
+	n, err := w.Write([]byte(`{"msg":"four"}`))
+	assert.EqualError(t, err, "throttled")
+	assert.Zero(t, n)
+}
+
+func Test_cloudWatchWriterBatching(t *testing.T) {
+	client := &fakeCloudWatchLogs{}
+	w, err := newCloudWatchWriter(client, "services", "calls")
+	require.NoError(t, err)
+
+	// 5 lines of 200KB, and a line over the event size limit
+	line := bytes.Repeat([]byte("a"), 200*1024)
+	long := bytes.Repeat([]byte("b"), cloudWatchMaxEventBytes)
+	var p []byte
+	for i := 0; i < 5; i++ {
+		p = append(append(p, line...), '\n')
+	}
+	p = append(append(p, '\n'), long...)
+
+	n, err := w.Write(p)
+	require.NoError(t, err)
+	assert.Equal(t, len(p), n)
+
+	var events []string
+	for _, in := range client.puts {
+		size := 0
+		for _, e := range in.LogEvents {
+			size += len(aws.StringValue(e.Message)) + cloudWatchEventOverhead
+			assert.LessOrEqual(t, len(aws.StringValue(e.Message))+cloudWatchEventOverhead, cloudWatchMaxEventBytes,
+				"Expected every event within the event size limit")
+		}
+		assert.LessOrEqual(t, size, cloudWatchMaxBatchBytes, "Expected every batch within the batch size limit")
+		assert.Equal(t, in.LogEvents[0].Timestamp, in.LogEvents[len(in.LogEvents)-1].Timestamp, "Expected the events of a write to share a timestamp")
+		events = append(events, messages(in)...)
+	}
+	assert.Len(t, client.puts, 2, "Expected the events split into batches within the size limit")
+
+	require.Len(t, events, 7, "Expected empty lines to be skipped and the long line split")
+	for _, e := range events[:5] {
+		assert.Equal(t, string(line), e)
+	}
+	assert.Equal(t, string(long), events[5]+events[6], "Expected the long line split into consecutive events")
+}
+
+func Test_cloudWatchWriterMaxBatchEvents(t *testing.T) {
+	client := &fakeCloudWatchLogs{}
+	w, err := newCloudWatchWriter(client, "services", "calls")
+	require.NoError(t, err)
+
+	_, err = w.Write(bytes.Repeat([]byte("x\n"), cloudWatchMaxBatchEvents+1))
+	require.NoError(t, err)
+
+	require.Len(t, client.puts, 2, "Expected a batch for every 10000 events")
+	assert.Len(t, client.puts[0].LogEvents, cloudWatchMaxBatchEvents)
+	assert.Len(t, client.puts[1].LogEvents, 1)
+}
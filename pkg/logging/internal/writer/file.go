@@ -0,0 +1,191 @@
+package writer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// backupTimeFormat is the time rotated files are named with, sortable and safe in file names on every platform
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+}
+
+// NewRotatingFile creates a writer that appends to the file at path, creating it and its directory if they don't
+// exist. Once the file would grow over maxSize bytes, or has been written to for longer than maxAge, it is renamed
+// with the time it was rotated, e.g. service-2020-06-11T16-20-00.000.log, and a new file is started. Only the
+// newest maxBackups rotated files are kept. A maxSize, maxAge or maxBackups of 0 disables that limit.
+// Each write must be a whole log entry, so entries are never split across files.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (zapcore.WriteSyncer, io.Closer, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, nil, err
+	}
+
+	return f, f, nil
+}
+
+// Write appends the byte slice to the file, rotating it first if the write would take it over a limit
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, fmt.Errorf("log file %s is closed", f.path)
+	}
+
+	if f.size > 0 && (f.overSize(len(p)) || f.overAge()) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync commits the file's contents to disk
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file. Only the first call has any effect
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.file.Close()
+}
+
+func (f *rotatingFile) overSize(n int) bool {
+	return f.maxSize > 0 && f.size+int64(n) > f.maxSize
+}
+
+func (f *rotatingFile) overAge() bool {
+	return f.maxAge > 0 && time.Since(f.opened) >= f.maxAge
+}
+
+// open opens the file for appending, carrying on from its current size. Must be called with the lock held
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+// rotate renames the current file to a backup, opens a new one, and removes the oldest backups over the
+// limit. Must be called with the lock held
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.path, f.backupName(time.Now())); err != nil {
+		return err
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	return f.removeOldBackups()
+}
+
+// backupName returns the name the file is rotated to at t
+func (f *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// removeOldBackups removes the oldest rotated files, keeping the newest maxBackups
+func (f *rotatingFile) removeOldBackups() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+
+	ext := filepath.Ext(f.path)
+	prefix := filepath.Base(strings.TrimSuffix(f.path, ext)) + "-"
+	dir := filepath.Dir(f.path)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, name)
+	}
+
+	if len(backups) <= f.maxBackups {
+		return nil
+	}
+
+	// the time format sorts oldest first
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-f.maxBackups] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
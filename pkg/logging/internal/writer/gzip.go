@@ -0,0 +1,74 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// Gzip returns a writer that compresses each write into a gzip member of its own before writing it to w. Each
+// record can be decompressed alone, and the records Firehose concatenates into one S3 object decompress as one
+// stream. Writers wrapping it, such as Retry, are given the uncompressed data, so fallbacks stay readable
+func Gzip(w io.Writer) io.Writer {
+	return &gzipWriter{w}
+}
+
+type gzipWriter struct {
+	w io.Writer
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	data, err := gzipRecord(p)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := g.w.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// gzipPutter compresses each record of a batch before putting it, returning the failed records uncompressed.
+// It is only used for Firehose, records put to a Kinesis Data Stream are compressed by the streamPutter
+type gzipPutter struct {
+	batchPutter
+}
+
+func (p gzipPutter) putBatch(records [][]byte) ([][]byte, error) {
+	compressed := make([][]byte, len(records))
+	for i, r := range records {
+		data, err := gzipRecord(r)
+		if err != nil {
+			return records, err
+		}
+		compressed[i] = data
+	}
+
+	failed, err := p.batchPutter.putBatch(compressed)
+	if len(failed) == 0 {
+		return nil, err
+	}
+
+	// the failed records are the compressed ones, in the order they were put
+	originals := make([][]byte, 0, len(failed))
+	for i, j := 0, 0; i < len(compressed) && j < len(failed); i++ {
+		if &compressed[i][0] == &failed[j][0] {
+			originals = append(originals, records[i])
+			j++
+		}
+	}
+	return originals, err
+}
+
+// gzipRecord compresses the record into a gzip member
+func gzipRecord(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,156 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err, "Expected a gzip stream")
+	out, err := ioutil.ReadAll(zr)
+	require.NoError(t, err, "Expected the gzip stream to decompress")
+	return string(out)
+}
+
+func Test_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	w := Gzip(&buf)
+
+	n, err := w.Write([]byte(`{"msg":"one"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 13, n, "Expected the uncompressed length to be returned")
+	first := buf.Len()
+	_, err = w.Write([]byte(`{"msg":"two"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"msg":"one"}`, gunzip(t, buf.Bytes()[:first]), "Expected each record to decompress alone")
+	assert.Equal(t, `{"msg":"one"}{"msg":"two"}`, gunzip(t, buf.Bytes()), "Expected concatenated records to decompress as one stream")
+}
+
+// failingPutter records the batches put to it, failing the records at the given indexes
+type failingPutter struct {
+	fail []int
+	put  [][]byte
+}
+
+func (p *failingPutter) putBatch(records [][]byte) ([][]byte, error) {
+	p.put = records
+	var failed [][]byte
+	for _, i := range p.fail {
+		failed = append(failed, records[i])
+	}
+	if len(failed) > 0 {
+		return failed, errors.New("throttled")
+	}
+	return nil, nil
+}
+
+func Test_gzipPutter(t *testing.T) {
+	records := [][]byte{[]byte("zero"), []byte("one"), []byte("two"), []byte("three")}
+	putter := &failingPutter{fail: []int{1, 3}}
+
+	failed, err := gzipPutter{putter}.putBatch(records)
+
+	assert.EqualError(t, err, "throttled")
+	require.Len(t, putter.put, 4)
+	for i, r := range putter.put {
+		assert.Equal(t, string(records[i]), gunzip(t, r), "Expected each record to be compressed")
+	}
+	assert.Equal(t, [][]byte{[]byte("one"), []byte("three")}, failed, "Expected the failed records back uncompressed, in order")
+
+	putter.fail = nil
+	failed, err = gzipPutter{putter}.putBatch(records)
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+}
+
+// fakeKinesis records the records put to it
+type fakeKinesis struct {
+	kinesisiface.KinesisAPI
+
+	put []*kinesis.PutRecordsRequestEntry
+}
+
+func (f *fakeKinesis) PutRecord(in *kinesis.PutRecordInput) (*kinesis.PutRecordOutput, error) {
+	f.put = append(f.put, &kinesis.PutRecordsRequestEntry{Data: in.Data, PartitionKey: in.PartitionKey})
+	return &kinesis.PutRecordOutput{}, nil
+}
+
+func (f *fakeKinesis) PutRecords(in *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+	f.put = append(f.put, in.Records...)
+	out := &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int64(0)}
+	for range in.Records {
+		out.Records = append(out.Records, &kinesis.PutRecordsResultEntry{})
+	}
+	return out, nil
+}
+
+// correlationKey keys records by the correlation ID of their JSON entry, like the correlation ID partition
+// strategy, returning none for records that aren't JSON
+func correlationKey(record []byte) string {
+	var entry struct {
+		CorrelationID string `json:"correlationID"`
+	}
+	if err := json.Unmarshal(record, &entry); err != nil {
+		return ""
+	}
+	return entry.CorrelationID
+}
+
+func Test_gzipStreamPartitionKeys(t *testing.T) {
+	records := [][]byte{[]byte(`{"correlationID":"a"}`), []byte(`{"correlationID":"b"}`)}
+
+	t.Run("batched", func(t *testing.T) {
+		client := &fakeKinesis{}
+		failed, err := (&streamPutter{client, "logs", correlationKey, false, true}).putBatch(records)
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+
+		require.Len(t, client.put, 2)
+		for i, r := range client.put {
+			assert.Equal(t, string(records[i]), gunzip(t, r.Data), "Expected each record to be compressed")
+		}
+		assert.Equal(t, "a", aws.StringValue(client.put[0].PartitionKey), "Expected the key chosen from the uncompressed record")
+		assert.Equal(t, "b", aws.StringValue(client.put[1].PartitionKey))
+	})
+
+	t.Run("aggregated", func(t *testing.T) {
+		client := &fakeKinesis{}
+		_, err := (&streamPutter{client, "logs", correlationKey, true, true}).putBatch(records)
+		require.NoError(t, err)
+
+		require.Len(t, client.put, 1)
+		assert.Equal(t, "a", aws.StringValue(client.put[0].PartitionKey))
+		entries := decodeKPL(t, client.put[0].Data)
+		require.Len(t, entries, 2)
+		for i, e := range entries {
+			assert.Equal(t, correlationKey(records[i]), e.key, "Expected each entry keyed from the uncompressed record")
+			assert.Equal(t, string(records[i]), gunzip(t, []byte(e.data)), "Expected each entry to be compressed")
+		}
+	})
+
+	t.Run("buffered", func(t *testing.T) {
+		client := &fakeKinesis{}
+		w := &kinesisStreamWriter{client, "logs", correlationKey, true}
+		n, err := w.Write(records[1])
+		require.NoError(t, err)
+		assert.Equal(t, len(records[1]), n, "Expected the uncompressed length to be returned")
+
+		require.Len(t, client.put, 1)
+		assert.Equal(t, "b", aws.StringValue(client.put[0].PartitionKey), "Expected the key chosen from the uncompressed record")
+		assert.Equal(t, string(records[1]), gunzip(t, client.put[0].Data))
+	})
+}
@@ -1,11 +1,17 @@
 package writer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
+	"math/rand"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 )
 
 type kinesisWriter struct {
@@ -14,11 +20,10 @@ type kinesisWriter struct {
 }
 
 // NewKinesisWriter creates an io.Writer that will write to the given kinesis stream name.NewKinesisWriter
-// All other AWS configuration is picked up from the runtime hardware via environnement variables. See AWS docs
-func NewKinesisWriter(streamName string) (io.Writer, error) {
-	ses, err := session.NewSession(&aws.Config{
-		CredentialsChainVerboseErrors: aws.Bool(true),
-	})
+// The session is created following sc, other AWS configuration is picked up from the runtime hardware via
+// environnement variables. See AWS docs
+func NewKinesisWriter(sc SessionConfig, streamName string) (io.Writer, error) {
+	ses, err := newSession(sc)
 	if err != nil {
 		return nil, err
 	}
@@ -54,3 +59,99 @@ func (k *kinesisWriter) Write(p []byte) (n int, err error) {
 
 	return len(p), nil
 }
+
+// PartitionKeyFunc returns the partition key a record is put to a Kinesis Data Stream with, so records that
+// should be read in order land on the same shard. Records given an empty key are put with a random one, and
+// keys longer than the 256 characters Kinesis accepts are put as their SHA-256 digest
+type PartitionKeyFunc func(record []byte) string
+
+// FixedPartitionKey puts every record with the same partition key, or with a random one if it is empty
+func FixedPartitionKey(key string) PartitionKeyFunc {
+	return func([]byte) string {
+		return key
+	}
+}
+
+type kinesisStreamWriter struct {
+	kinesisiface.KinesisAPI
+	streamName   string
+	partitionKey PartitionKeyFunc
+	compress     bool
+}
+
+// NewKinesisStreamWriter creates an io.Writer that will write to the given Kinesis Data Stream, rather than
+// the Firehose delivery stream written to by NewKinesisWriter. Each record is put with the key partitionKey
+// returns for it, or with a random one if it is nil or returns none, so records are spread across the shards.
+// If compress is set each record is gzipped, see Gzip, once its partition key is chosen from the record as written.
+// The session is created following sc, other AWS configuration is picked up from the runtime hardware via
+// environnement variables. See AWS docs
+func NewKinesisStreamWriter(sc SessionConfig, streamName string, partitionKey PartitionKeyFunc, compress bool) (io.Writer, error) {
+	k, err := newKinesisStreamClient(sc, streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kinesisStreamWriter{k, streamName, partitionKey, compress}, nil
+}
+
+// Write writes one byte slice as one record of the data stream, and blocks until the response is returned
+func (k *kinesisStreamWriter) Write(p []byte) (n int, err error) {
+	key := partitionKeyOf(p, k.partitionKey)
+	data := p
+	if k.compress {
+		if data, err = gzipRecord(p); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = k.PutRecord(&kinesis.PutRecordInput{
+		Data:         data,
+		PartitionKey: aws.String(key),
+		StreamName:   aws.String(k.streamName),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// newKinesisStreamClient creates a Kinesis Data Streams client, checking the stream exists
+func newKinesisStreamClient(sc SessionConfig, streamName string) (*kinesis.Kinesis, error) {
+	ses, err := newSession(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	k := kinesis.New(ses)
+
+	_, err = k.DescribeStreamSummary(
+		&kinesis.DescribeStreamSummaryInput{
+			StreamName: aws.String(streamName),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// maxPartitionKeyLength is the most characters Kinesis accepts in a partition key
+const maxPartitionKeyLength = 256
+
+// partitionKeyOf returns the partition key the record is put with, a random one if none is configured for it.
+// Keys can come from the client, e.g. a correlation ID header, so keys longer than Kinesis accepts are replaced
+// with their hex encoded SHA-256 digest, which keeps the records of a key on the same shard
+func partitionKeyOf(record []byte, partitionKey PartitionKeyFunc) string {
+	if partitionKey != nil {
+		if key := partitionKey(record); key != "" {
+			if utf8.RuneCountInString(key) > maxPartitionKeyLength {
+				sum := sha256.Sum256([]byte(key))
+				return hex.EncodeToString(sum[:])
+			}
+			return key
+		}
+	}
+	return strconv.FormatUint(rand.Uint64(), 36)
+}
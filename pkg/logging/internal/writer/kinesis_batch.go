@@ -0,0 +1,342 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"go.uber.org/zap/zapcore"
+)
+
+// Firehose limits on a single PutRecordBatch call, also within the Kinesis Data Streams limits on a single
+// PutRecords call
+const (
+	MaxBatchRecords = 500
+	MaxBatchBytes   = 4 * 1024 * 1024
+)
+
+// batchPutter puts a batch of records to a stream, returning the records that failed
+type batchPutter interface {
+	putBatch(records [][]byte) ([][]byte, error)
+}
+
+type kinesisBatchWriter struct {
+	putter     batchPutter
+	maxRecords int
+	maxBytes   int
+	delivery   Delivery
+	metrics    Metrics
+
+	mu           sync.Mutex
+	records      [][]byte
+	pendingBytes int
+
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// NewKinesisBatchWriter creates a writer that turns each write into one record of the given kinesis stream, and sends
+// them with PutRecordBatch once maxRecords or maxBytes are pending, or every flushInterval. Each write must be a
+// whole log entry, so the writer should not be wrapped in a Buffer.
+// if maxRecords = 0 or is over the firehose limit, we set it to MaxBatchRecords
+// if maxBytes = 0 or is over the firehose limit, we set it to MaxBatchBytes
+// if flushInterval = 0, we set it to DefaultFlushInterval
+// Batches that fail are retried, and written to the fallback once every attempt has failed, as the delivery configures.
+// Records written, pending bytes, batches sent and records dropped are counted in the metrics.
+// If compress is set each record is gzipped when it is put, see Gzip
+// The session is created following sc
+func NewKinesisBatchWriter(sc SessionConfig, streamName string, compress bool, maxRecords, maxBytes int, flushInterval time.Duration, delivery Delivery, metrics Metrics) (zapcore.WriteSyncer, io.Closer, error) {
+	ses, err := newSession(sc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := firehose.New(ses)
+
+	_, err = h.DescribeDeliveryStream(
+		&firehose.DescribeDeliveryStreamInput{
+			DeliveryStreamName: aws.String(streamName),
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var putter batchPutter = &firehosePutter{h, streamName}
+	if compress {
+		putter = gzipPutter{putter}
+	}
+
+	w := newKinesisBatchWriter(putter, maxRecords, maxBytes, flushInterval, delivery, metrics)
+	return w, w, nil
+}
+
+// NewKinesisStreamBatchWriter creates a writer like NewKinesisBatchWriter that sends the records to the given
+// Kinesis Data Stream with PutRecords, rather than to a Firehose delivery stream. Each record is put with the key
+// partitionKey returns for it, or with a random one if it is nil or returns none, so records are spread across
+// the shards of the stream. If aggregate is set the records of each batch are packed into KPL aggregated records
+// of up to MaxAggregatedBytes, cutting the number of records put. If compress is set each entry is gzipped when
+// it is put, see Gzip, once its partition key is chosen from the entry as written
+func NewKinesisStreamBatchWriter(sc SessionConfig, streamName string, partitionKey PartitionKeyFunc, aggregate, compress bool, maxRecords, maxBytes int, flushInterval time.Duration, delivery Delivery, metrics Metrics) (zapcore.WriteSyncer, io.Closer, error) {
+	k, err := newKinesisStreamClient(sc, streamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	putter := &streamPutter{k, streamName, partitionKey, aggregate, compress}
+	w := newKinesisBatchWriter(putter, maxRecords, maxBytes, flushInterval, delivery, metrics)
+	return w, w, nil
+}
+
+func newKinesisBatchWriter(putter batchPutter, maxRecords, maxBytes int, flushInterval time.Duration, delivery Delivery, metrics Metrics) *kinesisBatchWriter {
+	if maxRecords <= 0 || maxRecords > MaxBatchRecords {
+		maxRecords = MaxBatchRecords
+	}
+	if maxBytes <= 0 || maxBytes > MaxBatchBytes {
+		maxBytes = MaxBatchBytes
+	}
+	if flushInterval == 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &kinesisBatchWriter{
+		putter:     putter,
+		maxRecords: maxRecords,
+		maxBytes:   maxBytes,
+		delivery:   delivery,
+		metrics:    metrics,
+		cancel:     cancel,
+	}
+
+	// flush pending records every interval, until the writer is closed
+	ticker := time.NewTicker(flushInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := w.Sync(); err != nil {
+					log.Print(err.Error())
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// Write adds one byte slice as one kinesis record to the pending batch, flushing the batch first
+// if the record would take it over the size limit, and after if it is full
+func (w *kinesisBatchWriter) Write(p []byte) (int, error) {
+	// the caller may reuse p once Write returns
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pendingBytes+len(data) > w.maxBytes && len(w.records) > 0 {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	w.records = append(w.records, data)
+	w.pendingBytes += len(data)
+	w.metrics.entry()
+	w.metrics.buffered(w.pendingBytes)
+
+	if len(w.records) >= w.maxRecords || w.pendingBytes >= w.maxBytes {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Sync sends any pending records
+func (w *kinesisBatchWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Close sends any pending records and stops the regular flushes. Only the first call has any effect
+func (w *kinesisBatchWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		w.cancel()
+		err = w.Sync()
+	})
+	return err
+}
+
+// flush sends the pending records, retrying as the delivery configures. Each retry only sends the records
+// that failed last time. Records that fail every attempt are written to the fallback.
+// Must be called with the lock held
+func (w *kinesisBatchWriter) flush() error {
+	if len(w.records) == 0 {
+		return nil
+	}
+
+	records := w.records
+	w.records = nil
+	w.pendingBytes = 0
+	w.metrics.buffered(0)
+
+	start := time.Now()
+	err := w.delivery.do(func() error {
+		failed, err := w.putter.putBatch(records)
+		if err != nil {
+			return err
+		}
+		if records = failed; len(records) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("failed to put %d kinesis records", len(records))
+	})
+	w.metrics.flushed(start, err)
+	if err != nil {
+		if err := w.delivery.fallback(err, records...); err != nil {
+			w.metrics.dropped(len(records))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// firehosePutter puts batches of records to a Firehose delivery stream with PutRecordBatch
+type firehosePutter struct {
+	client     firehoseiface.FirehoseAPI
+	streamName string
+}
+
+func (p *firehosePutter) putBatch(records [][]byte) ([][]byte, error) {
+	in := make([]*firehose.Record, len(records))
+	for i, data := range records {
+		in[i] = &firehose.Record{Data: data}
+	}
+
+	out, err := p.client.PutRecordBatch(&firehose.PutRecordBatchInput{
+		DeliveryStreamName: aws.String(p.streamName),
+		Records:            in,
+	})
+	if err != nil {
+		return records, err
+	}
+	if aws.Int64Value(out.FailedPutCount) == 0 {
+		return nil, nil
+	}
+
+	// responses are in the same order as the records sent
+	var failed [][]byte
+	for i, r := range out.RequestResponses {
+		if r.ErrorCode != nil && i < len(records) {
+			failed = append(failed, records[i])
+		}
+	}
+	return failed, nil
+}
+
+// streamPutter puts batches of records to a Kinesis Data Stream with PutRecords. Compression is left to it
+// rather than to a gzipPutter, as the partition key of each record is chosen from the record uncompressed
+type streamPutter struct {
+	client       kinesisiface.KinesisAPI
+	streamName   string
+	partitionKey PartitionKeyFunc
+	aggregate    bool
+	compress     bool
+}
+
+func (p *streamPutter) putBatch(records [][]byte) ([][]byte, error) {
+	if p.aggregate {
+		return p.putAggregated(records)
+	}
+
+	in := make([]*kinesis.PutRecordsRequestEntry, len(records))
+	for i, r := range records {
+		key := partitionKeyOf(r, p.partitionKey)
+		data := r
+		if p.compress {
+			var err error
+			if data, err = gzipRecord(r); err != nil {
+				return records, err
+			}
+		}
+		in[i] = &kinesis.PutRecordsRequestEntry{
+			Data:         data,
+			PartitionKey: aws.String(key),
+		}
+	}
+
+	out, err := p.client.PutRecords(&kinesis.PutRecordsInput{
+		StreamName: aws.String(p.streamName),
+		Records:    in,
+	})
+	if err != nil {
+		return records, err
+	}
+	if aws.Int64Value(out.FailedRecordCount) == 0 {
+		return nil, nil
+	}
+
+	// results are in the same order as the records sent
+	var failed [][]byte
+	for i, r := range out.Records {
+		if r.ErrorCode != nil && i < len(records) {
+			failed = append(failed, records[i])
+		}
+	}
+	return failed, nil
+}
+
+// putAggregated puts the records packed into aggregated records, returning the records packed into the
+// aggregated records that failed
+func (p *streamPutter) putAggregated(records [][]byte) ([][]byte, error) {
+	aggregated, err := aggregate(records, p.partitionKey, p.compress, MaxAggregatedBytes)
+	if err != nil {
+		return records, err
+	}
+
+	in := make([]*kinesis.PutRecordsRequestEntry, len(aggregated))
+	for i, a := range aggregated {
+		in[i] = &kinesis.PutRecordsRequestEntry{
+			Data:         a.data,
+			PartitionKey: aws.String(a.partitionKey),
+		}
+	}
+
+	out, err := p.client.PutRecords(&kinesis.PutRecordsInput{
+		StreamName: aws.String(p.streamName),
+		Records:    in,
+	})
+	if err != nil {
+		return records, err
+	}
+	if aws.Int64Value(out.FailedRecordCount) == 0 {
+		return nil, nil
+	}
+
+	var failed [][]byte
+	for i, r := range out.Records {
+		if r.ErrorCode != nil && i < len(aggregated) {
+			failed = append(failed, aggregated[i].records...)
+		}
+	}
+	return failed, nil
+}
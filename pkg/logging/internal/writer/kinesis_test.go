@@ -0,0 +1,180 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyKinesis records the batches put to it with PutRecords, failing each record as many times as fail
+// gives for its data, and failing whole calls with callErr
+type flakyKinesis struct {
+	kinesisiface.KinesisAPI
+
+	fail    map[string]int
+	callErr error
+	calls   [][]*kinesis.PutRecordsRequestEntry
+}
+
+func (f *flakyKinesis) PutRecords(in *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+	f.calls = append(f.calls, in.Records)
+	if f.callErr != nil {
+		return nil, f.callErr
+	}
+
+	out := &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int64(0)}
+	for _, r := range in.Records {
+		result := &kinesis.PutRecordsResultEntry{}
+		if data := string(r.Data); f.fail[data] > 0 {
+			f.fail[data]--
+			result.ErrorCode = aws.String(kinesis.ErrCodeProvisionedThroughputExceededException)
+			*out.FailedRecordCount++
+		}
+		out.Records = append(out.Records, result)
+	}
+	return out, nil
+}
+
+// callData returns the data of the records of each call, in order
+func (f *flakyKinesis) callData() [][]string {
+	var calls [][]string
+	for _, records := range f.calls {
+		var data []string
+		for _, r := range records {
+			data = append(data, string(r.Data))
+		}
+		calls = append(calls, data)
+	}
+	return calls
+}
+
+func Test_partitionKeyOf(t *testing.T) {
+	assert.Equal(t, "abc", partitionKeyOf(nil, FixedPartitionKey("abc")), "Expected the configured key")
+
+	random := partitionKeyOf(nil, FixedPartitionKey(""))
+	assert.NotEmpty(t, random, "Expected a random key for an empty key")
+	assert.NotEqual(t, random, partitionKeyOf(nil, nil), "Expected a new random key for each record")
+
+	max := strings.Repeat("é", maxPartitionKeyLength)
+	assert.Equal(t, max, partitionKeyOf(nil, FixedPartitionKey(max)), "Expected a key of the maximum length to be kept")
+
+	long := strings.Repeat("a", maxPartitionKeyLength+1)
+	key := partitionKeyOf(nil, FixedPartitionKey(long))
+	assert.Len(t, key, 64, "Expected a key over the maximum length to be hashed")
+	assert.Equal(t, key, partitionKeyOf(nil, FixedPartitionKey(long)), "Expected the same long key to hash to the same key")
+	assert.NotEqual(t, key, partitionKeyOf(nil, FixedPartitionKey(long+"a")), "Expected different long keys to hash to different keys")
+}
+
+func Test_kinesisStreamWriter(t *testing.T) {
+	client := &fakeKinesis{}
+	w := &kinesisStreamWriter{client, "logs", correlationKey, false}
+
+	entry := []byte(`{"correlationID":"a"}`)
+	n, err := w.Write(entry)
+	require.NoError(t, err)
+	assert.Equal(t, len(entry), n)
+	_, err = w.Write([]byte("not json"))
+	require.NoError(t, err)
+
+	require.Len(t, client.put, 2, "Expected a record for each write")
+	assert.Equal(t, string(entry), string(client.put[0].Data))
+	assert.Equal(t, "a", aws.StringValue(client.put[0].PartitionKey), "Expected the key chosen for the record")
+	assert.NotEmpty(t, aws.StringValue(client.put[1].PartitionKey), "Expected a random key for a record without one")
+
+	client = &fakeKinesis{}
+	w = &kinesisStreamWriter{client, "logs", correlationKey, true}
+	_, err = w.Write(entry)
+	require.NoError(t, err)
+	require.Len(t, client.put, 1)
+	assert.Equal(t, string(entry), gunzip(t, client.put[0].Data), "Expected the record to be compressed")
+	assert.Equal(t, "a", aws.StringValue(client.put[0].PartitionKey), "Expected the key chosen from the record uncompressed")
+}
+
+func Test_streamPutterPartitionKeys(t *testing.T) {
+	client := &flakyKinesis{}
+	records := [][]byte{[]byte(`{"correlationID":"a"}`), []byte(`{"correlationID":"b"}`), []byte(`{}`)}
+
+	failed, err := (&streamPutter{client, "logs", correlationKey, false, false}).putBatch(records)
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+
+	require.Len(t, client.calls, 1, "Expected the batch put in one call")
+	batch := client.calls[0]
+	require.Len(t, batch, 3)
+	assert.Equal(t, "a", aws.StringValue(batch[0].PartitionKey))
+	assert.Equal(t, "b", aws.StringValue(batch[1].PartitionKey))
+	assert.NotEmpty(t, aws.StringValue(batch[2].PartitionKey), "Expected a random key for a record without one")
+}
+
+func Test_streamPutterFailedRecords(t *testing.T) {
+	client := &flakyKinesis{fail: map[string]int{"one": 1, "three": 1}}
+	records := [][]byte{[]byte("zero"), []byte("one"), []byte("two"), []byte("three")}
+
+	failed, err := (&streamPutter{client, "logs", nil, false, false}).putBatch(records)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("one"), []byte("three")}, failed, "Expected the failed records back, in order")
+
+	client.callErr = errors.New("stream unavailable")
+	failed, err = (&streamPutter{client, "logs", nil, false, false}).putBatch(records)
+	assert.EqualError(t, err, "stream unavailable")
+	assert.Equal(t, records, failed, "Expected every record back when the call fails")
+}
+
+func Test_kinesisStreamBatchWriterRetriesFailedRecords(t *testing.T) {
+	client := &flakyKinesis{fail: map[string]int{"one": 2, "three": 1}}
+	putter := &streamPutter{client, "logs", nil, false, false}
+	w := newKinesisBatchWriter(putter, 10, 0, 0, Delivery{Attempts: 3}, Metrics{})
+	defer w.Close()
+
+	for _, entry := range []string{"zero", "one", "two", "three"} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Sync())
+
+	assert.Equal(t, [][]string{
+		{"zero", "one", "two", "three"},
+		{"one", "three"},
+		{"one"},
+	}, client.callData(), "Expected each retry to only put the records that failed")
+}
+
+func Test_kinesisStreamBatchWriterFallback(t *testing.T) {
+	client := &flakyKinesis{fail: map[string]int{"one": 2}}
+	putter := &streamPutter{client, "logs", nil, false, false}
+	var fallback bytes.Buffer
+	w := newKinesisBatchWriter(putter, 10, 0, 0, Delivery{Attempts: 2, Fallback: &fallback}, Metrics{})
+	defer w.Close()
+
+	for _, entry := range []string{"zero", "one"} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Sync(), "Expected the records that failed every attempt written to the fallback")
+	assert.Equal(t, "one", fallback.String())
+	assert.Len(t, client.calls, 2)
+}
+
+func Test_kinesisStreamBatchWriterDropped(t *testing.T) {
+	client := &flakyKinesis{fail: map[string]int{"one": 2}}
+	putter := &streamPutter{client, "logs", nil, false, false}
+	dropped := prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"})
+	w := newKinesisBatchWriter(putter, 10, 0, 0, Delivery{Attempts: 2}, Metrics{Dropped: dropped})
+	defer w.Close()
+
+	for _, entry := range []string{"zero", "one"} {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	assert.Error(t, w.Sync(), "Expected the error without a fallback")
+	assert.Equal(t, 1.0, testutil.ToFloat64(dropped), "Expected the records given up on to be counted")
+}
@@ -0,0 +1,80 @@
+package writer
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics counts what a writer does with the entries written to it, so degraded log delivery can be alerted on.
+// Any of the metrics may be nil, and the zero value counts nothing
+type Metrics struct {
+	// The number of entries written
+	Entries prometheus.Counter
+	// The byte size of the entries waiting to be sent
+	Buffered prometheus.Gauge
+	// The number of times the waiting entries were sent
+	Flushes prometheus.Counter
+	// The number of times sending the waiting entries failed
+	FlushErrors prometheus.Counter
+	// The seconds each send of the waiting entries took, retries included
+	FlushDuration prometheus.Observer
+	// The unix time of the last send of the waiting entries that succeeded
+	LastSuccess prometheus.Gauge
+	// The number of sends of the waiting entries that failed since the last one that succeeded
+	ConsecutiveFailures prometheus.Gauge
+	// The number of records given up on once every attempt to deliver them failed, and the fallback did too
+	Dropped prometheus.Counter
+	// The number of entries dropped because the queue in front of the writer was full
+	QueueDropped prometheus.Counter
+}
+
+func (m Metrics) entry() {
+	if m.Entries != nil {
+		m.Entries.Inc()
+	}
+}
+
+func (m Metrics) buffered(n int) {
+	if m.Buffered != nil {
+		m.Buffered.Set(float64(n))
+	}
+}
+
+// flushed counts a flush started at start, and a flush error if err isn't nil
+func (m Metrics) flushed(start time.Time, err error) {
+	if m.Flushes != nil {
+		m.Flushes.Inc()
+	}
+	if m.FlushDuration != nil {
+		m.FlushDuration.Observe(time.Since(start).Seconds())
+	}
+
+	if err != nil {
+		if m.FlushErrors != nil {
+			m.FlushErrors.Inc()
+		}
+		if m.ConsecutiveFailures != nil {
+			m.ConsecutiveFailures.Inc()
+		}
+		return
+	}
+	if m.LastSuccess != nil {
+		m.LastSuccess.SetToCurrentTime()
+	}
+	if m.ConsecutiveFailures != nil {
+		m.ConsecutiveFailures.Set(0)
+	}
+}
+
+func (m Metrics) dropped(n int) {
+	if m.Dropped != nil {
+		m.Dropped.Add(float64(n))
+	}
+}
+
+func (m Metrics) queueDropped() {
+	if m.QueueDropped != nil {
+		m.QueueDropped.Inc()
+	}
+}
@@ -0,0 +1,380 @@
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// OTLPExportMethod is the gRPC method log records are exported with
+	OTLPExportMethod = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	// otlpMaxBatchRecords is the number of records that are exported as soon as they are pending
+	otlpMaxBatchRecords = 512
+	// otlpTimeout bounds each attempt to export a batch
+	otlpTimeout = 10 * time.Second
+)
+
+// The OTLP protobuf field numbers used, from opentelemetry/proto/collector/logs/v1/logs_service.proto,
+// opentelemetry/proto/logs/v1/logs.proto and opentelemetry/proto/common/v1/common.proto
+const (
+	otlpRequestResourceLogs protowire.Number = 1
+
+	otlpResourceLogsResource  protowire.Number = 1
+	otlpResourceLogsScopeLogs protowire.Number = 2
+	otlpResourceAttributes    protowire.Number = 1
+
+	otlpScopeLogsRecords protowire.Number = 2
+
+	otlpRecordTime         protowire.Number = 1
+	otlpRecordSeverity     protowire.Number = 2
+	otlpRecordSeverityText protowire.Number = 3
+	otlpRecordBody         protowire.Number = 5
+	otlpRecordAttributes   protowire.Number = 6
+
+	otlpKeyValueKey   protowire.Number = 1
+	otlpKeyValueValue protowire.Number = 2
+
+	otlpValueString protowire.Number = 1
+	otlpValueBool   protowire.Number = 2
+	otlpValueInt    protowire.Number = 3
+	otlpValueDouble protowire.Number = 4
+	otlpValueArray  protowire.Number = 5
+	otlpValueKVList protowire.Number = 6
+	otlpValueBytes  protowire.Number = 7
+
+	otlpListValues protowire.Number = 1
+)
+
+// OTLPRecord is a log record exported over OTLP
+type OTLPRecord struct {
+	Time time.Time
+	// The OTLP severity number, e.g. 9 for info and 17 for error
+	Severity     int32
+	SeverityText string
+	Body         string
+	// Values may be strings, bools, numbers, byte slices, times, durations, slices and maps of them.
+	// Other values are converted through JSON
+	Attributes map[string]interface{}
+}
+
+// EncodeOTLPRecord encodes the record as an OTLP LogRecord message, to be written to an OTLP writer
+func EncodeOTLPRecord(r OTLPRecord) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, otlpRecordTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(r.Time.UnixNano()))
+	b = protowire.AppendTag(b, otlpRecordSeverity, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(r.Severity))
+	b = protowire.AppendTag(b, otlpRecordSeverityText, protowire.BytesType)
+	b = protowire.AppendString(b, r.SeverityText)
+	b = protowire.AppendTag(b, otlpRecordBody, protowire.BytesType)
+	b = protowire.AppendBytes(b, appendOTLPValue(nil, r.Body))
+	return appendOTLPAttributes(b, otlpRecordAttributes, r.Attributes)
+}
+
+type otlpWriter struct {
+	conn     *grpc.ClientConn
+	headers  metadata.MD
+	resource []byte
+	delivery Delivery
+
+	mu      sync.Mutex
+	records [][]byte
+
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// NewOTLPWriter creates a writer that exports log records to an OpenTelemetry collector over OTLP/gRPC. Each write
+// must be one record encoded with EncodeOTLPRecord. Records are exported in batches, every flushInterval or as soon
+// as enough are pending, with the headers sent as gRPC metadata, e.g. for authentication, and the resource
+// attributes, e.g. service.name, describing the process they came from. The connection is plaintext when insecure
+// is set, and TLS otherwise. Batches that fail are retried as the delivery configures, then dropped
+func NewOTLPWriter(endpoint string, headers, resource map[string]string, insecure bool, flushInterval time.Duration, delivery Delivery) (zapcore.WriteSyncer, io.Closer, error) {
+	creds := grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	if insecure {
+		creds = grpc.WithInsecure()
+	}
+
+	// the connection is made in the background, and remade whenever it drops
+	conn, err := grpc.Dial(endpoint, creds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if flushInterval == 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attrs := make(map[string]interface{}, len(resource))
+	for k, v := range resource {
+		attrs[k] = v
+	}
+
+	w := &otlpWriter{
+		conn:     conn,
+		headers:  metadata.New(headers),
+		resource: appendOTLPAttributes(nil, otlpResourceAttributes, attrs),
+		delivery: delivery,
+		cancel:   cancel,
+	}
+
+	// export pending records every interval, until the writer is closed
+	ticker := time.NewTicker(flushInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := w.Sync(); err != nil {
+					log.Print(err.Error())
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return w, w, nil
+}
+
+// Write adds one encoded record to the pending batch, exporting the batch once it is full
+func (w *otlpWriter) Write(p []byte) (int, error) {
+	// the caller may reuse p once Write returns
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.records = append(w.records, data)
+	if len(w.records) >= otlpMaxBatchRecords {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Sync exports any pending records
+func (w *otlpWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Close exports any pending records, stops the regular exports and closes the connection.
+// Only the first call has any effect
+func (w *otlpWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		w.cancel()
+		err = w.Sync()
+		if cerr := w.conn.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// flush exports the pending records, retrying as the delivery configures. Must be called with the lock held
+func (w *otlpWriter) flush() error {
+	if len(w.records) == 0 {
+		return nil
+	}
+
+	req := w.request(w.records)
+	n := len(w.records)
+	w.records = nil
+
+	err := w.delivery.do(func() error {
+		ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), w.headers), otlpTimeout)
+		defer cancel()
+
+		var resp []byte
+		return w.conn.Invoke(ctx, OTLPExportMethod, req, &resp, grpc.ForceCodec(rawCodec{}))
+	})
+	if err != nil {
+		return fmt.Errorf("dropping %d log records, they could not be exported over OTLP: %s", n, err.Error())
+	}
+
+	return nil
+}
+
+// request encodes an ExportLogsServiceRequest message with the records
+func (w *otlpWriter) request(records [][]byte) []byte {
+	var scope []byte
+	for _, r := range records {
+		scope = protowire.AppendTag(scope, otlpScopeLogsRecords, protowire.BytesType)
+		scope = protowire.AppendBytes(scope, r)
+	}
+
+	var rl []byte
+	rl = protowire.AppendTag(rl, otlpResourceLogsResource, protowire.BytesType)
+	rl = protowire.AppendBytes(rl, w.resource)
+	rl = protowire.AppendTag(rl, otlpResourceLogsScopeLogs, protowire.BytesType)
+	rl = protowire.AppendBytes(rl, scope)
+
+	var req []byte
+	req = protowire.AppendTag(req, otlpRequestResourceLogs, protowire.BytesType)
+	return protowire.AppendBytes(req, rl)
+}
+
+// appendOTLPAttributes appends the attributes as repeated KeyValue messages of the field num, in key order
+func appendOTLPAttributes(b []byte, num protowire.Number, attrs map[string]interface{}) []byte {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var kv []byte
+		kv = protowire.AppendTag(kv, otlpKeyValueKey, protowire.BytesType)
+		kv = protowire.AppendString(kv, k)
+		kv = protowire.AppendTag(kv, otlpKeyValueValue, protowire.BytesType)
+		kv = protowire.AppendBytes(kv, appendOTLPValue(nil, attrs[k]))
+
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, kv)
+	}
+	return b
+}
+
+// appendOTLPValue appends the value as the fields of an AnyValue message
+func appendOTLPValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return b
+	case string:
+		return appendOTLPString(b, v)
+	case bool:
+		b = protowire.AppendTag(b, otlpValueBool, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int:
+		return appendOTLPInt(b, int64(v))
+	case int8:
+		return appendOTLPInt(b, int64(v))
+	case int16:
+		return appendOTLPInt(b, int64(v))
+	case int32:
+		return appendOTLPInt(b, int64(v))
+	case int64:
+		return appendOTLPInt(b, v)
+	case uint8:
+		return appendOTLPInt(b, int64(v))
+	case uint16:
+		return appendOTLPInt(b, int64(v))
+	case uint32:
+		return appendOTLPInt(b, int64(v))
+	case uint:
+		return appendOTLPUint(b, uint64(v))
+	case uint64:
+		return appendOTLPUint(b, v)
+	case uintptr:
+		return appendOTLPUint(b, uint64(v))
+	case float32:
+		return appendOTLPDouble(b, float64(v))
+	case float64:
+		return appendOTLPDouble(b, v)
+	case complex64, complex128:
+		return appendOTLPString(b, fmt.Sprint(v))
+	case []byte:
+		b = protowire.AppendTag(b, otlpValueBytes, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	case time.Time:
+		return appendOTLPString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendOTLPString(b, v.String())
+	case error:
+		return appendOTLPString(b, v.Error())
+	case fmt.Stringer:
+		return appendOTLPString(b, v.String())
+	case []interface{}:
+		var list []byte
+		for _, e := range v {
+			list = protowire.AppendTag(list, otlpListValues, protowire.BytesType)
+			list = protowire.AppendBytes(list, appendOTLPValue(nil, e))
+		}
+		b = protowire.AppendTag(b, otlpValueArray, protowire.BytesType)
+		return protowire.AppendBytes(b, list)
+	case map[string]interface{}:
+		b = protowire.AppendTag(b, otlpValueKVList, protowire.BytesType)
+		return protowire.AppendBytes(b, appendOTLPAttributes(nil, otlpListValues, v))
+	}
+
+	// anything else, e.g. a reflected struct, is converted to the values it is logged as in JSON
+	data, err := json.Marshal(v)
+	if err != nil {
+		return appendOTLPString(b, fmt.Sprint(v))
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return appendOTLPString(b, string(data))
+	}
+	return appendOTLPValue(b, decoded)
+}
+
+func appendOTLPString(b []byte, s string) []byte {
+	b = protowire.AppendTag(b, otlpValueString, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendOTLPInt(b []byte, i int64) []byte {
+	b = protowire.AppendTag(b, otlpValueInt, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(i))
+}
+
+// appendOTLPUint appends an unsigned int, as a string if it is too large for the signed int of an AnyValue
+func appendOTLPUint(b []byte, u uint64) []byte {
+	if u > math.MaxInt64 {
+		return appendOTLPString(b, fmt.Sprint(u))
+	}
+	return appendOTLPInt(b, int64(u))
+}
+
+func appendOTLPDouble(b []byte, f float64) []byte {
+	b = protowire.AppendTag(b, otlpValueDouble, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(f))
+}
+
+// rawCodec sends and receives messages that are already encoded, as byte slices
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name is the content subtype messages are sent with, they are protobuf encoded
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,197 @@
+package writer
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The policies of a queue that is full
+const (
+	// QueueBlock blocks writes until there is room in the queue, so no entry is dropped
+	QueueBlock = "block"
+	// QueueDropOldest drops the oldest queued entry to make room for the new one
+	QueueDropOldest = "drop-oldest"
+	// QueueDropNewest drops the entry being written
+	QueueDropNewest = "drop-newest"
+)
+
+// DefaultQueueSize is the number of entries a queue holds
+const DefaultQueueSize = 1024
+
+// errQueueClosed is returned by writes to a closed queue
+var errQueueClosed = errors.New("log queue is closed")
+
+type queueWriter struct {
+	// the number of entries dropped because the queue was full or closed, first so it is 64-bit aligned
+	dropped uint64
+
+	ws      zapcore.WriteSyncer
+	closer  io.Closer
+	policy  string
+	metrics Metrics
+	queue   chan []byte
+	stop    chan struct{}
+	stopped chan struct{}
+
+	mu      sync.Mutex
+	drained *sync.Cond
+	// the number of entries written but not yet written to ws or dropped
+	pending int
+	closed  bool
+
+	closeOnce sync.Once
+}
+
+// Queue wraps a WriteSyncer so entries are written to it from a background goroutine, and writes return as soon as
+// the entry is queued rather than once it is sent. When the queue of size entries is full the policy decides
+// whether the write blocks or an entry is dropped, QueueBlock, QueueDropOldest or QueueDropNewest.
+// if size = 0, we set it to DefaultQueueSize
+// if policy is empty or unrecognized, we set it to QueueBlock
+// The closer drains the queue before closing the wrapped writer's closer, which may be nil.
+// Entries dropped are counted in the metrics
+func Queue(ws zapcore.WriteSyncer, closer io.Closer, size int, policy string, metrics Metrics) (zapcore.WriteSyncer, io.Closer) {
+	if size == 0 {
+		size = DefaultQueueSize
+	}
+
+	if policy != QueueDropOldest && policy != QueueDropNewest {
+		policy = QueueBlock
+	}
+
+	q := &queueWriter{
+		ws:      ws,
+		closer:  closer,
+		policy:  policy,
+		metrics: metrics,
+		queue:   make(chan []byte, size),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	q.drained = sync.NewCond(&q.mu)
+
+	go q.run()
+
+	return q, q
+}
+
+// run writes the queued entries to the wrapped writer until the queue is stopped
+func (q *queueWriter) run() {
+	defer close(q.stopped)
+
+	for {
+		select {
+		case p := <-q.queue:
+			// failed writes have already been retried and sent to the fallback by the wrapped writer
+			if _, err := q.ws.Write(p); err != nil {
+				log.Print(err.Error())
+			}
+			q.done()
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// Write queues a copy of bs, as the caller may reuse it, returning without waiting for it to be written
+func (q *queueWriter) Write(bs []byte) (int, error) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		q.drop()
+		return 0, errQueueClosed
+	}
+	q.pending++
+	q.mu.Unlock()
+
+	p := make([]byte, len(bs))
+	copy(p, bs)
+
+	switch q.policy {
+	case QueueBlock:
+		q.queue <- p
+	case QueueDropNewest:
+		select {
+		case q.queue <- p:
+		default:
+			q.drop()
+			q.done()
+		}
+	case QueueDropOldest:
+		for queued := false; !queued; {
+			select {
+			case q.queue <- p:
+				queued = true
+			default:
+				select {
+				case <-q.queue:
+					q.drop()
+					q.done()
+				default:
+				}
+			}
+		}
+	}
+
+	return len(bs), nil
+}
+
+// Sync waits for the queued entries to be written, then syncs the wrapped writer
+func (q *queueWriter) Sync() error {
+	q.wait()
+	return q.ws.Sync()
+}
+
+// Close stops accepting entries, waits for the queued entries to be written and closes the wrapped writer.
+// Only the first call has any effect
+func (q *queueWriter) Close() error {
+	var err error
+	q.closeOnce.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+
+		q.wait()
+		close(q.stop)
+		<-q.stopped
+
+		if n := atomic.LoadUint64(&q.dropped); n > 0 {
+			log.Printf("dropped %d log entries that could not be queued", n)
+		}
+
+		if q.closer != nil {
+			err = q.closer.Close()
+		} else {
+			err = q.ws.Sync()
+		}
+	})
+	return err
+}
+
+// wait blocks until no entries are pending
+func (q *queueWriter) wait() {
+	q.mu.Lock()
+	for q.pending > 0 {
+		q.drained.Wait()
+	}
+	q.mu.Unlock()
+}
+
+// done marks a pending entry as written or dropped
+func (q *queueWriter) done() {
+	q.mu.Lock()
+	q.pending--
+	if q.pending == 0 {
+		q.drained.Broadcast()
+	}
+	q.mu.Unlock()
+}
+
+func (q *queueWriter) drop() {
+	atomic.AddUint64(&q.dropped, 1)
+	q.metrics.queueDropped()
+}
@@ -0,0 +1,130 @@
+package writer
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter records the entries written to it, each write waiting until the gate is opened
+type gatedWriter struct {
+	started chan struct{}
+	gate    chan struct{}
+
+	mu      sync.Mutex
+	written []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}, 16), gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.gate
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = append(w.written, string(p))
+	return len(p), nil
+}
+
+func (w *gatedWriter) Sync() error {
+	return nil
+}
+
+func (w *gatedWriter) entries() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.written...)
+}
+
+// fillQueue writes a, which the queue's goroutine blocks writing, then b, which fills a queue of one entry
+func fillQueue(t *testing.T, q io.Writer, ws *gatedWriter) {
+	t.Helper()
+
+	_, err := q.Write([]byte("a"))
+	require.NoError(t, err)
+	<-ws.started
+	_, err = q.Write([]byte("b"))
+	require.NoError(t, err)
+}
+
+func Test_QueueDropNewest(t *testing.T) {
+	ws := newGatedWriter()
+	dropped := prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"})
+	q, closer := Queue(ws, nil, 1, QueueDropNewest, Metrics{QueueDropped: dropped})
+
+	fillQueue(t, q, ws)
+	n, err := q.Write([]byte("c"))
+	assert.NoError(t, err, "Expected a dropped entry not to fail the write")
+	assert.Equal(t, 1, n)
+
+	close(ws.gate)
+	require.NoError(t, closer.Close())
+	assert.Equal(t, []string{"a", "b"}, ws.entries(), "Expected the new entry to be dropped")
+	assert.Equal(t, 1.0, testutil.ToFloat64(dropped), "Expected the dropped entry to be counted")
+}
+
+func Test_QueueDropOldest(t *testing.T) {
+	ws := newGatedWriter()
+	dropped := prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"})
+	q, closer := Queue(ws, nil, 1, QueueDropOldest, Metrics{QueueDropped: dropped})
+
+	fillQueue(t, q, ws)
+	_, err := q.Write([]byte("c"))
+	assert.NoError(t, err, "Expected a dropped entry not to fail the write")
+
+	close(ws.gate)
+	require.NoError(t, closer.Close())
+	assert.Equal(t, []string{"a", "c"}, ws.entries(), "Expected the oldest queued entry to be dropped")
+	assert.Equal(t, 1.0, testutil.ToFloat64(dropped), "Expected the dropped entry to be counted")
+}
+
+func Test_QueueBlock(t *testing.T) {
+	ws := newGatedWriter()
+	q, closer := Queue(ws, nil, 1, "", Metrics{})
+
+	fillQueue(t, q, ws)
+	written := make(chan struct{})
+	go func() {
+		q.Write([]byte("c"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("Expected the write to block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(ws.gate)
+	<-written
+	require.NoError(t, closer.Close())
+	assert.Equal(t, []string{"a", "b", "c"}, ws.entries(), "Expected no entry to be dropped")
+}
+
+func Test_QueueClose(t *testing.T) {
+	ws := newGatedWriter()
+	close(ws.gate)
+	dropped := prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"})
+	q, closer := Queue(ws, nil, 0, QueueBlock, Metrics{QueueDropped: dropped})
+
+	for _, e := range []string{"a", "b", "c"} {
+		_, err := q.Write([]byte(e))
+		require.NoError(t, err)
+	}
+	require.NoError(t, closer.Close())
+	assert.Equal(t, []string{"a", "b", "c"}, ws.entries(), "Expected close to drain the queue")
+
+	_, err := q.Write([]byte("d"))
+	assert.Equal(t, errQueueClosed, err, "Expected writes to a closed queue to fail")
+	assert.Equal(t, 1.0, testutil.ToFloat64(dropped), "Expected the write to a closed queue to be counted")
+	assert.NoError(t, closer.Close(), "Expected closing again to have no effect")
+}
@@ -0,0 +1,86 @@
+package writer
+
+import (
+	"io"
+	"log"
+	"time"
+)
+
+// maxRetryBackoff caps the wait between attempts, however many attempts are configured
+const maxRetryBackoff = 10 * time.Second
+
+// Delivery configures how writes that fail are retried, and where their data goes when every attempt fails
+type Delivery struct {
+	// The number of times a write is attempted. 0 or 1 means it is not retried
+	Attempts int
+	// The wait before the first retry, doubled before each retry after it
+	Backoff time.Duration
+	// Where the data of writes that fail every attempt is written, so it isn't lost. If nil the error is returned
+	Fallback io.Writer
+}
+
+// do calls put until it succeeds or every attempt fails, waiting between attempts
+func (d Delivery) do(put func() error) error {
+	backoff := d.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = put(); err == nil || attempt >= d.Attempts {
+			return err
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// fallback writes data that couldn't be delivered to the fallback, returning err if there is no fallback
+// or the fallback fails too
+func (d Delivery) fallback(err error, data ...[]byte) error {
+	if d.Fallback == nil {
+		return err
+	}
+
+	log.Printf("writing undelivered log data to the fallback: %s", err.Error())
+	for _, p := range data {
+		if _, ferr := d.Fallback.Write(p); ferr != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type retryWriter struct {
+	w        io.Writer
+	delivery Delivery
+	metrics  Metrics
+}
+
+// Retry wraps a writer so failed writes are retried with exponential backoff, and written to the
+// fallback once every attempt has failed. Writes block while they are retried. Writes that can't
+// be written to the fallback either are counted as dropped in the metrics
+func Retry(w io.Writer, delivery Delivery, metrics Metrics) io.Writer {
+	return &retryWriter{
+		w:        w,
+		delivery: delivery,
+		metrics:  metrics,
+	}
+}
+
+func (r *retryWriter) Write(p []byte) (int, error) {
+	err := r.delivery.do(func() error {
+		_, err := r.w.Write(p)
+		return err
+	})
+	if err != nil {
+		if err := r.delivery.fallback(err, p); err != nil {
+			r.metrics.dropped(1)
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,44 @@
+package writer
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// SessionConfig configures the AWS session the kinesis writers are created with. The zero value uses the
+// default credential chain and region, picked up from the runtime hardware via environnement variables
+type SessionConfig struct {
+	// The region of the stream, when it is not the region of the runtime
+	Region string
+	// The role assumed to write to the stream, e.g. a role of the centralized logging account
+	RoleArn string
+	// The external ID the role's trust policy requires, if any
+	ExternalID string
+}
+
+// newSession creates an AWS session following sc. When a role is set, its credentials are assumed with the
+// credentials of the default chain, and refreshed before they expire
+func newSession(sc SessionConfig) (*session.Session, error) {
+	cfg := &aws.Config{
+		CredentialsChainVerboseErrors: aws.Bool(true),
+	}
+	if sc.Region != "" {
+		cfg.Region = aws.String(sc.Region)
+	}
+
+	ses, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if sc.RoleArn == "" {
+		return ses, nil
+	}
+
+	creds := stscreds.NewCredentials(ses, sc.RoleArn, func(p *stscreds.AssumeRoleProvider) {
+		if sc.ExternalID != "" {
+			p.ExternalID = aws.String(sc.ExternalID)
+		}
+	})
+	return ses.Copy(&aws.Config{Credentials: creds}), nil
+}
@@ -0,0 +1,145 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// webhookQueueSize is the number of entries that can be waiting to be posted before new ones are dropped
+	webhookQueueSize = 1000
+	// webhookTimeout bounds each attempt to post an entry
+	webhookTimeout = 5 * time.Second
+)
+
+type webhookWriter struct {
+	client   *http.Client
+	url      string
+	delivery Delivery
+	queue    chan []byte
+	done     chan struct{}
+
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending int
+	closed  bool
+}
+
+// NewWebhookWriter creates a writer that posts each write, which must be a whole JSON log entry, as the body of a
+// request to the url, e.g. an alerting service's events endpoint. Writes are queued and posted in the background,
+// so a slow endpoint never blocks logging, and entries are dropped while the queue is full. Posts that fail are
+// retried, and written to the fallback once every attempt has failed, as the delivery configures
+func NewWebhookWriter(url string, delivery Delivery) (zapcore.WriteSyncer, io.Closer) {
+	w := &webhookWriter{
+		client:   &http.Client{Timeout: webhookTimeout},
+		url:      url,
+		delivery: delivery,
+		queue:    make(chan []byte, webhookQueueSize),
+		done:     make(chan struct{}),
+	}
+	w.idle = sync.NewCond(&w.mu)
+
+	go w.run()
+
+	return w, w
+}
+
+// Write queues the byte slice to be posted
+func (w *webhookWriter) Write(p []byte) (int, error) {
+	// the caller may reuse p once Write returns
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("webhook writer is closed")
+	}
+
+	select {
+	case w.queue <- data:
+		w.pending++
+	default:
+		log.Print("dropping log entry, the webhook queue is full")
+	}
+
+	return len(p), nil
+}
+
+// Sync blocks until every queued entry has been posted, or given up on
+func (w *webhookWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.pending > 0 {
+		w.idle.Wait()
+	}
+	return nil
+}
+
+// Close posts the queued entries and stops the background posts. Only the first call has any effect
+func (w *webhookWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// run posts queued entries until the queue is closed
+func (w *webhookWriter) run() {
+	defer close(w.done)
+
+	for data := range w.queue {
+		err := w.delivery.do(func() error {
+			return w.post(data)
+		})
+		if err != nil {
+			if err := w.delivery.fallback(err, data); err != nil {
+				log.Printf("dropping log entry, it could not be posted to the webhook: %s", err.Error())
+			}
+		}
+
+		w.mu.Lock()
+		w.pending--
+		if w.pending == 0 {
+			w.idle.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// post sends one entry to the webhook, returning an error unless it responds with a 2xx status
+func (w *webhookWriter) post(data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drained so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
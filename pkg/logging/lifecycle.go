@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrLoggerClosed is returned by Sync once the logger has been closed. Entries logged after
+// Close are written to stderr instead, see Logger.WritesAfterClose.
+var ErrLoggerClosed = errors.New("logger is closed")
+
+// lifecycle tracks whether a logger and the children sharing its cores have been closed. Once
+// closed, writes are routed to a stderr fallback rather than the closed buffers behind the cores.
+type lifecycle struct {
+	// held for reading while checking whether it is closed, and for writing while marking it closed
+	mu     sync.RWMutex
+	closed bool
+	// the writes through the cores that started before it was closed, which the cores are closed after, so
+	// no entry is written to a buffer once it starts closing. The lock isn't held while writing, so hooks
+	// that log don't wait on a close that is waiting on them
+	writes sync.WaitGroup
+	// closed once the cores are closed, after which closeErr holds the error closing them
+	done     chan struct{}
+	closeErr error
+
+	writesAfterClose int64
+	fallback         zapcore.Core
+	// where the errors writing to the cores are reported
+	errorOutput zapcore.WriteSyncer
+}
+
+func newLifecycle(enc zapcore.EncoderConfig) *lifecycle {
+	return &lifecycle{
+		fallback:    zapcore.NewCore(zapcore.NewJSONEncoder(enc), zapcore.Lock(os.Stderr), zapcore.DebugLevel),
+		errorOutput: zapcore.Lock(os.Stderr),
+		done:        make(chan struct{}),
+	}
+}
+
+// wrap returns the option that routes writes through the lifecycle
+func (lc *lifecycle) wrap() zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &lifecycleCore{
+			Core:     core,
+			fallback: lc.fallback,
+			lc:       lc,
+		}
+	})
+}
+
+// close runs f, which closes the cores, unless the lifecycle is already closed, and waits for the cores to be
+// closed or ctx to be done. Only the first call returns the error closing the cores, the others wait for them
+// and return nil. The cores are closed once the writes in progress finish, without holding the lock, so entries
+// logged while they drain, or after ctx is done, are written to the fallback rather than waiting on them
+func (lc *lifecycle) close(ctx context.Context, f func() error) error {
+	lc.mu.Lock()
+	first := !lc.closed
+	lc.closed = true
+	lc.mu.Unlock()
+
+	if first {
+		go func() {
+			lc.writes.Wait()
+			lc.closeErr = f()
+			close(lc.done)
+		}()
+	}
+
+	select {
+	case <-lc.done:
+		if first {
+			return lc.closeErr
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isClosed reports whether the lifecycle has been closed
+func (lc *lifecycle) isClosed() bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.closed
+}
+
+// begin reports whether the lifecycle is still open, and if so counts a write through the cores that
+// the caller must end with writes.Done
+func (lc *lifecycle) begin() bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if lc.closed {
+		return false
+	}
+	lc.writes.Add(1)
+	return true
+}
+
+// lifecycleCore writes to the wrapped core until the lifecycle is closed, and to the fallback after
+type lifecycleCore struct {
+	zapcore.Core
+	fallback zapcore.Core
+	lc       *lifecycle
+}
+
+func (c *lifecycleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &lifecycleCore{
+		Core:     c.Core.With(fields),
+		fallback: c.fallback.With(fields),
+		lc:       c.lc,
+	}
+}
+
+func (c *lifecycleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *lifecycleCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// written through the checked entry of the wrapped cores, so the ones that filter in Check still do. As
+	// the logger would, write errors are reported to stderr rather than returned
+	if c.lc.begin() {
+		defer c.lc.writes.Done()
+		if ce := c.Core.Check(ent, nil); ce != nil {
+			ce.ErrorOutput = c.lc.errorOutput
+			ce.Write(fields...)
+		}
+		return nil
+	}
+
+	if atomic.AddInt64(&c.lc.writesAfterClose, 1) == 1 {
+		fmt.Fprintln(os.Stderr, "logging: entries are being logged after the logger was closed, writing them to stderr")
+	}
+	return c.fallback.Write(ent, fields)
+}
+
+func (c *lifecycleCore) Sync() error {
+	if !c.lc.begin() {
+		return ErrLoggerClosed
+	}
+	defer c.lc.writes.Done()
+	return c.Core.Sync()
+}
@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_LoggerClose(t *testing.T) {
+	l, err := NewLogger(&Config{})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	fac, logs := observer.New(zap.DebugLevel)
+	l.monitorLogger = zap.New(fac).WithOptions(l.lifecycle.wrap())
+	child := l.NewChild(nil, String("child", "yes"))
+
+	t.Run("Writes to the outputs until closed", func(t *testing.T) {
+		child.Info("before close")
+		assert.Equal(t, 1, logs.Len(), "Expected the entry to be written")
+		assert.Equal(t, int64(0), l.WritesAfterClose(), "Expected no writes after close")
+	})
+
+	t.Run("Close can be called more than once", func(t *testing.T) {
+		assert.NoError(t, l.Close(), "Expected no error closing the logger")
+		assert.NoError(t, l.Close(), "Expected no error closing the logger again")
+	})
+
+	t.Run("Writes to the fallback and counts them once closed", func(t *testing.T) {
+		l.Info("after close")
+		child.Warn("after close")
+		assert.Equal(t, 1, logs.Len(), "Expected no more entries to be written to the outputs")
+		assert.Equal(t, int64(2), l.WritesAfterClose(), "Expected two writes after close")
+		assert.Equal(t, int64(2), child.WritesAfterClose(), "Expected the child to share the count")
+	})
+
+	t.Run("Sync returns a sentinel error once closed", func(t *testing.T) {
+		assert.True(t, errors.Is(l.Sync(), ErrLoggerClosed), "Expected ErrLoggerClosed")
+		assert.True(t, errors.Is(child.Sync(), ErrLoggerClosed), "Expected ErrLoggerClosed from the child")
+	})
+}
+
+func Test_NopLoggerClose(t *testing.T) {
+	l := NewNopLogger()
+
+	assert.NoError(t, l.Close(), "Expected no error closing the logger")
+	assert.NoError(t, l.Close(), "Expected no error closing the logger again")
+	assert.Equal(t, int64(0), l.WritesAfterClose(), "Expected no writes after close")
+}
+
+// blockingCloser is an output that doesn't finish draining until it is released
+type blockingCloser struct {
+	release chan struct{}
+}
+
+func (c blockingCloser) Close() error {
+	<-c.release
+	return nil
+}
+
+func Test_LoggerCloseWithContext(t *testing.T) {
+	l, err := NewLogger(&Config{})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	fac, logs := observer.New(zap.DebugLevel)
+	l.monitorLogger = zap.New(fac).WithOptions(l.lifecycle.wrap())
+	draining := blockingCloser{release: make(chan struct{})}
+	l.closers = append(l.closers, draining)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.CloseWithContext(ctx), "Expected the deadline to expire while draining")
+
+	l.Info("while draining")
+	assert.Equal(t, 0, logs.Len(), "Expected entries logged while draining not to wait on the outputs")
+	assert.Equal(t, int64(1), l.WritesAfterClose())
+
+	closed := make(chan error)
+	go func() { closed <- l.Close() }()
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the outputs to drain")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(draining.release)
+	assert.NoError(t, <-closed, "Expected Close to return once the outputs drained")
+}
+
+// checkFilterCore only enables the entries with its message, filtering in Check rather than Write
+type checkFilterCore struct {
+	zapcore.Core
+	message string
+}
+
+func (c checkFilterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Message != c.message {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func Test_lifecycleCoreChecksWrappedCore(t *testing.T) {
+	fac, logs := observer.New(zap.DebugLevel)
+	lc := newLifecycle(zap.NewProductionEncoderConfig())
+	zapL := zap.New(checkFilterCore{Core: fac, message: "kept"}).WithOptions(lc.wrap())
+
+	zapL.Info("kept")
+	zapL.Info("filtered")
+
+	require.Equal(t, 1, logs.Len(), "Expected the wrapped core's Check to filter entries")
+	assert.Equal(t, "kept", logs.All()[0].Message)
+}
+
+func Test_LoggerCloseWhileHookLogs(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var l *Logger
+	l, err := NewLogger(&Config{OnLog: []LogHook{func(level Level, message string, fields map[string]interface{}) {
+		if message != "hooked" {
+			return
+		}
+		close(entered)
+		<-release
+		l.Info("from the hook")
+	}}})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	logged := make(chan struct{})
+	go func() {
+		l.Info("hooked")
+		close(logged)
+	}()
+	<-entered
+
+	closed := make(chan error)
+	go func() { closed <- l.Close() }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-closed:
+		assert.NoError(t, err, "Expected no error closing the logger")
+	case <-time.After(time.Second):
+		t.Fatal("Expected Close not to wait on a hook that logs")
+	}
+	<-logged
+}
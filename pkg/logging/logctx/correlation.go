@@ -0,0 +1,71 @@
+package logctx
+
+import (
+	"context"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/caring/go-packages/v2/pkg/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// CorrelationIDHeader is the metadata key the correlation ID is read from and echoed in
+const CorrelationIDHeader = "x-correlation-id"
+
+// NewCorrelationIDUnaryInterceptor returns a unary interceptor that gives every request a correlation ID, the
+// one the client sent in the x-correlation-id header or a new uuid if it sent none. A child of the context's
+// logger, or of l if the context has none, carrying the ID is put on the context for Extract, the ID is put on
+// the context for the Ctx log methods, and it is echoed in the response headers
+func NewCorrelationIDUnaryInterceptor(l *logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, id := withCorrelationID(ctx, l)
+		grpc.SetHeader(ctx, metadata.Pairs(CorrelationIDHeader, id))
+
+		return handler(ctx, req)
+	}
+}
+
+// NewCorrelationIDStreamInterceptor returns a stream interceptor that gives every stream a correlation ID, like
+// NewCorrelationIDUnaryInterceptor
+func NewCorrelationIDStreamInterceptor(l *logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withCorrelationID(ss.Context(), l)
+		ss.SetHeader(metadata.Pairs(CorrelationIDHeader, id))
+
+		return handler(srv, &correlatedStream{ss, ctx})
+	}
+}
+
+// withCorrelationID reads the correlation ID from the incoming metadata, generating one if there is none, and
+// returns a copy of ctx carrying it and a logger with it
+func withCorrelationID(ctx context.Context, l *logging.Logger) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(CorrelationIDHeader); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	if existing, ok := ctx.Value(ctxKey).(*ctxLogger); ok && existing != nil {
+		l = existing.logger
+	}
+	if l == nil {
+		l = nullLogger
+	}
+
+	ctx = logging.ContextWithCorrelationID(ctx, id)
+	return ToContext(ctx, l.NewChild(&logging.FieldOpts{CorrelationID: id})), id
+}
+
+// correlatedStream is a server stream with a context carrying the correlation or traceability ID
+type correlatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *correlatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,120 @@
+package logctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// headerTransportStream records the headers a unary handler sets with grpc.SetHeader
+type headerTransportStream struct {
+	header metadata.MD
+}
+
+func (s *headerTransportStream) Method() string { return createMethod }
+
+func (s *headerTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerTransportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerTransportStream) SetTrailer(md metadata.MD) error { return nil }
+
+// headerStream is a server stream with a context that records the headers set on it
+type headerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *headerStream) Context() context.Context { return s.ctx }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// loggedCorrelationID logs with the context's logger, and returns the correlation ID the entry was logged with
+func loggedCorrelationID(t *testing.T, ctx context.Context, logs *logging.TestLogs) string {
+	t.Helper()
+
+	logs.TakeAll()
+	Extract(ctx).Info("handling")
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1, "Expected the context's logger to log")
+	id, _ := entries[0].Fields["correlationID"].(string)
+	return id
+}
+
+func TestCorrelationIDUnaryInterceptor(t *testing.T) {
+	l, logs := logging.NewTestLogger()
+	interceptor := NewCorrelationIDUnaryInterceptor(l)
+
+	var logged, carried string
+	var extracted *logging.Logger
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		extracted = Extract(ctx)
+		logged, carried = loggedCorrelationID(t, ctx, logs), logging.CorrelationIDFromContext(ctx)
+		return nil, nil
+	}
+
+	t.Run("Reads the ID from the metadata", func(t *testing.T) {
+		stream := &headerTransportStream{}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "correlation-1"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: createMethod}, handler)
+		require.NoError(t, err)
+
+		assert.NotSame(t, l, extracted, "Expected a child logger on the context")
+		assert.Equal(t, "correlation-1", logged, "Expected the context's logger to carry the ID")
+		assert.Equal(t, "correlation-1", carried, "Expected the context to carry the ID")
+		assert.Equal(t, []string{"correlation-1"}, stream.header.Get(CorrelationIDHeader), "Expected the ID echoed in the headers")
+	})
+
+	t.Run("Generates an ID when there is none", func(t *testing.T) {
+		stream := &headerTransportStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: createMethod}, handler)
+		require.NoError(t, err)
+
+		assert.Len(t, logged, 36, "Expected a generated uuid")
+		assert.Equal(t, logged, carried, "Expected the context to carry the generated ID")
+		assert.Equal(t, []string{logged}, stream.header.Get(CorrelationIDHeader), "Expected the generated ID echoed in the headers")
+	})
+
+	t.Run("Derives the child from the context's logger", func(t *testing.T) {
+		parent := l.NewChild(&logging.FieldOpts{TraceabilityID: "trace-1"})
+		ctx := ToContext(context.Background(), parent)
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(CorrelationIDHeader, "correlation-2"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &headerTransportStream{})
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: createMethod}, handler)
+		require.NoError(t, err)
+
+		assert.Equal(t, "correlation-2", logged)
+		assert.Equal(t, "trace-1", extracted.TraceabilityID(), "Expected the fields of the context's logger to be kept")
+	})
+}
+
+func TestCorrelationIDStreamInterceptor(t *testing.T) {
+	l, logs := logging.NewTestLogger()
+	interceptor := NewCorrelationIDStreamInterceptor(l)
+
+	stream := &headerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "correlation-1"))}
+	var logged, carried string
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: createMethod}, func(srv interface{}, ss grpc.ServerStream) error {
+		logged, carried = loggedCorrelationID(t, ss.Context(), logs), logging.CorrelationIDFromContext(ss.Context())
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "correlation-1", logged, "Expected the stream's logger to carry the ID")
+	assert.Equal(t, "correlation-1", carried, "Expected the stream's context to carry the ID")
+	assert.Equal(t, []string{"correlation-1"}, stream.header.Get(CorrelationIDHeader), "Expected the ID echoed in the headers")
+}
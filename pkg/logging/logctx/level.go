@@ -0,0 +1,48 @@
+package logctx
+
+import (
+	"context"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"google.golang.org/grpc"
+)
+
+// NewMethodLevelUnaryInterceptor returns a unary interceptor that logs each request at the level of its method,
+// when Logger.SetMethodLevel gave it one. A child of the context's logger, or of l if the context has none, at
+// the method's level is put on the context for Extract. It should run after the correlation ID interceptor, so
+// the child carries the ID
+func NewMethodLevelUnaryInterceptor(l *logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withMethodLevel(ctx, l, info.FullMethod), req)
+	}
+}
+
+// NewMethodLevelStreamInterceptor returns a stream interceptor that logs each stream at the level of its method,
+// like NewMethodLevelUnaryInterceptor
+func NewMethodLevelStreamInterceptor(l *logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &leveledStream{ss, withMethodLevel(ss.Context(), l, info.FullMethod)})
+	}
+}
+
+// withMethodLevel returns a copy of ctx carrying the logger for the method
+func withMethodLevel(ctx context.Context, l *logging.Logger, method string) context.Context {
+	if existing, ok := ctx.Value(ctxKey).(*ctxLogger); ok && existing != nil {
+		l = existing.logger
+	}
+	if l == nil {
+		return ctx
+	}
+
+	return ToContext(ctx, l.ForMethod(method))
+}
+
+// leveledStream is a server stream with a context carrying the logger for its method
+type leveledStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *leveledStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,77 @@
+package logctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+const (
+	createMethod = "/calls.v1.Calls/Create"
+	listMethod   = "/calls.v1.Calls/List"
+)
+
+// debugHandler logs a debug entry with the context's logger
+func debugHandler(ctx context.Context, req interface{}) (interface{}, error) {
+	Extract(ctx).Debug("handling " + req.(string))
+	return nil, nil
+}
+
+func TestMethodLevelUnaryInterceptor(t *testing.T) {
+	l, logs := logging.NewObservedLogger(logging.InfoLevel)
+	l.SetMethodLevel(createMethod, logging.DebugLevel, 0)
+	logs.TakeAll()
+	interceptor := NewMethodLevelUnaryInterceptor(l)
+
+	for _, method := range []string{createMethod, listMethod} {
+		_, err := interceptor(context.Background(), method, &grpc.UnaryServerInfo{FullMethod: method}, debugHandler)
+		require.NoError(t, err)
+	}
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1, "Expected only the method with a debug level to log at DEBUG")
+	assert.Equal(t, "handling "+createMethod, entries[0].Message)
+
+	t.Run("Keeps the context's logger", func(t *testing.T) {
+		ctx := ToContext(context.Background(), l.NewChild(&logging.FieldOpts{CorrelationID: "corr-1"}))
+		_, err := interceptor(ctx, createMethod, &grpc.UnaryServerInfo{FullMethod: createMethod}, debugHandler)
+		require.NoError(t, err)
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "corr-1", entries[0].ContextMap()["correlationID"], "Expected the child of the context's logger")
+	})
+}
+
+// contextStream is a server stream that only has a context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestMethodLevelStreamInterceptor(t *testing.T) {
+	l, logs := logging.NewObservedLogger(logging.InfoLevel)
+	l.SetMethodLevel(createMethod, logging.DebugLevel, 0)
+	logs.TakeAll()
+	interceptor := NewMethodLevelStreamInterceptor(l)
+
+	for _, method := range []string{createMethod, listMethod} {
+		err := interceptor(nil, &contextStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: method}, func(srv interface{}, ss grpc.ServerStream) error {
+			_, err := debugHandler(ss.Context(), method)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1, "Expected only the method with a debug level to log at DEBUG")
+	assert.Equal(t, "handling "+createMethod, entries[0].Message)
+}
@@ -0,0 +1,54 @@
+package logctx
+
+import (
+	"context"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceabilityIDHeader is the metadata key the traceability ID is read from
+const TraceabilityIDHeader = "x-traceability-id"
+
+// NewTraceabilityIDUnaryInterceptor returns a unary interceptor that carries the traceability ID the client sent
+// in the x-traceability-id header through the request. A child of the context's logger, or of l if the context
+// has none, carrying the ID is put on the context for Extract, and the ID is put on the context for the Ctx log
+// methods. When the client sent none, the child is given a new one if the logger generates traceability IDs
+func NewTraceabilityIDUnaryInterceptor(l *logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withTraceabilityID(ctx, l), req)
+	}
+}
+
+// NewTraceabilityIDStreamInterceptor returns a stream interceptor that carries the traceability ID the client
+// sent through the stream, like NewTraceabilityIDUnaryInterceptor
+func NewTraceabilityIDStreamInterceptor(l *logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &correlatedStream{ss, withTraceabilityID(ss.Context(), l)})
+	}
+}
+
+// withTraceabilityID reads the traceability ID from the incoming metadata and returns a copy of ctx carrying
+// it and a logger with it. The logger generates an ID when there is none and it is configured to
+func withTraceabilityID(ctx context.Context, l *logging.Logger) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(TraceabilityIDHeader); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+
+	if existing, ok := ctx.Value(ctxKey).(*ctxLogger); ok && existing != nil {
+		l = existing.logger
+	}
+	if l == nil {
+		l = nullLogger
+	}
+
+	child := l.NewChild(&logging.FieldOpts{TraceabilityID: id})
+	if id = child.TraceabilityID(); id != "" {
+		ctx = logging.ContextWithTraceabilityID(ctx, id)
+	}
+	return ToContext(ctx, child)
+}
@@ -0,0 +1,73 @@
+package logctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// traceabilityIDs returns the traceability IDs of the context's logger and of the context itself
+func traceabilityIDs(ctx context.Context) (string, string) {
+	return Extract(ctx).TraceabilityID(), logging.TraceabilityIDFromContext(ctx)
+}
+
+func TestTraceabilityIDUnaryInterceptor(t *testing.T) {
+	generate := true
+	l, err := logging.NewLogger(&logging.Config{GenerateTraceabilityID: &generate})
+	require.NoError(t, err)
+	defer l.Close()
+	interceptor := NewTraceabilityIDUnaryInterceptor(l)
+
+	var logged, carried string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		logged, carried = traceabilityIDs(ctx)
+		return nil, nil
+	}
+
+	t.Run("Reads the ID from the metadata", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceabilityIDHeader, "trace-1"))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: createMethod}, handler)
+		require.NoError(t, err)
+
+		assert.Equal(t, "trace-1", logged, "Expected the context's logger to carry the ID")
+		assert.Equal(t, "trace-1", carried, "Expected the context to carry the ID")
+	})
+
+	t.Run("Generates an ID when there is none", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: createMethod}, handler)
+		require.NoError(t, err)
+
+		assert.Len(t, logged, 36, "Expected a generated uuid")
+		assert.Equal(t, logged, carried, "Expected the context to carry the generated ID")
+	})
+
+	t.Run("Leaves the ID empty when the logger doesn't generate them", func(t *testing.T) {
+		l, _ := logging.NewObservedLogger(logging.InfoLevel)
+		_, err := NewTraceabilityIDUnaryInterceptor(l)(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: createMethod}, handler)
+		require.NoError(t, err)
+
+		assert.Equal(t, "", logged)
+		assert.Equal(t, "", carried)
+	})
+}
+
+func TestTraceabilityIDStreamInterceptor(t *testing.T) {
+	l, _ := logging.NewObservedLogger(logging.InfoLevel)
+	interceptor := NewTraceabilityIDStreamInterceptor(l)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceabilityIDHeader, "trace-1"))
+	var logged, carried string
+	err := interceptor(nil, &contextStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: createMethod}, func(srv interface{}, ss grpc.ServerStream) error {
+		logged, carried = traceabilityIDs(ss.Context())
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "trace-1", logged, "Expected the stream's logger to carry the ID")
+	assert.Equal(t, "trace-1", carried, "Expected the stream's context to carry the ID")
+}
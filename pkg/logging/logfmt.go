@@ -0,0 +1,408 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// The formats monitoring entries can be written in
+const (
+	// FormatJSON writes each entry as a JSON object, nested fields as nested objects
+	FormatJSON = "json"
+	// FormatLogfmt writes each entry as a line of key=value pairs, for log shippers that can't parse nested JSON.
+	// Nested fields are flattened into dotted keys, e.g. error.message, and arrays into indexed keys, e.g. ids.0
+	FormatLogfmt = "logfmt"
+)
+
+// the names the logfmt encoders are registered with in zap, so they can be selected in the zap config
+// used to build the stdout logger
+const (
+	logfmtEncoding       = "caring-logfmt"
+	sortedLogfmtEncoding = "caring-sorted-logfmt"
+)
+
+func init() {
+	err := zap.RegisterEncoder(logfmtEncoding, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return newLogfmtEncoder(cfg), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = zap.RegisterEncoder(sortedLogfmtEncoding, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return sortedEncoder{newLogfmtEncoder(cfg)}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder is a zapcore.Encoder writing entries as logfmt. Objects and namespaces are flattened
+// by prefixing the keys of their fields with their own key and a dot.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// prepended to the keys of fields, while an object or namespace is open
+	prefix string
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) *logfmtEncoder {
+	return &logfmtEncoder{
+		EncoderConfig: &cfg,
+		buf:           logfmtPool.Get(),
+	}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return e.clone()
+}
+
+func (e *logfmtEncoder) clone() *logfmtEncoder {
+	c := &logfmtEncoder{
+		EncoderConfig: e.EncoderConfig,
+		buf:           logfmtPool.Get(),
+		prefix:        e.prefix,
+	}
+	c.buf.Write(e.buf.Bytes())
+	return c
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{
+		EncoderConfig: e.EncoderConfig,
+		buf:           logfmtPool.Get(),
+	}
+
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" {
+		final.encodeWith(final.LevelKey, ent.Level.String(), func(enc zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeLevel != nil {
+				final.EncodeLevel(ent.Level, enc)
+			}
+		})
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.encodeWith(final.NameKey, ent.LoggerName, func(enc zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeName != nil {
+				final.EncodeName(ent.LoggerName, enc)
+			}
+		})
+	}
+	if ent.Caller.Defined && final.CallerKey != "" {
+		final.encodeWith(final.CallerKey, ent.Caller.String(), func(enc zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeCaller != nil {
+				final.EncodeCaller(ent.Caller, enc)
+			}
+		})
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+
+	if e.buf.Len() > 0 {
+		final.separate()
+		final.buf.Write(e.buf.Bytes())
+	}
+	// fields are added inside any namespace opened by the context
+	final.prefix = e.prefix
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.prefix = ""
+
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+
+	return final.buf, nil
+}
+
+// separate writes the space between pairs, unless the line is empty
+func (e *logfmtEncoder) separate() {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+}
+
+// addKey writes the key of the next pair, with the prefix of any open object or namespace
+func (e *logfmtEncoder) addKey(key string) {
+	e.separate()
+	writeLogfmtKey(e.buf, e.prefix+key)
+	e.buf.AppendByte('=')
+}
+
+// addValue writes a pair with a value that's already formatted
+func (e *logfmtEncoder) addValue(key, value string) {
+	e.addKey(key)
+	writeLogfmtValue(e.buf, value)
+}
+
+// encodeWith writes a pair with the value encoded by a zap primitive encoder from the encoder config,
+// or the fallback if the encoder writes nothing
+func (e *logfmtEncoder) encodeWith(key, fallback string, encode func(zapcore.PrimitiveArrayEncoder)) {
+	arr := &logfmtArray{enc: e, key: key, single: true}
+	encode(arr)
+	if arr.i == 0 {
+		e.addValue(key, fallback)
+	}
+}
+
+// addReflected writes a value decoded from JSON, flattening objects and arrays
+func (e *logfmtEncoder) addReflected(key string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			e.addReflected(key+"."+k, v[k])
+		}
+	case []interface{}:
+		for i, el := range v {
+			e.addReflected(key+"."+strconv.Itoa(i), el)
+		}
+	case string:
+		e.addValue(key, v)
+	case json.Number:
+		e.addValue(key, v.String())
+	case bool:
+		e.addValue(key, strconv.FormatBool(v))
+	case nil:
+		e.addValue(key, "null")
+	}
+}
+
+func (e *logfmtEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	return marshaler.MarshalLogArray(&logfmtArray{enc: e, key: key})
+}
+
+func (e *logfmtEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	prefix := e.prefix
+	e.prefix += key + "."
+	err := marshaler.MarshalLogObject(e)
+	e.prefix = prefix
+	return err
+}
+
+func (e *logfmtEncoder) AddBinary(key string, value []byte) {
+	e.addValue(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, value []byte) {
+	e.addValue(key, string(value))
+}
+
+func (e *logfmtEncoder) AddBool(key string, value bool) {
+	e.addValue(key, strconv.FormatBool(value))
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, value complex128) {
+	e.addValue(key, formatLogfmtComplex(value, 64))
+}
+
+func (e *logfmtEncoder) AddComplex64(key string, value complex64) {
+	e.addValue(key, formatLogfmtComplex(complex128(value), 32))
+}
+
+func (e *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	e.encodeWith(key, strconv.FormatInt(int64(value), 10), func(enc zapcore.PrimitiveArrayEncoder) {
+		if e.EncodeDuration != nil {
+			e.EncodeDuration(value, enc)
+		}
+	})
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, value float64) {
+	e.addValue(key, formatLogfmtFloat(value, 64))
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, value float32) {
+	e.addValue(key, formatLogfmtFloat(float64(value), 32))
+}
+
+func (e *logfmtEncoder) AddInt(key string, value int)     { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt32(key string, value int32) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt16(key string, value int16) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt8(key string, value int8)   { e.AddInt64(key, int64(value)) }
+
+func (e *logfmtEncoder) AddInt64(key string, value int64) {
+	e.addValue(key, strconv.FormatInt(value, 10))
+}
+
+func (e *logfmtEncoder) AddString(key, value string) {
+	e.addValue(key, value)
+}
+
+func (e *logfmtEncoder) AddTime(key string, value time.Time) {
+	e.encodeWith(key, strconv.FormatInt(value.UnixNano(), 10), func(enc zapcore.PrimitiveArrayEncoder) {
+		if e.EncodeTime != nil {
+			e.EncodeTime(value, enc)
+		}
+	})
+}
+
+func (e *logfmtEncoder) AddUint(key string, value uint)       { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint32(key string, value uint32)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint16(key string, value uint16)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint8(key string, value uint8)     { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUintptr(key string, value uintptr) { e.AddUint64(key, uint64(value)) }
+
+func (e *logfmtEncoder) AddUint64(key string, value uint64) {
+	e.addValue(key, strconv.FormatUint(value, 10))
+}
+
+// AddReflected flattens the value's JSON encoding, so maps and structs are written as dotted keys
+func (e *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	e.addReflected(key, v)
+	return nil
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+// logfmtArray writes the elements of an array as pairs with indexed keys, or the single value written by a
+// zap primitive encoder, such as a time or level encoder, under the key itself
+type logfmtArray struct {
+	enc    *logfmtEncoder
+	key    string
+	single bool
+	i      int
+}
+
+// next returns the key of the next element
+func (a *logfmtArray) next() string {
+	key := a.key
+	if !a.single || a.i > 0 {
+		key += "." + strconv.Itoa(a.i)
+	}
+	a.i++
+	return key
+}
+
+func (a *logfmtArray) AppendArray(v zapcore.ArrayMarshaler) error {
+	return a.enc.AddArray(a.next(), v)
+}
+
+func (a *logfmtArray) AppendObject(v zapcore.ObjectMarshaler) error {
+	return a.enc.AddObject(a.next(), v)
+}
+
+func (a *logfmtArray) AppendReflected(v interface{}) error {
+	return a.enc.AddReflected(a.next(), v)
+}
+
+func (a *logfmtArray) AppendBool(v bool)              { a.enc.AddBool(a.next(), v) }
+func (a *logfmtArray) AppendByteString(v []byte)      { a.enc.AddByteString(a.next(), v) }
+func (a *logfmtArray) AppendComplex128(v complex128)  { a.enc.AddComplex128(a.next(), v) }
+func (a *logfmtArray) AppendComplex64(v complex64)    { a.enc.AddComplex64(a.next(), v) }
+func (a *logfmtArray) AppendDuration(v time.Duration) { a.enc.AddDuration(a.next(), v) }
+func (a *logfmtArray) AppendFloat64(v float64)        { a.enc.AddFloat64(a.next(), v) }
+func (a *logfmtArray) AppendFloat32(v float32)        { a.enc.AddFloat32(a.next(), v) }
+func (a *logfmtArray) AppendInt(v int)                { a.enc.AddInt(a.next(), v) }
+func (a *logfmtArray) AppendInt64(v int64)            { a.enc.AddInt64(a.next(), v) }
+func (a *logfmtArray) AppendInt32(v int32)            { a.enc.AddInt32(a.next(), v) }
+func (a *logfmtArray) AppendInt16(v int16)            { a.enc.AddInt16(a.next(), v) }
+func (a *logfmtArray) AppendInt8(v int8)              { a.enc.AddInt8(a.next(), v) }
+func (a *logfmtArray) AppendString(v string)          { a.enc.AddString(a.next(), v) }
+func (a *logfmtArray) AppendTime(v time.Time)         { a.enc.AddTime(a.next(), v) }
+func (a *logfmtArray) AppendUint(v uint)              { a.enc.AddUint(a.next(), v) }
+func (a *logfmtArray) AppendUint64(v uint64)          { a.enc.AddUint64(a.next(), v) }
+func (a *logfmtArray) AppendUint32(v uint32)          { a.enc.AddUint32(a.next(), v) }
+func (a *logfmtArray) AppendUint16(v uint16)          { a.enc.AddUint16(a.next(), v) }
+func (a *logfmtArray) AppendUint8(v uint8)            { a.enc.AddUint8(a.next(), v) }
+func (a *logfmtArray) AppendUintptr(v uintptr)        { a.enc.AddUintptr(a.next(), v) }
+
+// formatLogfmtFloat formats a float the way the JSON encoder does, with NaN and the infinities as strings
+func formatLogfmtFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, bitSize)
+}
+
+// formatLogfmtComplex formats a complex number the way the JSON encoder does, with parts of the given bit size
+func formatLogfmtComplex(c complex128, bitSize int) string {
+	r, i := real(c), imag(c)
+	s := strconv.FormatFloat(r, 'f', -1, bitSize)
+	if i >= 0 {
+		s += "+"
+	}
+	return s + strconv.FormatFloat(i, 'f', -1, bitSize) + "i"
+}
+
+// writeLogfmtKey writes a key with any characters that would end it replaced with underscores
+func writeLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			buf.AppendByte('_')
+			continue
+		}
+		buf.AppendString(string(r))
+	}
+}
+
+// writeLogfmtValue writes a value, quoted and escaped if it is empty or contains anything that would end it
+func writeLogfmtValue(buf *buffer.Buffer, value string) {
+	if !logfmtNeedsQuotes(value) {
+		buf.AppendString(value)
+		return
+	}
+	buf.AppendString(strconv.Quote(value))
+}
+
+func logfmtNeedsQuotes(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	caringerrors "github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func testLogfmtEncoder() *logfmtEncoder {
+	return newLogfmtEncoder(zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		MessageKey:     "msg",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.EpochTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+}
+
+func Test_logfmtEncoder(t *testing.T) {
+	t.Run("Writes the entry and its fields as key value pairs", func(t *testing.T) {
+		enc := testLogfmtEncoder()
+
+		buf, err := enc.EncodeEntry(zapcore.Entry{
+			Level:      zapcore.WarnLevel,
+			Time:       time.Unix(1591892400, 0),
+			LoggerName: "calls",
+			Message:    "call ended",
+		}, []zapcore.Field{
+			zap.String("callID", "abc"),
+			zap.Int("attempts", 3),
+			zap.Bool("answered", true),
+			zap.Duration("took", 1500*time.Millisecond),
+		})
+
+		require.NoError(t, err, "Expected no error encoding the entry")
+		assert.Equal(t, `ts=1591892400 level=warn logger=calls msg="call ended" callID=abc attempts=3 answered=true took=1.5s`+"\n", buf.String())
+	})
+
+	t.Run("Quotes and escapes values that would end the pair", func(t *testing.T) {
+		enc := testLogfmtEncoder()
+		enc.TimeKey = ""
+		enc.LevelKey = ""
+
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hi"}, []zapcore.Field{
+			zap.String("empty", ""),
+			zap.String("equals", "a=b"),
+			zap.String("quote", `say "hi"`),
+			zap.String("newline", "a\nb"),
+			zap.String("bad key", "v"),
+		})
+
+		require.NoError(t, err, "Expected no error encoding the entry")
+		assert.Equal(t, `msg=hi empty="" equals="a=b" quote="say \"hi\"" newline="a\nb" bad_key=v`+"\n", buf.String())
+	})
+
+	t.Run("Flattens objects, arrays and reflected values into dotted keys", func(t *testing.T) {
+		enc := testLogfmtEncoder()
+		enc.TimeKey = ""
+		enc.LevelKey = ""
+
+		boom := errors.New("boom")
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hi"}, []zapcore.Field{
+			NamedError("err", boom).field,
+			zap.Strings("ids", []string{"a", "b"}),
+			zap.Any("meta", map[string]interface{}{"b": 2, "a": []int{1}}),
+		})
+
+		require.NoError(t, err, "Expected no error encoding the entry")
+		assert.Equal(t, `msg=hi err.message=boom err.fingerprint=`+caringerrors.Fingerprint(boom)+` ids.0=a ids.1=b meta.a.0=1 meta.b=2`+"\n", buf.String())
+	})
+
+	t.Run("Keeps context fields and namespaces on clones", func(t *testing.T) {
+		enc := testLogfmtEncoder()
+		enc.TimeKey = ""
+		enc.LevelKey = ""
+		enc.AddString("service", "calls")
+		enc.OpenNamespace("request")
+
+		clone := enc.Clone()
+		clone.AddString("id", "1")
+
+		buf, err := clone.EncodeEntry(zapcore.Entry{Message: "hi"}, []zapcore.Field{zap.String("path", "/calls")})
+
+		require.NoError(t, err, "Expected no error encoding the entry")
+		assert.Equal(t, `msg=hi service=calls request.id=1 request.path=/calls`+"\n", buf.String())
+	})
+}
+
+func Test_encoding(t *testing.T) {
+	c := newDefaultConfig()
+	assert.Equal(t, "json", encoding(c), "Expected json by default")
+
+	c.LogFormat = FormatLogfmt
+	assert.Equal(t, logfmtEncoding, encoding(c), "Expected logfmt")
+	_, ok := newMonitoringEncoder(c, zapcore.EncoderConfig{}).(*logfmtEncoder)
+	assert.True(t, ok, "Expected a logfmt monitoring encoder")
+
+	c.SortFields = &trueVar
+	assert.Equal(t, sortedLogfmtEncoding, encoding(c), "Expected sorted logfmt")
+}
+
+func Test_mergeAndPopulateConfigLogFormat(t *testing.T) {
+	_, err := mergeAndPopulateConfig(&Config{LogFormat: "xml"})
+	assert.EqualError(t, err, `unrecognized log format: "xml"`, "Expected an unknown format to be rejected")
+}
@@ -2,31 +2,48 @@
 package logging
 
 import (
+	"context"
 	"io"
+	"sync/atomic"
 
 	"github.com/caring/go-packages/v2/pkg/logging/internal/exit"
+	"github.com/caring/go-packages/v2/pkg/uuid"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
+// Logging is implemented by Logger, so code that logs can accept either a Logger or a wrapper of one, e.g. a
+// decorator adding fields or a fake in tests. It covers every leveled method of Logger
 type Logging interface {
 	GetInternalLogger() *zap.Logger
 	Sync() error
 	Close() error
+	CloseWithContext(ctx context.Context) error
 	NewChild(opts *FieldOpts, fields ...DataField) *Logger
 	With(opts *FieldOpts, fields ...DataField) *Logger
 	Debug(message string, additionalFields ...DataField)
-	Report(message string, additionalFields ...DataField)
+	Report(message string, additionalFields ...DataField) string
+	ReportEvent(event ReportEvent) (string, error)
 	Info(message string, additionalFields ...DataField)
 	Warn(message string, additionalFields ...DataField)
 	Error(message string, additionalFields ...DataField)
 	Panic(message string, additionalFields ...DataField)
 	DPanic(message string, additionalFields ...DataField)
 	Fatal(message string, additionalFields ...DataField)
-	getZapFields(fields ...DataField) []zap.Field
+	DebugCtx(ctx context.Context, message string, additionalFields ...DataField)
+	InfoCtx(ctx context.Context, message string, additionalFields ...DataField)
+	WarnCtx(ctx context.Context, message string, additionalFields ...DataField)
+	ErrorCtx(ctx context.Context, message string, additionalFields ...DataField)
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
 }
 
+var _ Logging = &Logger{}
+
 // Logger provides fast, structured, type safe leveled logging. All log output methods are safe for concurrent use
 type Logger struct {
 	// The name of the service
@@ -45,10 +62,26 @@ type Logger struct {
 	monitorLogger   *zap.Logger
 	reportingLogger *zap.Logger
 	closers         []io.Closer
+	// when set, child loggers without a traceability ID are given a new one
+	generateTraceabilityID bool
+	// when set, each report is also logged to the monitoring output
+	logReportEvents bool
+	// shared with child loggers, routes writes to stderr once the logger is closed
+	lifecycle *lifecycle
+	// the effective config the logger was built with
+	config ConfigSnapshot
+	// shared with child loggers, the level every output logs at
+	levels *levelControl
+	// the level of this logger and its children in place of the shared one, nil if it isn't overridden
+	levelOverride *Level
+	// shared with child loggers, counts the monitoring entries sampling dropped, nil if sampling is disabled
+	sampling *samplingStats
+	// shared with child loggers, records the Count and Gauge fields, nil if no registerer is configured
+	fieldMetrics *fieldMetrics
 }
 
 // NewLogger initializes a new logger and connects it to a kinesis stream if enabled
-func NewLogger(config *Config) (*Logger, error) {
+func NewLogger(config *Config) (_ *Logger, err error) {
 	var (
 		zapConfig zap.Config
 	)
@@ -63,67 +96,270 @@ func NewLogger(config *Config) (*Logger, error) {
 		env:         c.Env,
 		fields:      []DataField{},
 		loggerName:  c.LoggerName,
+
+		generateTraceabilityID: *c.GenerateTraceabilityID,
+		logReportEvents:        *c.LogReportEvents,
 	}
 
+	// the outputs built before one fails to build are closed, so their goroutines don't leak
+	defer func() {
+		if err != nil {
+			l.closeOutputs()
+		}
+	}()
+
 	if *c.EnableDevLogging {
 		zapConfig = newZapDevelopmentConfig()
 	} else {
 		zapConfig = zap.NewProductionConfig()
 	}
+	if c.MonitoringEncoderConfig != nil {
+		zapConfig.EncoderConfig = *c.MonitoringEncoderConfig
+	}
 
-	zapConfig.Encoding = "json"
+	if linkCallers(c) {
+		zapConfig.EncoderConfig.EncodeCaller = newSourceLinkCallerEncoder(c.SourceURLTemplate, c.SourceRevision, c.SourceRoot)
+	}
+	zapConfig.Encoding = encoding(c)
 	zapConfig.OutputPaths = []string{"stdout"}
 	zapConfig.ErrorOutputPaths = []string{"stderr"}
-	zapConfig.Level.SetLevel(zapcore.Level(c.LogLevel))
+	// the level is checked by the outermost core of each logger rather than by the outputs, so a child logger
+	// can be given its own level while sharing them
+	zapConfig.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	l.levels = newLevelControl(zap.NewAtomicLevelAt(zapcore.Level(c.LogLevel)))
+	// zap's production sampler is never used, whether or not sampling is enabled. The monitoring outputs are
+	// sampled below instead, only when EnableSampling is set, so reports are never sampled
+	zapConfig.Sampling = nil
 	// caller skip makes the caller appear as the line of code where this package is called,
 	// instead of where zap is called in this package
-	zapL, err := zapConfig.Build(zap.AddCallerSkip(1))
+	buildOpts := []zap.Option{zap.AddCallerSkip(1)}
+	if c.StacktraceLevel != nil {
+		buildOpts = append(buildOpts, zap.AddStacktrace(zapcore.Level(*c.StacktraceLevel)))
+	}
+	zapL, err := zapConfig.Build(buildOpts...)
 
 	if err != nil {
 		return nil, err
 	}
+	if *c.SplitStderr {
+		stderrConfig := zapConfig
+		stderrConfig.OutputPaths = []string{"stderr"}
+		stderrL, err := stderrConfig.Build(buildOpts...)
+		if err != nil {
+			return nil, err
+		}
+		zapL = zapL.WithOptions(zap.WrapCore(func(stdout zapcore.Core) zapcore.Core {
+			return newStderrSplitCore(stdout, stderrL.Core())
+		}))
+	}
 	zapL = zapL.Named(c.LoggerName)
 	l.monitorLogger = zapL
 	l.reportingLogger = zapL
 
+	// cores that monitoring logs are written to instead of stdout, if any are configured
+	var monitoringCores []zapcore.Core
+	// reports are written to stdout, at the logger's level, unless a reporting stream is configured
+	reportsToStdout := true
+
+	metrics, err := newPipelineMetrics(c.MetricsRegisterer)
+	if err != nil {
+		return nil, err
+	}
+	l.fieldMetrics, err = newFieldMetrics(c.MetricsRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
 	if !*c.DisableKinesis {
+		delivery, fallbackCloser, err := buildDelivery(c)
+		if err != nil {
+			return nil, err
+		}
+		// closed last, so records that fail to flush on close still reach the fallback. It is added to the
+		// closers once the cores delivering to it are built, or as soon as one of them fails to build
+		addFallbackCloser := func() {
+			if fallbackCloser != nil {
+				l.closers = append(l.closers, fallbackCloser)
+			}
+		}
+
+		kinesisLevel := routeEnabler(c.KinesisLevel)
 		monitoringCore, monitorCloser, err := buildMonitoringCore(
 			c.KinesisStreamMonitoring,
-			zapConfig.EncoderConfig,
-			c.BufferSize,
-			c.FlushInterval,
-			zapcore.Level(c.LogLevel),
+			newMonitoringEncoder(c, zapConfig.EncoderConfig),
+			c,
+			delivery,
+			metrics.forOutput(outputKinesisMonitoring),
+			kinesisLevel,
 		)
 		if err != nil {
+			addFallbackCloser()
 			return nil, err
 		}
 
-		l.monitorLogger = zapL.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
-			return monitoringCore
-		}))
-
+		monitoringCores = append(monitoringCores, newRoutedCore(monitoringCore, kinesisLevel))
 		l.closers = append(l.closers, monitorCloser)
 
 		// Only build a Kinesis stream for reporting if the name of the stream was supplied
 		if len(c.KinesisStreamReporting) > 0 {
 			reportingCore, reportCloser, err := buildReportingCore(
 				c.KinesisStreamReporting,
-				zapConfig.EncoderConfig,
-				c.BufferSize,
-				c.FlushInterval,
+				newEncoder(c, reportingEncoderConfig(c, zapConfig.EncoderConfig)),
+				c,
+				delivery,
+				metrics.forOutput(outputKinesisReporting),
 			)
 			if err != nil {
+				addFallbackCloser()
 				return nil, err
 			}
 
 			l.reportingLogger = zapL.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
 				return reportingCore
 			}))
+			reportsToStdout = false
 
 			l.closers = append(l.closers, reportCloser)
 		}
+
+		addFallbackCloser()
+	}
+
+	if c.CloudWatchLogGroup != "" {
+		cloudWatchLevel := routeEnabler(c.CloudWatchLevel)
+		cloudWatchCore, cloudWatchCloser, err := buildCloudWatchCore(
+			c.CloudWatchLogGroup,
+			c.CloudWatchLogStream,
+			newMonitoringEncoder(c, zapConfig.EncoderConfig),
+			c.BufferSize,
+			c.FlushInterval,
+			metrics.forOutput(outputCloudWatch),
+			cloudWatchLevel,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		monitoringCores = append(monitoringCores, newRoutedCore(cloudWatchCore, cloudWatchLevel))
+		l.closers = append(l.closers, cloudWatchCloser)
+	}
+
+	if c.FilePath != "" {
+		fileCore, fileCloser, err := buildFileCore(
+			newMonitoringEncoder(c, zapConfig.EncoderConfig),
+			c,
+			routeEnabler(c.FileLevel),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		monitoringCores = append(monitoringCores, fileCore)
+		l.closers = append(l.closers, fileCloser)
+	}
+
+	if c.OTLPEndpoint != "" {
+		otlpCore, otlpCloser, err := buildOTLPCore(
+			zapConfig.EncoderConfig,
+			c,
+			routeEnabler(c.OTLPLevel),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		monitoringCores = append(monitoringCores, otlpCore)
+		l.closers = append(l.closers, otlpCloser)
+	}
+
+	// stdout is written to alongside the other monitoring outputs only when it is given its own level
+	if len(monitoringCores) == 0 || c.StdoutLevel != nil {
+		stdoutCore := newRoutedCore(zapL.Core(), routeEnabler(c.StdoutLevel))
+		monitoringCores = append([]zapcore.Core{stdoutCore}, monitoringCores...)
+	}
+
+	// the webhook is written to in addition to the other outputs
+	if c.WebhookURL != "" {
+		webhookCore, webhookCloser := buildWebhookCore(
+			c.WebhookURL,
+			newEncoder(c, zapConfig.EncoderConfig),
+			c,
+			routeEnabler(c.WebhookLevel),
+		)
+
+		monitoringCores = append(monitoringCores, webhookCore)
+		l.closers = append(l.closers, webhookCloser)
+	}
+
+	l.monitorLogger = zapL.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(monitoringCores...)
+	}))
+
+	// wrapped inside the field filter, so the hooks are given the fields that were written
+	if len(c.OnLog) > 0 {
+		l.monitorLogger = l.monitorLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newHookCore(core, c.OnLog)
+		}))
+	}
+
+	if f := newFieldFilter(nil, c.MonitoringExcludedFields); f != nil {
+		l.monitorLogger = l.monitorLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newFilteredCore(core, f)
+		}))
+	}
+	if f := newFieldFilter(c.ReportingFields, c.ReportingExcludedFields); f != nil {
+		l.reportingLogger = l.reportingLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newFilteredCore(core, f)
+		}))
+	}
+
+	// outside the filters and hooks, so the hooks are given the scrubbed values, and applied to every field
+	// however it is added, so PII and identifiers added with the internal logger are scrubbed too
+	if s := newFieldScrubber(newFieldHasher(c.HashSalt, c.HashedFieldKeys), newFieldRedactor(c.RedactedFieldKeys, c.RedactFunc)); s != nil {
+		scrub := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newScrubbedCore(core, s)
+		})
+		l.monitorLogger = l.monitorLogger.WithOptions(scrub)
+		l.reportingLogger = l.reportingLogger.WithOptions(scrub)
+	}
+
+	if *c.EnableHostMetadata {
+		var hostFields []zap.Field
+		for _, f := range DetectHostMetadata().fields() {
+			hostFields = append(hostFields, f.getField())
+		}
+		l.monitorLogger = l.monitorLogger.With(hostFields...)
+		l.reportingLogger = l.reportingLogger.With(hostFields...)
+	}
+
+	l.lifecycle = newLifecycle(zapConfig.EncoderConfig)
+	l.monitorLogger = l.monitorLogger.WithOptions(l.lifecycle.wrap())
+	l.reportingLogger = l.reportingLogger.WithOptions(l.lifecycle.wrap())
+
+	// sample bursts before they reach the kinesis buffers
+	if *c.EnableSampling {
+		l.sampling = &samplingStats{}
+		l.monitorLogger = l.monitorLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newSampler(core, c, l.sampling)
+		}))
+	}
+
+	// collapse repeats outside the sampler, so the repeats it drops aren't sampled as well
+	if c.RepeatWindow > 0 {
+		l.monitorLogger = l.monitorLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newRepeatCollapser(core, c.RepeatWindow)
+		}))
 	}
 
+	l.monitorLogger = l.monitorLogger.WithOptions(l.levelOption())
+	if reportsToStdout {
+		l.reportingLogger = l.reportingLogger.WithOptions(l.levelOption())
+	}
+
+	// echo the effective config, so settings that silently fell back to defaults are visible
+	l.config = newConfigSnapshot(config, c)
+	l.Info("logger initialized", Any("config", l.config))
+
 	return &l, nil
 }
 
@@ -135,6 +371,21 @@ func NewNopLogger() *Logger {
 	}
 }
 
+// NewObservedLogger returns a logger that records the entries at or above the level in memory, instead of
+// writing them out, so tests can assert on them. Reports are recorded alongside the other entries
+func NewObservedLogger(level Level) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := newLevelControl(zap.NewAtomicLevelAt(zapcore.Level(level)))
+	zapL := zap.New(newLeveledCore(core, levels.level), zap.AddCaller(), zap.AddCallerSkip(1))
+
+	return &Logger{
+		fields:          []DataField{},
+		monitorLogger:   zapL,
+		reportingLogger: zapL,
+		levels:          levels,
+	}, logs
+}
+
 // GetInternalLogger returns the zap internal logger pointer.
 // Note: Zap should not be considered a stable dependency, another logger
 // may be substituted at any time
@@ -144,15 +395,38 @@ func (l *Logger) GetInternalLogger() *zap.Logger {
 
 // Sync calls the underlying logger's Sync method, flushing any buffered log
 // entries. Applications should take care to call Sync before exiting.
+// Once the logger is closed Sync returns ErrLoggerClosed.
 func (l *Logger) Sync() error {
+	if l.lifecycle != nil && l.lifecycle.isClosed() {
+		return ErrLoggerClosed
+	}
+
 	var err error
 	err = multierr.Append(err, l.reportingLogger.Sync())
 	return multierr.Append(err, l.monitorLogger.Sync())
 }
 
 // Close cleanly shuts down and closes any underlying data streams
-// and their goroutines for the logger, if present
+// and their goroutines for the logger, if present. Close may be called more than once,
+// only the first call closes the streams. Entries logged after Close, by the logger or
+// any of its children, are written to stderr instead.
 func (l *Logger) Close() error {
+	return l.CloseWithContext(context.Background())
+}
+
+// CloseWithContext closes the logger like Close, flushing the buffered entries of every output and waiting for
+// the writes in flight, but returns the context's error once it is done, e.g. when the deadline of a task's
+// shutdown is near. The outputs carry on draining in the background, and entries logged meanwhile are written
+// to stderr. A later Close or CloseWithContext waits for the outputs to finish draining.
+func (l *Logger) CloseWithContext(ctx context.Context) error {
+	if l.lifecycle == nil {
+		return l.closeOutputs()
+	}
+	return l.lifecycle.close(ctx, l.closeOutputs)
+}
+
+// closeOutputs closes the outputs of the logger in the order they were built
+func (l *Logger) closeOutputs() error {
 	var err error
 	for _, c := range l.closers {
 		err = multierr.Append(err, c.Close())
@@ -160,6 +434,15 @@ func (l *Logger) Close() error {
 	return err
 }
 
+// WritesAfterClose returns the number of entries that were logged after the logger was
+// closed, and so were written to stderr instead of the configured outputs
+func (l *Logger) WritesAfterClose() int64 {
+	if l.lifecycle == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.lifecycle.writesAfterClose)
+}
+
 // FieldOpts wraps internal field values that can be updated when spawning a child logger.
 type FieldOpts struct {
 	Endpoint       string
@@ -167,6 +450,10 @@ type FieldOpts struct {
 	TraceabilityID string
 	ClientID       string
 	UserID         string
+	// Overrides the level of the logger, e.g. to run a noisy subsystem such as the outbound HTTP
+	// client at WARN while the rest of the service logs at DEBUG. The override is kept by the
+	// logger's children, and SetLevel doesn't change it
+	Level *Level
 	// If set to true, the existing accumulated fields will be
 	// replaced with the fields passed in, a nil value writes the
 	// accumulated fields to an empty value
@@ -178,18 +465,34 @@ type FieldOpts struct {
 	ResetTraceabilityID bool
 	ResetClientID       bool
 	ResetUserID         bool
+	// Removes a level override, so the logger logs at the level it shares with its parent
+	ResetLevel bool
 }
 
 // NewChild clones logger and returns a child instance where any internal fields are overwritten
 // with any non 0 values passed in, or if the field reset is set to true then the field will
 // be set to a zero value. If nil options are passed in then the logger is simply cloned without change.
+//
+// If traceability ID generation is enabled and the child ends up without a traceability ID,
+// a new one is generated for it, unless ResetTraceabilityID was set to clear it.
 func (l *Logger) NewChild(opts *FieldOpts, fields ...DataField) *Logger {
 	newChild := *l
 	newChild.with(opts, fields...)
 
+	reset := opts != nil && opts.ResetTraceabilityID
+	if newChild.generateTraceabilityID && newChild.traceabilityID == "" && !reset {
+		newChild.traceabilityID = uuid.New().String()
+	}
+
 	return &newChild
 }
 
+// TraceabilityID returns the traceability ID of the logger, so that it can be
+// passed on to downstream services
+func (l *Logger) TraceabilityID() string {
+	return l.traceabilityID
+}
+
 // With sets the internal fields with the provided options.
 // See the options struct for more details
 func (l *Logger) With(opts *FieldOpts, fields ...DataField) *Logger {
@@ -205,9 +508,24 @@ func (l *Logger) Debug(message string, additionalFields ...DataField) {
 
 // Report logs the message at info level output to the BI pipeline. This includes the additional fields provided,
 // the standard fields and any fields accumulated on the logger.
-func (l *Logger) Report(message string, additionalFields ...DataField) {
-	f := l.getZapFields(additionalFields...)
+//
+// Each report is given a report ID, which is returned. When enabled in the config, a compact entry with the
+// message as the event and the report ID is also logged to the monitoring output, so the report can be confirmed
+// from the operational logs while the warehouse lags.
+func (l *Logger) Report(message string, additionalFields ...DataField) string {
+	reportID := uuid.New().String()
+
+	f := l.getZapFields(append(additionalFields[:len(additionalFields):len(additionalFields)], String("reportID", reportID))...)
 	l.reportingLogger.Info(message, f...)
+
+	if l.logReportEvents {
+		// only the standard fields, not the ones accumulated on the logger, to keep the entry compact
+		compact := *l
+		compact.fields = nil
+		l.monitorLogger.Info("report emitted", compact.getZapFields(String("event", message), String("reportID", reportID))...)
+	}
+
+	return reportID
 }
 
 // Info logs the message at info level output. This includes the additional fields provided,
@@ -266,7 +584,7 @@ func (l *Logger) Fatal(message string, additionalFields ...DataField) {
 // getZapFields aggregates the Logger fields into a typed and structured set of zap fields.
 func (l *Logger) getZapFields(fields ...DataField) []zap.Field {
 	var internalFieldcount = 7
-	// 6 is the number of internal fields that appear on every log entry
+	// 7 is the number of internal fields that appear on every log entry
 	total := internalFieldcount + len(fields) + len(l.fields)
 
 	zapped := make([]zap.Field, total)
@@ -289,15 +607,70 @@ func (l *Logger) getZapFields(fields ...DataField) []zap.Field {
 		zapped[i] = f.getField()
 		i++
 	}
+	// the warehouse can't load entries with duplicate keys
+	zapped = dedupeFields(zapped)
+	// only the fields of the entry are recorded, fields accumulated on the logger would be recorded again
+	// with every entry. They are recorded before the level is checked, so they don't depend on it
+	l.fieldMetrics.record(fields)
 
 	return zapped
 }
 
+// dedupeMapThreshold is the number of fields above which dedupeFields looks keys up in a map. Below it, scanning
+// the fields kept so far is cheaper than allocating the map
+const dedupeMapThreshold = 16
+
+// dedupeFields removes the fields whose key was already used, in place. When a key is repeated the last value
+// wins, and it takes the position of the key's first occurrence, so a field of the entry replaces one accumulated
+// on the logger and both replace the standard fields, while the standard fields stay first
+func dedupeFields(fields []zap.Field) []zap.Field {
+	if len(fields) > dedupeMapThreshold {
+		return dedupeFieldsIndexed(fields)
+	}
+
+	out := fields[:0]
+	for _, f := range fields {
+		replaced := false
+		// skipped fields have no key
+		if f.Key != "" {
+			for j := range out {
+				if out[j].Key == f.Key {
+					out[j] = f
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// dedupeFieldsIndexed is dedupeFields for many fields, indexing the position of each key kept in a map
+func dedupeFieldsIndexed(fields []zap.Field) []zap.Field {
+	out := fields[:0]
+	index := make(map[string]int, len(fields))
+	for _, f := range fields {
+		// skipped fields have no key
+		if f.Key != "" {
+			if j, ok := index[f.Key]; ok {
+				out[j] = f
+				continue
+			}
+			index[f.Key] = len(out)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
 // With sets the internal fields with the provided options.
 // See the options struct for more details
 func (l *Logger) with(opts *FieldOpts, fields ...DataField) *Logger {
 	if opts == nil {
-		l.fields = append(l.fields, fields...)
+		l.accumulateFields(fields...)
 		return l
 	}
 
@@ -331,6 +704,15 @@ func (l *Logger) with(opts *FieldOpts, fields ...DataField) *Logger {
 		l.userID = opts.UserID
 	}
 
+	if opts.ResetLevel && l.levelOverride != nil {
+		l.levelOverride = nil
+		l.monitorLogger = l.monitorLogger.WithOptions(l.levelOption())
+	} else if opts.Level != nil {
+		level := *opts.Level
+		l.levelOverride = &level
+		l.monitorLogger = l.monitorLogger.WithOptions(l.levelOption())
+	}
+
 	if opts.OverwriteAccumulatedFields {
 		l.writeFields(fields...)
 	} else {
@@ -340,16 +722,20 @@ func (l *Logger) with(opts *FieldOpts, fields ...DataField) *Logger {
 	return l
 }
 
-// accumulates the given fields onto the existing accumulated fields of logger
+// accumulates the given fields onto the existing accumulated fields of logger. The accumulated fields are
+// shared with the logger's parent and children, so they are copied rather than appended to in place
 func (l *Logger) accumulateFields(f ...DataField) {
-	l.fields = append(l.fields, f...)
+	if len(f) == 0 {
+		return
+	}
+
+	fields := make([]DataField, 0, len(l.fields)+len(f))
+	fields = append(fields, l.fields...)
+	l.fields = append(fields, f...)
 }
 
-// overwrites the accumulated fields of logger with the fields passed in,
-// a nil argument writes an empty slice to the fields
+// overwrites the accumulated fields of logger with a copy of the fields passed in, so the caller's slice
+// isn't shared, a nil argument writes an empty slice to the fields
 func (l *Logger) writeFields(f ...DataField) {
-	if f == nil {
-		l.fields = []DataField{}
-	}
-	l.fields = f
+	l.fields = append([]DataField{}, f...)
 }
@@ -1,6 +1,9 @@
 package logging
 
 import (
+	"os"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/caring/go-packages/v2/pkg/logging/internal/exit"
@@ -34,6 +37,135 @@ func Test_LoggerNewChild(t *testing.T) {
 	})
 }
 
+func Test_LoggerNewChildCopiesFields(t *testing.T) {
+	withLogger(config, func(logger *Logger, logs *observer.ObservedLogs) {
+		// accumulated one at a time, so the fields have spare capacity a child could append into
+		logger.With(nil, Int64("a", 1))
+		logger.With(nil, Int64("b", 2))
+		logger.With(nil, Int64("c", 3))
+
+		one := logger.NewChild(nil, String("child", "one"))
+		two := logger.NewChild(nil, String("child", "two"))
+		overwritten := logger.NewChild(&FieldOpts{OverwriteAccumulatedFields: true}, String("child", "three"))
+		overwritten.With(nil, Int64("d", 4))
+		one.Info("")
+		two.Info("")
+		logger.Info("")
+
+		assert.Equal(t, []observer.LoggedEntry{
+			{Context: commonFields(config, FieldOpts{}, zap.Int64("a", 1), zap.Int64("b", 2), zap.Int64("c", 3), zap.String("child", "one"))},
+			{Context: commonFields(config, FieldOpts{}, zap.Int64("a", 1), zap.Int64("b", 2), zap.Int64("c", 3), zap.String("child", "two"))},
+			{Context: commonFields(config, FieldOpts{}, zap.Int64("a", 1), zap.Int64("b", 2), zap.Int64("c", 3))},
+		}, logs.AllUntimed(), "Expected each child to keep its own fields")
+	})
+}
+
+func Test_LoggerDedupesFieldKeys(t *testing.T) {
+	withLogger(config, func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.With(nil, Int64("a", 1), String("b", "one"))
+		child := logger.NewChild(nil, Int64("a", 2))
+		child.Info("", String("b", "two"), String("correlationID", "corr-1"), Int64("c", 3))
+
+		assert.Equal(t, []observer.LoggedEntry{
+			{Context: commonFields(config, FieldOpts{CorrelationID: "corr-1"}, zap.Int64("a", 2), zap.String("b", "two"), zap.Int64("c", 3))},
+		}, logs.AllUntimed(), "Expected the last value of each key, at the position of its first")
+	})
+}
+
+func Test_dedupeFields(t *testing.T) {
+	// many keys, repeated out of order, with skipped fields, which have no key
+	var fields, expected []zap.Field
+	for i := 0; i < 2*dedupeMapThreshold; i++ {
+		fields = append(fields, zap.Int("k"+strconv.Itoa(i%dedupeMapThreshold), i), zap.Skip())
+	}
+	for i := 0; i < dedupeMapThreshold; i++ {
+		expected = append(expected, zap.Int("k"+strconv.Itoa(i), i+dedupeMapThreshold), zap.Skip())
+	}
+	// the skipped fields of the repeats are kept
+	for i := 0; i < dedupeMapThreshold; i++ {
+		expected = append(expected, zap.Skip())
+	}
+
+	assert.Equal(t, expected, dedupeFields(fields), "Expected the last value of each key, at the position of its first")
+
+	few := []zap.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("a", 3)}
+	assert.Equal(t, []zap.Field{zap.Int("a", 3), zap.Int("b", 2)}, dedupeFields(few), "Expected the same below the map threshold")
+}
+
+func Test_NewLoggerEnvWithoutStreams(t *testing.T) {
+	os.Setenv("ENV", "caring-prod")
+	defer os.Setenv("ENV", "")
+
+	l, err := NewLogger(&Config{ServiceName: "fooservice"})
+	require.NoError(t, err, "Expected no error creating the logger")
+	assert.Empty(t, l.closers, "Expected no kinesis cores to be built without streams")
+	assert.Equal(t, true, l.ConfigSnapshot()["DisableKinesis"].Value, "Expected kinesis to stay disabled")
+}
+
+func Test_LoggerNewChildConcurrent(t *testing.T) {
+	withLogger(config, func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.With(nil, Int64("a", 1))
+		logger.With(nil, Int64("b", 2))
+		logger.With(nil, Int64("c", 3))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				child := logger.NewChild(nil, Int64("child", int64(i)))
+				child.With(nil, Int64("grandchild", int64(i)))
+				child.NewChild(&FieldOpts{Endpoint: "endpoint"}, Int64("greatGrandchild", int64(i))).Info("")
+				child.Info("")
+			}(i)
+		}
+		wg.Wait()
+
+		require.Equal(t, 100, logs.Len())
+		for _, entry := range logs.All() {
+			fields := entry.ContextMap()
+			assert.Equal(t, int64(1), fields["a"], "Expected the parent's fields to be kept")
+			assert.Equal(t, int64(3), fields["c"], "Expected the parent's fields to be kept")
+			assert.Equal(t, fields["child"], fields["grandchild"], "Expected each child's fields to be its own")
+			if g, ok := fields["greatGrandchild"]; ok {
+				assert.Equal(t, fields["child"], g, "Expected each child's fields to be its own")
+			}
+		}
+	})
+}
+
+func Test_LoggerNewChildGeneratesTraceabilityID(t *testing.T) {
+	t.Run("Generates an ID when the child has none", func(t *testing.T) {
+		l := &Logger{generateTraceabilityID: true}
+
+		child := l.NewChild(&FieldOpts{Endpoint: "someendpoint"})
+
+		assert.Len(t, child.TraceabilityID(), 36, "Expected a generated uuid")
+		assert.Equal(t, "", l.TraceabilityID(), "Expected the parent to be unchanged")
+		assert.NotEqual(t, child.TraceabilityID(), l.NewChild(nil).TraceabilityID(), "Expected each child to get a new ID")
+	})
+
+	t.Run("Keeps a provided or inherited ID", func(t *testing.T) {
+		l := &Logger{generateTraceabilityID: true}
+
+		child := l.NewChild(&FieldOpts{TraceabilityID: "someID"})
+		assert.Equal(t, "someID", child.TraceabilityID(), "Expected the provided ID to be kept")
+		assert.Equal(t, "someID", child.NewChild(nil).TraceabilityID(), "Expected the inherited ID to be kept")
+	})
+
+	t.Run("Clears the ID when reset", func(t *testing.T) {
+		l := &Logger{generateTraceabilityID: true, traceabilityID: "someID"}
+
+		assert.Equal(t, "", l.NewChild(&FieldOpts{ResetTraceabilityID: true}).TraceabilityID(), "Expected no ID to be generated on reset")
+	})
+
+	t.Run("Does nothing when disabled", func(t *testing.T) {
+		l := &Logger{}
+
+		assert.Equal(t, "", l.NewChild(nil).TraceabilityID(), "Expected no ID to be generated")
+	})
+}
+
 func Test_LoggerSetInternalFields(t *testing.T) {
 	t.Run("Overwriting existing values with non 0 values", func(t *testing.T) {
 		l := &Logger{
@@ -228,3 +360,36 @@ func Test_LoggerNoOpsDisabledLevels(t *testing.T) {
 		)
 	})
 }
+
+func Test_LoggerReport(t *testing.T) {
+	t.Run("Logs a compact monitoring entry with the report ID when enabled", func(t *testing.T) {
+		c := *config
+		c.LogReportEvents = &trueVar
+		withLogger(&c, func(logger *Logger, monitorLogs *observer.ObservedLogs) {
+			fac, reportLogs := observer.New(zap.InfoLevel)
+			logger.reportingLogger = zap.New(fac)
+			logger.With(nil, String("accumulated", "yes"))
+
+			reportID := logger.Report("order placed", String("orderID", "42"))
+
+			assert.Len(t, reportID, 36, "Expected a uuid report ID")
+			require.Equal(t, 1, reportLogs.Len(), "Expected one report")
+			assert.Equal(t, commonFields(config, FieldOpts{}, zap.String("accumulated", "yes"), zap.String("orderID", "42"), zap.String("reportID", reportID)), reportLogs.All()[0].Context, "Expected the report ID on the report")
+
+			require.Equal(t, 1, monitorLogs.Len(), "Expected one monitoring entry")
+			assert.Equal(t, "report emitted", monitorLogs.All()[0].Message, "Expected the report emitted entry")
+			assert.Equal(t, commonFields(config, FieldOpts{}, zap.String("event", "order placed"), zap.String("reportID", reportID)), monitorLogs.All()[0].Context, "Expected only the standard fields, event and report ID")
+		})
+	})
+
+	t.Run("Does not log a monitoring entry by default", func(t *testing.T) {
+		withLogger(&Config{}, func(logger *Logger, monitorLogs *observer.ObservedLogs) {
+			fac, _ := observer.New(zap.InfoLevel)
+			logger.reportingLogger = zap.New(fac)
+
+			logger.Report("order placed")
+
+			assert.Equal(t, 0, monitorLogs.Len(), "Expected no monitoring entry")
+		})
+	})
+}
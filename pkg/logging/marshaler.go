@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ObjectEncoder is what an ObjectMarshaler adds its fields to. It is an alias of the zap interface, so
+// marshaling adds no indirection and types that already marshal themselves for zap can be logged as they are.
+type ObjectEncoder = zapcore.ObjectEncoder
+
+// ArrayEncoder is what an ArrayMarshaler appends its elements to, an alias of the zap interface like ObjectEncoder
+type ArrayEncoder = zapcore.ArrayEncoder
+
+// ObjectMarshaler is implemented by types that log themselves as an object, field by field, e.g.
+//
+//	func (c Call) MarshalLogObject(enc logging.ObjectEncoder) error {
+//		enc.AddString("id", c.ID)
+//		enc.AddInt64("durationSeconds", c.DurationSeconds)
+//		return nil
+//	}
+type ObjectMarshaler = zapcore.ObjectMarshaler
+
+// ArrayMarshaler is implemented by types that log themselves as an array, element by element
+type ArrayMarshaler = zapcore.ArrayMarshaler
+
+// ObjectMarshalerFunc is a func that implements ObjectMarshaler
+type ObjectMarshalerFunc = zapcore.ObjectMarshalerFunc
+
+// ArrayMarshalerFunc is a func that implements ArrayMarshaler
+type ArrayMarshalerFunc = zapcore.ArrayMarshalerFunc
+
+// Object constructs a field with the value logged as an object by its marshaler. Unlike Any, it doesn't
+// use reflection, and the marshaler is only called if the entry is written
+func Object(k string, v ObjectMarshaler) Field {
+	f := Field{}
+	o := zap.Object(k, v)
+	f.field = o
+
+	return f
+}
+
+// Array constructs a field with the value logged as an array by its marshaler. Unlike Any, it doesn't
+// use reflection, and the marshaler is only called if the entry is written
+func Array(k string, v ArrayMarshaler) Field {
+	f := Field{}
+	a := zap.Array(k, v)
+	f.field = a
+
+	return f
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type testCall struct {
+	ID       string
+	Duration int64
+}
+
+func (c testCall) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("id", c.ID)
+	enc.AddInt64("duration", c.Duration)
+	return nil
+}
+
+type testCalls []testCall
+
+func (cs testCalls) MarshalLogArray(enc ArrayEncoder) error {
+	for _, c := range cs {
+		if err := enc.AppendObject(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_Object(t *testing.T) {
+	fields := encodeField(Object("call", testCall{ID: "abc", Duration: 42}))
+
+	assert.Equal(t, map[string]interface{}{"id": "abc", "duration": int64(42)}, fields["call"])
+}
+
+func Test_Array(t *testing.T) {
+	fields := encodeField(Array("calls", testCalls{{ID: "abc"}, {ID: "def"}}))
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "abc", "duration": int64(0)},
+		map[string]interface{}{"id": "def", "duration": int64(0)},
+	}, fields["calls"])
+
+	fields = encodeField(Array("ids", ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+		enc.AppendString("abc")
+		return nil
+	})))
+	assert.Equal(t, []interface{}{"abc"}, fields["ids"])
+}
+
+func BenchmarkObject(b *testing.B) {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	call := testCall{ID: "abc", Duration: 42}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{Object("call", call).getField()})
+		buf.Free()
+	}
+}
+
+func BenchmarkAny(b *testing.B) {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	call := testCall{ID: "abc", Duration: 42}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// converted to a type without the marshaler, which Any would otherwise use
+		buf, _ := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{Any("call", struct {
+			ID       string
+			Duration int64
+		}(call)).getField()})
+		buf.Free()
+	}
+}
@@ -0,0 +1,249 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging/internal/writer"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The names of the outputs the pipeline metrics are labeled with
+const (
+	outputKinesisMonitoring = "kinesis_monitoring"
+	outputKinesisReporting  = "kinesis_reporting"
+	outputCloudWatch        = "cloudwatch"
+)
+
+// pipelineMetrics are the metrics of the writers that deliver log entries, labeled by output
+type pipelineMetrics struct {
+	entries      *prometheus.CounterVec
+	buffered     *prometheus.GaugeVec
+	flushes      *prometheus.CounterVec
+	flushErrors  *prometheus.CounterVec
+	dropped      *prometheus.CounterVec
+	queueDropped *prometheus.CounterVec
+	// the health of delivery, so a stalled output can be alerted on while nothing is being dropped yet
+	flushDuration       *prometheus.HistogramVec
+	lastSuccess         *prometheus.GaugeVec
+	consecutiveFailures *prometheus.GaugeVec
+}
+
+// newPipelineMetrics registers the pipeline metrics with reg. Metrics already registered by another logger
+// are shared with it. It returns nil if reg is nil, so nothing is counted
+func newPipelineMetrics(reg prometheus.Registerer) (*pipelineMetrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+
+	m := &pipelineMetrics{
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logging",
+			Name:      "entries_written_total",
+			Help:      "Number of log entries written to the output",
+		}, []string{"output"}),
+		buffered: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "logging",
+			Name:      "buffered_bytes",
+			Help:      "Byte size of the log entries waiting to be sent to the output",
+		}, []string{"output"}),
+		flushes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logging",
+			Name:      "flushes_total",
+			Help:      "Number of times the waiting log entries were sent to the output",
+		}, []string{"output"}),
+		flushErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logging",
+			Name:      "flush_errors_total",
+			Help:      "Number of times sending the waiting log entries to the output failed",
+		}, []string{"output"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logging",
+			Name:      "records_dropped_total",
+			Help:      "Number of log records dropped after every attempt to deliver them to the output failed",
+		}, []string{"output"}),
+		queueDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logging",
+			Name:      "queue_dropped_total",
+			Help:      "Number of log entries dropped because the queue in front of the output was full",
+		}, []string{"output"}),
+		flushDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "logging",
+			Name:      "flush_duration_seconds",
+			Help:      "Seconds each send of the waiting log entries to the output took, retries included",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"output"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "logging",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last send of log entries to the output that succeeded",
+		}, []string{"output"}),
+		consecutiveFailures: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "logging",
+			Name:      "consecutive_flush_failures",
+			Help:      "Number of sends of log entries to the output that failed since the last one that succeeded",
+		}, []string{"output"}),
+	}
+
+	var err error
+	if m.entries, err = registerCounterVec(reg, m.entries); err != nil {
+		return nil, err
+	}
+	if m.flushes, err = registerCounterVec(reg, m.flushes); err != nil {
+		return nil, err
+	}
+	if m.flushErrors, err = registerCounterVec(reg, m.flushErrors); err != nil {
+		return nil, err
+	}
+	if m.dropped, err = registerCounterVec(reg, m.dropped); err != nil {
+		return nil, err
+	}
+	if m.queueDropped, err = registerCounterVec(reg, m.queueDropped); err != nil {
+		return nil, err
+	}
+	if m.buffered, err = registerGaugeVec(reg, m.buffered); err != nil {
+		return nil, err
+	}
+	if m.lastSuccess, err = registerGaugeVec(reg, m.lastSuccess); err != nil {
+		return nil, err
+	}
+	if m.consecutiveFailures, err = registerGaugeVec(reg, m.consecutiveFailures); err != nil {
+		return nil, err
+	}
+	if m.flushDuration, err = registerHistogramVec(reg, m.flushDuration); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// registerCounterVec registers the counter with reg, returning the one already registered if there is one
+func registerCounterVec(reg prometheus.Registerer, c *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	if err := reg.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		return are.ExistingCollector.(*prometheus.CounterVec), nil
+	}
+	return c, nil
+}
+
+// registerGaugeVec registers the gauge with reg, returning the one already registered if there is one
+func registerGaugeVec(reg prometheus.Registerer, g *prometheus.GaugeVec) (*prometheus.GaugeVec, error) {
+	if err := reg.Register(g); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		return are.ExistingCollector.(*prometheus.GaugeVec), nil
+	}
+	return g, nil
+}
+
+// registerHistogramVec registers the histogram with reg, returning the one already registered if there is one
+func registerHistogramVec(reg prometheus.Registerer, h *prometheus.HistogramVec) (*prometheus.HistogramVec, error) {
+	if err := reg.Register(h); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		return are.ExistingCollector.(*prometheus.HistogramVec), nil
+	}
+	return h, nil
+}
+
+// forOutput returns the metrics of the writers of the output
+func (m *pipelineMetrics) forOutput(output string) writer.Metrics {
+	if m == nil {
+		return writer.Metrics{}
+	}
+
+	return writer.Metrics{
+		Entries:             m.entries.WithLabelValues(output),
+		Buffered:            m.buffered.WithLabelValues(output),
+		Flushes:             m.flushes.WithLabelValues(output),
+		FlushErrors:         m.flushErrors.WithLabelValues(output),
+		FlushDuration:       m.flushDuration.WithLabelValues(output),
+		LastSuccess:         m.lastSuccess.WithLabelValues(output),
+		ConsecutiveFailures: m.consecutiveFailures.WithLabelValues(output),
+		Dropped:             m.dropped.WithLabelValues(output),
+		QueueDropped:        m.queueDropped.WithLabelValues(output),
+	}
+}
+
+// fieldMetrics are the metrics the Count and Gauge fields are recorded in, labeled by the key of the field,
+// and the durations of timers, labeled by the name of the timer
+type fieldMetrics struct {
+	counts *prometheus.CounterVec
+	gauges *prometheus.GaugeVec
+	timers *prometheus.HistogramVec
+}
+
+// newFieldMetrics registers the field metrics with reg. Metrics already registered by another logger
+// are shared with it. It returns nil if reg is nil, so nothing is recorded
+func newFieldMetrics(reg prometheus.Registerer) (*fieldMetrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+
+	m := &fieldMetrics{
+		counts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logging",
+			Name:      "field_count_total",
+			Help:      "Sum of the values logged with the Count field of the key",
+		}, []string{"key"}),
+		gauges: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "logging",
+			Name:      "field_gauge",
+			Help:      "Last value logged with the Gauge field of the key",
+		}, []string{"key"}),
+		timers: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "logging",
+			Name:      "timer_duration_seconds",
+			Help:      "Duration of the timers of the name, from Timer to Stop",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"timer"}),
+	}
+
+	var err error
+	if m.counts, err = registerCounterVec(reg, m.counts); err != nil {
+		return nil, err
+	}
+	if m.gauges, err = registerGaugeVec(reg, m.gauges); err != nil {
+		return nil, err
+	}
+	if m.timers, err = registerHistogramVec(reg, m.timers); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// record adds the values of the Count fields to their counters and sets the gauges of the Gauge fields
+func (m *fieldMetrics) record(fields []DataField) {
+	if m == nil {
+		return
+	}
+
+	for _, f := range fields {
+		mf, ok := f.(metricField)
+		if !ok {
+			continue
+		}
+		key := mf.field.Key
+		switch {
+		case mf.gauge:
+			m.gauges.WithLabelValues(key).Set(mf.value)
+		case mf.value >= 0:
+			m.counts.WithLabelValues(key).Add(mf.value)
+		}
+	}
+}
+
+// observeTimer records the duration of the timer of the name
+func (m *fieldMetrics) observeTimer(name string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.timers.WithLabelValues(name).Observe(d.Seconds())
+}
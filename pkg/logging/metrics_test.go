@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging/internal/writer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("stream unavailable")
+}
+
+func Test_pipelineMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := newPipelineMetrics(reg)
+	require.NoError(t, err, "Expected no error registering the metrics")
+
+	again, err := newPipelineMetrics(reg)
+	require.NoError(t, err, "Expected a second logger to share the registered metrics")
+	assert.Equal(t, m.entries, again.entries)
+
+	var out bytes.Buffer
+	buf, closer := writer.Buffer(zapcore.AddSync(&out), 1024, time.Hour, m.forOutput(outputKinesisMonitoring))
+	defer closer.Close()
+
+	buf.Write([]byte("entry one\n"))
+	buf.Write([]byte("entry two\n"))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.entries.WithLabelValues(outputKinesisMonitoring)))
+	assert.Equal(t, 20.0, testutil.ToFloat64(m.buffered.WithLabelValues(outputKinesisMonitoring)))
+
+	require.NoError(t, buf.Sync())
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.flushes.WithLabelValues(outputKinesisMonitoring)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.buffered.WithLabelValues(outputKinesisMonitoring)))
+	assert.InDelta(t, float64(time.Now().Unix()), testutil.ToFloat64(m.lastSuccess.WithLabelValues(outputKinesisMonitoring)), 5, "Expected the time of the flush")
+	assert.Equal(t, 1, testutil.CollectAndCount(m.flushDuration), "Expected the flush duration to be observed")
+
+	failing := writer.Retry(failingWriter{}, writer.Delivery{Attempts: 1}, m.forOutput(outputKinesisReporting))
+	buf, closer = writer.Buffer(zapcore.AddSync(failing), 1024, time.Hour, m.forOutput(outputKinesisReporting))
+	defer closer.Close()
+
+	buf.Write([]byte("entry\n"))
+	assert.Error(t, buf.Sync(), "Expected the flush to fail")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.flushErrors.WithLabelValues(outputKinesisReporting)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.dropped.WithLabelValues(outputKinesisReporting)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.lastSuccess.WithLabelValues(outputKinesisReporting)), "Expected no successful flush")
+
+	buf.Write([]byte("entry\n"))
+	assert.Error(t, buf.Sync(), "Expected the flush to fail")
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.consecutiveFailures.WithLabelValues(outputKinesisReporting)), "Expected the failures to be counted until a flush succeeds")
+
+	var nilMetrics *pipelineMetrics
+	assert.Equal(t, writer.Metrics{}, nilMetrics.forOutput(outputCloudWatch), "Expected nothing to be counted without a registerer")
+}
+
+// blockedWriter blocks writes until it is released
+type blockedWriter struct {
+	release chan struct{}
+	out     bytes.Buffer
+}
+
+func (w *blockedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.out.Write(p)
+}
+
+func Test_pipelineMetricsQueueDropped(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := newPipelineMetrics(reg)
+	require.NoError(t, err, "Expected no error registering the metrics")
+
+	for _, policy := range []string{QueueDropOldest, QueueDropNewest} {
+		t.Run(policy, func(t *testing.T) {
+			w := &blockedWriter{release: make(chan struct{})}
+			q, closer := writer.Queue(zapcore.AddSync(w), nil, 1, policy, m.forOutput(policy))
+
+			// the first entry is taken off the queue and blocks the writer, the second fills the queue
+			q.Write([]byte("entry one\n"))
+			time.Sleep(10 * time.Millisecond)
+			q.Write([]byte("entry two\n"))
+			q.Write([]byte("entry three\n"))
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.queueDropped.WithLabelValues(policy)), "Expected an entry to be dropped from the full queue")
+
+			close(w.release)
+			require.NoError(t, closer.Close())
+			if policy == QueueDropOldest {
+				assert.Equal(t, "entry one\nentry three\n", w.out.String(), "Expected the oldest queued entry to be dropped")
+			} else {
+				assert.Equal(t, "entry one\nentry two\n", w.out.String(), "Expected the entry written to be dropped")
+			}
+		})
+	}
+}
+
+func Test_fieldMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	l, err := NewLogger(&Config{LogLevel: InfoLevel, DisableKinesis: &trueVar, MetricsRegisterer: reg})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	l.Info("claim approved", Count("claims", 2), Gauge("amount", 120.5))
+	l.Debug("claim approved", Count("claims", 1), Gauge("amount", 80))
+	l.Info("claim reversed", Count("claims", -1))
+
+	m := l.fieldMetrics
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.counts.WithLabelValues("claims")), "Expected counts below the level to be recorded")
+	assert.Equal(t, 80.0, testutil.ToFloat64(m.gauges.WithLabelValues("amount")), "Expected the last gauge value")
+
+	// fields accumulated on a child are logged with every entry, so they are not recorded
+	child := l.NewChild(nil, Count("claims", 5))
+	child.Info("child entry")
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.counts.WithLabelValues("claims")), "Expected accumulated counts not to be recorded")
+
+	var nilMetrics *fieldMetrics
+	nilMetrics.record([]DataField{Count("claims", 1)})
+}
@@ -1,9 +1,15 @@
 package logging
 
 import (
+	"context"
+	"path"
+	"time"
+
+	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/logging"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	"github.com/uber/jaeger-client-go"
 	jaeger_zap "github.com/uber/jaeger-client-go/log/zap"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
@@ -19,7 +25,7 @@ func (l *Logger) NewJaegerLogger() jaeger.Logger {
 // NewJaegerLogger returns a jaeger logging interface implementer that has been populated
 // with Loggers internal and accumulated fields as well as settings
 func NewJaegerLogger(l Logging) jaeger.Logger {
-	populatedL := l.GetInternalLogger().With(l.getZapFields()...)
+	populatedL := populatedLogger(l)
 	j := jaeger_zap.NewLogger(populatedL)
 
 	return j
@@ -33,8 +39,10 @@ func (l *Logger) NewGRPCUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return grpc_zap.UnaryServerInterceptor(populatedL)
 }
 
+// NewGRPCUnaryServerInterceptor returns a gRPC unary interceptor that has been populated
+// with the internal and accumulated fields of l as well as its settings
 func NewGRPCUnaryServerInterceptor(l Logging) grpc.UnaryServerInterceptor {
-	populatedL := l.GetInternalLogger().With(l.getZapFields()...)
+	populatedL := populatedLogger(l)
 
 	return grpc_zap.UnaryServerInterceptor(populatedL)
 }
@@ -47,8 +55,90 @@ func (l *Logger) NewGRPCStreamServerInterceptor() grpc.StreamServerInterceptor {
 	return grpc_zap.StreamServerInterceptor(populatedL)
 }
 
+// NewGRPCStreamServerInterceptor returns a gRPC stream interceptor that has been populated
+// with the internal and accumulated fields of l as well as its settings
 func NewGRPCStreamServerInterceptor(l Logging) grpc.StreamServerInterceptor {
-	populatedL := l.GetInternalLogger().With(l.getZapFields()...)
+	populatedL := populatedLogger(l)
 
 	return grpc_zap.StreamServerInterceptor(populatedL)
 }
+
+// NewGRPCUnaryClientInterceptor returns a gRPC unary client interceptor that logs the method, target, code
+// and latency of each outbound call, with Loggers internal and accumulated fields and the correlation and
+// trace IDs of the call's context
+func (l *Logger) NewGRPCUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return newGRPCUnaryClientInterceptor(l)
+}
+
+// NewGRPCUnaryClientInterceptor returns a gRPC unary client interceptor that logs the method, target, code
+// and latency of each outbound call, with the internal and accumulated fields of l and the correlation and
+// trace IDs of the call's context
+func NewGRPCUnaryClientInterceptor(l Logging) grpc.UnaryClientInterceptor {
+	return newGRPCUnaryClientInterceptor(l.With(nil))
+}
+
+// NewGRPCStreamClientInterceptor returns a gRPC stream client interceptor that logs the method, target, code
+// and latency of opening each outbound stream, with Loggers internal and accumulated fields and the correlation
+// and trace IDs of the stream's context
+func (l *Logger) NewGRPCStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return newGRPCStreamClientInterceptor(l)
+}
+
+// NewGRPCStreamClientInterceptor returns a gRPC stream client interceptor that logs the method, target, code
+// and latency of opening each outbound stream, with the internal and accumulated fields of l and the
+// correlation and trace IDs of the stream's context
+func NewGRPCStreamClientInterceptor(l Logging) grpc.StreamClientInterceptor {
+	return newGRPCStreamClientInterceptor(l.With(nil))
+}
+
+func newGRPCUnaryClientInterceptor(l *Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logClientCall(ctx, l, cc, method, start, err, "finished client unary call")
+		return err
+	}
+}
+
+func newGRPCStreamClientInterceptor(l *Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		logClientCall(ctx, l, cc, method, start, err, "finished client streaming call")
+		return stream, err
+	}
+}
+
+// logClientCall logs the outcome of an outbound call with the fields and levels of grpc_zap's client
+// interceptors. The fields of l, the correlation and trace IDs of ctx and the target of cc are written with
+// the entry, as grpc_zap's client interceptors don't read fields from the context, so no logger is built for
+// each call
+func logClientCall(ctx context.Context, l *Logger, cc *grpc.ClientConn, method string, start time.Time, err error, msg string) {
+	code := grpc_logging.DefaultErrorToCode(err)
+	ce := l.GetInternalLogger().Check(grpc_zap.DefaultClientCodeToLevel(code), msg)
+	if ce == nil {
+		return
+	}
+
+	fields := l.getZapFieldsCtx(ctx)
+	if cc != nil {
+		fields = append(fields, zap.String("grpc.target", cc.Target()))
+	}
+	fields = append(fields,
+		grpc_zap.SystemField,
+		grpc_zap.ClientField,
+		zap.String("grpc.service", path.Dir(method)[1:]),
+		zap.String("grpc.method", path.Base(method)),
+		zap.Error(err),
+		zap.String("grpc.code", code.String()),
+		grpc_zap.DefaultDurationToField(time.Since(start)),
+	)
+	ce.Write(fields...)
+}
+
+// populatedLogger returns the internal logger of l with its internal and accumulated fields. With
+// without options or fields returns the Logger that carries them, for wrappers of a Logger too
+func populatedLogger(l Logging) *zap.Logger {
+	logger := l.With(nil)
+	return logger.GetInternalLogger().With(logger.getZapFields()...)
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_GRPCClientInterceptors(t *testing.T) {
+	l, logs := NewTestLogger()
+	l = l.NewChild(&FieldOpts{UserID: "user-1"})
+
+	cc, err := grpc.Dial("calls.internal:443", grpc.WithInsecure())
+	require.NoError(t, err)
+	defer cc.Close()
+
+	ctx := ContextWithCorrelationID(context.Background(), "corr-1")
+
+	unary := NewGRPCUnaryClientInterceptor(wrappedLogger{l})
+	err = unary(ctx, "/calls.Calls/GetCall", nil, nil, cc,
+		func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			return status.Error(codes.NotFound, "call not found")
+		})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stream := l.NewGRPCStreamClientInterceptor()
+	_, err = stream(ctx, &grpc.StreamDesc{}, cc, "/calls.Calls/WatchCalls",
+		func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+			return nil, nil
+		})
+	require.NoError(t, err)
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "finished client unary call", entries[0].Message)
+	assert.Equal(t, "GetCall", entries[0].Fields["grpc.method"])
+	assert.Equal(t, "calls.Calls", entries[0].Fields["grpc.service"])
+	assert.Equal(t, "calls.internal:443", entries[0].Fields["grpc.target"])
+	assert.Equal(t, "NotFound", entries[0].Fields["grpc.code"])
+	assert.Contains(t, entries[0].Fields, "grpc.time_ms")
+	assert.Equal(t, "corr-1", entries[0].Fields["correlationID"], "Expected the fields of the call's context")
+	assert.Equal(t, "user-1", entries[0].Fields["userID"], "Expected the accumulated fields")
+
+	assert.Equal(t, "finished client streaming call", entries[1].Message)
+	assert.Equal(t, "WatchCalls", entries[1].Fields["grpc.method"])
+	assert.Equal(t, "OK", entries[1].Fields["grpc.code"])
+	assert.Equal(t, "corr-1", entries[1].Fields["correlationID"])
+}
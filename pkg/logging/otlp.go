@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"github.com/caring/go-packages/v2/pkg/logging/internal/writer"
+	"go.uber.org/zap/zapcore"
+)
+
+// otlpCore writes each entry as an OTLP log record: the message is the body, and the fields, logger name,
+// caller and stacktrace are attributes, nested as they are logged rather than flattened into a string
+type otlpCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.EncoderConfig
+	fields *zapcore.MapObjectEncoder
+	out    zapcore.WriteSyncer
+}
+
+func newOTLPCore(enc zapcore.EncoderConfig, out zapcore.WriteSyncer, lvl zapcore.LevelEnabler) zapcore.Core {
+	return &otlpCore{
+		LevelEnabler: lvl,
+		enc:          enc,
+		fields:       zapcore.NewMapObjectEncoder(),
+		out:          out,
+	}
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := c.clone()
+	for _, f := range fields {
+		f.AddTo(clone.fields)
+	}
+	return clone
+}
+
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := c.clone().fields
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	if ent.LoggerName != "" && c.enc.NameKey != "" {
+		enc.AddString(c.enc.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && c.enc.CallerKey != "" {
+		enc.AddString(c.enc.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if ent.Stack != "" && c.enc.StacktraceKey != "" {
+		enc.AddString(c.enc.StacktraceKey, ent.Stack)
+	}
+
+	_, err := c.out.Write(writer.EncodeOTLPRecord(writer.OTLPRecord{
+		Time:         ent.Time,
+		Severity:     otlpSeverity(ent.Level),
+		SeverityText: ent.Level.CapitalString(),
+		Body:         ent.Message,
+		Attributes:   enc.Fields,
+	}))
+	if err != nil {
+		return err
+	}
+
+	if ent.Level > zapcore.ErrorLevel {
+		// the process may be about to exit, so the record is exported now
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *otlpCore) Sync() error {
+	return c.out.Sync()
+}
+
+// clone copies the core, with its own copy of the fields added with With
+func (c *otlpCore) clone() *otlpCore {
+	fields := zapcore.NewMapObjectEncoder()
+	for k, v := range c.fields.Fields {
+		fields.Fields[k] = v
+	}
+
+	return &otlpCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc,
+		fields:       fields,
+		out:          c.out,
+	}
+}
+
+// otlpSeverity returns the OTLP severity number of the level
+func otlpSeverity(lvl zapcore.Level) int32 {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 19
+	case zapcore.FatalLevel:
+		return 21
+	}
+	return 0
+}
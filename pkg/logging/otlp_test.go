@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/logging/internal/writer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func Test_LoggerOTLP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests [][]byte
+		apiKeys  []string
+	)
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(
+		grpc.CustomCodec(testRawCodec{}),
+		grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			assert.Equal(t, writer.OTLPExportMethod, method)
+
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			md, _ := metadata.FromIncomingContext(stream.Context())
+
+			mu.Lock()
+			requests = append(requests, req)
+			apiKeys = append(apiKeys, md.Get("api-key")...)
+			mu.Unlock()
+
+			return stream.SendMsg([]byte{})
+		}),
+	)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	insecure := true
+	l, err := NewLogger(&Config{
+		ServiceName:  "fooservice",
+		LogLevel:     DebugLevel,
+		OTLPEndpoint: lis.Addr().String(),
+		OTLPHeaders:  map[string]string{"api-key": "abc123"},
+		OTLPInsecure: &insecure,
+	})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	l.Info("routine", Int64("attempt", 2))
+	l.NewChild(nil, String("child", "yes")).Error("on fire", Object("call", testObject{ID: "call-1"}))
+	require.NoError(t, l.Close(), "Expected no error closing the logger")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1, "Expected the records to be exported in one batch")
+	assert.Equal(t, []string{"abc123"}, apiKeys, "Expected the headers to be sent as metadata")
+
+	resourceLogs := protoFields(t, requests[0], 1)
+	require.Len(t, resourceLogs, 1)
+	resource := protoFields(t, resourceLogs[0], 1)[0]
+	assert.Equal(t, map[string]string{"service.name": "fooservice"}, stringAttributes(t, resource, 1))
+
+	// the logger's startup entry is exported too
+	records := map[string][]byte{}
+	for _, r := range protoFields(t, protoFields(t, resourceLogs[0], 2)[0], 2) {
+		records[anyString(t, protoFields(t, r, 5)[0])] = r
+	}
+	require.Contains(t, records, "routine")
+	require.Contains(t, records, "on fire")
+
+	assert.Equal(t, uint64(9), protoVarint(t, records["routine"], 2), "Expected an info severity")
+	assert.Equal(t, uint64(17), protoVarint(t, records["on fire"], 2), "Expected an error severity")
+	attrs := stringAttributes(t, records["on fire"], 6)
+	assert.Equal(t, "yes", attrs["child"], "Expected the child's fields as attributes")
+	assert.Contains(t, attrs, "call", "Expected object fields as attributes")
+}
+
+type testObject struct {
+	ID string
+}
+
+func (o testObject) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("id", o.ID)
+	return nil
+}
+
+// testRawCodec receives messages as the bytes they were sent as
+type testRawCodec struct{}
+
+func (testRawCodec) Marshal(v interface{}) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (testRawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (testRawCodec) String() string {
+	return "proto"
+}
+
+// protoFields returns the values of every length delimited field num in the message
+func protoFields(t *testing.T, b []byte, num protowire.Number) [][]byte {
+	t.Helper()
+
+	var values [][]byte
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		require.True(t, l >= 0, "Expected a valid tag")
+		b = b[l:]
+
+		if n == num && typ == protowire.BytesType {
+			v, l := protowire.ConsumeBytes(b)
+			require.True(t, l >= 0, "Expected a valid field")
+			values = append(values, v)
+			b = b[l:]
+			continue
+		}
+
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		require.True(t, l >= 0, "Expected a valid field")
+		b = b[l:]
+	}
+	return values
+}
+
+// protoVarint returns the value of the varint field num in the message
+func protoVarint(t *testing.T, b []byte, num protowire.Number) uint64 {
+	t.Helper()
+
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		require.True(t, l >= 0, "Expected a valid tag")
+		b = b[l:]
+
+		if n == num && typ == protowire.VarintType {
+			v, _ := protowire.ConsumeVarint(b)
+			return v
+		}
+
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		require.True(t, l >= 0, "Expected a valid field")
+		b = b[l:]
+	}
+	t.Fatalf("field %d is missing", num)
+	return 0
+}
+
+// anyString returns the string value of an AnyValue message, or a description of its type if it isn't one
+func anyString(t *testing.T, b []byte) string {
+	t.Helper()
+
+	if s := protoFields(t, b, 1); len(s) == 1 {
+		return string(s[0])
+	}
+	return fmt.Sprintf("non string value %x", b)
+}
+
+// stringAttributes returns the KeyValue messages of field num in the message, with their values as strings
+func stringAttributes(t *testing.T, b []byte, num protowire.Number) map[string]string {
+	t.Helper()
+
+	attrs := map[string]string{}
+	for _, kv := range protoFields(t, b, num) {
+		key := string(protoFields(t, kv, 1)[0])
+		attrs[key] = anyString(t, protoFields(t, kv, 2)[0])
+	}
+	return attrs
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/caring/go-packages/v2/pkg/logging/internal/writer"
+)
+
+// partitionKeyFunc returns the func the partition key of each record put to Kinesis Data Streams is chosen with,
+// following the partition strategy of c. A nil func puts each record with a random key
+func partitionKeyFunc(c *Config) writer.PartitionKeyFunc {
+	switch c.KinesisPartitionStrategy {
+	case PartitionFixed:
+		return writer.FixedPartitionKey(c.KinesisPartitionKey)
+	case PartitionCorrelationID:
+		return correlationIDOf
+	case PartitionCustom:
+		return c.KinesisPartitionKeyFunc
+	}
+	return nil
+}
+
+// correlationIDOf returns the correlation ID of the first entry in the record, which is encoded as JSON or
+// logfmt, or an empty string if it has none
+func correlationIDOf(record []byte) string {
+	if bytes.HasPrefix(record, []byte("{")) {
+		var entry struct {
+			CorrelationID string `json:"correlationID"`
+		}
+		// a buffered record holds many entries, only the first is decoded
+		if err := json.NewDecoder(bytes.NewReader(record)).Decode(&entry); err != nil {
+			return ""
+		}
+		return entry.CorrelationID
+	}
+
+	if i := bytes.IndexByte(record, '\n'); i >= 0 {
+		record = record[:i]
+	}
+	key := []byte("correlationID=")
+	for start := 0; ; {
+		i := bytes.Index(record[start:], key)
+		if i < 0 {
+			return ""
+		}
+		i += start
+		// skip keys that only end in correlationID
+		if i > 0 && record[i-1] != ' ' {
+			start = i + len(key)
+			continue
+		}
+		return logfmtValueAt(record[i+len(key):])
+	}
+}
+
+// logfmtValueAt returns the logfmt value at the start of b, unquoting it if it is quoted
+func logfmtValueAt(b []byte) string {
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		for end := 1; end < len(b); end++ {
+			switch b[end] {
+			case '\\':
+				end++
+			case '"':
+				v, err := strconv.Unquote(string(b[:end+1]))
+				if err != nil {
+					return ""
+				}
+				return v
+			}
+		}
+		return ""
+	}
+
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_correlationIDOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   string
+	}{
+		{"json", `{"msg":"hi","correlationID":"abc-123"}` + "\n", "abc-123"},
+		{"json buffered", `{"correlationID":"first"}` + "\n" + `{"correlationID":"second"}` + "\n", "first"},
+		{"json without one", `{"msg":"hi"}`, ""},
+		{"logfmt", `msg=hi correlationID=abc-123 userID=1` + "\n", "abc-123"},
+		{"logfmt quoted", `msg=hi correlationID="abc 123"`, "abc 123"},
+		{"logfmt empty", `parentCorrelationID=xyz correlationID=""`, ""},
+		{"logfmt suffixed key", `parentCorrelationID=xyz`, ""},
+		{"logfmt buffered", "correlationID=first\ncorrelationID=second\n", "first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, correlationIDOf([]byte(tt.record)))
+		})
+	}
+}
+
+func Test_partitionKeyFunc(t *testing.T) {
+	record := []byte(`{"correlationID":"abc-123"}`)
+
+	assert.Nil(t, partitionKeyFunc(&Config{KinesisPartitionStrategy: PartitionRandom}), "Expected random keys to be left to the writer")
+	assert.Equal(t, "shard-1", partitionKeyFunc(&Config{KinesisPartitionStrategy: PartitionFixed, KinesisPartitionKey: "shard-1"})(record))
+	assert.Equal(t, "abc-123", partitionKeyFunc(&Config{KinesisPartitionStrategy: PartitionCorrelationID})(record))
+
+	custom := partitionKeyFunc(&Config{
+		KinesisPartitionStrategy: PartitionCustom,
+		KinesisPartitionKeyFunc:  func(r []byte) string { return "custom" },
+	})
+	assert.Equal(t, "custom", custom(record))
+}
@@ -0,0 +1,243 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultMaxPayloadBytes is the most bytes of a payload's JSON logged when PayloadOptions doesn't set MaxBytes
+const DefaultMaxPayloadBytes = 4096
+
+// redactedPayloadValue replaces the values of the redacted fields of a payload
+const redactedPayloadValue = "[redacted]"
+
+// PayloadOptions configures the payload logging interceptors
+type PayloadOptions struct {
+	// The methods whose payloads are logged, each the full method, e.g. /calls.Calls/GetCall, or a service
+	// ending in a slash, e.g. /calls.Calls/. No payloads are logged when empty
+	Methods []string
+	// The most bytes of each payload's JSON that are logged, the rest is cut off. Defaults to DefaultMaxPayloadBytes
+	MaxBytes int
+	// The JSON names of the fields whose values are replaced with [redacted], at any depth of the message,
+	// e.g. dateOfBirth
+	RedactFields []string
+}
+
+// payloadLogger logs the payloads of the allowed methods at debug level
+type payloadLogger struct {
+	logger   *Logger
+	methods  []string
+	maxBytes int
+	redact   map[string]bool
+}
+
+func newPayloadLogger(l Logging, opts PayloadOptions) *payloadLogger {
+	p := &payloadLogger{
+		logger:   l.With(nil),
+		methods:  opts.Methods,
+		maxBytes: opts.MaxBytes,
+		redact:   make(map[string]bool, len(opts.RedactFields)),
+	}
+	if p.maxBytes <= 0 {
+		p.maxBytes = DefaultMaxPayloadBytes
+	}
+	for _, f := range opts.RedactFields {
+		p.redact[f] = true
+	}
+	return p
+}
+
+// NewGRPCPayloadUnaryServerInterceptor returns a gRPC unary interceptor that logs the request and response
+// of the allowed methods as JSON at debug level, with their redacted fields hidden and cut off at the
+// max bytes. It is meant for debugging integrations in staging rather than for production, where payloads
+// are likely to hold PII; nothing is marshaled unless debug is enabled.
+func NewGRPCPayloadUnaryServerInterceptor(l Logging, opts PayloadOptions) grpc.UnaryServerInterceptor {
+	p := newPayloadLogger(l, opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !p.allowed(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		p.log(ctx, "server request payload", info.FullMethod, "grpc.request", req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			p.log(ctx, "server response payload", info.FullMethod, "grpc.response", resp)
+		}
+		return resp, err
+	}
+}
+
+// NewGRPCPayloadStreamServerInterceptor returns a gRPC stream interceptor that logs each message the allowed
+// methods receive and send, like NewGRPCPayloadUnaryServerInterceptor
+func NewGRPCPayloadStreamServerInterceptor(l Logging, opts PayloadOptions) grpc.StreamServerInterceptor {
+	p := newPayloadLogger(l, opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !p.allowed(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &payloadServerStream{ServerStream: ss, payloads: p, method: info.FullMethod})
+	}
+}
+
+// NewGRPCPayloadUnaryClientInterceptor returns a gRPC unary client interceptor that logs the request and
+// response of the allowed methods, like NewGRPCPayloadUnaryServerInterceptor
+func NewGRPCPayloadUnaryClientInterceptor(l Logging, opts PayloadOptions) grpc.UnaryClientInterceptor {
+	p := newPayloadLogger(l, opts)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !p.allowed(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		p.log(ctx, "client request payload", method, "grpc.request", req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			p.log(ctx, "client response payload", method, "grpc.response", reply)
+		}
+		return err
+	}
+}
+
+// NewGRPCPayloadStreamClientInterceptor returns a gRPC stream client interceptor that logs each message the
+// allowed methods send and receive, like NewGRPCPayloadUnaryServerInterceptor
+func NewGRPCPayloadStreamClientInterceptor(l Logging, opts PayloadOptions) grpc.StreamClientInterceptor {
+	p := newPayloadLogger(l, opts)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil || !p.allowed(method) {
+			return cs, err
+		}
+		return &payloadClientStream{ClientStream: cs, payloads: p, method: method}, nil
+	}
+}
+
+// allowed reports whether the payloads of the full method are logged
+func (p *payloadLogger) allowed(method string) bool {
+	for _, m := range p.methods {
+		if m == method || (strings.HasSuffix(m, "/") && strings.HasPrefix(method, m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// log logs the payload under the key, with the fields of ctx, if debug is enabled
+func (p *payloadLogger) log(ctx context.Context, message, method, key string, payload interface{}) {
+	ce := p.logger.monitorLogger.Check(zap.DebugLevel, message)
+	if ce == nil {
+		return
+	}
+
+	content, size := p.content(payload)
+	fields := []DataField{
+		String("grpc.method", method),
+		String(key+".content", content),
+		Int64(key+".size", int64(size)),
+	}
+	if len(content) < size {
+		fields = append(fields, Bool(key+".truncated", true))
+	}
+	ce.Write(p.logger.getZapFieldsCtx(ctx, fields...)...)
+}
+
+// content returns the redacted JSON of the payload, cut off at the max bytes, and the size of the whole JSON
+func (p *payloadLogger) content(payload interface{}) (string, int) {
+	var (
+		data []byte
+		err  error
+	)
+	if m, ok := payload.(proto.Message); ok {
+		data, err = protojson.Marshal(proto.MessageV2(m))
+	} else {
+		data, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return "unable to marshal payload: " + err.Error(), 0
+	}
+
+	if len(p.redact) > 0 {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err == nil {
+			if redacted, err := json.Marshal(p.redactValue(v)); err == nil {
+				data = redacted
+			}
+		}
+	}
+
+	if len(data) > p.maxBytes {
+		return string(data[:p.maxBytes]), len(data)
+	}
+	return string(data), len(data)
+}
+
+// redactValue replaces the values of the redacted fields of the decoded JSON, at any depth
+func (p *payloadLogger) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if p.redact[k] {
+				v[k] = redactedPayloadValue
+			} else {
+				v[k] = p.redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = p.redactValue(e)
+		}
+	}
+	return v
+}
+
+// payloadServerStream logs the messages a server stream receives and sends
+type payloadServerStream struct {
+	grpc.ServerStream
+	payloads *payloadLogger
+	method   string
+}
+
+func (s *payloadServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.payloads.log(s.Context(), "server request payload", s.method, "grpc.request", m)
+	}
+	return err
+}
+
+func (s *payloadServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.payloads.log(s.Context(), "server response payload", s.method, "grpc.response", m)
+	}
+	return err
+}
+
+// payloadClientStream logs the messages a client stream sends and receives
+type payloadClientStream struct {
+	grpc.ClientStream
+	payloads *payloadLogger
+	method   string
+}
+
+func (s *payloadClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.payloads.log(s.Context(), "client request payload", s.method, "grpc.request", m)
+	}
+	return err
+}
+
+func (s *payloadClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.payloads.log(s.Context(), "client response payload", s.method, "grpc.response", m)
+	}
+	return err
+}
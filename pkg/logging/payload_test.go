@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_GRPCPayloadUnaryServerInterceptor(t *testing.T) {
+	l, logs := NewTestLogger()
+
+	interceptor := NewGRPCPayloadUnaryServerInterceptor(l, PayloadOptions{
+		Methods:      []string{"/calls.Calls/"},
+		RedactFields: []string{"dateOfBirth"},
+	})
+
+	req := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"callID": {Kind: &structpb.Value_StringValue{StringValue: "call-1"}},
+		"caller": {Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"dateOfBirth": {Kind: &structpb.Value_StringValue{StringValue: "1950-01-01"}},
+		}}}},
+	}}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: true}}, nil
+	}
+
+	_, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/calls.Calls/GetCall"}, handler)
+	require.NoError(t, err)
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "server request payload", entries[0].Message)
+	assert.Equal(t, "/calls.Calls/GetCall", entries[0].Fields["grpc.method"])
+	assert.Equal(t, `{"callID":"call-1","caller":{"dateOfBirth":"[redacted]"}}`, entries[0].Fields["grpc.request.content"])
+	assert.Equal(t, "server response payload", entries[1].Message)
+	assert.Equal(t, "true", entries[1].Fields["grpc.response.content"])
+
+	// methods that aren't allowed aren't logged
+	_, err = interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/accounts.Accounts/GetAccount"}, handler)
+	require.NoError(t, err)
+	assert.Len(t, logs.All(), 2)
+}
+
+func Test_GRPCPayloadUnaryClientInterceptor(t *testing.T) {
+	l, logs := NewTestLogger()
+
+	interceptor := NewGRPCPayloadUnaryClientInterceptor(l, PayloadOptions{
+		Methods:  []string{"/calls.Calls/GetCall"},
+		MaxBytes: 10,
+	})
+
+	req := &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: strings.Repeat("a", 20)}}
+	err := interceptor(context.Background(), "/calls.Calls/GetCall", req, nil, nil,
+		func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			return nil
+		})
+	require.NoError(t, err)
+
+	entries := logs.FilterMessage("client request payload")
+	require.Len(t, entries, 1)
+	assert.Equal(t, `"aaaaaaaaa`, entries[0].Fields["grpc.request.content"])
+	assert.Equal(t, int64(22), entries[0].Fields["grpc.request.size"])
+	assert.Equal(t, true, entries[0].Fields["grpc.request.truncated"])
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The profiles that set the defaults of a logger for the environment it runs in, see Config.Profile
+const (
+	// ProfileProduction logs at info, samples bursts, writes JSON to kinesis and adds stacktraces to errors
+	ProfileProduction = "production"
+	// ProfileStaging logs at info and writes JSON to kinesis like production, without sampling, so
+	// nothing is missed while testing
+	ProfileStaging = "staging"
+	// ProfileDevelopment logs at debug to stdout, in the dev format, and adds stacktraces to warnings
+	ProfileDevelopment = "development"
+)
+
+// devLoggingEnvs are the values of Env that enable dev logging when no profile is set, so a service run
+// locally logs for a developer without any other settings
+var devLoggingEnvs = map[string]bool{
+	"local": true,
+}
+
+// applyProfile replaces the defaults in c with the ones of the profile. An empty profile keeps the defaults
+func applyProfile(c *Config, profile string) error {
+	switch strings.ToLower(profile) {
+	case "":
+	case ProfileProduction:
+		c.LogLevel = InfoLevel
+		c.EnableSampling = &trueVar
+		c.EnableDevLogging = &falseVar
+		c.DisableKinesis = &falseVar
+		c.StacktraceLevel = &errorLevelVar
+	case ProfileStaging:
+		c.LogLevel = InfoLevel
+		c.EnableSampling = &falseVar
+		c.EnableDevLogging = &falseVar
+		c.DisableKinesis = &falseVar
+		c.StacktraceLevel = &errorLevelVar
+	case ProfileDevelopment:
+		c.LogLevel = DebugLevel
+		c.EnableSampling = &falseVar
+		c.EnableDevLogging = &trueVar
+		c.DisableKinesis = &trueVar
+		c.StacktraceLevel = &warnLevelVar
+	default:
+		return fmt.Errorf("unrecognized log profile: %q", profile)
+	}
+
+	c.Profile = strings.ToLower(profile)
+	return nil
+}
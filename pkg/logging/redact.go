@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"strings"
+)
+
+// RedactFunc decides whether the value of a field is PII that must not be logged. It is given the key and the
+// value rendered as a string, and returns the value to log in its place and true, or false to leave it as it is.
+// It is called for every field of every entry, so it should be fast and must be safe for concurrent use.
+type RedactFunc func(key, value string) (string, bool)
+
+// fieldRedactor decides which field values are PII, for the scrubber that replaces them before they are
+// encoded, in every output including the BI pipeline
+type fieldRedactor struct {
+	// lower cased, so keys match whatever their case
+	keys map[string]struct{}
+	fn   RedactFunc
+}
+
+// newFieldRedactor returns a redactor for the given keys and func, or nil if there is nothing to redact
+func newFieldRedactor(keys []string, fn RedactFunc) *fieldRedactor {
+	if len(keys) == 0 && fn == nil {
+		return nil
+	}
+
+	r := &fieldRedactor{
+		keys: make(map[string]struct{}, len(keys)),
+		fn:   fn,
+	}
+	for _, k := range keys {
+		r.keys[strings.ToLower(k)] = struct{}{}
+	}
+
+	return r
+}
+
+// configured reports whether key is redacted, whatever its case. It is safe to call on a nil redactor
+func (r *fieldRedactor) configured(key string) bool {
+	if r == nil {
+		return false
+	}
+
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// replace returns the value logged in place of the value of key and true, or false to log it as it is. The
+// values of configured keys are redacted, the redact func decides for the rest
+func (r *fieldRedactor) replace(key, value string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+
+	if r.configured(key) {
+		return redacted, true
+	}
+	if r.fn != nil {
+		return r.fn(key, value)
+	}
+	return "", false
+}
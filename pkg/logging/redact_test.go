@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_fieldRedactor(t *testing.T) {
+	t.Run("Returns nil when nothing is configured", func(t *testing.T) {
+		assert.Nil(t, newFieldRedactor(nil, nil), "Expected a nil redactor")
+	})
+
+	t.Run("Redacts configured keys whatever their case", func(t *testing.T) {
+		r := newFieldRedactor([]string{"ssn", "Phone"}, nil)
+
+		v, ok := r.replace("SSN", "123-45-6789")
+		assert.True(t, ok, "Expected ssn to be redacted")
+		assert.Equal(t, redacted, v)
+		v, ok = r.replace("phone", "5551234")
+		assert.True(t, ok, "Expected phone to be redacted")
+		assert.Equal(t, redacted, v)
+		_, ok = r.replace("other", "abc")
+		assert.False(t, ok, "Expected unconfigured keys to be untouched")
+	})
+
+	t.Run("Applies the redact func to the other keys", func(t *testing.T) {
+		r := newFieldRedactor([]string{"ssn"}, func(key, value string) (string, bool) {
+			if strings.Contains(value, "@") {
+				return "[email]", true
+			}
+			return "", false
+		})
+
+		v, ok := r.replace("note", "contact someone@caring.com")
+		assert.True(t, ok, "Expected the func's replacement")
+		assert.Equal(t, "[email]", v)
+		v, ok = r.replace("ssn", "someone@caring.com")
+		assert.True(t, ok, "Expected configured keys to be redacted without the func")
+		assert.Equal(t, redacted, v)
+		_, ok = r.replace("other", "abc")
+		assert.False(t, ok, "Expected values the func declines to be untouched")
+	})
+
+	t.Run("Redacts nothing when nil", func(t *testing.T) {
+		var r *fieldRedactor
+		assert.False(t, r.configured("ssn"))
+		_, ok := r.replace("ssn", "123-45-6789")
+		assert.False(t, ok)
+	})
+}
+
+func Test_LoggerRedactsFields(t *testing.T) {
+	var written []map[string]interface{}
+	l, err := NewLogger(&Config{
+		LogLevel:          DebugLevel,
+		RedactedFieldKeys: []string{"email", "userID", "ssn"},
+		HashedFieldKeys:   []string{"userID"},
+		HashSalt:          "caring-dev",
+		LogReportEvents:   &falseVar,
+		OnLog: []LogHook{func(level Level, message string, fields map[string]interface{}) {
+			written = append(written, fields)
+		}},
+	})
+	require.NoError(t, err, "Expected no error creating the logger")
+	defer l.Close()
+	// the entry echoing the config
+	written = nil
+
+	l.With(&FieldOpts{UserID: "someuser"})
+	l.Info("", String("email", "someone@caring.com"))
+	l.GetInternalLogger().With(zap.String("ssn", "123-45-6789")).Info("", zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("email", "someone@caring.com")
+		return nil
+	})))
+
+	require.Len(t, written, 2)
+	assert.Equal(t, redacted, written[0]["email"], "Expected the email field to be redacted")
+	assert.Equal(t, redacted, written[0]["userID"], "Expected redaction to win over hashing")
+	assert.Equal(t, redacted, written[1]["ssn"], "Expected fields added to the internal logger to be redacted")
+	assert.Equal(t, map[string]interface{}{"email": redacted}, written[1]["user"], "Expected nested fields to be redacted")
+}
@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxRepeatKeys is how many distinct entries the repeat collapser tracks. Once it is reached the expired
+// ones are forgotten, and new ones aren't tracked until there is room
+const maxRepeatKeys = 1000
+
+// repeatKey identifies the entries that are collapsed together
+type repeatKey struct {
+	level   zapcore.Level
+	message string
+}
+
+// repeatRecord tracks the window of an entry, and the last of its repeats that were dropped with its fields,
+// so the count is written with the fields that tell where the repeats came from
+type repeatRecord struct {
+	start   time.Time
+	dropped int64
+	last    zapcore.Entry
+	fields  []zapcore.Field
+	core    zapcore.Core
+}
+
+// repeatState is shared by a core and the cores created from it with With, so repeats are collapsed
+// across child loggers
+type repeatState struct {
+	mu      sync.Mutex
+	window  time.Duration
+	records map[repeatKey]*repeatRecord
+}
+
+// repeatCore collapses entries with the same level and message. Once one is written, the repeats within
+// the window are dropped and counted, and the count is added to the next one written as the repeated field.
+type repeatCore struct {
+	zapcore.Core
+	state *repeatState
+}
+
+// newRepeatCollapser wraps a core so that repeats of an entry within the window are collapsed
+func newRepeatCollapser(core zapcore.Core, window time.Duration) zapcore.Core {
+	return &repeatCore{
+		Core: core,
+		state: &repeatState{
+			window:  window,
+			records: make(map[repeatKey]*repeatRecord),
+		},
+	}
+}
+
+func (c *repeatCore) With(fields []zapcore.Field) zapcore.Core {
+	return &repeatCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *repeatCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// added itself, rather than the wrapped core, so the fields of the repeats it drops are kept
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *repeatCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := repeatKey{level: ent.Level, message: ent.Message}
+
+	c.state.mu.Lock()
+	r := c.state.records[key]
+	if r != nil && ent.Time.Sub(r.start) < c.state.window {
+		r.dropped++
+		r.last = ent
+		// copied, as the caller may reuse the slice once the entry is written
+		r.fields = append([]zapcore.Field(nil), fields...)
+		r.core = c.Core
+		c.state.mu.Unlock()
+		return nil
+	}
+
+	var dropped int64
+	if r != nil {
+		dropped = r.dropped
+		delete(c.state.records, key)
+	}
+	var expired []repeatRecord
+	if len(c.state.records) >= maxRepeatKeys {
+		expired = c.state.purge(ent.Time)
+	}
+	// while every tracked entry is still in its window, new entries are written without being tracked
+	if len(c.state.records) < maxRepeatKeys {
+		c.state.records[key] = &repeatRecord{start: ent.Time}
+	}
+	c.state.mu.Unlock()
+
+	writeRepeats(expired)
+
+	if dropped > 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Int64("repeated", dropped))
+	}
+	writeChecked(c.Core, ent, fields)
+	return nil
+}
+
+// Sync writes the counts of the repeats dropped since the last entry of each window was written, so
+// they aren't lost when the logger is shut down
+func (c *repeatCore) Sync() error {
+	writeRepeats(c.state.flush())
+	return c.Core.Sync()
+}
+
+// writeRepeats writes the last repeat of each record, with its fields and the count of the repeats dropped
+func writeRepeats(records []repeatRecord) {
+	for _, r := range records {
+		writeChecked(r.core, r.last, append(r.fields[:len(r.fields):len(r.fields)], zap.Int64("repeated", r.dropped)))
+	}
+}
+
+// writeChecked writes the entry through the checked entry of core, so the wrapped cores that decide in Check,
+// such as the sampler, still do
+func writeChecked(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// flush returns the records with dropped repeats, and resets their counts
+func (s *repeatState) flush() []repeatRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []repeatRecord
+	for _, r := range s.records {
+		if r.dropped > 0 {
+			pending = append(pending, *r)
+			r.dropped = 0
+		}
+	}
+	return pending
+}
+
+// purge forgets the records whose window has passed, so entries with distinct messages don't grow the
+// map without bound, and returns those with dropped repeats so their counts can be written. It must be
+// called with the lock held
+func (s *repeatState) purge(now time.Time) []repeatRecord {
+	var expired []repeatRecord
+	for k, r := range s.records {
+		if now.Sub(r.start) >= s.window {
+			if r.dropped > 0 {
+				expired = append(expired, *r)
+			}
+			delete(s.records, k)
+		}
+	}
+	return expired
+}
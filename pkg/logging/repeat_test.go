@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_newRepeatCollapser(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	collapser := newRepeatCollapser(core, time.Minute)
+	child := collapser.With([]zapcore.Field{zap.String("child", "true")})
+
+	start := time.Now()
+	write := func(c zapcore.Core, level zapcore.Level, msg string, at time.Duration, fields ...zapcore.Field) {
+		ent := zapcore.Entry{Level: level, Message: msg, Time: start.Add(at)}
+		if ce := c.Check(ent, nil); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		write(collapser, zap.ErrorLevel, "connection refused", time.Duration(i)*time.Second, zap.Int("attempt", i))
+	}
+	// repeats from child loggers are collapsed too, entries at other levels aren't
+	write(child, zap.ErrorLevel, "connection refused", 10*time.Second)
+	write(collapser, zap.WarnLevel, "connection refused", 10*time.Second)
+
+	require.Equal(t, 2, logs.Len(), "Expected the first error and the warning to be written")
+	assert.NotContains(t, logs.All()[0].ContextMap(), "repeated")
+
+	// the first entry after the window carries the count of the dropped repeats
+	write(collapser, zap.ErrorLevel, "connection refused", 2*time.Minute)
+	require.Equal(t, 3, logs.Len())
+	assert.Equal(t, int64(5), logs.All()[2].ContextMap()["repeated"])
+
+	// pending counts are written on sync, with the fields of the last repeat and those it was written with
+	write(child, zap.ErrorLevel, "connection refused", 2*time.Minute+time.Second, zap.String("service", "calls"), zap.Int("attempt", 7))
+	require.NoError(t, collapser.Sync())
+	require.Equal(t, 4, logs.Len())
+	assert.Equal(t, int64(1), logs.All()[3].ContextMap()["repeated"])
+	assert.Equal(t, "true", logs.All()[3].ContextMap()["child"])
+	assert.Equal(t, "calls", logs.All()[3].ContextMap()["service"], "Expected the fields of the last repeat")
+	assert.Equal(t, int64(7), logs.All()[3].ContextMap()["attempt"], "Expected the fields of the last repeat")
+
+	require.NoError(t, collapser.Sync())
+	assert.Equal(t, 4, logs.Len(), "Expected nothing more to be written once the counts are synced")
+}
+
+func Test_newRepeatCollapserBoundsKeys(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	collapser := newRepeatCollapser(core, time.Minute)
+	state := collapser.(*repeatCore).state
+
+	start := time.Now()
+	write := func(msg string, at time.Duration) {
+		ent := zapcore.Entry{Level: zap.ErrorLevel, Message: msg, Time: start.Add(at)}
+		if ce := collapser.Check(ent, nil); ce != nil {
+			ce.Write()
+		}
+	}
+
+	// every distinct entry is repeated, so each record has a count pending
+	for i := 0; i < maxRepeatKeys; i++ {
+		msg := "call " + strconv.Itoa(i) + " failed"
+		write(msg, 0)
+		write(msg, time.Second)
+	}
+	require.Equal(t, maxRepeatKeys, logs.Len())
+	require.Len(t, state.records, maxRepeatKeys)
+
+	// once full, new entries within the window are written but not tracked
+	write("queue full", 30*time.Second)
+	write("queue full", 31*time.Second)
+	assert.Equal(t, maxRepeatKeys+2, logs.Len(), "Expected untracked entries not to be collapsed")
+	assert.Len(t, state.records, maxRepeatKeys)
+
+	// expired records are evicted whatever their count, and their counts written
+	write("disk full", 2*time.Minute)
+	assert.Len(t, state.records, 1)
+	summaries := logs.FilterField(zap.Int64("repeated", 1)).Len()
+	assert.Equal(t, maxRepeatKeys, summaries, "Expected the count of each evicted record to be written")
+	assert.Equal(t, 2*maxRepeatKeys+3, logs.Len())
+
+	require.NoError(t, collapser.Sync())
+	assert.Equal(t, 2*maxRepeatKeys+3, logs.Len(), "Expected evicted counts not to be written again on sync")
+}
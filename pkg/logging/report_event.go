@@ -0,0 +1,238 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidReportEvent is returned, wrapped with the reason, for report events that don't match their schema
+var ErrInvalidReportEvent = errors.New("invalid report event")
+
+// ReportEvent is a BI event defined as a struct, so the shape of the reports the warehouse loads is checked
+// by the compiler and validated before it is written. Its fields are the exported struct fields tagged with
+// the key they are reported as, and required ones must not be their zero value, e.g.
+//
+//	type SignUp struct {
+//		AccountID string    `report:"accountID,required"`
+//		Plan      string    `report:"plan,required"`
+//		Referrer  *string   `report:"referrer"`
+//		At        time.Time `report:"at,required"`
+//	}
+//
+//	func (SignUp) ReportSchema() (string, int) { return "sign_up", 2 }
+//
+// Fields may be strings, bools, numbers, time.Time, time.Duration, slices of strings, bools and numbers,
+// or pointers to any of them, which are left out of the report when nil
+type ReportEvent interface {
+	// ReportSchema returns the name and version of the event's schema. The version should be bumped
+	// whenever fields are added, removed or change type, so loaders can tell the shapes apart
+	ReportSchema() (name string, version int)
+}
+
+// ReportValidator is implemented by report events with rules beyond their required fields. Validate is
+// called after the required fields are checked
+type ReportValidator interface {
+	Validate() error
+}
+
+// ReportEvent validates the event and reports it like Report, with the schema name as the message. The
+// schema name and version are written as the schema and schemaVersion fields, followed by the event's fields,
+// the standard fields and any fields accumulated on the logger. Events that fail validation aren't written,
+// and an error wrapping ErrInvalidReportEvent is returned instead of a report ID.
+func (l *Logger) ReportEvent(event ReportEvent) (string, error) {
+	name, fields, err := reportEventFields(event)
+	if err != nil {
+		return "", err
+	}
+
+	return l.Report(name, fields...), nil
+}
+
+// reportKeysReserved are the keys written with every report event, which its fields can't use
+var reportKeysReserved = append([]string{"schema", "schemaVersion"}, standardFieldKeys...)
+
+// reportField is a struct field written to a report
+type reportField struct {
+	index    int
+	key      string
+	required bool
+}
+
+// reportSchemas caches the fields of each report event type, or the error the type's tags have
+var reportSchemas sync.Map
+
+type reportSchema struct {
+	fields []reportField
+	err    error
+}
+
+// reportEventFields validates the event and returns its schema name and the fields it is reported with
+func reportEventFields(event ReportEvent) (string, []DataField, error) {
+	if event == nil {
+		return "", nil, fmt.Errorf("%w: nil event", ErrInvalidReportEvent)
+	}
+
+	name, version := event.ReportSchema()
+	if name == "" {
+		return "", nil, fmt.Errorf("%w: %T has no schema name", ErrInvalidReportEvent, event)
+	}
+	if version < 1 {
+		return "", nil, fmt.Errorf("%w: %s has schema version %d, versions start at 1", ErrInvalidReportEvent, name, version)
+	}
+
+	v := reflect.ValueOf(event)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil, fmt.Errorf("%w: nil %T", ErrInvalidReportEvent, event)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", nil, fmt.Errorf("%w: %s is a %s, not a struct", ErrInvalidReportEvent, name, v.Kind())
+	}
+
+	schema := reportSchemaOf(v.Type())
+	if schema.err != nil {
+		return "", nil, fmt.Errorf("%w: %s: %s", ErrInvalidReportEvent, name, schema.err.Error())
+	}
+
+	fields := make([]DataField, 0, len(schema.fields)+2)
+	fields = append(fields, String("schema", name), Int64("schemaVersion", int64(version)))
+	for _, f := range schema.fields {
+		fv := v.Field(f.index)
+		if fv.IsZero() {
+			if f.required {
+				return "", nil, fmt.Errorf("%w: %s field %s is required", ErrInvalidReportEvent, name, f.key)
+			}
+			if fv.Kind() == reflect.Ptr {
+				continue
+			}
+		}
+		fields = append(fields, reportFieldValue(f.key, fv))
+	}
+
+	if validator, ok := event.(ReportValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return "", nil, fmt.Errorf("%w: %s: %s", ErrInvalidReportEvent, name, err.Error())
+		}
+	}
+
+	return name, fields, nil
+}
+
+// reportSchemaOf returns the cached fields of the report event type, parsing its tags the first time
+func reportSchemaOf(t reflect.Type) *reportSchema {
+	if s, ok := reportSchemas.Load(t); ok {
+		return s.(*reportSchema)
+	}
+
+	s := parseReportSchema(t)
+	reportSchemas.Store(t, s)
+	return s
+}
+
+func parseReportSchema(t reflect.Type) *reportSchema {
+	s := &reportSchema{}
+	keys := map[string]struct{}{}
+	for _, k := range reportKeysReserved {
+		keys[k] = struct{}{}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("report")
+		// unexported fields can't be read
+		if !ok || tag == "-" || sf.PkgPath != "" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		f := reportField{index: i, key: parts[0]}
+		if f.key == "" {
+			f.key = sf.Name
+		}
+		for _, opt := range parts[1:] {
+			if opt != "required" {
+				s.err = fmt.Errorf("field %s has the unrecognized option %q", sf.Name, opt)
+				return s
+			}
+			f.required = true
+		}
+
+		if _, ok := keys[f.key]; ok {
+			s.err = fmt.Errorf("field %s reuses the key %q", sf.Name, f.key)
+			return s
+		}
+		keys[f.key] = struct{}{}
+
+		if !reportTypeSupported(sf.Type) {
+			s.err = fmt.Errorf("field %s has the unsupported type %s", sf.Name, sf.Type)
+			return s
+		}
+
+		s.fields = append(s.fields, f)
+	}
+
+	return s
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func reportTypeSupported(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == durationType {
+		return true
+	}
+	if t.Kind() == reflect.Slice {
+		return reportScalar(t.Elem().Kind())
+	}
+	return reportScalar(t.Kind())
+}
+
+func reportScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// reportFieldValue returns the field for a value of a supported type
+func reportFieldValue(key string, v reflect.Value) DataField {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case timeType:
+		return Time(key, v.Interface().(time.Time))
+	case durationType:
+		return Duration(key, time.Duration(v.Int()))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return String(key, v.String())
+	case reflect.Bool:
+		return Bool(key, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int64(key, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Uint64(key, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return Float64(key, v.Float())
+	}
+	return Any(key, v.Interface())
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signUp struct {
+	AccountID string        `report:"accountID,required"`
+	Plan      string        `report:"plan,required"`
+	Referrer  *string       `report:"referrer"`
+	Seats     int           `report:"seats"`
+	Tags      []string      `report:"tags"`
+	Trial     time.Duration `report:"trial"`
+	At        time.Time     `report:"at,required"`
+	internal  string
+}
+
+func (signUp) ReportSchema() (string, int) { return "sign_up", 2 }
+
+func (s signUp) Validate() error {
+	if s.Seats < 0 {
+		return errors.New("seats can't be negative")
+	}
+	return nil
+}
+
+type badSchema struct {
+	ID    string            `report:"id"`
+	Attrs map[string]string `report:"attrs"`
+}
+
+func (badSchema) ReportSchema() (string, int) { return "bad", 1 }
+
+type reservedKey struct {
+	Service string `report:"service"`
+}
+
+func (*reservedKey) ReportSchema() (string, int) { return "reserved", 1 }
+
+func Test_LoggerReportEvent(t *testing.T) {
+	l, logs := NewObservedLogger(DebugLevel)
+	at := time.Date(2020, 6, 11, 16, 20, 0, 0, time.UTC)
+
+	reportID, err := l.ReportEvent(signUp{AccountID: "acct-1", Plan: "family", Seats: 3, At: at, internal: "skipped"})
+	require.NoError(t, err, "Expected a valid event to be reported")
+	assert.NotEmpty(t, reportID)
+
+	reports := logs.FilterMessage("sign_up").All()
+	require.Len(t, reports, 1)
+	fields := reports[0].ContextMap()
+	assert.Equal(t, "sign_up", fields["schema"])
+	assert.Equal(t, int64(2), fields["schemaVersion"])
+	assert.Equal(t, "acct-1", fields["accountID"])
+	assert.Equal(t, int64(3), fields["seats"])
+	assert.Equal(t, at, fields["at"])
+	assert.Equal(t, reportID, fields["reportID"])
+	assert.NotContains(t, fields, "referrer", "Expected nil pointers to be left out")
+	assert.NotContains(t, fields, "internal", "Expected unexported fields to be left out")
+
+	logs.TakeAll()
+	for name, event := range map[string]ReportEvent{
+		"missing required field": signUp{AccountID: "acct-1", At: at},
+		"failed validation":      signUp{AccountID: "acct-1", Plan: "family", Seats: -1, At: at},
+		"unsupported type":       badSchema{ID: "id"},
+		"reserved key":           &reservedKey{Service: "svc"},
+		"nil pointer":            (*reservedKey)(nil),
+	} {
+		_, err := l.ReportEvent(event)
+		assert.True(t, errors.Is(err, ErrInvalidReportEvent), "Expected an invalid event error for %s, got %v", name, err)
+	}
+	assert.Equal(t, 0, logs.Len(), "Expected invalid events not to be written")
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// routeEnabler enables the entries routed to an output with the given minimum level. The level the logger is
+// logging at is checked before entries are routed, by its leveledCore, so changing it at runtime still applies.
+// A nil level routes every entry the logger is logging at
+func routeEnabler(min *Level) zapcore.LevelEnabler {
+	if min == nil {
+		return zapcore.DebugLevel
+	}
+	return zapcore.Level(*min)
+}
+
+// newStderrSplitCore writes the entries below error to the stdout core and the others to the stderr core
+func newStderrSplitCore(stdout, stderr zapcore.Core) zapcore.Core {
+	belowError := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return lvl < zapcore.ErrorLevel
+	})
+	return zapcore.NewTee(
+		newRoutedCore(stdout, belowError),
+		newRoutedCore(stderr, zapcore.ErrorLevel),
+	)
+}
+
+// routedCore only writes the entries its enabler enables to the wrapped core. Unlike the level of the wrapped
+// core, it is also applied to writes that skip Check, such as those of the tee core
+type routedCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func newRoutedCore(core zapcore.Core, enabler zapcore.LevelEnabler) zapcore.Core {
+	return &routedCore{
+		Core:    core,
+		enabler: enabler,
+	}
+}
+
+func (c *routedCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *routedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &routedCore{
+		Core:    c.Core.With(fields),
+		enabler: c.enabler,
+	}
+}
+
+func (c *routedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *routedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.enabler.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
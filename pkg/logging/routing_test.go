@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_routedCore(t *testing.T) {
+	levels := newLevelControl(zap.NewAtomicLevelAt(zap.InfoLevel))
+	warn := WarnLevel
+
+	stdout, stdoutLogs := observer.New(zap.DebugLevel)
+	kinesis, kinesisLogs := observer.New(zap.DebugLevel)
+	tee := zapcore.NewTee(
+		newRoutedCore(stdout, routeEnabler(nil)),
+		newRoutedCore(kinesis, routeEnabler(&warn)),
+	)
+
+	// the tee writes to its cores without checking them
+	lc := newLifecycle(zap.NewProductionEncoderConfig())
+	zapL := zap.New(tee).WithOptions(lc.wrap(), zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newLeveledCore(core, levels.level)
+	})).With(zap.String("child", "yes"))
+
+	zapL.Debug("debug")
+	zapL.Info("info")
+	zapL.Error("error")
+
+	assert.Equal(t, 2, stdoutLogs.Len(), "Expected info and error to be written to stdout")
+	assert.Equal(t, 1, kinesisLogs.Len(), "Expected only error to be written to kinesis")
+	assert.Equal(t, "error", kinesisLogs.All()[0].Message)
+
+	levels.set(DebugLevel, 0)
+	zapL.Debug("debug")
+	assert.Equal(t, 3, stdoutLogs.Len(), "Expected runtime level changes to apply")
+	assert.Equal(t, 1, kinesisLogs.Len(), "Expected the route's own level to still apply")
+}
+
+func Test_stderrSplitCore(t *testing.T) {
+	stdout, stdoutLogs := observer.New(zap.DebugLevel)
+	stderr, stderrLogs := observer.New(zap.DebugLevel)
+
+	// the split is a tee, which writes to its cores without checking them
+	lc := newLifecycle(zap.NewProductionEncoderConfig())
+	zapL := zap.New(newStderrSplitCore(stdout, stderr)).WithOptions(lc.wrap())
+
+	zapL.Info("info")
+	zapL.Warn("warn")
+	zapL.Error("error")
+	zapL.DPanic("dpanic")
+
+	require.Equal(t, 2, stdoutLogs.Len(), "Expected warn and below to be written to stdout")
+	assert.Equal(t, "warn", stdoutLogs.All()[1].Message)
+	require.Equal(t, 2, stderrLogs.Len(), "Expected error and above to be written to stderr")
+	assert.Equal(t, "error", stderrLogs.All()[0].Message)
+}
+
+func Test_LoggerWebhook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &entry), "Expected each post to be one JSON entry")
+
+		mu.Lock()
+		messages = append(messages, entry["msg"].(string))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	l, err := NewLogger(&Config{LogLevel: DebugLevel, WebhookURL: server.URL})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	l.Info("routine")
+	l.Error("on fire")
+	l.NewChild(nil).Error("child on fire")
+	require.NoError(t, l.Close(), "Expected no error closing the logger")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"on fire", "child on fire"}, messages, "Expected only errors to be posted")
+}
@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// samplingTick is the period sampling counts entries over, so the sample settings are per second
+const samplingTick = time.Second
+
+// samplingStats counts the entries sampling dropped, shared with child loggers
+type samplingStats struct {
+	dropped int64
+}
+
+// newSampler wraps a core so that each second, only the first entries with the same level and message are
+// written, then every nth entry after them, as configured. Entries that are dropped are counted in stats.
+func newSampler(core zapcore.Core, c *Config, stats *samplingStats) zapcore.Core {
+	return zapcore.NewSamplerWithOptions(
+		core,
+		samplingTick,
+		c.SampleInitial,
+		c.SampleThereafter,
+		zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped != 0 {
+				atomic.AddInt64(&stats.dropped, 1)
+			}
+		}),
+	)
+}
+
+// SampledOut returns the number of monitoring entries, logged by the logger or any of its
+// children, that sampling dropped instead of writing
+func (l *Logger) SampledOut() int64 {
+	if l.sampling == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.sampling.dropped)
+}
@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_newSampler(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	stats := &samplingStats{}
+	c := &Config{SampleInitial: 2, SampleThereafter: 3}
+
+	zapL := zap.New(newSampler(core, c, stats))
+	for i := 0; i < 10; i++ {
+		zapL.Info("burst")
+	}
+	zapL.Warn("burst")
+
+	// the first 2, then the 5th and 8th. Entries at other levels are counted separately
+	infos := 0
+	for _, e := range logs.All() {
+		if e.Level == zap.InfoLevel {
+			infos++
+		}
+	}
+	assert.Equal(t, 4, infos, "Expected 4 of the info entries to be written")
+	assert.Equal(t, 5, logs.Len(), "Expected the warn entry to be written")
+	assert.Equal(t, int64(6), stats.dropped, "Expected 6 entries to be counted as dropped")
+
+	l := &Logger{sampling: stats}
+	assert.Equal(t, int64(6), l.SampledOut())
+	assert.Equal(t, int64(0), NewNopLogger().SampledOut())
+}
@@ -0,0 +1,199 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldScrubber replaces the values of the fields written to a core, including the fields nested in objects,
+// arrays and reflected values, so they are replaced however the fields reach the core
+type fieldScrubber struct {
+	hasher   *fieldHasher
+	redactor *fieldRedactor
+}
+
+// newFieldScrubber returns a scrubber for the hasher and redactor, or nil if there is nothing to scrub
+func newFieldScrubber(hasher *fieldHasher, redactor *fieldRedactor) *fieldScrubber {
+	if hasher == nil && redactor == nil {
+		return nil
+	}
+
+	return &fieldScrubber{
+		hasher:   hasher,
+		redactor: redactor,
+	}
+}
+
+// configured reports whether the whole value of key is replaced, rather than the values nested in it
+func (s *fieldScrubber) configured(key string) bool {
+	return s.redactor.configured(key) || s.hasher.configured(key)
+}
+
+// replace returns the value logged in place of the value of key and true, or false to log it as it is.
+// Empty values are left untouched, so a missing ID doesn't turn into a digest that looks like a real one
+func (s *fieldScrubber) replace(key, value string) (string, bool) {
+	if value == "" {
+		return "", false
+	}
+
+	// redaction wins over hashing for a key configured for both
+	if !s.redactor.configured(key) && s.hasher.configured(key) {
+		return s.hasher.hash(value), true
+	}
+	return s.redactor.replace(key, value)
+}
+
+// apply returns the fields with their values scrubbed. The fields are copied when any is scrubbed, they may be
+// shared with other outputs
+func (s *fieldScrubber) apply(fields []zapcore.Field) []zapcore.Field {
+	var scrubbed []zapcore.Field
+	for i, f := range fields {
+		replacement, ok := s.field(f)
+		if !ok {
+			continue
+		}
+		if scrubbed == nil {
+			scrubbed = append(make([]zapcore.Field, 0, len(fields)), fields...)
+		}
+		scrubbed[i] = replacement
+	}
+
+	if scrubbed == nil {
+		return fields
+	}
+	return scrubbed
+}
+
+// field returns the field with its value scrubbed and true, or false if nothing in it was scrubbed
+func (s *fieldScrubber) field(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return f, false
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		if s.configured(f.Key) {
+			break
+		}
+
+		// the values nested in the field are scrubbed by their own keys, and the field is written as the
+		// reflected result when any of them was
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		v, ok := s.value(f.Key, enc.Fields[f.Key])
+		if !ok {
+			return f, false
+		}
+		return zap.Reflect(f.Key, v), true
+	}
+
+	if v, ok := s.replace(f.Key, fieldValue(f)); ok {
+		return zap.String(f.Key, v), true
+	}
+	return f, false
+}
+
+// value returns v, as added to a map object encoder, with the values nested in it scrubbed and true, or false if
+// nothing in it was scrubbed. The elements of arrays are scrubbed by the key of the array
+func (s *fieldScrubber) value(key string, v interface{}) (interface{}, bool) {
+	if v == nil {
+		return v, false
+	}
+	if s.configured(key) {
+		return s.replace(key, fmt.Sprint(v))
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		var scrubbed map[string]interface{}
+		for k, el := range v {
+			replacement, ok := s.value(k, el)
+			if !ok {
+				continue
+			}
+			if scrubbed == nil {
+				scrubbed = make(map[string]interface{}, len(v))
+				for k, el := range v {
+					scrubbed[k] = el
+				}
+			}
+			scrubbed[k] = replacement
+		}
+		if scrubbed == nil {
+			return v, false
+		}
+		return scrubbed, true
+	case []interface{}:
+		var scrubbed []interface{}
+		for i, el := range v {
+			replacement, ok := s.value(key, el)
+			if !ok {
+				continue
+			}
+			if scrubbed == nil {
+				scrubbed = append(make([]interface{}, 0, len(v)), v...)
+			}
+			scrubbed[i] = replacement
+		}
+		if scrubbed == nil {
+			return v, false
+		}
+		return scrubbed, true
+	}
+
+	// values added with AddReflected are scrubbed as their JSON encoding, which is how they are written
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v, false
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			return v, false
+		}
+		if _, ok := decoded.(string); ok {
+			// e.g. times and byte slices, which are leaves
+			break
+		}
+		return s.value(key, decoded)
+	}
+
+	return s.replace(key, fmt.Sprint(v))
+}
+
+// scrubbedCore scrubs the fields written to the wrapped core, whether they are added by the logger, with With,
+// or by zap directly, e.g. by the interceptors given the internal logger. Like filteredCore, it also applies to
+// writes that skip Check
+type scrubbedCore struct {
+	zapcore.Core
+	scrubber *fieldScrubber
+}
+
+func newScrubbedCore(core zapcore.Core, scrubber *fieldScrubber) zapcore.Core {
+	return &scrubbedCore{
+		Core:     core,
+		scrubber: scrubber,
+	}
+}
+
+func (c *scrubbedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &scrubbedCore{
+		Core:     c.Core.With(c.scrubber.apply(fields)),
+		scrubber: c.scrubber,
+	}
+}
+
+func (c *scrubbedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// added itself, rather than the wrapped core, so the fields are scrubbed when the entry is written
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *scrubbedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.scrubber.apply(fields))
+}
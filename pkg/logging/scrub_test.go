@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_newFieldScrubber(t *testing.T) {
+	assert.Nil(t, newFieldScrubber(nil, nil), "Expected a nil scrubber when nothing is scrubbed")
+}
+
+func Test_fieldScrubberApply(t *testing.T) {
+	s := newFieldScrubber(nil, newFieldRedactor([]string{"ssn", "user"}, nil))
+
+	fields := []zap.Field{
+		zap.String("ssn", "123-45-6789"),
+		zap.String("ssn", ""),
+		zap.String("other", "abc"),
+	}
+	scrubbed := s.apply(fields)
+	assert.Equal(t, zap.String("ssn", redacted), scrubbed[0], "Expected ssn to be redacted")
+	assert.Equal(t, zap.String("ssn", ""), scrubbed[1], "Expected empty values not to be redacted")
+	assert.Equal(t, zap.String("other", "abc"), scrubbed[2], "Expected unconfigured keys to be untouched")
+	assert.Equal(t, zap.String("ssn", "123-45-6789"), fields[0], "Expected the fields given not to be changed")
+
+	untouched := []zap.Field{zap.String("other", "abc")}
+	assert.Equal(t, &untouched[0], &s.apply(untouched)[0], "Expected the fields not to be copied when none is scrubbed")
+
+	user := zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("name", "someone")
+		return nil
+	}))
+	assert.Equal(t, zap.String("user", redacted), s.apply([]zap.Field{user})[0], "Expected a configured object to be redacted whole")
+}
+
+func Test_fieldScrubberNested(t *testing.T) {
+	s := newFieldScrubber(nil, newFieldRedactor([]string{"ssn"}, nil))
+	enc := func(f zap.Field) interface{} {
+		m := zapcore.NewMapObjectEncoder()
+		s.apply([]zap.Field{f})[0].AddTo(m)
+		return m.Fields[f.Key]
+	}
+
+	t.Run("Redacts the keys of objects", func(t *testing.T) {
+		f := zap.Object("patient", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", "someone")
+			return enc.AddObject("ids", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddInt64("ssn", 123456789)
+				return nil
+			}))
+		}))
+
+		assert.Equal(t, map[string]interface{}{"name": "someone", "ids": map[string]interface{}{"ssn": redacted}}, enc(f))
+	})
+
+	t.Run("Redacts the objects in arrays", func(t *testing.T) {
+		f := zap.Array("patients", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			return arr.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("ssn", "123-45-6789")
+				return nil
+			}))
+		}))
+
+		assert.Equal(t, []interface{}{map[string]interface{}{"ssn": redacted}}, enc(f))
+	})
+
+	t.Run("Redacts the keys of reflected values", func(t *testing.T) {
+		type patient struct {
+			Name string `json:"name"`
+			SSN  string `json:"ssn"`
+		}
+
+		assert.Equal(t, map[string]interface{}{"name": "someone", "ssn": redacted}, enc(zap.Reflect("patient", patient{"someone", "123-45-6789"})))
+	})
+
+	t.Run("Redacts the fields of errors", func(t *testing.T) {
+		err := errors.Build("lookup failed").WithField("ssn", "123-45-6789").Err()
+
+		e := enc(NamedError("error", err).field).(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"ssn": redacted}, e["fields"])
+		assert.Equal(t, "lookup failed", e["message"])
+	})
+}
+
+func Test_scrubbedCore(t *testing.T) {
+	fac, logs := observer.New(zap.InfoLevel)
+	s := newFieldScrubber(nil, newFieldRedactor([]string{"ssn"}, nil))
+
+	lc := newLifecycle(zap.NewProductionEncoderConfig())
+	zapL := zap.New(newScrubbedCore(fac, s)).WithOptions(lc.wrap()).With(zap.String("ssn", "123-45-6789"))
+	zapL.Info("looked up", zap.String("ssn", "987-65-4321"), zap.String("callID", "call-1"))
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, []zap.Field{zap.String("ssn", redacted)}, logs.All()[0].Context[:1], "Expected fields added with With to be redacted")
+	assert.Equal(t, []zap.Field{zap.String("ssn", redacted), zap.String("callID", "call-1")}, logs.All()[0].Context[1:],
+		"Expected the fields written to be redacted")
+}
@@ -0,0 +1,124 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSlogHandler returns a slog.Handler that writes the records of a slog.Logger through l, so libraries
+// logging with the standard library reach the same outputs, with the standard fields and the fields
+// accumulated on l. Like the Ctx methods, the correlation and traceability IDs and the tracing span of the
+// context a record is logged with are added. Attributes of groups are written with dotted keys, e.g. http.status
+func NewSlogHandler(l *Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+type slogHandler struct {
+	logger *Logger
+	// the prefix of the keys of the attributes, from the groups opened with WithGroup
+	prefix string
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.logger.monitorLogger.Core().Enabled(slogLevel(lvl))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ce := h.logger.monitorLogger.Check(slogLevel(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+	// the caller is the line that called slog, rather than this handler
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]DataField, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, a)
+		return true
+	})
+	ce.Write(h.logger.getZapFieldsCtx(ctx, fields...)...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []DataField
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.prefix, a)
+	}
+	return &slogHandler{
+		logger: h.logger.NewChild(nil, fields...),
+		prefix: h.prefix,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{
+		logger: h.logger,
+		prefix: h.prefix + name + ".",
+	}
+}
+
+// slogLevel maps a slog level onto the closest level of the logger at or below it
+func slogLevel(lvl slog.Level) zapcore.Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case lvl < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case lvl < slog.LevelError:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+// appendSlogAttr appends the attribute as a field, or the attributes of a group as fields with the group's
+// name in their keys. Empty attributes are skipped, and the attributes of groups without a name are inlined
+func appendSlogAttr(fields []DataField, prefix string, a slog.Attr) []DataField {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendSlogAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	if a.Key == "" {
+		return fields
+	}
+
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, String(key, v.String()))
+	case slog.KindInt64:
+		return append(fields, Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, Duration(key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, Time(key, v.Time()))
+	}
+	return append(fields, Any(key, v.Any()))
+}
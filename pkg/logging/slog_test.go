@@ -0,0 +1,42 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_SlogHandler(t *testing.T) {
+	l, logs := NewObservedLogger(InfoLevel)
+	l = l.NewChild(&FieldOpts{CorrelationID: "corr-1"})
+
+	s := slog.New(NewSlogHandler(l)).With("component", "poller").WithGroup("http")
+	s.Debug("skipped")
+	s.Info("request handled", "status", 200, slog.Group("client", "id", "client-1"), slog.Bool("cached", true))
+	s.ErrorContext(ContextWithCorrelationID(context.Background(), "corr-2"), "request failed", "error", assert.AnError)
+
+	entries := logs.All()
+	require.Len(t, entries, 2, "Expected the disabled debug entry to be skipped")
+
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, "request handled", entries[0].Message)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "corr-1", fields["correlationID"], "Expected the standard fields to be added")
+	assert.Equal(t, "poller", fields["component"], "Expected the attributes of the logger to be added")
+	assert.Equal(t, int64(200), fields["http.status"], "Expected attributes to be prefixed with their group")
+	assert.Equal(t, "client-1", fields["http.client.id"], "Expected nested groups to be flattened")
+	assert.Equal(t, true, fields["http.cached"])
+	assert.Equal(t, "slog_test.go", filepath.Base(entries[0].Caller.File), "Expected the caller to be the line that logged")
+
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, "corr-2", entries[1].ContextMap()["correlationID"], "Expected the correlation ID of the context")
+	assert.Equal(t, assert.AnError.Error(), entries[1].ContextMap()["http.error"])
+}
@@ -0,0 +1,488 @@
+package logging
+
+import "os"
+
+// Where the effective value of a config setting came from
+const (
+	ConfigSourceConfig  = "config"
+	ConfigSourceEnv     = "env"
+	ConfigSourceDefault = "default"
+)
+
+// redacted replaces the value of secret settings in a config snapshot
+const redacted = "[redacted]"
+
+// ConfigValue is the effective value of a config setting and where it came from
+type ConfigValue struct {
+	Value interface{} `json:"value"`
+	// One of ConfigSourceConfig, ConfigSourceEnv or ConfigSourceDefault
+	Source string `json:"source"`
+	// The environment variable the setting is read from
+	EnvVar string `json:"envVar"`
+}
+
+// ConfigSnapshot is the effective config of a logger, keyed by setting name
+type ConfigSnapshot map[string]ConfigValue
+
+// configSetting describes a config setting for config snapshots
+type configSetting struct {
+	name   string
+	envVar string
+	secret bool
+	// reports whether the setting was set in the config passed to NewLogger
+	isSet func(c *Config) bool
+	// returns the value of the setting
+	value func(c *Config) interface{}
+}
+
+// configSettings lists every config setting that is read from the environment
+var configSettings = []configSetting{
+	{
+		name:   "LoggerName",
+		envVar: "LOG_NAME",
+		isSet:  func(c *Config) bool { return c.LoggerName != "" },
+		value:  func(c *Config) interface{} { return c.LoggerName },
+	},
+	{
+		name:   "ServiceName",
+		envVar: "SERVICE_NAME",
+		isSet:  func(c *Config) bool { return c.ServiceName != "" },
+		value:  func(c *Config) interface{} { return c.ServiceName },
+	},
+	{
+		name:   "LogLevel",
+		envVar: "LOG_LEVEL",
+		isSet:  logLevelSet,
+		value:  func(c *Config) interface{} { return c.LogLevel.String() },
+	},
+	{
+		name:   "Profile",
+		envVar: "LOG_PROFILE",
+		isSet:  func(c *Config) bool { return c.Profile != "" },
+		value:  func(c *Config) interface{} { return c.Profile },
+	},
+	{
+		name:   "StacktraceLevel",
+		envVar: "LOG_STACKTRACE_LEVEL",
+		isSet:  func(c *Config) bool { return c.StacktraceLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.StacktraceLevel) },
+	},
+	{
+		name:   "StdoutLevel",
+		envVar: "LOG_STDOUT_LEVEL",
+		isSet:  func(c *Config) bool { return c.StdoutLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.StdoutLevel) },
+	},
+	{
+		name:   "SplitStderr",
+		envVar: "LOG_SPLIT_STDERR",
+		isSet:  func(c *Config) bool { return c.SplitStderr != nil },
+		value:  func(c *Config) interface{} { return *c.SplitStderr },
+	},
+	{
+		name:   "KinesisLevel",
+		envVar: "LOG_KINESIS_LEVEL",
+		isSet:  func(c *Config) bool { return c.KinesisLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.KinesisLevel) },
+	},
+	{
+		name:   "CloudWatchLevel",
+		envVar: "LOG_CLOUDWATCH_LEVEL",
+		isSet:  func(c *Config) bool { return c.CloudWatchLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.CloudWatchLevel) },
+	},
+	{
+		name:   "WebhookURL",
+		envVar: "LOG_WEBHOOK_URL",
+		// webhook URLs carry their token in the path
+		secret: true,
+		isSet:  func(c *Config) bool { return c.WebhookURL != "" },
+		value:  func(c *Config) interface{} { return c.WebhookURL },
+	},
+	{
+		name:   "WebhookLevel",
+		envVar: "LOG_WEBHOOK_LEVEL",
+		isSet:  func(c *Config) bool { return c.WebhookLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.WebhookLevel) },
+	},
+	{
+		name:   "EnableDevLogging",
+		envVar: "LOG_ENABLE_DEV",
+		isSet:  func(c *Config) bool { return c.EnableDevLogging != nil },
+		value:  func(c *Config) interface{} { return *c.EnableDevLogging },
+	},
+	{
+		name:   "KinesisStreamMonitoring",
+		envVar: "LOG_STREAM_MONITORING",
+		isSet:  func(c *Config) bool { return c.KinesisStreamMonitoring != "" },
+		value:  func(c *Config) interface{} { return c.KinesisStreamMonitoring },
+	},
+	{
+		name:   "KinesisStreamReporting",
+		envVar: "LOG_STREAM_REPORTING",
+		isSet:  func(c *Config) bool { return c.KinesisStreamReporting != "" },
+		value:  func(c *Config) interface{} { return c.KinesisStreamReporting },
+	},
+	{
+		name:   "ReportingGzip",
+		envVar: "LOG_REPORTING_GZIP",
+		isSet:  func(c *Config) bool { return c.ReportingGzip != nil },
+		value:  func(c *Config) interface{} { return *c.ReportingGzip },
+	},
+	{
+		name:   "DisableKinesis",
+		envVar: "LOG_DISABLE_KINESIS",
+		isSet:  func(c *Config) bool { return c.DisableKinesis != nil },
+		value:  func(c *Config) interface{} { return *c.DisableKinesis },
+	},
+	{
+		name:   "KinesisBackend",
+		envVar: "LOG_KINESIS_BACKEND",
+		isSet:  func(c *Config) bool { return c.KinesisBackend != "" },
+		value:  func(c *Config) interface{} { return c.KinesisBackend },
+	},
+	{
+		name:   "KinesisPartitionKey",
+		envVar: "LOG_KINESIS_PARTITION_KEY",
+		isSet:  func(c *Config) bool { return c.KinesisPartitionKey != "" },
+		value:  func(c *Config) interface{} { return c.KinesisPartitionKey },
+	},
+	{
+		name:   "KinesisPartitionStrategy",
+		envVar: "LOG_KINESIS_PARTITION_STRATEGY",
+		isSet:  func(c *Config) bool { return c.KinesisPartitionStrategy != "" },
+		value:  func(c *Config) interface{} { return c.KinesisPartitionStrategy },
+	},
+	{
+		name:   "KinesisRegion",
+		envVar: "LOG_KINESIS_REGION",
+		isSet:  func(c *Config) bool { return c.KinesisRegion != "" },
+		value:  func(c *Config) interface{} { return c.KinesisRegion },
+	},
+	{
+		name:   "KinesisRoleArn",
+		envVar: "LOG_KINESIS_ROLE_ARN",
+		isSet:  func(c *Config) bool { return c.KinesisRoleArn != "" },
+		value:  func(c *Config) interface{} { return c.KinesisRoleArn },
+	},
+	{
+		name:   "KinesisExternalID",
+		envVar: "LOG_KINESIS_EXTERNAL_ID",
+		secret: true,
+		isSet:  func(c *Config) bool { return c.KinesisExternalID != "" },
+		value:  func(c *Config) interface{} { return c.KinesisExternalID },
+	},
+	{
+		name:   "FlushInterval",
+		envVar: "LOG_FLUSH_INTERVAL",
+		isSet:  func(c *Config) bool { return c.FlushInterval != 0 },
+		value:  func(c *Config) interface{} { return c.FlushInterval.String() },
+	},
+	{
+		name:   "BufferSize",
+		envVar: "LOG_BUFFER_SIZE",
+		isSet:  func(c *Config) bool { return c.BufferSize != 0 },
+		value:  func(c *Config) interface{} { return c.BufferSize },
+	},
+	{
+		name:   "KinesisBatching",
+		envVar: "LOG_KINESIS_BATCHING",
+		isSet:  func(c *Config) bool { return c.KinesisBatching != nil },
+		value:  func(c *Config) interface{} { return *c.KinesisBatching },
+	},
+	{
+		name:   "KinesisAggregation",
+		envVar: "LOG_KINESIS_AGGREGATION",
+		isSet:  func(c *Config) bool { return c.KinesisAggregation != nil },
+		value:  func(c *Config) interface{} { return *c.KinesisAggregation },
+	},
+	{
+		name:   "BatchMaxRecords",
+		envVar: "LOG_BATCH_MAX_RECORDS",
+		isSet:  func(c *Config) bool { return c.BatchMaxRecords != 0 },
+		value:  func(c *Config) interface{} { return c.BatchMaxRecords },
+	},
+	{
+		name:   "BatchMaxBytes",
+		envVar: "LOG_BATCH_MAX_BYTES",
+		isSet:  func(c *Config) bool { return c.BatchMaxBytes != 0 },
+		value:  func(c *Config) interface{} { return c.BatchMaxBytes },
+	},
+	{
+		name:   "DeliveryAttempts",
+		envVar: "LOG_DELIVERY_ATTEMPTS",
+		isSet:  func(c *Config) bool { return c.DeliveryAttempts != 0 },
+		value:  func(c *Config) interface{} { return c.DeliveryAttempts },
+	},
+	{
+		name:   "DeliveryBackoff",
+		envVar: "LOG_DELIVERY_BACKOFF",
+		isSet:  func(c *Config) bool { return c.DeliveryBackoff != 0 },
+		value:  func(c *Config) interface{} { return c.DeliveryBackoff.String() },
+	},
+	{
+		name:   "DeliveryFallback",
+		envVar: "LOG_DELIVERY_FALLBACK",
+		isSet:  func(c *Config) bool { return c.DeliveryFallback != "" },
+		value:  func(c *Config) interface{} { return c.DeliveryFallback },
+	},
+	{
+		name:   "QueueSize",
+		envVar: "LOG_QUEUE_SIZE",
+		isSet:  func(c *Config) bool { return c.QueueSize != 0 },
+		value:  func(c *Config) interface{} { return c.QueueSize },
+	},
+	{
+		name:   "QueuePolicy",
+		envVar: "LOG_QUEUE_POLICY",
+		isSet:  func(c *Config) bool { return c.QueuePolicy != "" },
+		value:  func(c *Config) interface{} { return c.QueuePolicy },
+	},
+	{
+		name:   "EnableSampling",
+		envVar: "LOG_SAMPLING",
+		isSet:  func(c *Config) bool { return c.EnableSampling != nil },
+		value:  func(c *Config) interface{} { return *c.EnableSampling },
+	},
+	{
+		name:   "SampleInitial",
+		envVar: "LOG_SAMPLE_INITIAL",
+		isSet:  func(c *Config) bool { return c.SampleInitial != 0 },
+		value:  func(c *Config) interface{} { return c.SampleInitial },
+	},
+	{
+		name:   "SampleThereafter",
+		envVar: "LOG_SAMPLE_THEREAFTER",
+		isSet:  func(c *Config) bool { return c.SampleThereafter != 0 },
+		value:  func(c *Config) interface{} { return c.SampleThereafter },
+	},
+	{
+		name:   "RepeatWindow",
+		envVar: "LOG_REPEAT_WINDOW",
+		isSet:  func(c *Config) bool { return c.RepeatWindow != 0 },
+		value:  func(c *Config) interface{} { return c.RepeatWindow.String() },
+	},
+	{
+		name:   "Env",
+		envVar: "ENV",
+		isSet:  func(c *Config) bool { return c.Env != "" },
+		value:  func(c *Config) interface{} { return c.Env },
+	},
+	{
+		name:   "HashedFieldKeys",
+		envVar: "LOG_HASH_FIELDS",
+		isSet:  func(c *Config) bool { return len(c.HashedFieldKeys) != 0 },
+		value:  func(c *Config) interface{} { return c.HashedFieldKeys },
+	},
+	{
+		name:   "HashSalt",
+		envVar: "LOG_HASH_SALT",
+		secret: true,
+		isSet:  func(c *Config) bool { return c.HashSalt != "" },
+		value:  func(c *Config) interface{} { return c.HashSalt },
+	},
+	{
+		name:   "RedactedFieldKeys",
+		envVar: "LOG_REDACT_FIELDS",
+		isSet:  func(c *Config) bool { return len(c.RedactedFieldKeys) != 0 },
+		value:  func(c *Config) interface{} { return c.RedactedFieldKeys },
+	},
+	{
+		name:   "ReportingFields",
+		envVar: "LOG_REPORTING_FIELDS",
+		isSet:  func(c *Config) bool { return len(c.ReportingFields) != 0 },
+		value:  func(c *Config) interface{} { return c.ReportingFields },
+	},
+	{
+		name:   "ReportingExcludedFields",
+		envVar: "LOG_REPORTING_EXCLUDED_FIELDS",
+		isSet:  func(c *Config) bool { return len(c.ReportingExcludedFields) != 0 },
+		value:  func(c *Config) interface{} { return c.ReportingExcludedFields },
+	},
+	{
+		name:   "MonitoringExcludedFields",
+		envVar: "LOG_MONITORING_EXCLUDED_FIELDS",
+		isSet:  func(c *Config) bool { return len(c.MonitoringExcludedFields) != 0 },
+		value:  func(c *Config) interface{} { return c.MonitoringExcludedFields },
+	},
+	{
+		name:   "LogFormat",
+		envVar: "LOG_FORMAT",
+		isSet:  func(c *Config) bool { return c.LogFormat != "" },
+		value:  func(c *Config) interface{} { return c.LogFormat },
+	},
+	{
+		name:   "SortFields",
+		envVar: "LOG_SORT_FIELDS",
+		isSet:  func(c *Config) bool { return c.SortFields != nil },
+		value:  func(c *Config) interface{} { return *c.SortFields },
+	},
+	{
+		name:   "GenerateTraceabilityID",
+		envVar: "LOG_GENERATE_TRACEABILITY_ID",
+		isSet:  func(c *Config) bool { return c.GenerateTraceabilityID != nil },
+		value:  func(c *Config) interface{} { return *c.GenerateTraceabilityID },
+	},
+	{
+		name:   "LogReportEvents",
+		envVar: "LOG_REPORT_EVENTS",
+		isSet:  func(c *Config) bool { return c.LogReportEvents != nil },
+		value:  func(c *Config) interface{} { return *c.LogReportEvents },
+	},
+	{
+		name:   "LinkCallers",
+		envVar: "LOG_LINK_CALLERS",
+		isSet:  func(c *Config) bool { return c.LinkCallers != nil },
+		value:  func(c *Config) interface{} { return *c.LinkCallers },
+	},
+	{
+		name:   "SourceURLTemplate",
+		envVar: "LOG_SOURCE_URL_TEMPLATE",
+		isSet:  func(c *Config) bool { return c.SourceURLTemplate != "" },
+		value:  func(c *Config) interface{} { return c.SourceURLTemplate },
+	},
+	{
+		name:   "SourceRevision",
+		envVar: "LOG_SOURCE_REVISION",
+		isSet:  func(c *Config) bool { return c.SourceRevision != "" },
+		value:  func(c *Config) interface{} { return c.SourceRevision },
+	},
+	{
+		name:   "SourceRoot",
+		envVar: "LOG_SOURCE_ROOT",
+		isSet:  func(c *Config) bool { return c.SourceRoot != "" },
+		value:  func(c *Config) interface{} { return c.SourceRoot },
+	},
+	{
+		name:   "CloudWatchLogGroup",
+		envVar: "LOG_CLOUDWATCH_GROUP",
+		isSet:  func(c *Config) bool { return c.CloudWatchLogGroup != "" },
+		value:  func(c *Config) interface{} { return c.CloudWatchLogGroup },
+	},
+	{
+		name:   "CloudWatchLogStream",
+		envVar: "LOG_CLOUDWATCH_STREAM",
+		isSet:  func(c *Config) bool { return c.CloudWatchLogStream != "" },
+		value:  func(c *Config) interface{} { return c.CloudWatchLogStream },
+	},
+	{
+		name:   "FilePath",
+		envVar: "LOG_FILE",
+		isSet:  func(c *Config) bool { return c.FilePath != "" },
+		value:  func(c *Config) interface{} { return c.FilePath },
+	},
+	{
+		name:   "FileMaxSize",
+		envVar: "LOG_FILE_MAX_SIZE",
+		isSet:  func(c *Config) bool { return c.FileMaxSize != 0 },
+		value:  func(c *Config) interface{} { return c.FileMaxSize },
+	},
+	{
+		name:   "FileMaxAge",
+		envVar: "LOG_FILE_MAX_AGE",
+		isSet:  func(c *Config) bool { return c.FileMaxAge != 0 },
+		value:  func(c *Config) interface{} { return c.FileMaxAge.String() },
+	},
+	{
+		name:   "FileMaxBackups",
+		envVar: "LOG_FILE_MAX_BACKUPS",
+		isSet:  func(c *Config) bool { return c.FileMaxBackups != 0 },
+		value:  func(c *Config) interface{} { return c.FileMaxBackups },
+	},
+	{
+		name:   "FileLevel",
+		envVar: "LOG_FILE_LEVEL",
+		isSet:  func(c *Config) bool { return c.FileLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.FileLevel) },
+	},
+	{
+		name:   "OTLPEndpoint",
+		envVar: "LOG_OTLP_ENDPOINT",
+		isSet:  func(c *Config) bool { return c.OTLPEndpoint != "" },
+		value:  func(c *Config) interface{} { return c.OTLPEndpoint },
+	},
+	{
+		// header values are often credentials, so only the names are shown
+		name:   "OTLPHeaders",
+		envVar: "LOG_OTLP_HEADERS",
+		isSet:  func(c *Config) bool { return len(c.OTLPHeaders) != 0 },
+		value:  func(c *Config) interface{} { return redactedValues(c.OTLPHeaders) },
+	},
+	{
+		name:   "OTLPInsecure",
+		envVar: "LOG_OTLP_INSECURE",
+		isSet:  func(c *Config) bool { return c.OTLPInsecure != nil },
+		value:  func(c *Config) interface{} { return *c.OTLPInsecure },
+	},
+	{
+		name:   "OTLPLevel",
+		envVar: "LOG_OTLP_LEVEL",
+		isSet:  func(c *Config) bool { return c.OTLPLevel != nil },
+		value:  func(c *Config) interface{} { return levelValue(c.OTLPLevel) },
+	},
+	{
+		name:   "EnableHostMetadata",
+		envVar: "LOG_HOST_METADATA",
+		isSet:  func(c *Config) bool { return c.EnableHostMetadata != nil },
+		value:  func(c *Config) interface{} { return *c.EnableHostMetadata },
+	},
+}
+
+// levelValue returns the name of an output's level, or an empty string if it isn't set
+func levelValue(l *Level) string {
+	if l == nil {
+		return ""
+	}
+	return l.String()
+}
+
+// redactedValues copies a map with each of its values redacted
+func redactedValues(m map[string]string) map[string]string {
+	r := make(map[string]string, len(m))
+	for k := range m {
+		r[k] = redacted
+	}
+	return r
+}
+
+// newConfigSnapshot builds the snapshot of the final config, using the config passed to NewLogger
+// and the environment to work out where each value came from. It follows the precedence of
+// mergeAndPopulateConfig, config values first, then the environment, then defaults.
+func newConfigSnapshot(input, final *Config) ConfigSnapshot {
+	if input == nil {
+		input = &Config{}
+	}
+
+	snapshot := make(ConfigSnapshot, len(configSettings))
+	for _, s := range configSettings {
+		v := ConfigValue{
+			Value:  s.value(final),
+			Source: ConfigSourceDefault,
+			EnvVar: s.envVar,
+		}
+
+		if s.isSet(input) {
+			v.Source = ConfigSourceConfig
+		} else if os.Getenv(s.envVar) != "" {
+			v.Source = ConfigSourceEnv
+		}
+
+		if s.secret && v.Value != "" {
+			v.Value = redacted
+		}
+
+		snapshot[s.name] = v
+	}
+
+	return snapshot
+}
+
+// ConfigSnapshot returns the effective config of the logger, and where each value came from.
+// Secret values are redacted.
+func (l *Logger) ConfigSnapshot() ConfigSnapshot {
+	snapshot := make(ConfigSnapshot, len(l.config))
+	for k, v := range l.config {
+		snapshot[k] = v
+	}
+	return snapshot
+}
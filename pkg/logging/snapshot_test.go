@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newConfigSnapshot(t *testing.T) {
+	os.Setenv("LOG_NAME", "envlogger")
+	defer os.Setenv("LOG_NAME", "")
+
+	input := &Config{
+		ServiceName: "fooservice",
+		HashSalt:    "supersecret",
+	}
+	final, err := mergeAndPopulateConfig(input)
+	require.NoError(t, err, "Expected no error creating config")
+
+	snapshot := newConfigSnapshot(input, final)
+
+	assert.Equal(t, ConfigValue{Value: "fooservice", Source: ConfigSourceConfig, EnvVar: "SERVICE_NAME"}, snapshot["ServiceName"], "Expected the service name from config")
+	assert.Equal(t, ConfigValue{Value: "envlogger", Source: ConfigSourceEnv, EnvVar: "LOG_NAME"}, snapshot["LoggerName"], "Expected the logger name from the environment")
+	assert.Equal(t, ConfigValue{Value: "info", Source: ConfigSourceDefault, EnvVar: "LOG_LEVEL"}, snapshot["LogLevel"], "Expected the default log level")
+	assert.Equal(t, ConfigValue{Value: true, Source: ConfigSourceDefault, EnvVar: "LOG_DISABLE_KINESIS"}, snapshot["DisableKinesis"], "Expected kinesis disabled by default")
+	assert.Equal(t, redacted, snapshot["HashSalt"].Value, "Expected the hash salt to be redacted")
+}
+
+func Test_newConfigSnapshotInfoLevel(t *testing.T) {
+	input := &Config{Profile: ProfileDevelopment, LogLevel: InfoLevel, LogLevelSet: true}
+	final, err := mergeAndPopulateConfig(input)
+	require.NoError(t, err, "Expected no error creating config")
+
+	snapshot := newConfigSnapshot(input, final)
+
+	assert.Equal(t, ConfigValue{Value: "info", Source: ConfigSourceConfig, EnvVar: "LOG_LEVEL"}, snapshot["LogLevel"], "Expected the info level from config")
+}
+
+func Test_newConfigSnapshotRedactsWebhookURL(t *testing.T) {
+	input := &Config{WebhookURL: "https://hooks.slack.com/services/T000/B000/token123"}
+	final, err := mergeAndPopulateConfig(input)
+	require.NoError(t, err, "Expected no error creating config")
+
+	snapshot := newConfigSnapshot(input, final)
+
+	assert.Equal(t, ConfigValue{Value: redacted, Source: ConfigSourceConfig, EnvVar: "LOG_WEBHOOK_URL"}, snapshot["WebhookURL"], "Expected the webhook URL to be redacted")
+	assert.NotContains(t, fmt.Sprintf("%v", snapshot), "token123", "Expected the webhook token not to be printed")
+}
+
+func Test_LoggerConfigSnapshot(t *testing.T) {
+	l, err := NewLogger(&Config{ServiceName: "fooservice"})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	snapshot := l.ConfigSnapshot()
+	assert.Equal(t, "fooservice", snapshot["ServiceName"].Value, "Expected the service name in the snapshot")
+	assert.Equal(t, "", snapshot["HashSalt"].Value, "Expected an unset secret to be shown as empty")
+
+	snapshot["ServiceName"] = ConfigValue{Value: "changed"}
+	assert.Equal(t, "fooservice", l.ConfigSnapshot()["ServiceName"].Value, "Expected the snapshot to be a copy")
+}
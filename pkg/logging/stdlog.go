@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StdLogger returns a standard library *log.Logger whose output is logged at the level, with the standard fields
+// and any fields accumulated on the logger, for code that only takes a standard logger such as
+// http.Server.ErrorLog or the AWS SDK. Each line written is one entry, with the trailing newline trimmed.
+// Levels above error are logged at error, so writing to the standard logger never panics or exits.
+func (l *Logger) StdLogger(level Level) *log.Logger {
+	if level > ErrorLevel {
+		level = ErrorLevel
+	}
+	return log.New(&stdLogWriter{logger: l, level: zapcore.Level(level)}, "", 0)
+}
+
+// stdLogWriter logs each write of a standard logger as an entry
+type stdLogWriter struct {
+	logger *Logger
+	level  zapcore.Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	// the caller is the line that called the standard logger, rather than the standard logger itself
+	zapL := w.logger.monitorLogger.WithOptions(zap.AddCallerSkip(2))
+	if ce := zapL.Check(w.level, string(bytes.TrimSuffix(p, []byte("\n")))); ce != nil {
+		ce.Write(w.logger.getZapFields()...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_LoggerStdLogger(t *testing.T) {
+	l, logs := NewObservedLogger(InfoLevel)
+	l = l.NewChild(&FieldOpts{CorrelationID: "corr-1"})
+
+	l.StdLogger(DebugLevel).Print("skipped")
+	l.StdLogger(WarnLevel).Printf("http: TLS handshake error from %s", "10.0.0.1")
+	l.StdLogger(FatalLevel).Println("fatal")
+
+	entries := logs.All()
+	require.Len(t, entries, 2, "Expected the disabled debug entry to be skipped")
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "http: TLS handshake error from 10.0.0.1", entries[0].Message, "Expected the trailing newline to be trimmed")
+	assert.Equal(t, "corr-1", entries[0].ContextMap()["correlationID"], "Expected the standard fields to be added")
+	assert.Equal(t, "stdlog_test.go", filepath.Base(entries[0].Caller.File), "Expected the caller to be the line that logged")
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level, "Expected levels above error to be logged at error")
+}
+
+func Test_LoggerStdLoggerCaller(t *testing.T) {
+	l, logs := NewObservedLogger(InfoLevel)
+	std := l.StdLogger(InfoLevel)
+
+	_, file, line, _ := runtime.Caller(0)
+	std.Print("print")
+	std.Printf("%s", "printf")
+	std.Println("println")
+
+	entries := logs.All()
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		assert.True(t, e.Caller.Defined, "Expected the caller of %s", e.Message)
+		assert.Equal(t, file, e.Caller.File, "Expected the caller of %s to be in the test", e.Message)
+		assert.Equal(t, line+1+i, e.Caller.Line, "Expected the caller of %s to be the line that called the standard logger", e.Message)
+	}
+}
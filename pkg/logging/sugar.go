@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Debugf formats the message like fmt.Sprintf and logs it at debug level output like Debug, with the
+// standard fields and any fields accumulated on the logger. The message is only formatted if the level is
+// enabled. Prefer Debug with fields on busy paths, formatted values can't be searched or aggregated.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.monitorLogger.Core().Enabled(zapcore.DebugLevel) {
+		l.monitorLogger.Debug(fmt.Sprintf(format, args...), l.getZapFields()...)
+	}
+}
+
+// Infof formats the message like fmt.Sprintf and logs it at info level output like Info
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if l.monitorLogger.Core().Enabled(zapcore.InfoLevel) {
+		l.monitorLogger.Info(fmt.Sprintf(format, args...), l.getZapFields()...)
+	}
+}
+
+// Warnf formats the message like fmt.Sprintf and logs it at warn level output like Warn
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.monitorLogger.Core().Enabled(zapcore.WarnLevel) {
+		l.monitorLogger.Warn(fmt.Sprintf(format, args...), l.getZapFields()...)
+	}
+}
+
+// Errorf formats the message like fmt.Sprintf and logs it at error level output like Error
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if l.monitorLogger.Core().Enabled(zapcore.ErrorLevel) {
+		l.monitorLogger.Error(fmt.Sprintf(format, args...), l.getZapFields()...)
+	}
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoggerFormatted(t *testing.T) {
+	l, logs := NewObservedLogger(InfoLevel)
+	l = l.NewChild(&FieldOpts{CorrelationID: "corr-1"})
+
+	l.Debugf("skipped %d", 1)
+	l.Infof("processed %d calls in %s", 3, "queue-a")
+	l.Warnf("retrying %s", "call-1")
+	l.Errorf("gave up on %s: %v", "call-1", assert.AnError)
+
+	entries := logs.All()
+	require.Len(t, entries, 3, "Expected the disabled debug entry to be skipped")
+	assert.Equal(t, "processed 3 calls in queue-a", entries[0].Message)
+	assert.Equal(t, "retrying call-1", entries[1].Message)
+	assert.Equal(t, "gave up on call-1: "+assert.AnError.Error(), entries[2].Message)
+	assert.Equal(t, "corr-1", entries[0].ContextMap()["correlationID"], "Expected the standard fields to be added")
+	assert.Equal(t, "sugar_test.go", filepath.Base(entries[0].Caller.File), "Expected the caller to be the line that logged")
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"time"
+
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestEntry is an entry recorded by a test logger
+type TestEntry struct {
+	Level   Level
+	Message string
+	Time    time.Time
+	// The fields of the entry, including the standard fields, by key
+	Fields map[string]interface{}
+}
+
+// TestLogs are the entries recorded by a test logger, in the order they were logged. They are safe for
+// concurrent use, so entries logged by the code under test on other goroutines are recorded too
+type TestLogs struct {
+	logs *observer.ObservedLogs
+}
+
+// NewTestLogger returns a logger that records every entry in memory instead of writing it out, so tests can
+// assert on what the code under test logged without depending on zap. Reports are recorded alongside the
+// other entries, and carry the reportID field
+func NewTestLogger() (*Logger, *TestLogs) {
+	l, logs := NewObservedLogger(DebugLevel)
+	return l, &TestLogs{logs: logs}
+}
+
+// Len returns the number of entries recorded
+func (t *TestLogs) Len() int {
+	return t.logs.Len()
+}
+
+// All returns every entry recorded
+func (t *TestLogs) All() []TestEntry {
+	return testEntries(t.logs.All())
+}
+
+// TakeAll returns every entry recorded and clears them, so the next assertion only sees new entries
+func (t *TestLogs) TakeAll() []TestEntry {
+	return testEntries(t.logs.TakeAll())
+}
+
+// FilterMessage returns the entries recorded with the message
+func (t *TestLogs) FilterMessage(message string) []TestEntry {
+	return testEntries(t.logs.FilterMessage(message).All())
+}
+
+// FilterLevel returns the entries recorded at or above the level
+func (t *TestLogs) FilterLevel(level Level) []TestEntry {
+	var entries []TestEntry
+	for _, e := range t.All() {
+		if e.Level >= level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// FilterField returns the entries recorded with the field set to the value. Numbers are recorded as
+// int64, uint64 or float64, so compare against one of them
+func (t *TestLogs) FilterField(key string, value interface{}) []TestEntry {
+	var entries []TestEntry
+	for _, e := range t.All() {
+		if v, ok := e.Fields[key]; ok && v == value {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func testEntries(logged []observer.LoggedEntry) []TestEntry {
+	entries := make([]TestEntry, len(logged))
+	for i, e := range logged {
+		entries[i] = TestEntry{
+			Level:   Level(e.Level),
+			Message: e.Message,
+			Time:    e.Time,
+			Fields:  e.ContextMap(),
+		}
+	}
+	return entries
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wrappedLogger is a decorator of a Logger, which the Logging interface must be implementable by
+type wrappedLogger struct {
+	*Logger
+}
+
+func Test_NewTestLogger(t *testing.T) {
+	l, logs := NewTestLogger()
+	l = l.NewChild(&FieldOpts{CorrelationID: "corr-1"})
+
+	l.Debug("starting")
+	l.Warn("retrying", String("callID", "call-1"))
+	l.Error("gave up", String("callID", "call-1"), Int64("attempts", 3))
+	reportID := l.Report("call ended")
+
+	assert.Equal(t, 4, logs.Len())
+	assert.Len(t, logs.FilterLevel(WarnLevel), 2)
+	assert.Len(t, logs.FilterField("callID", "call-1"), 2)
+	assert.Len(t, logs.FilterField("attempts", int64(3)), 1)
+
+	gaveUp := logs.FilterMessage("gave up")
+	require.Len(t, gaveUp, 1)
+	assert.Equal(t, ErrorLevel, gaveUp[0].Level)
+	assert.Equal(t, "corr-1", gaveUp[0].Fields["correlationID"], "Expected the standard fields to be recorded")
+
+	reports := logs.FilterField("reportID", reportID)
+	require.Len(t, reports, 1, "Expected reports to be recorded")
+	assert.Equal(t, "call ended", reports[0].Message)
+
+	assert.Len(t, logs.TakeAll(), 4)
+	assert.Equal(t, 0, logs.Len(), "Expected the taken entries to be cleared")
+
+	var wrapped Logging = wrappedLogger{l}
+	NewGRPCUnaryServerInterceptor(wrapped)
+	NewJaegerLogger(wrapped).Infof("reporting span %s", "span-1")
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "corr-1", entries[0].Fields["correlationID"], "Expected the wrapped logger's fields to be used")
+}
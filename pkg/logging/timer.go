@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// StopFunc stops a timer, logging its duration with the fields
+type StopFunc func(fields ...DataField)
+
+// Timer starts timing an operation. The StopFunc it returns logs the name as the message at info level, with
+// the elapsed time in the duration_ms field and the fields it is given. If a MetricsRegisterer is configured,
+// the duration is also recorded in logging_timer_duration_seconds, labeled with the name, whatever the level.
+//
+//	stop := logger.Timer("fetch call")
+//	call, err := store.GetCall(ctx, id)
+//	stop(logging.String("callID", id))
+//
+// The name is a metric label, so it must be a constant rather than include IDs.
+func (l *Logger) Timer(name string) StopFunc {
+	return l.timer(name, zapcore.InfoLevel)
+}
+
+// DebugTimer starts timing an operation like Timer, logging its duration at debug level
+func (l *Logger) DebugTimer(name string) StopFunc {
+	return l.timer(name, zapcore.DebugLevel)
+}
+
+func (l *Logger) timer(name string, level zapcore.Level) StopFunc {
+	start := time.Now()
+
+	return func(fields ...DataField) {
+		elapsed := time.Since(start)
+		l.fieldMetrics.observeTimer(name, elapsed)
+
+		f := l.getZapFields(append(fields[:len(fields):len(fields)], Int64("duration_ms", elapsed.Milliseconds()))...)
+		// the caller skip of the monitoring logger lands on the call to the StopFunc
+		if level == zapcore.DebugLevel {
+			l.monitorLogger.Debug(name, f...)
+		} else {
+			l.monitorLogger.Info(name, f...)
+		}
+	}
+}
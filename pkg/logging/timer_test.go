@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Timer(t *testing.T) {
+	l, logs := NewTestLogger()
+
+	stop := l.Timer("fetch call")
+	stop(String("callID", "call-1"))
+	l.DebugTimer("decode call")()
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "fetch call", entries[0].Message)
+	assert.Equal(t, InfoLevel, entries[0].Level)
+	assert.Equal(t, "call-1", entries[0].Fields["callID"])
+	assert.Contains(t, entries[0].Fields, "duration_ms")
+	assert.Equal(t, "decode call", entries[1].Message)
+	assert.Equal(t, DebugLevel, entries[1].Level)
+}
+
+func Test_TimerMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	l, err := NewLogger(&Config{LogLevel: InfoLevel, DisableKinesis: &trueVar, MetricsRegisterer: reg})
+	require.NoError(t, err, "Expected no error creating the logger")
+
+	l.Timer("fetch call")()
+	l.DebugTimer("fetch call")()
+
+	assert.Equal(t, 1, testutil.CollectAndCount(l.fieldMetrics.timers), "Expected one histogram per timer name")
+	assert.Contains(t, gatherHistogramCounts(t, reg), uint64(2), "Expected durations below the level to be recorded")
+
+	var nilMetrics *fieldMetrics
+	nilMetrics.observeTimer("fetch call", 0)
+}
+
+// gatherHistogramCounts returns the sample counts of the timer histograms in reg
+func gatherHistogramCounts(t *testing.T, reg *prometheus.Registry) []uint64 {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var counts []uint64
+	for _, f := range families {
+		if f.GetName() != "logging_timer_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			counts = append(counts, m.GetHistogram().GetSampleCount())
+		}
+	}
+	return counts
+}
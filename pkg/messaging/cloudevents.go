@@ -0,0 +1,285 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/caring/go-packages/v2/pkg/uuid"
+	"github.com/opentracing/opentracing-go"
+)
+
+// The CloudEvents content modes a CloudEvent can be published in
+const (
+	// CloudEventsStructured sends the whole event, attributes and data, as a JSON message body
+	CloudEventsStructured = "structured"
+	// CloudEventsBinary sends the data as the message body and each attribute as a ce_ prefixed message attribute
+	CloudEventsBinary = "binary"
+)
+
+// CloudEventsSpecVersion is the version of the CloudEvents spec events are published with
+const CloudEventsSpecVersion = "1.0"
+
+// cloudEventAttributePrefix prefixes the message attributes of an event published in binary mode
+const cloudEventAttributePrefix = "ce_"
+
+// CloudEvent is a CloudEvents 1.0 event, with the correlation ID and distributed tracing extensions
+type CloudEvent struct {
+	SpecVersion     string     `json:"specversion"`
+	ID              string     `json:"id"`
+	Source          string     `json:"source"`
+	Type            string     `json:"type"`
+	Subject         string     `json:"subject,omitempty"`
+	Time            *time.Time `json:"time,omitempty"`
+	DataContentType string     `json:"datacontenttype,omitempty"`
+	// The data of the event when it is JSON
+	Data json.RawMessage `json:"data,omitempty"`
+	// The data of the event when it isn't JSON
+	DataBase64 []byte `json:"data_base64,omitempty"`
+
+	// The correlation ID of the request the event was published while handling
+	CorrelationID string `json:"correlationid,omitempty"`
+	// The W3C trace context of the span the event was published in
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+	// The span context of the span the event was published in as the tracer propagates it, its TextMap keys URL
+	// encoded. Set on structured mode events, so tracers that don't propagate a W3C trace context, such as
+	// Jaeger's, link the consumer's span to the producer
+	TraceContext string `json:"tracecontext,omitempty"`
+}
+
+// NewCloudEvent returns an event with a new ID, the current time and JSON data. The correlation ID ctx
+// carries is added to the event
+func NewCloudEvent(ctx context.Context, source, eventType string, data json.RawMessage) *CloudEvent {
+	now := time.Now().UTC()
+	return &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              uuid.New().String(),
+		Source:          source,
+		Type:            eventType,
+		Time:            &now,
+		DataContentType: "application/json",
+		Data:            data,
+		CorrelationID:   logging.CorrelationIDFromContext(ctx),
+	}
+}
+
+// validate checks the event has the attributes the spec requires
+func (e *CloudEvent) validate() error {
+	if e.SpecVersion != CloudEventsSpecVersion {
+		return errors.Errorf("unsupported cloud events spec version: %q", e.SpecVersion)
+	}
+	if e.ID == "" || e.Source == "" || e.Type == "" {
+		return errors.New("cloud event must have an id, source and type")
+	}
+	return nil
+}
+
+// PublishCloudEvent publishes event to the topic in the structured or binary content mode. The span
+// context of the span in ctx is added as the event's trace extension. It is also sent, as the tracer
+// propagates it, in the message attributes in binary mode and as the tracecontext extension in structured
+// mode, both read by the consumer's span. Subscriptions must use raw message delivery for binary mode
+// events to reach SQS with their attributes.
+//
+// SQS drops messages with more than 10 attributes, so binary mode events should leave optional
+// attributes empty when the tracer propagates more than one key.
+func PublishCloudEvent(ctx context.Context, client snsiface.SNSAPI, tracer opentracing.Tracer, topicArn, mode string, event *CloudEvent) (string, error) {
+	if client == nil {
+		return "", errors.New("No SNS client input")
+	}
+	if event == nil {
+		return "", errors.New("No cloud event input")
+	}
+
+	trace := opentracing.TextMapCarrier{}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if tracer == nil {
+			tracer = opentracing.GlobalTracer()
+		}
+		if err := tracer.Inject(span.Context(), opentracing.TextMap, trace); err != nil {
+			return "", errors.Wrap(err, "unable to inject trace context")
+		}
+	}
+
+	e := *event
+	if e.SpecVersion == "" {
+		e.SpecVersion = CloudEventsSpecVersion
+	}
+	if e.TraceParent == "" {
+		e.TraceParent, e.TraceState = trace["traceparent"], trace["tracestate"]
+	}
+	if err := e.validate(); err != nil {
+		return "", err
+	}
+
+	input := &sns.PublishInput{
+		TopicArn:          aws.String(topicArn),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{},
+	}
+
+	switch mode {
+	case CloudEventsStructured:
+		if e.TraceContext == "" && len(trace) > 0 {
+			values := url.Values{}
+			for k, v := range trace {
+				values.Set(k, v)
+			}
+			e.TraceContext = values.Encode()
+		}
+		body, err := json.Marshal(e)
+		if err != nil {
+			return "", errors.Wrap(err, "unable to marshal cloud event")
+		}
+		input.Message = aws.String(string(body))
+	case CloudEventsBinary:
+		for k, v := range e.attributes() {
+			input.MessageAttributes[cloudEventAttributePrefix+k] = snsStringAttribute(v)
+		}
+		for k, v := range trace {
+			input.MessageAttributes[k] = snsStringAttribute(v)
+		}
+		if e.DataBase64 != nil {
+			input.Message = aws.String(string(e.DataBase64))
+		} else {
+			input.Message = aws.String(string(e.Data))
+		}
+	default:
+		return "", errors.Errorf("unrecognized cloud events mode: %q", mode)
+	}
+
+	out, err := client.PublishWithContext(ctx, input)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to publish cloud event")
+	}
+
+	return aws.StringValue(out.MessageId), nil
+}
+
+// attributes returns the attributes of the event that are set, by their name in the spec
+func (e *CloudEvent) attributes() map[string]string {
+	attrs := map[string]string{
+		"specversion":     e.SpecVersion,
+		"id":              e.ID,
+		"source":          e.Source,
+		"type":            e.Type,
+		"subject":         e.Subject,
+		"datacontenttype": e.DataContentType,
+		"correlationid":   e.CorrelationID,
+		"traceparent":     e.TraceParent,
+		"tracestate":      e.TraceState,
+	}
+	if e.Time != nil {
+		attrs["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+	for k, v := range attrs {
+		if v == "" {
+			delete(attrs, k)
+		}
+	}
+	return attrs
+}
+
+// ParseCloudEvent reads the CloudEvent a message carries, in binary mode when it has a ce_specversion
+// attribute and in structured mode otherwise
+func ParseCloudEvent(msg *sqs.Message) (*CloudEvent, error) {
+	if _, ok := msg.MessageAttributes[cloudEventAttributePrefix+"specversion"]; !ok {
+		e := &CloudEvent{}
+		if err := json.Unmarshal([]byte(aws.StringValue(msg.Body)), e); err != nil {
+			return nil, errors.Wrap(err, "unable to read structured cloud event")
+		}
+		if err := e.validate(); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	attr := func(name string) string {
+		if v := msg.MessageAttributes[cloudEventAttributePrefix+name]; v != nil {
+			return aws.StringValue(v.StringValue)
+		}
+		return ""
+	}
+	for k, v := range msg.MessageAttributes {
+		if strings.HasPrefix(k, cloudEventAttributePrefix) && (v == nil || v.StringValue == nil) {
+			return nil, errors.Errorf("cloud event attribute %s must be a string", k)
+		}
+	}
+
+	e := &CloudEvent{
+		SpecVersion:     attr("specversion"),
+		ID:              attr("id"),
+		Source:          attr("source"),
+		Type:            attr("type"),
+		Subject:         attr("subject"),
+		DataContentType: attr("datacontenttype"),
+		CorrelationID:   attr("correlationid"),
+		TraceParent:     attr("traceparent"),
+		TraceState:      attr("tracestate"),
+	}
+	if s := attr("time"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read cloud event time")
+		}
+		e.Time = &t
+	}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	body := aws.StringValue(msg.Body)
+	if json.Valid([]byte(body)) {
+		e.Data = json.RawMessage(body)
+	} else {
+		e.DataBase64 = []byte(body)
+	}
+
+	return e, nil
+}
+
+type cloudEventKey struct{}
+
+// CloudEventFromContext returns the event of the message being handled, when the consumer parses
+// messages as CloudEvents
+func CloudEventFromContext(ctx context.Context) (*CloudEvent, bool) {
+	e, ok := ctx.Value(cloudEventKey{}).(*CloudEvent)
+	return e, ok
+}
+
+// withCloudEvent returns a copy of ctx carrying the event and its correlation ID
+func withCloudEvent(ctx context.Context, e *CloudEvent) context.Context {
+	ctx = context.WithValue(ctx, cloudEventKey{}, e)
+	if e.CorrelationID != "" {
+		ctx = logging.ContextWithCorrelationID(ctx, e.CorrelationID)
+	}
+	return ctx
+}
+
+// traceContextCarrier returns the carrier of the span context in the tracecontext extension of the event
+func (e *CloudEvent) traceContextCarrier() (opentracing.TextMapCarrier, error) {
+	values, err := url.ParseQuery(e.TraceContext)
+	if err != nil {
+		return nil, err
+	}
+
+	carrier := opentracing.TextMapCarrier{}
+	for k := range values {
+		carrier[k] = values.Get(k)
+	}
+	return carrier, nil
+}
+
+func snsStringAttribute(s string) *sns.MessageAttributeValue {
+	return &sns.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(s),
+	}
+}
@@ -0,0 +1,243 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePublisher records the messages published to it
+type fakePublisher struct {
+	snsiface.SNSAPI
+
+	published []*sns.PublishInput
+}
+
+func (f *fakePublisher) PublishWithContext(ctx aws.Context, in *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	f.published = append(f.published, in)
+	return &sns.PublishOutput{MessageId: aws.String("published-1")}, nil
+}
+
+// delivered returns the message as SQS receives it from a subscription with raw message delivery
+func delivered(in *sns.PublishInput) *sqs.Message {
+	attrs := map[string]*sqs.MessageAttributeValue{}
+	for k, v := range in.MessageAttributes {
+		attrs[k] = &sqs.MessageAttributeValue{DataType: v.DataType, StringValue: v.StringValue}
+	}
+	return &sqs.Message{Body: in.Message, MessageAttributes: attrs}
+}
+
+func testCloudEvent() *CloudEvent {
+	at := time.Date(2021, 2, 3, 4, 5, 6, 7, time.UTC)
+	return &CloudEvent{
+		ID:              "event-1",
+		Source:          "/calls",
+		Type:            "com.caring.call.created",
+		Subject:         "call-42",
+		Time:            &at,
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"id":"call-42"}`),
+		CorrelationID:   "correlation-1",
+		TraceParent:     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+}
+
+func Test_PublishCloudEventStructured(t *testing.T) {
+	client := &fakePublisher{}
+	event := testCloudEvent()
+
+	id, err := PublishCloudEvent(context.Background(), client, nil, "arn:aws:sns:us-east-1:123:calls", CloudEventsStructured, event)
+	require.NoError(t, err)
+	assert.Equal(t, "published-1", id)
+	require.Len(t, client.published, 1)
+
+	in := client.published[0]
+	assert.Empty(t, in.MessageAttributes, "Expected structured events to carry every attribute in the body")
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(aws.StringValue(in.Message)), &body))
+	assert.Equal(t, "1.0", body["specversion"], "Expected the spec version to be set")
+	assert.Equal(t, "correlation-1", body["correlationid"])
+
+	parsed, err := ParseCloudEvent(delivered(in))
+	require.NoError(t, err)
+	event.SpecVersion = CloudEventsSpecVersion
+	assert.Equal(t, event, parsed)
+}
+
+func Test_PublishCloudEventBinary(t *testing.T) {
+	client := &fakePublisher{}
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("publish")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	event := testCloudEvent()
+
+	_, err := PublishCloudEvent(ctx, client, tracer, "arn:aws:sns:us-east-1:123:calls", CloudEventsBinary, event)
+	require.NoError(t, err)
+	require.Len(t, client.published, 1)
+
+	in := client.published[0]
+	assert.Equal(t, `{"id":"call-42"}`, aws.StringValue(in.Message), "Expected the data as the body")
+	assert.Equal(t, "com.caring.call.created", aws.StringValue(in.MessageAttributes["ce_type"].StringValue))
+	assert.Equal(t, "2021-02-03T04:05:06.000000007Z", aws.StringValue(in.MessageAttributes["ce_time"].StringValue))
+	assert.NotContains(t, in.MessageAttributes, "ce_tracestate", "Expected empty attributes to be left out")
+
+	producer, err := tracer.Extract(opentracing.TextMap, messageAttributesCarrier(delivered(in).MessageAttributes))
+	require.NoError(t, err, "Expected the span context in the message attributes")
+	assert.Equal(t, span.Context().(mocktracer.MockSpanContext).SpanID, producer.(mocktracer.MockSpanContext).SpanID)
+
+	parsed, err := ParseCloudEvent(delivered(in))
+	require.NoError(t, err)
+	event.SpecVersion = CloudEventsSpecVersion
+	assert.Equal(t, event, parsed)
+}
+
+func Test_PublishCloudEventBinaryNonJSONData(t *testing.T) {
+	client := &fakePublisher{}
+	event := testCloudEvent()
+	event.Data = nil
+	event.DataContentType = "text/plain"
+	event.DataBase64 = []byte("call created")
+
+	_, err := PublishCloudEvent(context.Background(), client, nil, "arn:aws:sns:us-east-1:123:calls", CloudEventsBinary, event)
+	require.NoError(t, err)
+
+	parsed, err := ParseCloudEvent(delivered(client.published[0]))
+	require.NoError(t, err)
+	assert.Nil(t, parsed.Data)
+	assert.Equal(t, []byte("call created"), parsed.DataBase64)
+}
+
+func Test_PublishCloudEventInvalid(t *testing.T) {
+	client := &fakePublisher{}
+
+	event := testCloudEvent()
+	event.Type = ""
+	_, err := PublishCloudEvent(context.Background(), client, nil, "arn", CloudEventsStructured, event)
+	assert.Error(t, err, "Expected an event without a type to be rejected")
+
+	_, err = PublishCloudEvent(context.Background(), client, nil, "arn", "batched", testCloudEvent())
+	assert.Error(t, err, "Expected an unknown mode to be rejected")
+
+	_, err = PublishCloudEvent(context.Background(), client, nil, "arn", CloudEventsStructured, nil)
+	assert.Error(t, err)
+	assert.Empty(t, client.published, "Expected nothing to be published")
+}
+
+func Test_ParseCloudEventInvalid(t *testing.T) {
+	cases := map[string]*sqs.Message{
+		"not JSON":     {Body: aws.String("call created")},
+		"spec version": {Body: aws.String(`{"specversion":"0.3","id":"1","source":"/calls","type":"created"}`)},
+		"binary without id": {
+			Body: aws.String("{}"),
+			MessageAttributes: map[string]*sqs.MessageAttributeValue{
+				"ce_specversion": stringAttribute("1.0"),
+				"ce_source":      stringAttribute("/calls"),
+				"ce_type":        stringAttribute("created"),
+			},
+		},
+		"binary attribute not a string": {
+			Body: aws.String("{}"),
+			MessageAttributes: map[string]*sqs.MessageAttributeValue{
+				"ce_specversion": stringAttribute("1.0"),
+				"ce_id":          {DataType: aws.String("Binary"), BinaryValue: []byte("1")},
+			},
+		},
+	}
+	for name, msg := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCloudEvent(msg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_ConsumerCloudEvents(t *testing.T) {
+	publisher := &fakePublisher{}
+	_, err := PublishCloudEvent(context.Background(), publisher, nil, "arn", CloudEventsBinary, testCloudEvent())
+	require.NoError(t, err)
+
+	client := newFakeSQS()
+	msg := delivered(publisher.published[0])
+	client.send(testQueueURL, aws.StringValue(msg.Body), msg.MessageAttributes)
+	client.send(testQueueURL, "not an event", nil)
+
+	type handled struct {
+		event         *CloudEvent
+		correlationID string
+	}
+	events := make(chan handled, 1)
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second, CloudEvents: true}, func(ctx context.Context, msg *sqs.Message) error {
+		e, _ := CloudEventFromContext(ctx)
+		events <- handled{event: e, correlationID: logging.CorrelationIDFromContext(ctx)}
+		return nil
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	h := <-events
+	for len(client.deletedHandles()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = c.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	require.NotNil(t, h.event, "Expected the handler context to carry the event")
+	assert.Equal(t, "event-1", h.event.ID)
+	assert.Equal(t, "correlation-1", h.correlationID, "Expected the handler context to carry the event's correlation ID")
+	assert.Len(t, events, 0, "Expected the message that isn't an event not to be handled")
+	assert.Equal(t, []string{"handle-0"}, client.deletedHandles(), "Expected the message that isn't an event to be left on the queue")
+}
+
+func Test_ConsumerCloudEventsStructuredTraceContext(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("publish")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	event := testCloudEvent()
+	event.TraceParent = ""
+
+	publisher := &fakePublisher{}
+	_, err := PublishCloudEvent(ctx, publisher, tracer, "arn", CloudEventsStructured, event)
+	require.NoError(t, err)
+	span.Finish()
+
+	client := newFakeSQS()
+	msg := delivered(publisher.published[0])
+	client.send(testQueueURL, aws.StringValue(msg.Body), msg.MessageAttributes)
+
+	handled := make(chan struct{}, 1)
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second, CloudEvents: true, Tracer: tracer}, func(ctx context.Context, msg *sqs.Message) error {
+		handled <- struct{}{}
+		return nil
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	<-handled
+	_, err = c.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	var consume *mocktracer.MockSpan
+	for _, s := range tracer.FinishedSpans() {
+		if s.OperationName == "sqs.consume" {
+			consume = s
+		}
+	}
+	require.NotNil(t, consume, "Expected the message to be handled in a span")
+	producer := span.Context().(mocktracer.MockSpanContext)
+	assert.Equal(t, producer.TraceID, consume.SpanContext.TraceID, "Expected the consumer's span in the producer's trace")
+	assert.Equal(t, producer.SpanID, consume.ParentID, "Expected the consumer's span to follow from the producer's")
+}
@@ -3,6 +3,7 @@ package messaging
 import (
 	"errors"
 	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
 	"os"
 )
 
@@ -18,6 +19,12 @@ type Config struct {
 	RoleArn string
 	// The instance of our own logger to use for logging traces
 	Logger *logging.Logger
+	// The retry policy of the client's calls to AWS, DefaultRetryPolicy when nil
+	Retry *RetryPolicy
+	// Guards the client with a circuit breaker when set, see CircuitBreaker
+	CircuitBreaker *CircuitBreakerConfig
+	// The registerer the circuit breaker metrics are registered with, nothing is recorded when nil
+	MetricsRegisterer prometheus.Registerer
 }
 
 func newDefaultConfig() *Config {
@@ -27,6 +34,8 @@ func newDefaultConfig() *Config {
 		SecretAccessKey: "",
 		RoleArn:         "",
 		Logger:          nil,
+		Retry:           &DefaultRetryPolicy,
+		CircuitBreaker:  nil,
 	}
 }
 
@@ -68,5 +77,11 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.SecretAccessKey = c.SecretAccessKey
 	}
 
+	if c.Retry != nil {
+		final.Retry = c.Retry
+	}
+	final.CircuitBreaker = c.CircuitBreaker
+	final.MetricsRegisterer = c.MetricsRegisterer
+
 	return final, nil
 }
@@ -0,0 +1,414 @@
+package messaging
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/opentracing/opentracing-go"
+)
+
+// Handler processes a single message received from a queue. If the handler returns nil
+// the message is deleted from the queue, otherwise it becomes visible again once its
+// visibility timeout expires and is redelivered. ctx carries the span the message is
+// handled in, which follows from the producer's span if it was sent with InjectTraceContext.
+type Handler func(ctx context.Context, msg *sqs.Message) error
+
+// ConsumerConfig contains initialization config for NewConsumer
+type ConsumerConfig struct {
+	// The URL of the queue to consume from
+	QueueURL string
+	// The maximum number of messages returned by each poll, between 1 and 10
+	MaxMessages int64
+	// How long each poll waits for messages to arrive before returning empty
+	WaitTime time.Duration
+	// The maximum number of messages handled at the same time
+	Concurrency int
+	// How long messages that are still being handled when the drain window
+	// expires are hidden from other consumers
+	ShutdownVisibilityExtension time.Duration
+	// The instance of our own logger to use for logging consumer events
+	Logger *logging.Logger
+	// The tracer used to start a span for each message. Defaults to the global tracer
+	Tracer opentracing.Tracer
+	// Parse each message as a CloudEvent in either content mode, which handlers read with
+	// CloudEventFromContext. Messages that aren't CloudEvents are left on the queue
+	CloudEvents bool
+}
+
+func newDefaultConsumerConfig() *ConsumerConfig {
+	return &ConsumerConfig{
+		QueueURL:                    "",
+		MaxMessages:                 10,
+		WaitTime:                    20 * time.Second,
+		Concurrency:                 10,
+		ShutdownVisibilityExtension: 30 * time.Second,
+		Logger:                      logging.NewNopLogger(),
+		Tracer:                      opentracing.GlobalTracer(),
+	}
+}
+
+// mergeConsumerConfig starts with a default consumer config and overwrites it
+// with any non 0 values from the config passed in
+func mergeConsumerConfig(c *ConsumerConfig) (*ConsumerConfig, error) {
+	final := newDefaultConsumerConfig()
+
+	if c.QueueURL == "" {
+		return nil, errors.New("No queue URL input")
+	}
+	final.QueueURL = c.QueueURL
+
+	if c.MaxMessages != 0 {
+		final.MaxMessages = c.MaxMessages
+	}
+	if c.WaitTime != 0 {
+		final.WaitTime = c.WaitTime
+	}
+	if c.Concurrency != 0 {
+		final.Concurrency = c.Concurrency
+	}
+	if c.ShutdownVisibilityExtension != 0 {
+		final.ShutdownVisibilityExtension = c.ShutdownVisibilityExtension
+	}
+	if c.Logger != nil {
+		final.Logger = c.Logger
+	}
+	if c.Tracer != nil {
+		final.Tracer = c.Tracer
+	}
+	final.CloudEvents = c.CloudEvents
+
+	return final, nil
+}
+
+// DrainStats reports what happened to the consumer's messages when it shut down
+type DrainStats struct {
+	// Messages whose handlers finished within the drain window
+	Completed int
+	// Messages still being handled when the drain window expired, their visibility was extended
+	Extended int
+	// Messages that were received but never handled, they were released back to the queue
+	Released int
+}
+
+// receiveFunc receives the next messages to handle and the URL of the queue they came from
+type receiveFunc func(ctx context.Context) (string, []*sqs.Message, error)
+
+// inFlightMessage is a message being handled and the queue it came from
+type inFlightMessage struct {
+	queueURL string
+	msg      *sqs.Message
+}
+
+// Consumer polls an SQS queue and hands each message to a Handler. A consumer is
+// started once with Start and stopped once with Shutdown.
+type Consumer struct {
+	client  sqsiface.SQSAPI
+	config  *ConsumerConfig
+	handler Handler
+	logger  *logging.Logger
+	// the logger of each queue, with its URL
+	queueLoggers map[string]*logging.Logger
+	receive      receiveFunc
+
+	slots          chan struct{}
+	wg             sync.WaitGroup
+	done           chan struct{}
+	handlerCtx     context.Context
+	cancelHandlers context.CancelFunc
+
+	mu       sync.Mutex
+	stopPoll context.CancelFunc
+	inFlight map[string]inFlightMessage
+	released int
+}
+
+// NewConsumer initializes a new consumer for the queue in config
+func NewConsumer(client sqsiface.SQSAPI, config *ConsumerConfig, handler Handler) (*Consumer, error) {
+	if client == nil {
+		return nil, errors.New("No SQS client input")
+	}
+	if handler == nil {
+		return nil, errors.New("No handler input")
+	}
+	if config == nil {
+		config = &ConsumerConfig{}
+	}
+
+	c, err := mergeConsumerConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := newConsumer(client, c, handler, []string{c.QueueURL})
+	consumer.logger = consumer.queueLoggers[c.QueueURL]
+	consumer.receive = func(ctx context.Context) (string, []*sqs.Message, error) {
+		msgs, err := consumer.receiveFrom(ctx, c.QueueURL, c.WaitTime)
+		return c.QueueURL, msgs, err
+	}
+
+	return consumer, nil
+}
+
+// newConsumer initializes the parts of a consumer shared by every way of receiving messages,
+// for the queues it consumes from
+func newConsumer(client sqsiface.SQSAPI, c *ConsumerConfig, handler Handler, queueURLs []string) *Consumer {
+	handlerCtx, cancel := context.WithCancel(context.Background())
+
+	queueLoggers := make(map[string]*logging.Logger, len(queueURLs))
+	for _, url := range queueURLs {
+		queueLoggers[url] = c.Logger.NewChild(nil, logging.String("queueURL", url))
+	}
+
+	return &Consumer{
+		client:         client,
+		config:         c,
+		handler:        handler,
+		logger:         c.Logger,
+		queueLoggers:   queueLoggers,
+		slots:          make(chan struct{}, c.Concurrency),
+		done:           make(chan struct{}),
+		handlerCtx:     handlerCtx,
+		cancelHandlers: cancel,
+		inFlight:       map[string]inFlightMessage{},
+	}
+}
+
+// receiveFrom polls the queue once, waiting up to wait for messages to arrive
+func (c *Consumer) receiveFrom(ctx context.Context, queueURL string, wait time.Duration) ([]*sqs.Message, error) {
+	out, err := c.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              aws.String(queueURL),
+		MaxNumberOfMessages:   aws.Int64(c.config.MaxMessages),
+		WaitTimeSeconds:       aws.Int64(int64(wait / time.Second)),
+		AttributeNames:        []*string{aws.String(sqs.QueueAttributeNameAll)},
+		MessageAttributeNames: []*string{aws.String(sqs.QueueAttributeNameAll)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Messages, nil
+}
+
+// Start polls the queue and dispatches messages to the handler until ctx is cancelled
+// or Shutdown is called. It blocks, so it is usually run in its own goroutine.
+func (c *Consumer) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.stopPoll != nil {
+		c.mu.Unlock()
+		return errors.New("consumer has already been started")
+	}
+	pollCtx, cancel := context.WithCancel(ctx)
+	c.stopPoll = cancel
+	c.mu.Unlock()
+
+	defer close(c.done)
+
+	for {
+		queueURL, msgs, err := c.receive(pollCtx)
+		if pollCtx.Err() != nil {
+			if err == nil {
+				c.release(queueURL, msgs)
+			}
+			return nil
+		}
+		if err != nil {
+			c.logger.Error("error receiving messages", logging.Error(err))
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-pollCtx.Done():
+				return nil
+			}
+		}
+
+		for i, msg := range msgs {
+			select {
+			case c.slots <- struct{}{}:
+				c.dispatch(queueURL, msg)
+			case <-pollCtx.Done():
+				c.release(queueURL, msgs[i:])
+				return nil
+			}
+		}
+	}
+}
+
+// Shutdown stops polling immediately, then waits for in-flight handlers to finish until
+// ctx expires. This is the drain window, so ctx should carry a deadline shorter than the
+// time left before the process is killed. Messages that are still being handled once the
+// window expires have their visibility extended, so that other consumers don't pick them
+// up while this one may still complete them, and their handler context is cancelled.
+// Messages that were received but never handled are released back to the queue.
+//
+// Shutdown matches the signature used by http.Server, so it can be registered directly
+// with a process-wide shutdown coordinator.
+func (c *Consumer) Shutdown(ctx context.Context) (DrainStats, error) {
+	c.mu.Lock()
+	stop := c.stopPoll
+	c.mu.Unlock()
+	if stop == nil {
+		return DrainStats{}, errors.New("consumer has not been started")
+	}
+
+	stop()
+	<-c.done
+
+	c.mu.Lock()
+	inFlight := len(c.inFlight)
+	c.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	remaining := map[string][]*sqs.Message{}
+	extended := 0
+	for _, m := range c.inFlight {
+		remaining[m.queueURL] = append(remaining[m.queueURL], m.msg)
+		extended++
+	}
+	stats := DrainStats{
+		Completed: inFlight - extended,
+		Extended:  extended,
+		Released:  c.released,
+	}
+	c.mu.Unlock()
+
+	var err error
+	for queueURL, msgs := range remaining {
+		if verr := c.changeVisibility(queueURL, msgs, c.config.ShutdownVisibilityExtension); verr != nil && err == nil {
+			err = verr
+		}
+	}
+	c.cancelHandlers()
+
+	c.logger.Info(
+		"consumer drained",
+		logging.Int64("completed", int64(stats.Completed)),
+		logging.Int64("extended", int64(stats.Extended)),
+		logging.Int64("released", int64(stats.Released)),
+	)
+
+	return stats, err
+}
+
+// dispatch runs the handler for msg in its own goroutine. A slot must be held before calling dispatch
+func (c *Consumer) dispatch(queueURL string, msg *sqs.Message) {
+	handle := aws.StringValue(msg.ReceiptHandle)
+	logger := c.queueLoggers[queueURL]
+
+	c.mu.Lock()
+	c.inFlight[handle] = inFlightMessage{queueURL: queueURL, msg: msg}
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() { <-c.slots }()
+		defer func() {
+			c.mu.Lock()
+			delete(c.inFlight, handle)
+			c.mu.Unlock()
+		}()
+
+		var (
+			event *CloudEvent
+			err   error
+		)
+		if c.config.CloudEvents {
+			event, err = ParseCloudEvent(msg)
+		}
+		ctx, finish := c.startMessageSpan(c.handlerCtx, queueURL, msg, event)
+		if err == nil {
+			if event != nil {
+				ctx = withCloudEvent(ctx, event)
+			}
+			err = c.handler(ctx, msg)
+		}
+		finish(err)
+
+		if err != nil {
+			logger.Error(
+				"error handling message",
+				logging.String("messageID", aws.StringValue(msg.MessageId)),
+				logging.Error(err),
+			)
+			return
+		}
+
+		_, err = c.client.DeleteMessageWithContext(context.Background(), &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(queueURL),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+		if err != nil {
+			logger.Error(
+				"error deleting message",
+				logging.String("messageID", aws.StringValue(msg.MessageId)),
+				logging.Error(err),
+			)
+		}
+	}()
+}
+
+// release makes messages that will not be handled visible to other consumers straight away
+func (c *Consumer) release(queueURL string, msgs []*sqs.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	if err := c.changeVisibility(queueURL, msgs, 0); err != nil {
+		c.queueLoggers[queueURL].Error("error releasing messages", logging.Error(err))
+	}
+
+	c.mu.Lock()
+	c.released += len(msgs)
+	c.mu.Unlock()
+}
+
+// changeVisibility sets the visibility timeout of msgs, in batches of the 10 entries SQS allows
+func (c *Consumer) changeVisibility(queueURL string, msgs []*sqs.Message, timeout time.Duration) error {
+	const maxBatch = 10
+
+	for start := 0; start < len(msgs); start += maxBatch {
+		end := start + maxBatch
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, 0, end-start)
+		for i, msg := range msgs[start:end] {
+			entries = append(entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(strconv.Itoa(i)),
+				ReceiptHandle:     msg.ReceiptHandle,
+				VisibilityTimeout: aws.Int64(int64(timeout / time.Second)),
+			})
+		}
+
+		out, err := c.client.ChangeMessageVisibilityBatchWithContext(context.Background(), &sqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to change message visibility")
+		}
+		if len(out.Failed) > 0 {
+			return errors.Errorf("unable to change visibility of %d messages", len(out.Failed))
+		}
+	}
+
+	return nil
+}
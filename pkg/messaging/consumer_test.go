@@ -0,0 +1,294 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveCall is a poll of a queue made with a fakeSQS
+type receiveCall struct {
+	queueURL string
+	wait     int64
+}
+
+// fakeSQS is an in memory SQS. Messages are handed out once, in the order they were sent, and
+// polls of an empty queue with a wait time block until a message arrives or their context is done
+type fakeSQS struct {
+	sqsiface.SQSAPI
+
+	mu            sync.Mutex
+	queues        map[string][]*sqs.Message
+	sent          int
+	receives      []receiveCall
+	deleted       []string
+	visibility    []*sqs.ChangeMessageVisibilityBatchInput
+	createdQueues []string
+	deletedQueues []string
+}
+
+func newFakeSQS() *fakeSQS {
+	return &fakeSQS{queues: map[string][]*sqs.Message{}}
+}
+
+// send adds a message to the queue, returning it as it will be received
+func (f *fakeSQS) send(queueURL, body string, attrs map[string]*sqs.MessageAttributeValue) *sqs.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := strconv.Itoa(f.sent)
+	f.sent++
+	msg := &sqs.Message{
+		MessageId:         aws.String("msg-" + id),
+		ReceiptHandle:     aws.String("handle-" + id),
+		Body:              aws.String(body),
+		MessageAttributes: attrs,
+	}
+	f.queues[queueURL] = append(f.queues[queueURL], msg)
+	return msg
+}
+
+func (f *fakeSQS) SendMessageWithContext(ctx aws.Context, in *sqs.SendMessageInput, _ ...request.Option) (*sqs.SendMessageOutput, error) {
+	msg := f.send(aws.StringValue(in.QueueUrl), aws.StringValue(in.MessageBody), in.MessageAttributes)
+	return &sqs.SendMessageOutput{MessageId: msg.MessageId}, nil
+}
+
+func (f *fakeSQS) ReceiveMessageWithContext(ctx aws.Context, in *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	url := aws.StringValue(in.QueueUrl)
+
+	f.mu.Lock()
+	f.receives = append(f.receives, receiveCall{queueURL: url, wait: aws.Int64Value(in.WaitTimeSeconds)})
+	f.mu.Unlock()
+
+	for {
+		f.mu.Lock()
+		msgs := f.queues[url]
+		n := int(aws.Int64Value(in.MaxNumberOfMessages))
+		if n > len(msgs) {
+			n = len(msgs)
+		}
+		f.queues[url] = msgs[n:]
+		f.mu.Unlock()
+
+		if n > 0 || aws.Int64Value(in.WaitTimeSeconds) == 0 {
+			return &sqs.ReceiveMessageOutput{Messages: msgs[:n]}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (f *fakeSQS) DeleteMessageWithContext(ctx aws.Context, in *sqs.DeleteMessageInput, _ ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, aws.StringValue(in.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibilityBatchWithContext(ctx aws.Context, in *sqs.ChangeMessageVisibilityBatchInput, _ ...request.Option) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.visibility = append(f.visibility, in)
+	return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func (f *fakeSQS) CreateQueue(in *sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	url := "https://sqs.test/" + aws.StringValue(in.QueueName)
+	f.createdQueues = append(f.createdQueues, url)
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String(url)}, nil
+}
+
+func (f *fakeSQS) DeleteQueueWithContext(ctx aws.Context, in *sqs.DeleteQueueInput, _ ...request.Option) (*sqs.DeleteQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deletedQueues = append(f.deletedQueues, aws.StringValue(in.QueueUrl))
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+func (f *fakeSQS) polls() []receiveCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]receiveCall(nil), f.receives...)
+}
+
+func (f *fakeSQS) deletedHandles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleted...)
+}
+
+// visibilityChanges returns the receipt handles and timeouts of each visibility batch
+func (f *fakeSQS) visibilityChanges() []map[string]int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var changes []map[string]int64
+	for _, in := range f.visibility {
+		batch := map[string]int64{}
+		for _, e := range in.Entries {
+			batch[aws.StringValue(e.ReceiptHandle)] = aws.Int64Value(e.VisibilityTimeout)
+		}
+		changes = append(changes, batch)
+	}
+	return changes
+}
+
+const testQueueURL = "https://sqs.test/calls"
+
+// startConsumer runs Start in its own goroutine, returning the channel its error is sent on
+func startConsumer(c *Consumer) chan error {
+	errc := make(chan error, 1)
+	go func() { errc <- c.Start(context.Background()) }()
+	return errc
+}
+
+func Test_ConsumerDeletesHandledMessages(t *testing.T) {
+	client := newFakeSQS()
+	client.send(testQueueURL, "ok", nil)
+	client.send(testQueueURL, "fail", nil)
+
+	handled := make(chan string, 2)
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second}, func(ctx context.Context, msg *sqs.Message) error {
+		defer func() { handled <- aws.StringValue(msg.Body) }()
+		if aws.StringValue(msg.Body) == "fail" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	<-handled
+	<-handled
+
+	_, err = c.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	assert.Equal(t, []string{"handle-0"}, client.deletedHandles(), "Expected only the handled message to be deleted")
+	assert.Empty(t, client.visibilityChanges(), "Expected the failed message to be left to its visibility timeout")
+}
+
+func Test_ConsumerShutdownDrains(t *testing.T) {
+	client := newFakeSQS()
+	client.send(testQueueURL, "one", nil)
+	client.send(testQueueURL, "two", nil)
+
+	started := make(chan struct{}, 2)
+	gate := make(chan struct{})
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second}, func(ctx context.Context, msg *sqs.Message) error {
+		started <- struct{}{}
+		<-gate
+		return nil
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	<-started
+	<-started
+
+	// the handlers finish within the drain window
+	time.AfterFunc(50*time.Millisecond, func() { close(gate) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stats, err := c.Shutdown(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	assert.Equal(t, DrainStats{Completed: 2}, stats)
+	assert.ElementsMatch(t, []string{"handle-0", "handle-1"}, client.deletedHandles(), "Expected drained messages to be deleted")
+	assert.Empty(t, client.visibilityChanges(), "Expected no visibility change when every handler finished")
+}
+
+func Test_ConsumerShutdownExtendsVisibility(t *testing.T) {
+	client := newFakeSQS()
+	for i := 0; i < 3; i++ {
+		client.send(testQueueURL, "call", nil)
+	}
+
+	started := make(chan struct{}, 1)
+	handlerErr := make(chan error, 1)
+	c, err := NewConsumer(client, &ConsumerConfig{
+		QueueURL:                    testQueueURL,
+		WaitTime:                    time.Second,
+		Concurrency:                 1,
+		ShutdownVisibilityExtension: 45 * time.Second,
+	}, func(ctx context.Context, msg *sqs.Message) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		handlerErr <- ctx.Err()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	<-started
+
+	// the handler outlives the drain window, and the messages waiting for its slot are never handled
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	stats, err := c.Shutdown(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	assert.Equal(t, DrainStats{Extended: 1, Released: 2}, stats)
+	assert.Equal(t, []map[string]int64{
+		{"handle-1": 0, "handle-2": 0},
+		{"handle-0": 45},
+	}, client.visibilityChanges(), "Expected the waiting messages released and the handled one extended")
+	assert.Equal(t, context.Canceled, <-handlerErr, "Expected the handler context to be cancelled after the extension")
+	assert.Empty(t, client.deletedHandles(), "Expected the unfinished message not to be deleted")
+}
+
+func Test_ConsumerChangeVisibilityBatches(t *testing.T) {
+	client := newFakeSQS()
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL}, func(context.Context, *sqs.Message) error { return nil })
+	require.NoError(t, err)
+
+	var msgs []*sqs.Message
+	for i := 0; i < 12; i++ {
+		msgs = append(msgs, &sqs.Message{ReceiptHandle: aws.String("handle-" + strconv.Itoa(i))})
+	}
+	require.NoError(t, c.changeVisibility(testQueueURL, msgs, 0))
+
+	require.Len(t, client.visibility, 2, "Expected the messages to be split into batches of 10")
+	assert.Len(t, client.visibility[0].Entries, 10)
+	assert.Len(t, client.visibility[1].Entries, 2)
+	assert.Equal(t, "0", aws.StringValue(client.visibility[1].Entries[0].Id), "Expected entry IDs to be unique within a batch")
+}
+
+func Test_ConsumerStartAndShutdownOnce(t *testing.T) {
+	client := newFakeSQS()
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second}, func(context.Context, *sqs.Message) error { return nil })
+	require.NoError(t, err)
+
+	_, err = c.Shutdown(context.Background())
+	assert.Error(t, err, "Expected shutting down a consumer that wasn't started to fail")
+
+	errc := startConsumer(c)
+	// wait for the first poll, so the consumer has started
+	for len(client.polls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Error(t, c.Start(context.Background()), "Expected starting a consumer twice to fail")
+
+	_, err = c.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+}
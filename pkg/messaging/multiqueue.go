@@ -0,0 +1,181 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/opentracing/opentracing-go"
+)
+
+// WeightedQueue is one of the queues a MultiQueueConsumer consumes from
+type WeightedQueue struct {
+	// The URL of the queue
+	URL string
+	// The share of polls the queue gets first pick of while every queue has messages waiting,
+	// relative to the weights of the other queues. Defaults to 1
+	Weight int
+}
+
+// MultiQueueConsumerConfig contains initialization config for NewMultiQueueConsumer
+type MultiQueueConsumerConfig struct {
+	// The queues to consume from, highest priority first
+	Queues []WeightedQueue
+	// The maximum number of messages returned by each poll, between 1 and 10
+	MaxMessages int64
+	// How long to wait for messages to arrive when every queue is empty. Queues are only
+	// long polled when all of them are empty, so this bounds how long a message arriving on
+	// a higher priority queue can wait behind the poll of a lower priority one
+	WaitTime time.Duration
+	// The maximum number of messages handled at the same time, across every queue
+	Concurrency int
+	// How long messages that are still being handled when the drain window
+	// expires are hidden from other consumers
+	ShutdownVisibilityExtension time.Duration
+	// The instance of our own logger to use for logging consumer events
+	Logger *logging.Logger
+	// The tracer used to start a span for each message. Defaults to the global tracer
+	Tracer opentracing.Tracer
+	// Parse each message as a CloudEvent in either content mode, which handlers read with
+	// CloudEventFromContext. Messages that aren't CloudEvents are left on the queue
+	CloudEvents bool
+}
+
+// MultiQueueConsumer polls a set of SQS queues with weighted priorities and hands each
+// message to a single Handler, since SQS has no message priorities of its own. A consumer
+// is started once with Start and stopped once with Shutdown.
+//
+// Each poll first picks a queue by weighted round robin, so a queue with weight 3 gets three
+// picks for every one a queue with weight 1 gets. If the picked queue is empty the others are
+// polled in priority order. High priority queues are drained first, while lower priority queues
+// are still guaranteed their share of polls and are never starved by a busy one.
+type MultiQueueConsumer struct {
+	*Consumer
+
+	queues []WeightedQueue
+
+	// the smooth weighted round robin state of each queue, guarded by pickMu
+	pickMu  sync.Mutex
+	current []int
+	total   int
+}
+
+// NewMultiQueueConsumer initializes a new consumer for the queues in config
+func NewMultiQueueConsumer(client sqsiface.SQSAPI, config *MultiQueueConsumerConfig, handler Handler) (*MultiQueueConsumer, error) {
+	if client == nil {
+		return nil, errors.New("No SQS client input")
+	}
+	if handler == nil {
+		return nil, errors.New("No handler input")
+	}
+	if config == nil || len(config.Queues) == 0 {
+		return nil, errors.New("No queues input")
+	}
+
+	queues := make([]WeightedQueue, len(config.Queues))
+	urls := make([]string, len(config.Queues))
+	seen := map[string]struct{}{}
+	total := 0
+	for i, q := range config.Queues {
+		if q.URL == "" {
+			return nil, errors.New("No queue URL input")
+		}
+		if _, ok := seen[q.URL]; ok {
+			return nil, errors.Errorf("Queue %s input more than once", q.URL)
+		}
+		seen[q.URL] = struct{}{}
+
+		if q.Weight < 0 {
+			return nil, errors.Errorf("Weight of queue %s can not be negative", q.URL)
+		}
+		if q.Weight == 0 {
+			q.Weight = 1
+		}
+
+		queues[i] = q
+		urls[i] = q.URL
+		total += q.Weight
+	}
+
+	// the queue URL only needs to be set to pass validation, each poll sets its own
+	c, err := mergeConsumerConfig(&ConsumerConfig{
+		QueueURL:                    urls[0],
+		MaxMessages:                 config.MaxMessages,
+		WaitTime:                    config.WaitTime,
+		Concurrency:                 config.Concurrency,
+		ShutdownVisibilityExtension: config.ShutdownVisibilityExtension,
+		Logger:                      config.Logger,
+		Tracer:                      config.Tracer,
+		CloudEvents:                 config.CloudEvents,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config.WaitTime == 0 {
+		c.WaitTime = time.Second
+	}
+
+	m := &MultiQueueConsumer{
+		Consumer: newConsumer(client, c, handler, urls),
+		queues:   queues,
+		current:  make([]int, len(queues)),
+		total:    total,
+	}
+	m.receive = m.receiveNext
+
+	return m, nil
+}
+
+// next returns the index of the queue with first pick of the next poll, by smooth weighted round robin.
+// Ties go to the higher priority queue.
+func (m *MultiQueueConsumer) next() int {
+	m.pickMu.Lock()
+	defer m.pickMu.Unlock()
+
+	best := 0
+	for i, q := range m.queues {
+		m.current[i] += q.Weight
+		if m.current[i] > m.current[best] {
+			best = i
+		}
+	}
+	m.current[best] -= m.total
+
+	return best
+}
+
+// receiveNext short polls the picked queue, then the others in priority order, and returns the first
+// messages received. If every queue is empty it long polls the picked queue.
+func (m *MultiQueueConsumer) receiveNext(ctx context.Context) (string, []*sqs.Message, error) {
+	picked := m.next()
+
+	order := make([]int, 0, len(m.queues))
+	order = append(order, picked)
+	for i := range m.queues {
+		if i != picked {
+			order = append(order, i)
+		}
+	}
+
+	for _, i := range order {
+		url := m.queues[i].URL
+		msgs, err := m.receiveFrom(ctx, url, 0)
+		if err != nil {
+			return url, nil, errors.Wrapf(err, "error receiving from queue %s", url)
+		}
+		if len(msgs) > 0 {
+			return url, msgs, nil
+		}
+	}
+
+	url := m.queues[picked].URL
+	msgs, err := m.receiveFrom(ctx, url, m.config.WaitTime)
+	if err != nil {
+		return url, nil, errors.Wrapf(err, "error receiving from queue %s", url)
+	}
+	return url, msgs, nil
+}
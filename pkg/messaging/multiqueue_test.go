@@ -0,0 +1,137 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHighQueueURL = "https://sqs.test/high"
+	testLowQueueURL  = "https://sqs.test/low"
+)
+
+func newTestMultiQueueConsumer(t *testing.T, client *fakeSQS) *MultiQueueConsumer {
+	t.Helper()
+
+	m, err := NewMultiQueueConsumer(client, &MultiQueueConsumerConfig{
+		Queues: []WeightedQueue{{URL: testHighQueueURL, Weight: 3}, {URL: testLowQueueURL}},
+	}, func(context.Context, *sqs.Message) error { return nil })
+	require.NoError(t, err)
+	return m
+}
+
+func Test_MultiQueueConsumerWeightedOrder(t *testing.T) {
+	m := newTestMultiQueueConsumer(t, newFakeSQS())
+
+	var picks []int
+	for i := 0; i < 8; i++ {
+		picks = append(picks, m.next())
+	}
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 0, 1, 0}, picks, "Expected three picks of the high queue for each of the low queue, spread out")
+}
+
+func Test_MultiQueueConsumerFallsBackToOtherQueues(t *testing.T) {
+	client := newFakeSQS()
+	m := newTestMultiQueueConsumer(t, client)
+	client.send(testLowQueueURL, "low", nil)
+
+	url, msgs, err := m.receiveNext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, testLowQueueURL, url)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "low", aws.StringValue(msgs[0].Body))
+	assert.Equal(t, []receiveCall{
+		{queueURL: testHighQueueURL, wait: 0},
+		{queueURL: testLowQueueURL, wait: 0},
+	}, client.polls(), "Expected the picked queue to be short polled before the others")
+}
+
+func Test_MultiQueueConsumerLongPollsWhenEmpty(t *testing.T) {
+	client := newFakeSQS()
+	m := newTestMultiQueueConsumer(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := m.receiveNext(ctx)
+	assert.Error(t, err, "Expected the long poll to end with its context")
+	assert.Equal(t, []receiveCall{
+		{queueURL: testHighQueueURL, wait: 0},
+		{queueURL: testLowQueueURL, wait: 0},
+		{queueURL: testHighQueueURL, wait: 1},
+	}, client.polls(), "Expected the picked queue to be long polled once every queue was empty")
+}
+
+func Test_MultiQueueConsumerDrainsHighPriorityFirst(t *testing.T) {
+	client := newFakeSQS()
+	m := newTestMultiQueueConsumer(t, client)
+	m.config.MaxMessages = 1
+	for i := 0; i < 4; i++ {
+		client.send(testHighQueueURL, "high", nil)
+		client.send(testLowQueueURL, "low", nil)
+	}
+
+	var received []string
+	for i := 0; i < 8; i++ {
+		_, msgs, err := m.receiveNext(context.Background())
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		received = append(received, aws.StringValue(msgs[0].Body))
+	}
+	assert.Equal(t, []string{"high", "high", "low", "high", "high", "low", "low", "low"}, received,
+		"Expected the low queue to get its share while the high queue has messages, and the rest once it is empty")
+}
+
+func Test_NewMultiQueueConsumerInvalidConfig(t *testing.T) {
+	handler := func(context.Context, *sqs.Message) error { return nil }
+	cases := map[string][]WeightedQueue{
+		"no queues":       nil,
+		"empty URL":       {{URL: ""}},
+		"duplicate queue": {{URL: testHighQueueURL}, {URL: testHighQueueURL}},
+		"negative weight": {{URL: testHighQueueURL, Weight: -1}},
+	}
+	for name, queues := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMultiQueueConsumer(newFakeSQS(), &MultiQueueConsumerConfig{Queues: queues}, handler)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_MultiQueueConsumerTagsSpanWithQueue(t *testing.T) {
+	tracer := mocktracer.New()
+	client := newFakeSQS()
+	client.send(testLowQueueURL, "low", nil)
+
+	handled := make(chan struct{}, 1)
+	m, err := NewMultiQueueConsumer(client, &MultiQueueConsumerConfig{
+		Queues:   []WeightedQueue{{URL: testHighQueueURL, Weight: 3}, {URL: testLowQueueURL}},
+		WaitTime: time.Second,
+		Tracer:   tracer,
+	}, func(context.Context, *sqs.Message) error {
+		handled <- struct{}{}
+		return nil
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(m.Consumer)
+	<-handled
+	_, err = m.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	var consume *mocktracer.MockSpan
+	for _, s := range tracer.FinishedSpans() {
+		if s.OperationName == "sqs.consume" {
+			consume = s
+		}
+	}
+	require.NotNil(t, consume, "Expected the message to be handled in a span")
+	assert.Equal(t, "low", consume.Tag("queue.name"), "Expected the span tagged with the queue the message came from")
+}
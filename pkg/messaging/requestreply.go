@@ -0,0 +1,358 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/caring/go-packages/v2/pkg/uuid"
+	"github.com/opentracing/opentracing-go"
+)
+
+// The message attributes a request carries, so the responder knows where to send the reply and
+// the requester can match the reply to its request
+const (
+	ReplyToAttribute       = "ReplyTo"
+	CorrelationIDAttribute = "CorrelationID"
+)
+
+// ErrRequestTimeout is returned by Request when no reply arrives before the request times out
+var ErrRequestTimeout = errors.New("timed out waiting for a reply")
+
+// deleteReplyQueueTimeout bounds deleting the temporary reply queue once the context of Shutdown is done
+const deleteReplyQueueTimeout = 5 * time.Second
+
+// RequestReplyConfig contains initialization config for NewRequestReply
+type RequestReplyConfig struct {
+	// The URL of the queue requests are sent to
+	RequestQueueURL string
+	// The URL of the queue replies are received from. If empty, a temporary queue is created
+	// for this client and deleted when it shuts down
+	ReplyQueueURL string
+	// The prefix of the name of the temporary reply queue, the rest is a random ID
+	TemporaryQueuePrefix string
+	// How long Request waits for a reply, unless its context expires first
+	Timeout time.Duration
+	// How long each poll of the reply queue waits for messages to arrive before returning empty
+	WaitTime time.Duration
+	// The instance of our own logger to use for logging request reply events
+	Logger *logging.Logger
+	// The tracer whose span context is sent with each request. Defaults to the global tracer
+	Tracer opentracing.Tracer
+}
+
+func newDefaultRequestReplyConfig() *RequestReplyConfig {
+	return &RequestReplyConfig{
+		RequestQueueURL:      "",
+		ReplyQueueURL:        "",
+		TemporaryQueuePrefix: "reply-",
+		Timeout:              30 * time.Second,
+		WaitTime:             20 * time.Second,
+		Logger:               logging.NewNopLogger(),
+		Tracer:               opentracing.GlobalTracer(),
+	}
+}
+
+// mergeRequestReplyConfig starts with a default request reply config and overwrites it
+// with any non 0 values from the config passed in
+func mergeRequestReplyConfig(c *RequestReplyConfig) (*RequestReplyConfig, error) {
+	final := newDefaultRequestReplyConfig()
+
+	if c.RequestQueueURL == "" {
+		return nil, errors.New("No request queue URL input")
+	}
+	final.RequestQueueURL = c.RequestQueueURL
+
+	if c.ReplyQueueURL != "" {
+		final.ReplyQueueURL = c.ReplyQueueURL
+	}
+	if c.TemporaryQueuePrefix != "" {
+		final.TemporaryQueuePrefix = c.TemporaryQueuePrefix
+	}
+	if c.Timeout != 0 {
+		final.Timeout = c.Timeout
+	}
+	if c.WaitTime != 0 {
+		final.WaitTime = c.WaitTime
+	}
+	if c.Logger != nil {
+		final.Logger = c.Logger
+	}
+	if c.Tracer != nil {
+		final.Tracer = c.Tracer
+	}
+
+	return final, nil
+}
+
+// RequestReply sends requests to a queue and waits for their replies, for integrations that
+// can't expose gRPC. Replies are received by a dispatcher that is started once with Start and
+// stopped once with Shutdown.
+//
+// A shared reply queue must only be read by one RequestReply. Replies that match no waiting request,
+// e.g. because they arrived after their request timed out, are logged and deleted.
+type RequestReply struct {
+	client    sqsiface.SQSAPI
+	config    *RequestReplyConfig
+	logger    *logging.Logger
+	temporary bool
+
+	done chan struct{}
+
+	mu       sync.Mutex
+	stopPoll context.CancelFunc
+	shutdown bool
+	waiting  map[string]chan *sqs.Message
+}
+
+// NewRequestReply initializes a new request reply client for the queues in config, creating the
+// temporary reply queue if no reply queue is configured
+func NewRequestReply(client sqsiface.SQSAPI, config *RequestReplyConfig) (*RequestReply, error) {
+	if client == nil {
+		return nil, errors.New("No SQS client input")
+	}
+	if config == nil {
+		config = &RequestReplyConfig{}
+	}
+
+	c, err := mergeRequestReplyConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RequestReply{
+		client:  client,
+		config:  c,
+		done:    make(chan struct{}),
+		waiting: map[string]chan *sqs.Message{},
+	}
+
+	if c.ReplyQueueURL == "" {
+		out, err := client.CreateQueue(&sqs.CreateQueueInput{
+			QueueName: aws.String(c.TemporaryQueuePrefix + uuid.New().String()),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create the temporary reply queue")
+		}
+		c.ReplyQueueURL = aws.StringValue(out.QueueUrl)
+		r.temporary = true
+	}
+
+	r.logger = c.Logger.NewChild(nil, logging.String("replyQueueURL", c.ReplyQueueURL))
+
+	return r, nil
+}
+
+// Request sends body to the request queue and waits for the reply. It returns ErrRequestTimeout
+// if no reply arrives before the configured timeout, or the error of ctx if it expires first.
+// attrs are sent with the request and may be nil.
+func (r *RequestReply) Request(ctx context.Context, body string, attrs map[string]*sqs.MessageAttributeValue) (*sqs.Message, error) {
+	correlationID := uuid.New().String()
+
+	reply := make(chan *sqs.Message, 1)
+	r.mu.Lock()
+	r.waiting[correlationID] = reply
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.waiting, correlationID)
+		r.mu.Unlock()
+	}()
+
+	msgAttrs := make(map[string]*sqs.MessageAttributeValue, len(attrs)+2)
+	for k, v := range attrs {
+		msgAttrs[k] = v
+	}
+	msgAttrs[ReplyToAttribute] = stringAttribute(r.config.ReplyQueueURL)
+	msgAttrs[CorrelationIDAttribute] = stringAttribute(correlationID)
+
+	if err := InjectTraceContext(ctx, r.config.Tracer, msgAttrs); err != nil {
+		r.logger.Warn("unable to inject trace context", logging.String("error", err.Error()))
+	}
+
+	_, err := r.client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(r.config.RequestQueueURL),
+		MessageBody:       aws.String(body),
+		MessageAttributes: msgAttrs,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to send request")
+	}
+
+	timer := time.NewTimer(r.config.Timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-reply:
+		return msg, nil
+	case <-timer.C:
+		return nil, ErrRequestTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reply sends body as the reply to request, to the queue and with the correlation ID the request
+// carries. It is used by the responder, usually from a consumer's Handler.
+func Reply(ctx context.Context, client sqsiface.SQSAPI, request *sqs.Message, body string) error {
+	replyTo := request.MessageAttributes[ReplyToAttribute]
+	correlationID := request.MessageAttributes[CorrelationIDAttribute]
+	if replyTo == nil || correlationID == nil {
+		return errors.New("message is not a request, it has no reply to queue or correlation ID")
+	}
+
+	_, err := client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:    replyTo.StringValue,
+		MessageBody: aws.String(body),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			CorrelationIDAttribute: correlationID,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to send reply")
+	}
+
+	return nil
+}
+
+// Start polls the reply queue and hands each reply to the request waiting for it until ctx is
+// cancelled or Shutdown is called. It blocks, so it is usually run in its own goroutine.
+func (r *RequestReply) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.stopPoll != nil {
+		r.mu.Unlock()
+		return errors.New("request reply has already been started")
+	}
+	if r.shutdown {
+		r.mu.Unlock()
+		return errors.New("request reply has been shut down")
+	}
+	pollCtx, cancel := context.WithCancel(ctx)
+	r.stopPoll = cancel
+	r.mu.Unlock()
+
+	defer close(r.done)
+
+	for {
+		out, err := r.client.ReceiveMessageWithContext(pollCtx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(r.config.ReplyQueueURL),
+			MaxNumberOfMessages:   aws.Int64(10),
+			WaitTimeSeconds:       aws.Int64(int64(r.config.WaitTime / time.Second)),
+			MessageAttributeNames: []*string{aws.String(CorrelationIDAttribute)},
+		})
+		if pollCtx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			r.logger.Error("error receiving replies", logging.String("error", err.Error()))
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-pollCtx.Done():
+				return nil
+			}
+		}
+
+		for _, msg := range out.Messages {
+			r.deliver(msg)
+		}
+	}
+}
+
+// Shutdown stops polling the reply queue, and deletes it if it is temporary, whether or not Start
+// was called. Requests still waiting for a reply time out. If ctx is done before polling stops,
+// the temporary queue is still deleted and the error of ctx is returned. Shutdown matches the
+// signature used by http.Server, so it can be registered directly with a process-wide shutdown
+// coordinator.
+func (r *RequestReply) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	stop := r.stopPoll
+	r.shutdown = true
+	r.mu.Unlock()
+
+	var drainErr error
+	if stop != nil {
+		stop()
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			drainErr = ctx.Err()
+		}
+	}
+
+	if !r.temporary {
+		return drainErr
+	}
+
+	// deleted with a context of its own once ctx is done, so the queue isn't left behind
+	deleteCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		deleteCtx, cancel = context.WithTimeout(context.Background(), deleteReplyQueueTimeout)
+		defer cancel()
+	}
+	_, err := r.client.DeleteQueueWithContext(deleteCtx, &sqs.DeleteQueueInput{
+		QueueUrl: aws.String(r.config.ReplyQueueURL),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "unable to delete the temporary reply queue")
+		if drainErr == nil {
+			return err
+		}
+		r.logger.Error("error deleting the temporary reply queue", logging.Error(err))
+	}
+
+	return drainErr
+}
+
+// deliver hands a reply to the request waiting for it and deletes it from the reply queue
+func (r *RequestReply) deliver(msg *sqs.Message) {
+	var correlationID string
+	if attr := msg.MessageAttributes[CorrelationIDAttribute]; attr != nil {
+		correlationID = aws.StringValue(attr.StringValue)
+	}
+
+	r.mu.Lock()
+	reply, ok := r.waiting[correlationID]
+	r.mu.Unlock()
+
+	if ok {
+		// the request only takes the first reply, duplicates are dropped
+		select {
+		case reply <- msg:
+		default:
+		}
+	} else {
+		r.logger.Warn(
+			"discarding reply that matches no waiting request",
+			logging.String("messageID", aws.StringValue(msg.MessageId)),
+			logging.String("correlationID", correlationID),
+		)
+	}
+
+	_, err := r.client.DeleteMessageWithContext(context.Background(), &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(r.config.ReplyQueueURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	})
+	if err != nil {
+		r.logger.Error(
+			"error deleting reply",
+			logging.String("messageID", aws.StringValue(msg.MessageId)),
+			logging.String("error", err.Error()),
+		)
+	}
+}
+
+// stringAttribute returns a string message attribute with value s
+func stringAttribute(s string) *sqs.MessageAttributeValue {
+	return &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(s),
+	}
+}
@@ -0,0 +1,171 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRequestQueueURL = "https://sqs.test/requests"
+
+// startRequestReply runs Start in its own goroutine, returning the channel its error is sent on
+func startRequestReply(r *RequestReply) chan error {
+	errc := make(chan error, 1)
+	go func() { errc <- r.Start(context.Background()) }()
+	return errc
+}
+
+// respond replies to the next request on the request queue with the reply body, returning the request
+func respond(t *testing.T, client *fakeSQS, reply string) chan *sqs.Message {
+	requests := make(chan *sqs.Message, 1)
+	go func() {
+		out, err := client.ReceiveMessageWithContext(context.Background(), &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(testRequestQueueURL),
+			MaxNumberOfMessages: aws.Int64(1),
+			WaitTimeSeconds:     aws.Int64(1),
+		})
+		if err != nil || len(out.Messages) == 0 {
+			close(requests)
+			return
+		}
+		if err := Reply(context.Background(), client, out.Messages[0], reply); err != nil {
+			t.Errorf("Expected the reply to be sent: %v", err)
+		}
+		requests <- out.Messages[0]
+	}()
+	return requests
+}
+
+func Test_RequestReply(t *testing.T) {
+	client := newFakeSQS()
+	r, err := NewRequestReply(client, &RequestReplyConfig{RequestQueueURL: testRequestQueueURL, WaitTime: time.Second})
+	require.NoError(t, err)
+
+	require.Len(t, client.createdQueues, 1, "Expected a temporary reply queue to be created")
+	replyQueueURL := client.createdQueues[0]
+	assert.True(t, strings.HasPrefix(replyQueueURL, "https://sqs.test/reply-"), "Expected the temporary queue name to have the prefix")
+
+	errc := startRequestReply(r)
+	requests := respond(t, client, "pong")
+
+	reply, err := r.Request(context.Background(), "ping", map[string]*sqs.MessageAttributeValue{"Kind": stringAttribute("ping")})
+	require.NoError(t, err)
+	assert.Equal(t, "pong", aws.StringValue(reply.Body))
+
+	request := <-requests
+	require.NotNil(t, request)
+	assert.Equal(t, "ping", aws.StringValue(request.Body))
+	assert.Equal(t, replyQueueURL, aws.StringValue(request.MessageAttributes[ReplyToAttribute].StringValue))
+	assert.Equal(t, "ping", aws.StringValue(request.MessageAttributes["Kind"].StringValue), "Expected the request attributes to be sent")
+	assert.Equal(t,
+		aws.StringValue(request.MessageAttributes[CorrelationIDAttribute].StringValue),
+		aws.StringValue(reply.MessageAttributes[CorrelationIDAttribute].StringValue),
+		"Expected the reply to carry the correlation ID of the request",
+	)
+
+	require.NoError(t, r.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+	assert.Equal(t, []string{aws.StringValue(reply.ReceiptHandle)}, client.deletedHandles(), "Expected the reply to be deleted")
+	assert.Equal(t, []string{replyQueueURL}, client.deletedQueues, "Expected the temporary reply queue to be deleted")
+}
+
+func Test_RequestReplySharedQueue(t *testing.T) {
+	client := newFakeSQS()
+	r, err := NewRequestReply(client, &RequestReplyConfig{
+		RequestQueueURL: testRequestQueueURL,
+		ReplyQueueURL:   "https://sqs.test/replies",
+		WaitTime:        time.Second,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, client.createdQueues, "Expected no queue to be created when a reply queue is configured")
+
+	errc := startRequestReply(r)
+	for len(client.polls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, r.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+	assert.Empty(t, client.deletedQueues, "Expected a configured reply queue not to be deleted")
+}
+
+func Test_RequestReplyTimeout(t *testing.T) {
+	client := newFakeSQS()
+	r, err := NewRequestReply(client, &RequestReplyConfig{
+		RequestQueueURL: testRequestQueueURL,
+		Timeout:         20 * time.Millisecond,
+		WaitTime:        time.Second,
+	})
+	require.NoError(t, err)
+	errc := startRequestReply(r)
+	for len(client.polls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err = r.Request(context.Background(), "ping", nil)
+	assert.Equal(t, ErrRequestTimeout, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = r.Request(ctx, "ping", nil)
+	assert.Equal(t, context.Canceled, err, "Expected the error of the request context when it is done first")
+
+	require.NoError(t, r.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+}
+
+func Test_RequestReplyDiscardsUnmatchedReplies(t *testing.T) {
+	client := newFakeSQS()
+	r, err := NewRequestReply(client, &RequestReplyConfig{RequestQueueURL: testRequestQueueURL, WaitTime: time.Second})
+	require.NoError(t, err)
+
+	// a reply that arrives after its request timed out
+	stale := client.send(r.config.ReplyQueueURL, "late", map[string]*sqs.MessageAttributeValue{
+		CorrelationIDAttribute: stringAttribute("timed-out"),
+	})
+
+	errc := startRequestReply(r)
+	for len(client.deletedHandles()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, r.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+
+	assert.Equal(t, []string{aws.StringValue(stale.ReceiptHandle)}, client.deletedHandles(), "Expected the unmatched reply to be deleted")
+}
+
+func Test_ReplyToNonRequest(t *testing.T) {
+	err := Reply(context.Background(), newFakeSQS(), &sqs.Message{Body: aws.String("ping")}, "pong")
+	assert.Error(t, err, "Expected replying to a message without a reply to queue to fail")
+}
+
+func Test_RequestReplyShutdownBeforeStart(t *testing.T) {
+	client := newFakeSQS()
+	r, err := NewRequestReply(client, &RequestReplyConfig{RequestQueueURL: testRequestQueueURL})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Shutdown(context.Background()))
+	assert.Equal(t, client.createdQueues, client.deletedQueues, "Expected the temporary reply queue to be deleted")
+	assert.Error(t, r.Start(context.Background()), "Expected Start to fail once shut down")
+}
+
+func Test_RequestReplyShutdownDeadline(t *testing.T) {
+	client := newFakeSQS()
+	r, err := NewRequestReply(client, &RequestReplyConfig{RequestQueueURL: testRequestQueueURL, WaitTime: time.Second})
+	require.NoError(t, err)
+
+	// Start never stops, as if it was stuck delivering a reply
+	r.mu.Lock()
+	r.stopPoll = func() {}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, r.Shutdown(ctx), "Expected the deadline to expire while polling stops")
+	assert.Equal(t, client.createdQueues, client.deletedQueues, "Expected the temporary reply queue to be deleted anyway")
+}
@@ -0,0 +1,293 @@
+package messaging
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrCircuitOpen is returned by the calls of a client whose circuit breaker is open, without calling AWS
+var ErrCircuitOpen = errors.New("messaging: circuit breaker is open")
+
+// RetryPolicy is the retry policy of the AWS calls of a client. Failed calls are retried with exponential backoff
+// and full jitter, throttled calls, e.g. with the Throttling or TooManyRequestsException codes, with the throttle
+// delays. The delays are kept short by default, as events are often published on request paths
+type RetryPolicy struct {
+	// The number of times a failed call is retried
+	MaxRetries int
+	// The delay before the first retry, doubled for each retry after it up to MaxDelay
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// The delays of retries of throttled calls
+	MinThrottleDelay time.Duration
+	MaxThrottleDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of the clients created by NewSNS and NewSQS unless the config sets one
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:       3,
+	MinDelay:         30 * time.Millisecond,
+	MaxDelay:         time.Second,
+	MinThrottleDelay: 100 * time.Millisecond,
+	MaxThrottleDelay: 2 * time.Second,
+}
+
+// Retryer returns the retryer following the policy, for the Retryer of an aws.Config, so clients created
+// elsewhere, e.g. EventBridge clients, can share the policy
+func (p RetryPolicy) Retryer() request.Retryer {
+	return client.DefaultRetryer{
+		NumMaxRetries:    p.MaxRetries,
+		MinRetryDelay:    p.MinDelay,
+		MaxRetryDelay:    p.MaxDelay,
+		MinThrottleDelay: p.MinThrottleDelay,
+		MaxThrottleDelay: p.MaxThrottleDelay,
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breaker of a client
+type CircuitBreakerConfig struct {
+	// The name of the client in the metrics, defaults to the name of the AWS service, e.g. sns
+	Name string
+	// The number of calls in a row that fail, after their retries, that open the circuit. Only throttling,
+	// server and connection errors count as failures. Defaults to 5
+	FailureThreshold int
+	// How long the circuit stays open before a trial call is let through. If it succeeds the circuit is
+	// closed, otherwise it opens again. Defaults to 30s
+	OpenTimeout time.Duration
+}
+
+// The states of a circuit breaker, as reported by the state metric
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker stops the calls of a client to AWS after repeated failures, failing them straight away with
+// ErrCircuitOpen, so a throttled or unavailable service doesn't hold up every caller for the length of its
+// retries. Its state is reported in the messaging_circuit_breaker_state metric, 0 closed, 1 open and
+// 2 half open, and the calls it failed in messaging_circuit_breaker_rejected_total.
+type CircuitBreaker struct {
+	config  CircuitBreakerConfig
+	metrics *breakerMetrics
+	now     func() time.Time
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker returns a closed circuit breaker, registering its metrics with reg, which may be nil.
+// Attach it to the clients it guards
+func NewCircuitBreaker(config CircuitBreakerConfig, reg prometheus.Registerer) (*CircuitBreaker, error) {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+
+	metrics, err := newBreakerMetrics(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CircuitBreaker{
+		config:  config,
+		metrics: metrics,
+		now:     time.Now,
+	}, nil
+}
+
+// Attach guards the calls of the client with the breaker, e.g. snsClient.Client
+func (b *CircuitBreaker) Attach(c *client.Client) {
+	if b.config.Name == "" {
+		b.config.Name = c.ServiceName
+	}
+	b.metrics.setState(b.config.Name, circuitClosed)
+
+	c.Handlers.Validate.PushFrontNamed(request.NamedHandler{
+		Name: "messaging.CircuitBreakerGate",
+		Fn: func(r *request.Request) {
+			if !b.allow() {
+				r.Error = ErrCircuitOpen
+			}
+		},
+	})
+	c.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "messaging.CircuitBreakerRecord",
+		Fn: func(r *request.Request) {
+			if r.Error == ErrCircuitOpen {
+				return
+			}
+			if isCanceled(r.Error) {
+				b.canceled()
+				return
+			}
+			b.metrics.retried(b.config.Name, r.RetryCount)
+			b.record(r.Error != nil && isFailure(r))
+		},
+	})
+}
+
+// allow reports whether a call may go ahead, letting a trial call through once the open timeout has passed
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+			b.setState(circuitHalfOpen)
+			return true
+		}
+	case circuitHalfOpen:
+		// a trial call is in flight
+	default:
+		return true
+	}
+
+	b.metrics.rejected(b.config.Name)
+	return false
+}
+
+// record records the outcome of a call, closing the circuit on success and opening it once enough calls in a
+// row failed, or the trial call failed
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(circuitOpen)
+	}
+}
+
+// canceled releases the trial call of a half open circuit when it was canceled by its context, which says
+// nothing about the service, so the next call is let through as the trial instead. Otherwise the circuit
+// would stay half open, failing every call, as no trial call would ever be recorded
+func (b *CircuitBreaker) canceled() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitHalfOpen {
+		b.openedAt = b.now().Add(-b.config.OpenTimeout)
+		b.setState(circuitOpen)
+	}
+}
+
+// setState changes the state, must be called with the lock held
+func (b *CircuitBreaker) setState(state int) {
+	if b.state != state {
+		b.state = state
+		b.metrics.setState(b.config.Name, state)
+	}
+}
+
+// isFailure reports whether the error of a call means the service is struggling, rather than that the call
+// was invalid, e.g. a missing queue
+func isFailure(r *request.Request) bool {
+	if r.IsErrorThrottle() || r.IsErrorRetryable() {
+		return true
+	}
+	return r.HTTPResponse != nil && r.HTTPResponse.StatusCode >= 500
+}
+
+// isCanceled reports whether the call was canceled by its context, which says nothing about the service
+func isCanceled(err error) bool {
+	type coder interface{ Code() string }
+	c, ok := err.(coder)
+	return ok && c.Code() == request.CanceledErrorCode
+}
+
+// breakerMetrics are the metrics of the circuit breakers, labeled by client
+type breakerMetrics struct {
+	states  *prometheus.GaugeVec
+	rejects *prometheus.CounterVec
+	retries *prometheus.CounterVec
+}
+
+// newBreakerMetrics registers the circuit breaker metrics with reg. Metrics already registered by another
+// breaker are shared with it. It returns nil if reg is nil, so nothing is recorded
+func newBreakerMetrics(reg prometheus.Registerer) (*breakerMetrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+
+	m := &breakerMetrics{
+		states: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "messaging",
+			Name:      "circuit_breaker_state",
+			Help:      "State of the circuit breaker of the client, 0 closed, 1 open and 2 half open",
+		}, []string{"client"}),
+		rejects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "messaging",
+			Name:      "circuit_breaker_rejected_total",
+			Help:      "Number of calls failed by the open circuit breaker of the client without calling AWS",
+		}, []string{"client"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "messaging",
+			Name:      "aws_retries_total",
+			Help:      "Number of times the calls of the client to AWS were retried",
+		}, []string{"client"}),
+	}
+
+	if err := reg.Register(m.states); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		m.states = are.ExistingCollector.(*prometheus.GaugeVec)
+	}
+	var err error
+	if m.rejects, err = registerCounterVec(reg, m.rejects); err != nil {
+		return nil, err
+	}
+	if m.retries, err = registerCounterVec(reg, m.retries); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// registerCounterVec registers the counter with reg, returning the one already registered if there is one
+func registerCounterVec(reg prometheus.Registerer, c *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	if err := reg.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		return are.ExistingCollector.(*prometheus.CounterVec), nil
+	}
+	return c, nil
+}
+
+func (m *breakerMetrics) setState(name string, state int) {
+	if m != nil {
+		m.states.WithLabelValues(name).Set(float64(state))
+	}
+}
+
+func (m *breakerMetrics) rejected(name string) {
+	if m != nil {
+		m.rejects.WithLabelValues(name).Inc()
+	}
+}
+
+func (m *breakerMetrics) retried(name string, n int) {
+	if m != nil && n > 0 {
+		m.retries.WithLabelValues(name).Add(float64(n))
+	}
+}
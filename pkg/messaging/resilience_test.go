@@ -0,0 +1,211 @@
+package messaging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sqsDeleted     = `<DeleteMessageResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DeleteMessageResponse>`
+	sqsNoQueue     = `<ErrorResponse><Error><Type>Sender</Type><Code>AWS.SimpleQueueService.NonExistentQueue</Code><Message>no queue</Message></Error><RequestId>1</RequestId></ErrorResponse>`
+	sqsUnavailable = `<ErrorResponse><Error><Type>Receiver</Type><Code>InternalError</Code><Message>unavailable</Message></Error><RequestId>1</RequestId></ErrorResponse>`
+)
+
+// fakeSQSServer answers every call with the status and body it is set to, counting the calls
+type fakeSQSServer struct {
+	*httptest.Server
+
+	calls  int32
+	status int32
+	body   atomic.Value
+}
+
+func newFakeSQSServer() *fakeSQSServer {
+	s := &fakeSQSServer{}
+	s.set(http.StatusOK, sqsDeleted)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&s.status)))
+		w.Write([]byte(s.body.Load().(string)))
+	}))
+	return s
+}
+
+func (s *fakeSQSServer) set(status int, body string) {
+	atomic.StoreInt32(&s.status, int32(status))
+	s.body.Store(body)
+}
+
+// newTestSQSClient returns an SQS client calling the server, retrying following the policy
+func newTestSQSClient(t *testing.T, url string, policy RetryPolicy) *sqs.SQS {
+	t.Helper()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(url),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		Retryer:     policy.Retryer(),
+	})
+	require.NoError(t, err)
+	return sqs.New(sess)
+}
+
+func deleteMessage(c *sqs.SQS) error {
+	_, err := c.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("https://sqs.test/calls"),
+		ReceiptHandle: aws.String("handle-0"),
+	})
+	return err
+}
+
+var fastRetries = RetryPolicy{
+	MaxRetries:       2,
+	MinDelay:         time.Millisecond,
+	MaxDelay:         time.Millisecond,
+	MinThrottleDelay: time.Millisecond,
+	MaxThrottleDelay: time.Millisecond,
+}
+
+func Test_RetryPolicyRetryer(t *testing.T) {
+	r, ok := DefaultRetryPolicy.Retryer().(client.DefaultRetryer)
+	require.True(t, ok)
+	assert.Equal(t, client.DefaultRetryer{
+		NumMaxRetries:    3,
+		MinRetryDelay:    30 * time.Millisecond,
+		MaxRetryDelay:    time.Second,
+		MinThrottleDelay: 100 * time.Millisecond,
+		MaxThrottleDelay: 2 * time.Second,
+	}, r)
+}
+
+func Test_CircuitBreaker(t *testing.T) {
+	server := newFakeSQSServer()
+	defer server.Close()
+	c := newTestSQSClient(t, server.URL, fastRetries)
+
+	reg := prometheus.NewRegistry()
+	breaker, err := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}, reg)
+	require.NoError(t, err)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	breaker.Attach(c.Client)
+	state := func() float64 { return testutil.ToFloat64(breaker.metrics.states.WithLabelValues("sqs")) }
+
+	require.NoError(t, deleteMessage(c))
+
+	server.set(http.StatusInternalServerError, sqsUnavailable)
+	assert.Error(t, deleteMessage(c))
+	assert.Equal(t, float64(circuitClosed), state(), "Expected one failure not to open the circuit")
+	assert.Error(t, deleteMessage(c))
+	assert.Equal(t, float64(circuitOpen), state(), "Expected the circuit to open after the failure threshold")
+	assert.EqualValues(t, 7, atomic.LoadInt32(&server.calls), "Expected each failed call to be retried")
+	assert.Equal(t, 4.0, testutil.ToFloat64(breaker.metrics.retries.WithLabelValues("sqs")))
+
+	server.set(http.StatusOK, sqsDeleted)
+	assert.Equal(t, ErrCircuitOpen, deleteMessage(c))
+	assert.EqualValues(t, 7, atomic.LoadInt32(&server.calls), "Expected the open circuit not to call AWS")
+	assert.Equal(t, 1.0, testutil.ToFloat64(breaker.metrics.rejects.WithLabelValues("sqs")))
+
+	// the trial call once the open timeout has passed closes the circuit
+	now = now.Add(time.Minute)
+	require.NoError(t, deleteMessage(c))
+	assert.Equal(t, float64(circuitClosed), state())
+	require.NoError(t, deleteMessage(c))
+}
+
+func Test_CircuitBreakerFailedTrial(t *testing.T) {
+	server := newFakeSQSServer()
+	defer server.Close()
+	c := newTestSQSClient(t, server.URL, RetryPolicy{})
+
+	breaker, err := NewCircuitBreaker(CircuitBreakerConfig{Name: "calls", FailureThreshold: 1, OpenTimeout: time.Minute}, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	breaker.Attach(c.Client)
+
+	server.set(http.StatusInternalServerError, sqsUnavailable)
+	assert.Error(t, deleteMessage(c))
+	assert.Equal(t, ErrCircuitOpen, deleteMessage(c))
+
+	// a failed trial call opens the circuit again for another open timeout
+	now = now.Add(time.Minute)
+	assert.Error(t, deleteMessage(c))
+	assert.Equal(t, ErrCircuitOpen, deleteMessage(c))
+	now = now.Add(time.Minute - time.Second)
+	assert.Equal(t, ErrCircuitOpen, deleteMessage(c))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.calls))
+}
+
+func Test_CircuitBreakerCanceledTrial(t *testing.T) {
+	server := newFakeSQSServer()
+	defer server.Close()
+	c := newTestSQSClient(t, server.URL, RetryPolicy{})
+
+	breaker, err := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	breaker.Attach(c.Client)
+
+	server.set(http.StatusInternalServerError, sqsUnavailable)
+	assert.Error(t, deleteMessage(c))
+	server.set(http.StatusOK, sqsDeleted)
+
+	// the trial call is canceled before it reaches AWS
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("https://sqs.test/calls"),
+		ReceiptHandle: aws.String("handle-0"),
+	})
+	require.Error(t, err)
+	assert.NotEqual(t, ErrCircuitOpen, err)
+
+	// so the next call is let through as the trial, and closes the circuit
+	require.NoError(t, deleteMessage(c), "Expected the canceled trial not to leave the circuit half open")
+	require.NoError(t, deleteMessage(c))
+}
+
+func Test_CircuitBreakerIgnoresClientErrors(t *testing.T) {
+	server := newFakeSQSServer()
+	defer server.Close()
+	c := newTestSQSClient(t, server.URL, fastRetries)
+
+	breaker, err := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1}, nil)
+	require.NoError(t, err)
+	breaker.Attach(c.Client)
+
+	server.set(http.StatusBadRequest, sqsNoQueue)
+	assert.Error(t, deleteMessage(c))
+	assert.Error(t, deleteMessage(c))
+	assert.NotEqual(t, ErrCircuitOpen, deleteMessage(c), "Expected invalid calls not to open the circuit")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&server.calls), "Expected invalid calls not to be retried")
+}
+
+func Test_NewCircuitBreakerSharesMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	a, err := NewCircuitBreaker(CircuitBreakerConfig{Name: "sns"}, reg)
+	require.NoError(t, err)
+	b, err := NewCircuitBreaker(CircuitBreakerConfig{Name: "sqs"}, reg)
+	require.NoError(t, err, "Expected a second breaker to share the registered metrics")
+
+	assert.Equal(t, a.metrics.states, b.metrics.states)
+	assert.Equal(t, 5, a.config.FailureThreshold, "Expected the default failure threshold")
+	assert.Equal(t, 30*time.Second, a.config.OpenTimeout, "Expected the default open timeout")
+}
@@ -0,0 +1,210 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+)
+
+// ErrTopicNotFound is returned by Resolver.TopicArn when no topic has the name
+var ErrTopicNotFound = errors.New("topic not found")
+
+// GetQueueURL returns the URL of the queue with the name
+func GetQueueURL(client sqsiface.SQSAPI, queue string) (string, error) {
+	return getQueueURL(context.Background(), client, queue)
+}
+
+func getQueueURL(ctx context.Context, client sqsiface.SQSAPI, queue string) (string, error) {
+	if queue == "" {
+		return "", errors.New("Invalid empty parameter queue")
+	}
+
+	out, err := client.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{
+		QueueName: aws.String(queue),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Error executing sqs.GetQueueUrl")
+	}
+	return aws.StringValue(out.QueueUrl), nil
+}
+
+// ResolverConfig contains initialization config for NewResolver
+type ResolverConfig struct {
+	// The client topic ARNs are resolved with. Topics can't be resolved when nil
+	SNS snsiface.SNSAPI
+	// The client queue URLs are resolved with. Queues can't be resolved when nil
+	SQS sqsiface.SQSAPI
+	// How long resolved ARNs and URLs are cached for
+	TTL time.Duration
+}
+
+func newDefaultResolverConfig() *ResolverConfig {
+	return &ResolverConfig{
+		SNS: nil,
+		SQS: nil,
+		TTL: 15 * time.Minute,
+	}
+}
+
+// mergeResolverConfig starts with a default resolver config and overwrites it
+// with any non 0 values from the config passed in
+func mergeResolverConfig(c *ResolverConfig) (*ResolverConfig, error) {
+	final := newDefaultResolverConfig()
+
+	if c.SNS == nil && c.SQS == nil {
+		return nil, errors.New("No SNS or SQS client input")
+	}
+	final.SNS = c.SNS
+	final.SQS = c.SQS
+
+	if c.TTL != 0 {
+		final.TTL = c.TTL
+	}
+
+	return final, nil
+}
+
+// resolved is a cached ARN or URL
+type resolved struct {
+	value   string
+	expires time.Time
+}
+
+// Resolver resolves topic names to ARNs and queue names to URLs, caching them so hot publish paths
+// don't call the AWS APIs, and their rate limits, on every message. Resolving a topic lists every topic,
+// so all of them are cached at once. Cached values are resolved again once the TTL passes, or after they
+// are invalidated, e.g. when a publish fails because a topic was recreated. A Resolver is safe for
+// concurrent use.
+type Resolver struct {
+	config *ResolverConfig
+	now    func() time.Time
+
+	mu     sync.Mutex
+	topics map[string]resolved
+	queues map[string]resolved
+}
+
+// NewResolver initializes a new resolver for the clients in config
+func NewResolver(config *ResolverConfig) (*Resolver, error) {
+	if config == nil {
+		config = &ResolverConfig{}
+	}
+
+	c, err := mergeResolverConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{
+		config: c,
+		now:    time.Now,
+		topics: map[string]resolved{},
+		queues: map[string]resolved{},
+	}, nil
+}
+
+// TopicArn returns the ARN of the topic with the name, from the cache unless it has expired
+func (r *Resolver) TopicArn(ctx context.Context, topic string) (string, error) {
+	if topic == "" {
+		return "", errors.New("Invalid empty parameter topic")
+	}
+	if r.config.SNS == nil {
+		return "", errors.New("No SNS client to resolve topics with")
+	}
+	if arn, ok := r.cached(r.topics, topic); ok {
+		return arn, nil
+	}
+
+	arns := map[string]string{}
+	err := r.config.SNS.ListTopicsPagesWithContext(ctx, &sns.ListTopicsInput{}, func(out *sns.ListTopicsOutput, last bool) bool {
+		for _, t := range out.Topics {
+			arn := aws.StringValue(t.TopicArn)
+			arns[arn[strings.LastIndex(arn, ":")+1:]] = arn
+		}
+		return true
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Error executing sns.ListTopics")
+	}
+
+	r.mu.Lock()
+	expires := r.now().Add(r.config.TTL)
+	for name, arn := range arns {
+		r.topics[name] = resolved{value: arn, expires: expires}
+	}
+	r.mu.Unlock()
+
+	arn, ok := arns[topic]
+	if !ok {
+		return "", errors.Wrapf(ErrTopicNotFound, "Error resolving topic %s", topic)
+	}
+	return arn, nil
+}
+
+// QueueURL returns the URL of the queue with the name, from the cache unless it has expired
+func (r *Resolver) QueueURL(ctx context.Context, queue string) (string, error) {
+	if r.config.SQS == nil {
+		return "", errors.New("No SQS client to resolve queues with")
+	}
+	if url, ok := r.cached(r.queues, queue); ok {
+		return url, nil
+	}
+
+	url, err := getQueueURL(ctx, r.config.SQS, queue)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.queues[queue] = resolved{value: url, expires: r.now().Add(r.config.TTL)}
+	r.mu.Unlock()
+
+	return url, nil
+}
+
+// InvalidateTopic drops the cached ARN of the topic, so it is resolved again the next time it is needed
+func (r *Resolver) InvalidateTopic(topic string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.topics, topic)
+}
+
+// InvalidateQueue drops the cached URL of the queue, so it is resolved again the next time it is needed
+func (r *Resolver) InvalidateQueue(queue string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.queues, queue)
+}
+
+// InvalidateAll drops every cached ARN and URL
+func (r *Resolver) InvalidateAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// cleared in place, the maps are read without the lock held by the callers of cached
+	for name := range r.topics {
+		delete(r.topics, name)
+	}
+	for name := range r.queues {
+		delete(r.queues, name)
+	}
+}
+
+// cached returns the cached value of the name, if it hasn't expired
+func (r *Resolver) cached(cache map[string]resolved, name string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := cache[name]
+	if !ok || !r.now().Before(v.expires) {
+		return "", false
+	}
+	return v.value, true
+}
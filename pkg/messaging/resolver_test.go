@@ -0,0 +1,150 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTopicLister lists its topics one page at a time, counting the listings
+type fakeTopicLister struct {
+	snsiface.SNSAPI
+
+	pages    [][]string
+	listings int
+}
+
+func (f *fakeTopicLister) ListTopicsPagesWithContext(ctx aws.Context, in *sns.ListTopicsInput, fn func(*sns.ListTopicsOutput, bool) bool, _ ...request.Option) error {
+	f.listings++
+	for i, page := range f.pages {
+		out := &sns.ListTopicsOutput{}
+		for _, arn := range page {
+			out.Topics = append(out.Topics, &sns.Topic{TopicArn: aws.String(arn)})
+		}
+		if !fn(out, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// fakeQueueLookup resolves queue names to URLs, counting the lookups
+type fakeQueueLookup struct {
+	sqsiface.SQSAPI
+
+	lookups int
+}
+
+func (f *fakeQueueLookup) GetQueueUrlWithContext(ctx aws.Context, in *sqs.GetQueueUrlInput, _ ...request.Option) (*sqs.GetQueueUrlOutput, error) {
+	f.lookups++
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String("https://sqs.test/" + aws.StringValue(in.QueueName))}, nil
+}
+
+// newTestResolver returns a resolver for the clients whose clock is advanced by moving the time now points to
+func newTestResolver(t *testing.T, topics *fakeTopicLister, queues *fakeQueueLookup, now *time.Time) *Resolver {
+	t.Helper()
+
+	r, err := NewResolver(&ResolverConfig{SNS: topics, SQS: queues, TTL: time.Minute})
+	require.NoError(t, err)
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func Test_ResolverTopicArn(t *testing.T) {
+	topics := &fakeTopicLister{pages: [][]string{
+		{"arn:aws:sns:us-east-1:123:calls"},
+		{"arn:aws:sns:us-east-1:123:texts"},
+	}}
+	now := time.Now()
+	r := newTestResolver(t, topics, &fakeQueueLookup{}, &now)
+
+	arn, err := r.TopicArn(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:us-east-1:123:calls", arn)
+
+	arn, err = r.TopicArn(context.Background(), "texts")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:sns:us-east-1:123:texts", arn, "Expected topics on every page to be resolved")
+	assert.Equal(t, 1, topics.listings, "Expected every topic to be cached by the first listing")
+
+	now = now.Add(time.Minute)
+	_, err = r.TopicArn(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, 2, topics.listings, "Expected topics to be listed again once the TTL passed")
+
+	r.InvalidateTopic("calls")
+	_, err = r.TopicArn(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, 3, topics.listings, "Expected an invalidated topic to be listed again")
+
+	_, err = r.TopicArn(context.Background(), "missing")
+	assert.True(t, errors.Is(err, ErrTopicNotFound), "Expected ErrTopicNotFound, got %v", err)
+}
+
+func Test_ResolverQueueURL(t *testing.T) {
+	queues := &fakeQueueLookup{}
+	now := time.Now()
+	r := newTestResolver(t, &fakeTopicLister{}, queues, &now)
+
+	url, err := r.QueueURL(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sqs.test/calls", url)
+
+	_, err = r.QueueURL(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, 1, queues.lookups, "Expected the URL to be cached")
+
+	now = now.Add(time.Minute)
+	_, err = r.QueueURL(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, 2, queues.lookups, "Expected the URL to be looked up again once the TTL passed")
+
+	r.InvalidateQueue("calls")
+	_, err = r.QueueURL(context.Background(), "calls")
+	require.NoError(t, err)
+	assert.Equal(t, 3, queues.lookups, "Expected an invalidated URL to be looked up again")
+
+	_, err = r.QueueURL(context.Background(), "")
+	assert.Error(t, err, "Expected an empty queue name to be rejected")
+}
+
+func Test_ResolverInvalidateAll(t *testing.T) {
+	topics := &fakeTopicLister{pages: [][]string{{"arn:aws:sns:us-east-1:123:calls"}}}
+	queues := &fakeQueueLookup{}
+	now := time.Now()
+	r := newTestResolver(t, topics, queues, &now)
+
+	_, err := r.TopicArn(context.Background(), "calls")
+	require.NoError(t, err)
+	_, err = r.QueueURL(context.Background(), "calls")
+	require.NoError(t, err)
+
+	r.InvalidateAll()
+	_, err = r.TopicArn(context.Background(), "calls")
+	require.NoError(t, err)
+	_, err = r.QueueURL(context.Background(), "calls")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, topics.listings)
+	assert.Equal(t, 2, queues.lookups)
+}
+
+func Test_NewResolverWithoutClients(t *testing.T) {
+	_, err := NewResolver(nil)
+	assert.Error(t, err, "Expected a resolver without clients to be rejected")
+
+	r, err := NewResolver(&ResolverConfig{SQS: &fakeQueueLookup{}})
+	require.NoError(t, err)
+	_, err = r.TopicArn(context.Background(), "calls")
+	assert.Error(t, err, "Expected resolving a topic without an SNS client to fail")
+}
@@ -10,7 +10,8 @@ import (
 	"strings"
 )
 
-// NewSNS initializes a new AWS SNS client
+// NewSNS initializes a new AWS SNS client. Its calls are retried following the config's retry policy, and guarded
+// by a circuit breaker when the config sets one
 func NewSNS(config *Config) (*sns.SNS, error) {
 	c, err := mergeAndPopulateConfig(config)
 	if err != nil {
@@ -20,6 +21,7 @@ func NewSNS(config *Config) (*sns.SNS, error) {
 	awscfg := &aws.Config{
 		Region:                        aws.String(c.AWSRegion),
 		CredentialsChainVerboseErrors: aws.Bool(true),
+		Retryer:                       c.Retry.Retryer(),
 	}
 
 	sess, err := session.NewSession(awscfg)
@@ -32,6 +34,13 @@ func NewSNS(config *Config) (*sns.SNS, error) {
 	if client == nil {
 		return nil, err
 	}
+	if c.CircuitBreaker != nil {
+		breaker, err := NewCircuitBreaker(*c.CircuitBreaker, c.MetricsRegisterer)
+		if err != nil {
+			return nil, err
+		}
+		breaker.Attach(client.Client)
+	}
 	return client, nil
 }
 
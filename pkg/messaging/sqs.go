@@ -8,7 +8,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs"
 )
 
-// NewSQS initializes a new AWS SQS client
+// NewSQS initializes a new AWS SQS client. Its calls are retried following the config's retry policy, and guarded
+// by a circuit breaker when the config sets one
 func NewSQS(config *Config) (*sqs.SQS, error) {
 	c, err := mergeAndPopulateConfig(config)
 	if err != nil {
@@ -18,6 +19,7 @@ func NewSQS(config *Config) (*sqs.SQS, error) {
 	awscfg := &aws.Config{
 		Region:                        aws.String(c.AWSRegion),
 		CredentialsChainVerboseErrors: aws.Bool(true),
+		Retryer:                       c.Retry.Retryer(),
 	}
 	// if c.RoleArn != "" {
 	// 	creds := stscreds.NewCredentials(sess, c.RoleArn)
@@ -40,5 +42,12 @@ func NewSQS(config *Config) (*sqs.SQS, error) {
 	if client == nil {
 		return nil, err
 	}
+	if c.CircuitBreaker != nil {
+		breaker, err := NewCircuitBreaker(*c.CircuitBreaker, c.MetricsRegisterer)
+		if err != nil {
+			return nil, err
+		}
+		breaker.Attach(client.Client)
+	}
 	return client, nil
 }
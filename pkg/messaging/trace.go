@@ -0,0 +1,98 @@
+package messaging
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// InjectTraceContext adds the span context of the span in ctx to the message attributes of a
+// message about to be sent, so the consumer's span for the message is linked to the producer.
+// Attributes are added to attrs, which must not be nil. If ctx has no span attrs is unchanged.
+func InjectTraceContext(ctx context.Context, tracer opentracing.Tracer, attrs map[string]*sqs.MessageAttributeValue) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	if tracer == nil {
+		tracer = opentracing.GlobalTracer()
+	}
+
+	return tracer.Inject(span.Context(), opentracing.TextMap, messageAttributesCarrier(attrs))
+}
+
+// messageAttributesCarrier carries a span context in the string attributes of a message
+type messageAttributesCarrier map[string]*sqs.MessageAttributeValue
+
+// Set implements opentracing.TextMapWriter
+func (c messageAttributesCarrier) Set(key, val string) {
+	c[key] = &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(val),
+	}
+}
+
+// ForeachKey implements opentracing.TextMapReader
+func (c messageAttributesCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		if v == nil || v.StringValue == nil {
+			continue
+		}
+		if err := handler(k, *v.StringValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startMessageSpan starts the span a message is handled in, following from the producer's span when
+// the message carries one in its attributes or in the tracecontext extension of its event. The span is
+// tagged with the queue the message was received from. It returns the handler context with the span and
+// the func to call with the outcome once the handler returns.
+func (c *Consumer) startMessageSpan(ctx context.Context, queueURL string, msg *sqs.Message, event *CloudEvent) (context.Context, func(error)) {
+	opts := []opentracing.StartSpanOption{
+		ext.SpanKindConsumer,
+		opentracing.Tag{Key: "queue.name", Value: queueName(queueURL)},
+		opentracing.Tag{Key: "message.id", Value: aws.StringValue(msg.MessageId)},
+	}
+
+	producer, err := c.config.Tracer.Extract(opentracing.TextMap, messageAttributesCarrier(msg.MessageAttributes))
+	if err != nil && event != nil && event.TraceContext != "" {
+		if carrier, cerr := event.traceContextCarrier(); cerr == nil {
+			producer, err = c.config.Tracer.Extract(opentracing.TextMap, carrier)
+		}
+	}
+	if err == nil {
+		opts = append(opts, opentracing.FollowsFrom(producer))
+	}
+
+	if n, err := strconv.ParseInt(aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]), 10, 64); err == nil {
+		opts = append(opts, opentracing.Tag{Key: "message.receive_count", Value: n})
+	}
+	if ms, err := strconv.ParseInt(aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]), 10, 64); err == nil {
+		inQueue := time.Since(time.Unix(0, ms*int64(time.Millisecond)))
+		opts = append(opts, opentracing.Tag{Key: "message.time_in_queue_ms", Value: inQueue.Milliseconds()})
+	}
+
+	span := c.config.Tracer.StartSpan("sqs.consume", opts...)
+
+	return opentracing.ContextWithSpan(ctx, span), func(err error) {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(log.Error(err))
+		}
+		span.Finish()
+	}
+}
+
+// queueName returns the name of the queue from its URL
+func queueName(queueURL string) string {
+	return queueURL[strings.LastIndex(queueURL, "/")+1:]
+}
@@ -0,0 +1,140 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InjectTraceContext(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("publish")
+	defer span.Finish()
+
+	attrs := map[string]*sqs.MessageAttributeValue{}
+	require.NoError(t, InjectTraceContext(opentracing.ContextWithSpan(context.Background(), span), tracer, attrs))
+	require.NotEmpty(t, attrs, "Expected the span context in the attributes")
+	for k, v := range attrs {
+		assert.Equal(t, "String", aws.StringValue(v.DataType), "Expected %s to be a string attribute", k)
+	}
+
+	extracted, err := tracer.Extract(opentracing.TextMap, messageAttributesCarrier(attrs))
+	require.NoError(t, err)
+	producer := span.Context().(mocktracer.MockSpanContext)
+	assert.Equal(t, producer.TraceID, extracted.(mocktracer.MockSpanContext).TraceID, "Expected the trace to round trip")
+	assert.Equal(t, producer.SpanID, extracted.(mocktracer.MockSpanContext).SpanID, "Expected the span to round trip")
+}
+
+func Test_InjectTraceContextWithoutSpan(t *testing.T) {
+	attrs := map[string]*sqs.MessageAttributeValue{"kind": {DataType: aws.String("String"), StringValue: aws.String("call")}}
+	require.NoError(t, InjectTraceContext(context.Background(), mocktracer.New(), attrs))
+	assert.Len(t, attrs, 1, "Expected the attributes to be unchanged without a span")
+}
+
+func Test_InjectTraceContextGlobalTracer(t *testing.T) {
+	tracer := mocktracer.New()
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(previous)
+
+	span := tracer.StartSpan("publish")
+	defer span.Finish()
+
+	attrs := map[string]*sqs.MessageAttributeValue{}
+	require.NoError(t, InjectTraceContext(opentracing.ContextWithSpan(context.Background(), span), nil, attrs))
+	_, err := tracer.Extract(opentracing.TextMap, messageAttributesCarrier(attrs))
+	assert.NoError(t, err, "Expected the span context injected by the global tracer")
+}
+
+func Test_MessageAttributesCarrierSkipsNonStrings(t *testing.T) {
+	carrier := messageAttributesCarrier{
+		"traceid": {DataType: aws.String("String"), StringValue: aws.String("1")},
+		"payload": {DataType: aws.String("Binary"), BinaryValue: []byte{1}},
+		"missing": nil,
+	}
+
+	read := map[string]string{}
+	require.NoError(t, carrier.ForeachKey(func(key, val string) error {
+		read[key] = val
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"traceid": "1"}, read, "Expected only the string attributes")
+}
+
+func Test_ConsumerLinksTraceContext(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("publish")
+	attrs := map[string]*sqs.MessageAttributeValue{}
+	require.NoError(t, InjectTraceContext(opentracing.ContextWithSpan(context.Background(), span), tracer, attrs))
+	span.Finish()
+
+	client := newFakeSQS()
+	msg := client.send(testQueueURL, "call-42", attrs)
+	msg.Attributes = map[string]*string{
+		sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("2"),
+		sqs.MessageSystemAttributeNameSentTimestamp:           aws.String(strconv.FormatInt(time.Now().Add(-time.Second).UnixNano()/int64(time.Millisecond), 10)),
+	}
+
+	handled := make(chan opentracing.Span, 1)
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second, Tracer: tracer}, func(ctx context.Context, msg *sqs.Message) error {
+		handled <- opentracing.SpanFromContext(ctx)
+		return errors.New("boom")
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	require.NotNil(t, <-handled, "Expected the handler context to carry the message's span")
+	_, err = c.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	var consume *mocktracer.MockSpan
+	for _, s := range tracer.FinishedSpans() {
+		if s.OperationName == "sqs.consume" {
+			consume = s
+		}
+	}
+	require.NotNil(t, consume, "Expected the message to be handled in a span")
+	producer := span.Context().(mocktracer.MockSpanContext)
+	assert.Equal(t, producer.TraceID, consume.SpanContext.TraceID, "Expected the consumer's span in the producer's trace")
+	assert.Equal(t, producer.SpanID, consume.ParentID, "Expected the consumer's span to follow from the producer's")
+	assert.Equal(t, "calls", consume.Tag("queue.name"))
+	assert.Equal(t, "msg-0", consume.Tag("message.id"))
+	assert.Equal(t, int64(2), consume.Tag("message.receive_count"))
+	assert.GreaterOrEqual(t, consume.Tag("message.time_in_queue_ms"), int64(1000), "Expected the time since the message was sent")
+	assert.Equal(t, true, consume.Tag("error"), "Expected the span marked as failed when the handler fails")
+}
+
+func Test_ConsumerTraceWithoutTraceContext(t *testing.T) {
+	tracer := mocktracer.New()
+	client := newFakeSQS()
+	client.send(testQueueURL, "call-42", nil)
+
+	handled := make(chan struct{}, 1)
+	c, err := NewConsumer(client, &ConsumerConfig{QueueURL: testQueueURL, WaitTime: time.Second, Tracer: tracer}, func(ctx context.Context, msg *sqs.Message) error {
+		handled <- struct{}{}
+		return nil
+	})
+	require.NoError(t, err)
+
+	errc := startConsumer(c)
+	<-handled
+	_, err = c.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "sqs.consume", spans[0].OperationName)
+	assert.Zero(t, spans[0].ParentID, "Expected a root span without a trace context")
+	assert.Nil(t, spans[0].Tag("error"))
+}
@@ -0,0 +1,53 @@
+package pagination
+
+// SizeBudget keeps list responses under a size ceiling, e.g. the 4MiB gRPC max message size, for endpoints whose
+// items are large enough that the allowed page sizes can blow past it. Sizes are approximate, leave headroom.
+type SizeBudget struct {
+	// The byte size responses are kept under
+	MaxBytes int64
+	// The approximate byte size of each item, used to reduce the limit before the page is read
+	ItemBytes int64
+	// The byte size of the rest of the response, e.g. the page info, taken off MaxBytes
+	OverheadBytes int64
+}
+
+// Limit returns the limit reduced to the number of items that fit in the budget. A limit of 0, when the client
+// didn't ask for one, is replaced with the number that fit. At least one item is always allowed, so paging through
+// items larger than the budget still makes progress. Without an item size the limit is returned as it is.
+func (b SizeBudget) Limit(limit int64) int64 {
+	if b.ItemBytes <= 0 {
+		return limit
+	}
+
+	fit := (b.MaxBytes - b.OverheadBytes) / b.ItemBytes
+	if fit < 1 {
+		fit = 1
+	}
+	if limit == 0 || limit > fit {
+		return fit
+	}
+	return limit
+}
+
+// Fit returns how many of the first n items fit in the budget, measuring each with size, e.g. proto.Size of the
+// item's message. Items that don't fit should be left off the page and the page marked as having more, with
+// its end cursor built from the last item that fit. At least one item is always returned if n isn't 0.
+func (b SizeBudget) Fit(n int, size func(i int) int64) int {
+	remaining := b.MaxBytes - b.OverheadBytes
+	for i := 0; i < n; i++ {
+		remaining -= size(i)
+		if remaining < 0 {
+			if i == 0 {
+				return 1
+			}
+			return i
+		}
+	}
+	return n
+}
+
+// ApplySizeBudget reduces the limit of the pager to the number of items that fit in the budget, see
+// SizeBudget.Limit
+func (p *Pager) ApplySizeBudget(b SizeBudget) {
+	p.Limit = b.Limit(p.Limit)
+}
@@ -0,0 +1,34 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeBudgetLimit(t *testing.T) {
+	b := SizeBudget{MaxBytes: 1000, ItemBytes: 100, OverheadBytes: 200}
+
+	assert.Equal(t, int64(8), b.Limit(50), "Expected the limit to be reduced to the items that fit")
+	assert.Equal(t, int64(5), b.Limit(5), "Expected a limit that fits to be kept")
+	assert.Equal(t, int64(8), b.Limit(0), "Expected no limit to be replaced with the items that fit")
+	assert.Equal(t, int64(1), SizeBudget{MaxBytes: 100, ItemBytes: 500}.Limit(10), "Expected at least one item")
+	assert.Equal(t, int64(50), SizeBudget{MaxBytes: 1000}.Limit(50), "Expected the limit as it is without an item size")
+}
+
+func TestSizeBudgetFit(t *testing.T) {
+	b := SizeBudget{MaxBytes: 1000, OverheadBytes: 100}
+	sizes := []int64{300, 300, 300, 300}
+	size := func(i int) int64 { return sizes[i] }
+
+	assert.Equal(t, 3, b.Fit(len(sizes), size), "Expected the items up to the budget")
+	assert.Equal(t, 2, b.Fit(2, size), "Expected every item when they fit")
+	assert.Equal(t, 0, b.Fit(0, size))
+	assert.Equal(t, 1, SizeBudget{MaxBytes: 100}.Fit(len(sizes), size), "Expected at least one item")
+}
+
+func TestPagerApplySizeBudget(t *testing.T) {
+	p := &Pager{Limit: 100}
+	p.ApplySizeBudget(SizeBudget{MaxBytes: 4 << 20, ItemBytes: 64 << 10})
+	assert.Equal(t, int64(64), p.Limit)
+}
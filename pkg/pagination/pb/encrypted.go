@@ -0,0 +1,171 @@
+package pagination
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrCursorKeyUnknown is returned when a cursor was encrypted with a key the cipher no longer has, e.g. one
+// retired after a rotation
+var ErrCursorKeyUnknown = errors.New("invalid pagination request. cursor was encrypted with an unknown key")
+
+// ErrCursorTampered is returned when an encrypted cursor can't be decrypted, because it was altered or
+// wasn't created by the cipher
+var ErrCursorTampered = errors.New("invalid pagination request. cursor could not be decrypted")
+
+// encryptedCursorVersion is the first byte of every encrypted cursor, so the format can change later
+const encryptedCursorVersion = 1
+
+// CursorKey is a key cursors are encrypted with. The ID is written in the clear in the header of each cursor,
+// so the key can be found when the cursor comes back, and should never be reused for another secret
+type CursorKey struct {
+	// Identifies the key, at most 255 bytes, e.g. "2021-03"
+	ID string
+	// The AES key, 16, 24 or 32 bytes for AES-128, AES-192 or AES-256
+	Secret []byte
+}
+
+// CursorCipher encrypts cursors with AES-GCM, so the values they hold, e.g. the timestamps, IDs or scores a page
+// is sorted by, can't be read by clients. GCM authenticates the cursor as well, so a cursor that was altered
+// is rejected with ErrCursorTampered instead of paging from an arbitrary position.
+//
+// Cursors are encrypted with the current key and decrypted with the key named in their header. To rotate keys,
+// make the new key current and keep the old one as a previous key until the cursors encrypted with it expire.
+type CursorCipher struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewCursorCipher returns a cipher encrypting cursors with the current key, and decrypting cursors encrypted
+// with the current or any of the previous keys
+func NewCursorCipher(current CursorKey, previous ...CursorKey) (*CursorCipher, error) {
+	c := &CursorCipher{
+		current: current.ID,
+		aeads:   make(map[string]cipher.AEAD, 1+len(previous)),
+	}
+	for _, k := range append([]CursorKey{current}, previous...) {
+		if len(k.ID) == 0 || len(k.ID) > 255 {
+			return nil, errors.New("invalid cursor key. the id must be between 1 and 255 bytes: " + k.ID)
+		}
+		if _, ok := c.aeads[k.ID]; ok {
+			return nil, errors.New("invalid cursor key. the id is used more than once: " + k.ID)
+		}
+
+		block, err := aes.NewCipher(k.Secret)
+		if err != nil {
+			return nil, errors.New("invalid cursor key " + k.ID + ": " + err.Error())
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.New("invalid cursor key " + k.ID + ": " + err.Error())
+		}
+		c.aeads[k.ID] = aead
+	}
+	return c, nil
+}
+
+// EncryptCursor encrypts the cursor value with the current key, returning it base64 encoded
+func (c *CursorCipher) EncryptCursor(s string) (string, error) {
+	aead := c.aeads[c.current]
+
+	header := append([]byte{encryptedCursorVersion, byte(len(c.current))}, c.current...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.New("unable to encrypt cursor: " + err.Error())
+	}
+
+	// the header is authenticated along with the value, so the key ID can't be swapped
+	out := make([]byte, 0, len(header)+len(nonce)+len(s)+aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, []byte(s), header)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecryptCursor decrypts a cursor created with EncryptCursor. An empty cursor decrypts to an empty string
+func (c *CursorCipher) DecryptCursor(cursor string) (string, error) {
+	if len(cursor) == 0 {
+		return "", nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", ErrCursorTampered
+	}
+	if len(b) < 2 || b[0] != encryptedCursorVersion || len(b) < 2+int(b[1]) {
+		return "", ErrCursorTampered
+	}
+	header, rest := b[:2+int(b[1])], b[2+int(b[1]):]
+
+	aead, ok := c.aeads[string(header[2:])]
+	if !ok {
+		return "", ErrCursorKeyUnknown
+	}
+	if len(rest) < aead.NonceSize() {
+		return "", ErrCursorTampered
+	}
+	value, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return "", ErrCursorTampered
+	}
+	return string(value), nil
+}
+
+// EncryptFilteredCursor encrypts the cursor value together with a hash of the filters active when the page was
+// produced, like EncodeFilteredCursor
+func (c *CursorCipher) EncryptFilteredCursor(s string, filters interface{}) (string, error) {
+	h, err := FilterHash(filters)
+	if err != nil {
+		return "", err
+	}
+	return c.EncryptCursor(h + filterHashSeparator + s)
+}
+
+// DecryptFilteredCursor decrypts a cursor created with EncryptFilteredCursor. If the cursor was created with
+// different filters ErrCursorFilterMismatch is returned. An empty cursor decrypts to an empty string.
+func (c *CursorCipher) DecryptFilteredCursor(cursor string, filters interface{}) (string, error) {
+	decrypted, err := c.DecryptCursor(cursor)
+	if err != nil || len(decrypted) == 0 {
+		return decrypted, err
+	}
+
+	parts := strings.SplitN(decrypted, filterHashSeparator, 2)
+	if len(parts) != 2 {
+		return "", errors.New("Decode error: cursor is not bound to filters")
+	}
+
+	h, err := FilterHash(filters)
+	if err != nil {
+		return "", err
+	}
+	if parts[0] != h {
+		return "", ErrCursorFilterMismatch
+	}
+
+	return parts[1], nil
+}
+
+// NewPager creates Pager object from proto struct, like NewFilteredPager, for cursors created with
+// EncryptFilteredCursor
+func (c *CursorCipher) NewPager(pr *PaginationRequest, filters interface{}) (*Pager, error) {
+	cursor := pr.GetAfter()
+	if len(cursor) == 0 {
+		cursor = pr.GetBefore()
+	}
+
+	p, err := NewPager(pr)
+	if err != nil {
+		return nil, err
+	}
+	p.DecCursor, err = c.DecryptFilteredCursor(cursor, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
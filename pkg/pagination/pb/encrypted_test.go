@@ -0,0 +1,130 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = CursorKey{ID: "2021-01", Secret: bytes.Repeat([]byte{1}, 32)}
+	newKey = CursorKey{ID: "2021-02", Secret: bytes.Repeat([]byte{2}, 16)}
+)
+
+func TestCursorCipherRoundTrip(t *testing.T) {
+	c, err := NewCursorCipher(newKey)
+	require.NoError(t, err)
+
+	cursor, err := c.EncryptCursor("2021-02-03T04:05:06Z|call-42")
+	require.NoError(t, err)
+	assert.NotContains(t, cursor, base64.StdEncoding.EncodeToString([]byte("call-42")), "Expected the value to be hidden")
+
+	again, err := c.EncryptCursor("2021-02-03T04:05:06Z|call-42")
+	require.NoError(t, err)
+	assert.NotEqual(t, cursor, again, "Expected a new nonce for every cursor")
+
+	value, err := c.DecryptCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-02-03T04:05:06Z|call-42", value)
+
+	value, err = c.DecryptCursor("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", value, "Expected an empty cursor to decrypt to an empty string")
+}
+
+func TestCursorCipherRotation(t *testing.T) {
+	before, err := NewCursorCipher(oldKey)
+	require.NoError(t, err)
+	cursor, err := before.EncryptCursor("call-42")
+	require.NoError(t, err)
+
+	// the old key is kept as a previous key while its cursors are still in use
+	rotated, err := NewCursorCipher(newKey, oldKey)
+	require.NoError(t, err)
+	value, err := rotated.DecryptCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "call-42", value)
+
+	// and rotated out once they have expired
+	retired, err := NewCursorCipher(newKey)
+	require.NoError(t, err)
+	_, err = retired.DecryptCursor(cursor)
+	assert.Equal(t, ErrCursorKeyUnknown, err)
+}
+
+func TestCursorCipherTampered(t *testing.T) {
+	c, err := NewCursorCipher(newKey, oldKey)
+	require.NoError(t, err)
+	cursor, err := c.EncryptCursor("call-42")
+	require.NoError(t, err)
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	require.NoError(t, err)
+
+	tamper := func(f func(b []byte) []byte) string {
+		return base64.StdEncoding.EncodeToString(f(append([]byte(nil), b...)))
+	}
+	header := 2 + len(newKey.ID)
+
+	cases := map[string]string{
+		"ciphertext": tamper(func(b []byte) []byte { b[len(b)-1] ^= 1; return b }),
+		"nonce":      tamper(func(b []byte) []byte { b[header] ^= 1; return b }),
+		// swapping the key ID for another the cipher has fails authentication, as the header is authenticated
+		"key id":    tamper(func(b []byte) []byte { return append(append(b[:2:2], oldKey.ID...), b[header:]...) }),
+		"version":   tamper(func(b []byte) []byte { b[0] = 2; return b }),
+		"truncated": tamper(func(b []byte) []byte { return b[:header+4] }),
+		"base64":    "not base64!",
+	}
+	for name, cursor := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.DecryptCursor(cursor)
+			assert.Equal(t, ErrCursorTampered, err)
+		})
+	}
+}
+
+func TestCursorCipherUnknownKey(t *testing.T) {
+	other, err := NewCursorCipher(CursorKey{ID: "elsewhere", Secret: bytes.Repeat([]byte{3}, 32)})
+	require.NoError(t, err)
+	cursor, err := other.EncryptCursor("call-42")
+	require.NoError(t, err)
+
+	c, err := NewCursorCipher(newKey, oldKey)
+	require.NoError(t, err)
+	_, err = c.DecryptCursor(cursor)
+	assert.Equal(t, ErrCursorKeyUnknown, err)
+}
+
+func TestNewCursorCipherInvalidKeys(t *testing.T) {
+	_, err := NewCursorCipher(CursorKey{ID: "", Secret: newKey.Secret})
+	assert.Error(t, err, "Expected an empty key ID to be rejected")
+
+	_, err = NewCursorCipher(CursorKey{ID: strings.Repeat("a", 256), Secret: newKey.Secret})
+	assert.Error(t, err, "Expected a key ID over 255 bytes to be rejected")
+
+	_, err = NewCursorCipher(newKey, CursorKey{ID: newKey.ID, Secret: oldKey.Secret})
+	assert.Error(t, err, "Expected a reused key ID to be rejected")
+
+	_, err = NewCursorCipher(CursorKey{ID: "short", Secret: []byte("too short")})
+	assert.Error(t, err, "Expected an invalid AES key to be rejected")
+}
+
+func TestCursorCipherFilteredPager(t *testing.T) {
+	c, err := NewCursorCipher(newKey)
+	require.NoError(t, err)
+	filters := map[string]string{"status": "open"}
+
+	cursor, err := c.EncryptFilteredCursor("call-42", filters)
+	require.NoError(t, err)
+
+	p, err := c.NewPager(&PaginationRequest{After: cursor, First: 10}, filters)
+	require.NoError(t, err)
+	assert.Equal(t, "call-42", p.DecCursor)
+	assert.Equal(t, int64(10), p.Limit)
+
+	_, err = c.NewPager(&PaginationRequest{After: cursor, First: 10}, map[string]string{"status": "closed"})
+	assert.Equal(t, ErrCursorFilterMismatch, err)
+}
@@ -0,0 +1,90 @@
+package pagination
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+// FieldColumns maps each field a list endpoint lets clients request in a partial response to the SQL
+// columns it is read from. A field can be read from more than one column, e.g. a name from first_name
+// and last_name. Nested fields, e.g. "author.name", are allowed when their top level field is.
+type FieldColumns map[string][]string
+
+// NewPartialPager creates Pager object from proto struct, like NewPager, for list endpoints supporting
+// partial responses. The paths of the field mask are validated against the allowed fields and kept on
+// the pager, an empty or nil mask requests every field.
+//
+// For filtered cursors, create the pager with NewFilteredPager and set its Fields with ValidateFieldMask.
+func NewPartialPager(pr *PaginationRequest, mask *field_mask.FieldMask, allowed FieldColumns) (*Pager, error) {
+	fields, err := ValidateFieldMask(mask, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := NewPager(pr)
+	if err != nil {
+		return nil, err
+	}
+	p.Fields = fields
+
+	return p, nil
+}
+
+// ValidateFieldMask returns the top level fields requested by the field mask, sorted and without duplicates,
+// or an error if it requests a field that isn't allowed. An empty or nil mask returns no fields, which
+// requests every field.
+func ValidateFieldMask(mask *field_mask.FieldMask, allowed FieldColumns) ([]string, error) {
+	seen := map[string]struct{}{}
+	fields := []string{}
+
+	for _, path := range mask.GetPaths() {
+		field := strings.SplitN(path, ".", 2)[0]
+		if _, ok := allowed[field]; !ok {
+			return nil, errors.New("invalid pagination request. field " + path + " can not be requested")
+		}
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// Columns returns the SQL columns to select for the fields requested of the pager, or for every allowed field
+// if none were requested. The key columns, which the page is ordered by and its cursors are built from, are
+// always selected first, so keyset pagination keeps working whichever fields are requested.
+func (p *Pager) Columns(allowed FieldColumns, keyColumns ...string) []string {
+	fields := p.Fields
+	if len(fields) == 0 {
+		fields = make([]string, 0, len(allowed))
+		for f := range allowed {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+	}
+
+	seen := map[string]struct{}{}
+	columns := []string{}
+	add := func(cols ...string) {
+		for _, c := range cols {
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+			columns = append(columns, c)
+		}
+	}
+
+	add(keyColumns...)
+	for _, f := range fields {
+		add(allowed[f]...)
+	}
+
+	return columns
+}
@@ -0,0 +1,52 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+var callColumns = FieldColumns{
+	"id":     {"id"},
+	"caller": {"caller_first_name", "caller_last_name"},
+	"status": {"status"},
+	"notes":  {"notes"},
+}
+
+func TestValidateFieldMask(t *testing.T) {
+	fields, err := ValidateFieldMask(&field_mask.FieldMask{Paths: []string{"status", "caller.first_name", "caller.last_name"}}, callColumns)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"caller", "status"}, fields, "Expected the top level fields, sorted and without duplicates")
+
+	fields, err = ValidateFieldMask(nil, callColumns)
+	require.NoError(t, err)
+	assert.Empty(t, fields, "Expected a nil mask to request every field")
+
+	_, err = ValidateFieldMask(&field_mask.FieldMask{Paths: []string{"status", "recording.url"}}, callColumns)
+	assert.EqualError(t, err, "invalid pagination request. field recording.url can not be requested")
+}
+
+func TestNewPartialPager(t *testing.T) {
+	p, err := NewPartialPager(&PaginationRequest{First: 10}, &field_mask.FieldMask{Paths: []string{"notes"}}, callColumns)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"notes"}, p.Fields)
+	assert.Equal(t, int64(10), p.Limit)
+
+	_, err = NewPartialPager(&PaginationRequest{First: 10}, &field_mask.FieldMask{Paths: []string{"secret"}}, callColumns)
+	assert.Error(t, err, "Expected a field that isn't allowed to be rejected")
+
+	_, err = NewPartialPager(&PaginationRequest{First: -1}, nil, callColumns)
+	assert.Error(t, err, "Expected the request to still be validated")
+}
+
+func TestPagerColumns(t *testing.T) {
+	p := &Pager{Fields: []string{"caller", "status"}}
+	assert.Equal(t, []string{"created_at", "id", "caller_first_name", "caller_last_name", "status"}, p.Columns(callColumns, "created_at", "id"),
+		"Expected the key columns first, then the columns of the requested fields")
+
+	p = &Pager{}
+	assert.Equal(t, []string{"id", "caller_first_name", "caller_last_name", "notes", "status"}, p.Columns(callColumns, "id"),
+		"Expected every allowed field when none were requested, without repeating the key columns")
+}
@@ -0,0 +1,132 @@
+package pagination
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// LegacyCursorCodec recognises and decodes cursors in a format a service used before it moved
+// to filtered cursors, so cursors already handed out keep working for a deprecation window
+type LegacyCursorCodec interface {
+	// Match reports whether the encoded cursor is in the legacy format
+	Match(c string) bool
+	// Decode returns the value of an encoded legacy cursor
+	Decode(c string) (string, error)
+}
+
+// OffsetCursorCodec decodes legacy cursors holding a base64 encoded row offset, optionally
+// after a prefix, e.g. base64("offset:40"). The decoded value is the offset.
+type OffsetCursorCodec struct {
+	Prefix string
+}
+
+// Match implements LegacyCursorCodec
+func (o OffsetCursorCodec) Match(c string) bool {
+	_, err := o.Decode(c)
+	return err == nil
+}
+
+// Decode implements LegacyCursorCodec
+func (o OffsetCursorCodec) Decode(c string) (string, error) {
+	decoded, err := DecodeCursor(c)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(decoded, o.Prefix) {
+		return "", errors.New("Decode error: cursor is not a legacy offset cursor " + fmt.Sprint(c))
+	}
+
+	offset := strings.TrimPrefix(decoded, o.Prefix)
+	if i, err := strconv.ParseInt(offset, 10, 64); err != nil || i < 0 {
+		return "", errors.New("Decode error: cursor is not a legacy offset cursor " + fmt.Sprint(c))
+	}
+
+	return offset, nil
+}
+
+// PlainCursorCodec decodes legacy cursors created with EncodeCursor, for services moving from
+// plain cursors to filtered cursors. The decoded value is the cursor value.
+type PlainCursorCodec struct{}
+
+// Match implements LegacyCursorCodec
+func (PlainCursorCodec) Match(c string) bool {
+	decoded, err := DecodeCursor(c)
+	if err != nil {
+		return false
+	}
+
+	parts := strings.SplitN(decoded, filterHashSeparator, 2)
+	if len(parts) == 2 && len(parts[0]) == 16 {
+		if _, err := hex.DecodeString(parts[0]); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Decode implements LegacyCursorCodec
+func (PlainCursorCodec) Decode(c string) (string, error) {
+	return DecodeCursor(c)
+}
+
+// CursorMigration accepts legacy cursors alongside filtered cursors, counting how often each is
+// used so the legacy format can be removed once clients stop sending it
+type CursorMigration struct {
+	codecs  []LegacyCursorCodec
+	metrics cursorMetrics
+}
+
+// cursorMetrics count the cursors accepted by a migration by their format
+type cursorMetrics struct {
+	Legacy  metrics.Counter `metric:"pagination_cursors" tags:"format=legacy" help:"Number of pagination cursors received, by format"`
+	Current metrics.Counter `metric:"pagination_cursors" tags:"format=current" help:"Number of pagination cursors received, by format"`
+}
+
+// NewCursorMigration returns a migration accepting cursors matched by any of the codecs, tried in
+// order. Usage is reported to the metrics factory, which may be nil.
+func NewCursorMigration(factory metrics.Factory, codecs ...LegacyCursorCodec) *CursorMigration {
+	m := &CursorMigration{codecs: codecs}
+	metrics.MustInit(&m.metrics, factory, nil)
+	return m
+}
+
+// NewPager creates Pager object from proto struct, like NewFilteredPager. If the cursor matches one of
+// the legacy codecs instead, it is decoded by that codec and the pager is marked as Legacy, so the
+// service can page with the old semantics.
+func (m *CursorMigration) NewPager(pr *PaginationRequest, filters interface{}) (*Pager, error) {
+	cursor := pr.GetAfter()
+	if len(cursor) == 0 {
+		cursor = pr.GetBefore()
+	}
+	if len(cursor) == 0 {
+		return NewFilteredPager(pr, filters)
+	}
+
+	for _, codec := range m.codecs {
+		if !codec.Match(cursor) {
+			continue
+		}
+
+		p, err := NewPager(pr)
+		if err != nil {
+			return nil, err
+		}
+		p.DecCursor, err = codec.Decode(cursor)
+		if err != nil {
+			return nil, err
+		}
+		p.Legacy = true
+
+		m.metrics.Legacy.Inc(1)
+		return p, nil
+	}
+
+	m.metrics.Current.Inc(1)
+	return NewFilteredPager(pr, filters)
+}
@@ -0,0 +1,72 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+)
+
+func TestOffsetCursorCodec(t *testing.T) {
+	codec := OffsetCursorCodec{Prefix: "offset:"}
+
+	cursor := EncodeCursor("offset:40")
+	assert.True(t, codec.Match(cursor))
+	offset, err := codec.Decode(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "40", offset)
+
+	assert.False(t, codec.Match(EncodeCursor("page:40")), "Expected another prefix not to match")
+	assert.False(t, codec.Match(EncodeCursor("offset:-1")), "Expected a negative offset not to match")
+	assert.False(t, codec.Match(EncodeCursor("offset:abc")), "Expected a non numeric offset not to match")
+	assert.False(t, codec.Match("not base64!"), "Expected an invalid cursor not to match")
+}
+
+func TestPlainCursorCodec(t *testing.T) {
+	codec := PlainCursorCodec{}
+
+	cursor := EncodeCursor("2021-02-03|call-42")
+	assert.True(t, codec.Match(cursor))
+	value, err := codec.Decode(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-02-03|call-42", value)
+
+	filtered, err := EncodeFilteredCursor("call-42", callFilters{Status: "open"})
+	require.NoError(t, err)
+	assert.False(t, codec.Match(filtered), "Expected a filtered cursor not to match")
+	assert.True(t, codec.Match(EncodeCursor("host:8080")), "Expected a value with a separator to match")
+}
+
+func TestCursorMigration(t *testing.T) {
+	factory := metricstest.NewFactory(0)
+	defer factory.Stop()
+
+	filters := callFilters{Status: "open"}
+	m := NewCursorMigration(factory, OffsetCursorCodec{Prefix: "offset:"})
+
+	p, err := m.NewPager(&PaginationRequest{After: EncodeCursor("offset:40"), First: 10}, filters)
+	require.NoError(t, err)
+	assert.True(t, p.Legacy, "Expected a legacy cursor to mark the pager")
+	assert.Equal(t, "40", p.DecCursor)
+	assert.Equal(t, int64(10), p.Limit)
+
+	cursor, err := EncodeFilteredCursor("call-42", filters)
+	require.NoError(t, err)
+	p, err = m.NewPager(&PaginationRequest{After: cursor, First: 10}, filters)
+	require.NoError(t, err)
+	assert.False(t, p.Legacy)
+	assert.Equal(t, "call-42", p.DecCursor)
+
+	p, err = m.NewPager(&PaginationRequest{First: 10}, filters)
+	require.NoError(t, err)
+	assert.Equal(t, "", p.DecCursor, "Expected the first page to have no cursor")
+
+	_, err = m.NewPager(&PaginationRequest{After: cursor, First: 10}, callFilters{Status: "closed"})
+	assert.Equal(t, ErrCursorFilterMismatch, err, "Expected current cursors to still be bound to their filters")
+
+	factory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "pagination_cursors", Tags: map[string]string{"format": "legacy"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "pagination_cursors", Tags: map[string]string{"format": "current"}, Value: 2},
+	)
+}
@@ -1,17 +1,33 @@
 package pagination
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
+// ErrCursorFilterMismatch is returned when a cursor is replayed against a different set of
+// filters than the ones that were active when it was created
+var ErrCursorFilterMismatch = errors.New("invalid pagination request. cursor was created with different filters")
+
+// filterHashSeparator separates the filter hash from the cursor value in a filtered cursor
+const filterHashSeparator = ":"
+
 // Pager represents params from list request
 type Pager struct {
 	// Store the decoded cursor within the service, encode/decode it as it passes through the API
 	DecCursor         string
 	Limit             int64
 	ForwardPagination bool
+	// Set when the cursor was accepted by a LegacyCursorCodec, DecCursor then holds the legacy value
+	Legacy bool
+	// The top level fields requested for a partial response, empty if every field was requested.
+	// See NewPartialPager
+	Fields []string
 }
 
 // NewPager creates Pager object from proto struct
@@ -62,6 +78,27 @@ func NewPager(pr *PaginationRequest) (*Pager, error) {
 	}, nil
 }
 
+// NewFilteredPager creates Pager object from proto struct, like NewPager, for cursors created with
+// EncodeFilteredCursor. The cursor is rejected with ErrCursorFilterMismatch if filters differ from the
+// filters it was created with.
+func NewFilteredPager(pr *PaginationRequest, filters interface{}) (*Pager, error) {
+	cursor := pr.GetAfter()
+	if len(cursor) == 0 {
+		cursor = pr.GetBefore()
+	}
+
+	p, err := NewPager(pr)
+	if err != nil {
+		return nil, err
+	}
+	p.DecCursor, err = DecodeFilteredCursor(cursor, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
 // NewPageInfo creates PageInfo object
 func NewPageInfo(hasNextPage bool, hasPrevPage bool, firstCursor string, lastCursor string) *PageInfo {
 	return &PageInfo{
@@ -100,3 +137,52 @@ func DecodeCursor(c string) (string, error) {
 func EncodeCursor(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
+
+// FilterHash returns a stable hash of the given filters. Filters may be any value that marshals to JSON,
+// typically the params struct or map used to build the list query.
+func FilterHash(filters interface{}) (string, error) {
+	b, err := json.Marshal(filters)
+	if err != nil {
+		return "", errors.New("unable to hash cursor filters: " + err.Error())
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// EncodeFilteredCursor base64 encodes the given string together with a hash of the filters
+// active when the page was produced, binding the cursor to those filters
+func EncodeFilteredCursor(s string, filters interface{}) (string, error) {
+	h, err := FilterHash(filters)
+	if err != nil {
+		return "", err
+	}
+	return EncodeCursor(h + filterHashSeparator + s), nil
+}
+
+// DecodeFilteredCursor decodes a cursor created with EncodeFilteredCursor. If the cursor was created
+// with different filters ErrCursorFilterMismatch is returned. An empty cursor decodes to an empty string.
+func DecodeFilteredCursor(c string, filters interface{}) (string, error) {
+	if len(c) == 0 {
+		return "", nil
+	}
+
+	decoded, err := DecodeCursor(c)
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.SplitN(decoded, filterHashSeparator, 2)
+	if len(parts) != 2 {
+		return "", errors.New("Decode error: cursor is not bound to filters " + fmt.Sprint(c))
+	}
+
+	h, err := FilterHash(filters)
+	if err != nil {
+		return "", err
+	}
+	if parts[0] != h {
+		return "", ErrCursorFilterMismatch
+	}
+
+	return parts[1], nil
+}
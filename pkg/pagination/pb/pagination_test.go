@@ -0,0 +1,78 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type callFilters struct {
+	Status string `json:"status"`
+	TeamID string `json:"teamID"`
+}
+
+func TestFilterHash(t *testing.T) {
+	a, err := FilterHash(map[string]interface{}{"status": "open", "teamID": "t1"})
+	require.NoError(t, err)
+	b, err := FilterHash(map[string]interface{}{"teamID": "t1", "status": "open"})
+	require.NoError(t, err)
+	assert.Equal(t, a, b, "Expected the hash not to depend on the order of map keys")
+	assert.Len(t, a, 16)
+
+	c, err := FilterHash(map[string]interface{}{"status": "closed", "teamID": "t1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, a, c, "Expected different filters to hash differently")
+
+	_, err = FilterHash(func() {})
+	assert.Error(t, err, "Expected filters that can't be marshaled to be rejected")
+}
+
+func TestFilteredCursorRoundTrip(t *testing.T) {
+	filters := callFilters{Status: "open", TeamID: "t1"}
+
+	cursor, err := EncodeFilteredCursor("2021-02-03|call-42", filters)
+	require.NoError(t, err)
+
+	value, err := DecodeFilteredCursor(cursor, filters)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-02-03|call-42", value, "Expected the value, including separators, back")
+
+	value, err = DecodeFilteredCursor("", filters)
+	assert.NoError(t, err)
+	assert.Equal(t, "", value, "Expected an empty cursor to decode to an empty string")
+}
+
+func TestFilteredCursorMismatch(t *testing.T) {
+	cursor, err := EncodeFilteredCursor("call-42", callFilters{Status: "open"})
+	require.NoError(t, err)
+
+	_, err = DecodeFilteredCursor(cursor, callFilters{Status: "closed"})
+	assert.Equal(t, ErrCursorFilterMismatch, err, "Expected a cursor replayed with other filters to be rejected")
+
+	_, err = DecodeFilteredCursor(EncodeCursor("call-42"), callFilters{Status: "open"})
+	assert.Error(t, err, "Expected a cursor that isn't bound to filters to be rejected")
+
+	_, err = DecodeFilteredCursor("not base64!", callFilters{Status: "open"})
+	assert.Error(t, err, "Expected an invalid cursor to be rejected")
+}
+
+func TestNewFilteredPager(t *testing.T) {
+	filters := callFilters{Status: "open"}
+	cursor, err := EncodeFilteredCursor("call-42", filters)
+	require.NoError(t, err)
+
+	p, err := NewFilteredPager(&PaginationRequest{Before: cursor, Last: 5}, filters)
+	require.NoError(t, err)
+	assert.Equal(t, "call-42", p.DecCursor)
+	assert.Equal(t, int64(5), p.Limit)
+	assert.False(t, p.ForwardPagination)
+
+	p, err = NewFilteredPager(&PaginationRequest{First: 10}, filters)
+	require.NoError(t, err)
+	assert.Equal(t, "", p.DecCursor, "Expected the first page to have no cursor")
+	assert.True(t, p.ForwardPagination)
+
+	_, err = NewFilteredPager(&PaginationRequest{After: cursor, First: 10}, callFilters{Status: "closed"})
+	assert.Equal(t, ErrCursorFilterMismatch, err)
+}
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/uber/jaeger-client-go"
 )
 
 // Config contains initialization config for NewTracer
@@ -27,6 +29,24 @@ type Config struct {
 	Logger logging.Logging
 	// key values pairs that will be included on all spans
 	GlobalTags map[string]string
+	// If set, every span that takes longer than this, sampled or not, is logged at debug level
+	// with its operation name, duration and tags
+	SlowSpanThreshold time.Duration
+	// The built in reporters spans are sent to, any of LoggingReporter and RemoteReporter. The remote reporter
+	// is skipped while reporting is disabled. Defaults to both
+	Reporters []string
+	// Reporters spans are sent to as well as the built in ones, e.g. a second collector during a migration.
+	// A reporter that fails doesn't stop spans reaching the others
+	AdditionalReporters []jaeger.Reporter
+	// Tags every span with the hostname, pid, container ID, ECS task ARN or Kubernetes pod name, availability
+	// zone and service version of the process, using the same keys as the logging package's host metadata
+	ResourceTags *bool
+	// The version of the service spans are tagged with when ResourceTags is enabled. Defaults to the version
+	// of the main module in the binary's build info
+	ServiceVersion string
+	// Records an event with the sequence number and size of every nth message a traced stream sends and
+	// receives, from the first, so long lived streams show more than one opaque span. 0 disables the events
+	StreamMessageEvents int
 }
 
 var (
@@ -43,6 +63,12 @@ func newDefaultConfig() *Config {
 		SampleRate:           0.0,
 		Logger:               nil,
 		GlobalTags:           nil,
+		SlowSpanThreshold:    0,
+		Reporters:            []string{LoggingReporter, RemoteReporter},
+		AdditionalReporters:  nil,
+		ResourceTags:         &falseVar,
+		ServiceVersion:       "",
+		StreamMessageEvents:  0,
 	}
 }
 
@@ -102,5 +128,51 @@ func mergeAndPopulateConfig(c *Config) (*Config, error) {
 		final.GlobalTags = map[string]string{}
 	}
 
+	if c.SlowSpanThreshold != 0 {
+		final.SlowSpanThreshold = c.SlowSpanThreshold
+	} else if s := os.Getenv("TRACE_SLOW_SPAN_THRESHOLD"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		final.SlowSpanThreshold = d
+	}
+
+	if c.Reporters != nil {
+		final.Reporters = c.Reporters
+	} else if s := os.Getenv("TRACE_REPORTERS"); s != "" {
+		final.Reporters = splitList(s)
+	}
+
+	final.AdditionalReporters = c.AdditionalReporters
+
+	if c.ResourceTags != nil {
+		final.ResourceTags = c.ResourceTags
+	} else if s := os.Getenv("TRACE_RESOURCE_TAGS"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		final.ResourceTags = &b
+	}
+
+	if c.ServiceVersion != "" {
+		final.ServiceVersion = c.ServiceVersion
+	} else if s := os.Getenv("SERVICE_VERSION"); s != "" {
+		final.ServiceVersion = s
+	} else {
+		final.ServiceVersion = buildVersion()
+	}
+
+	if c.StreamMessageEvents != 0 {
+		final.StreamMessageEvents = c.StreamMessageEvents
+	} else if s := os.Getenv("TRACE_STREAM_MESSAGE_EVENTS"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		final.StreamMessageEvents = i
+	}
+
 	return final, nil
 }
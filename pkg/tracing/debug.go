@@ -0,0 +1,182 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// maxDebugOperations caps the number of operations the sampler decisions are kept for, so
+// operation names built from unbounded values can't grow the state forever
+const maxDebugOperations = 500
+
+// otherOperations is the operation decisions are counted under once the cap is reached
+const otherOperations = "other"
+
+// DebugState is a snapshot of the tracer's sampler and reporter state, used to tell whether
+// missing traces are a sampling or a delivery problem
+type DebugState struct {
+	// The configured sample rate
+	SampleRate float64 `json:"sampleRate"`
+	// The share of traces started by this tracer that were sampled
+	EffectiveSampleRate float64 `json:"effectiveSampleRate"`
+	// Traces started by this tracer, by sampling decision
+	TracesSampled    int64 `json:"tracesSampled"`
+	TracesNotSampled int64 `json:"tracesNotSampled"`
+	// Traces joined from upstream services, where the sampling decision was made upstream
+	TracesJoinedSampled    int64 `json:"tracesJoinedSampled"`
+	TracesJoinedNotSampled int64 `json:"tracesJoinedNotSampled"`
+	// Sampling decisions of the spans started by this tracer, by operation
+	Operations map[string]OperationSampling `json:"operations"`
+	// Whether spans are sent to the collector, or only logged
+	ReportingEnabled bool `json:"reportingEnabled"`
+	// Spans waiting in the reporter queue to be sent
+	ReporterQueueLength int64 `json:"reporterQueueLength"`
+	// Spans sent, spans the sender failed to send, and spans dropped because the queue was full
+	ReportedSpans int64 `json:"reportedSpans"`
+	FailedSpans   int64 `json:"failedSpans"`
+	DroppedSpans  int64 `json:"droppedSpans"`
+}
+
+// OperationSampling counts the sampling decisions of the spans started for an operation
+type OperationSampling struct {
+	Sampled    int64 `json:"sampled"`
+	NotSampled int64 `json:"notSampled"`
+}
+
+// DebugState returns a snapshot of the tracer's sampler and reporter state
+func (t *Tracer) DebugState() DebugState {
+	return t.debug.snapshot()
+}
+
+// DebugHandler returns a handler that writes the tracer's sampler and reporter state as JSON,
+// to be mounted on an internal admin mux
+func (t *Tracer) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.DebugState())
+	})
+}
+
+// debugState collects the state shown by the debug handler
+type debugState struct {
+	sampleRate       float64
+	reportingEnabled bool
+
+	tracesSampled          int64
+	tracesNotSampled       int64
+	tracesJoinedSampled    int64
+	tracesJoinedNotSampled int64
+	queueLength            int64
+	reported               int64
+	failed                 int64
+	dropped                int64
+
+	mu         sync.Mutex
+	operations map[string]*OperationSampling
+}
+
+func newDebugState(sampleRate float64, reportingEnabled bool) *debugState {
+	return &debugState{
+		sampleRate:       sampleRate,
+		reportingEnabled: reportingEnabled,
+		operations:       map[string]*OperationSampling{},
+	}
+}
+
+// instrument tees the tracer metrics the debug state is built from into it
+func (d *debugState) instrument(m *jaeger.Metrics) {
+	m.TracesStartedSampled = teeCounter{m.TracesStartedSampled, &d.tracesSampled}
+	m.TracesStartedNotSampled = teeCounter{m.TracesStartedNotSampled, &d.tracesNotSampled}
+	m.TracesJoinedSampled = teeCounter{m.TracesJoinedSampled, &d.tracesJoinedSampled}
+	m.TracesJoinedNotSampled = teeCounter{m.TracesJoinedNotSampled, &d.tracesJoinedNotSampled}
+	m.ReporterSuccess = teeCounter{m.ReporterSuccess, &d.reported}
+	m.ReporterFailure = teeCounter{m.ReporterFailure, &d.failed}
+	m.ReporterDropped = teeCounter{m.ReporterDropped, &d.dropped}
+	m.ReporterQueueLength = teeGauge{m.ReporterQueueLength, &d.queueLength}
+}
+
+// OnStartSpan implements jaeger.ContribObserver, counting the sampling decision of each span by operation
+func (d *debugState) OnStartSpan(sp opentracing.Span, operationName string, options opentracing.StartSpanOptions) (jaeger.ContribSpanObserver, bool) {
+	sc, ok := sp.Context().(jaeger.SpanContext)
+	if !ok {
+		return nil, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	op, ok := d.operations[operationName]
+	if !ok {
+		if len(d.operations) >= maxDebugOperations {
+			operationName = otherOperations
+		}
+		if op, ok = d.operations[operationName]; !ok {
+			op = &OperationSampling{}
+			d.operations[operationName] = op
+		}
+	}
+
+	if sc.IsSampled() {
+		op.Sampled++
+	} else {
+		op.NotSampled++
+	}
+
+	return nil, false
+}
+
+func (d *debugState) snapshot() DebugState {
+	s := DebugState{
+		SampleRate:             d.sampleRate,
+		TracesSampled:          atomic.LoadInt64(&d.tracesSampled),
+		TracesNotSampled:       atomic.LoadInt64(&d.tracesNotSampled),
+		TracesJoinedSampled:    atomic.LoadInt64(&d.tracesJoinedSampled),
+		TracesJoinedNotSampled: atomic.LoadInt64(&d.tracesJoinedNotSampled),
+		ReportingEnabled:       d.reportingEnabled,
+		ReporterQueueLength:    atomic.LoadInt64(&d.queueLength),
+		ReportedSpans:          atomic.LoadInt64(&d.reported),
+		FailedSpans:            atomic.LoadInt64(&d.failed),
+		DroppedSpans:           atomic.LoadInt64(&d.dropped),
+	}
+
+	if total := s.TracesSampled + s.TracesNotSampled; total > 0 {
+		s.EffectiveSampleRate = float64(s.TracesSampled) / float64(total)
+	}
+
+	d.mu.Lock()
+	s.Operations = make(map[string]OperationSampling, len(d.operations))
+	for name, op := range d.operations {
+		s.Operations[name] = *op
+	}
+	d.mu.Unlock()
+
+	return s
+}
+
+// teeCounter increments a local count along with the reported counter
+type teeCounter struct {
+	metrics.Counter
+	count *int64
+}
+
+func (c teeCounter) Inc(delta int64) {
+	atomic.AddInt64(c.count, delta)
+	c.Counter.Inc(delta)
+}
+
+// teeGauge keeps a local value along with the reported gauge
+type teeGauge struct {
+	metrics.Gauge
+	value *int64
+}
+
+func (g teeGauge) Update(value int64) {
+	atomic.StoreInt64(g.value, value)
+	g.Gauge.Update(value)
+}
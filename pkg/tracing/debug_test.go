@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/uber/jaeger-client-go"
+)
+
+// newDebugTracer returns a tracer with the sampler that counts its spans in the debug state d
+func newDebugTracer(d *debugState, sampler jaeger.Sampler) *Tracer {
+	metrics := jaeger.NewNullMetrics()
+	d.instrument(metrics)
+
+	reporter := jaeger.NewInMemoryReporter()
+	t := &Tracer{debug: d, reporter: reporter}
+	t.tracer, t.tracingCloser = jaeger.NewTracer("tracing-test", sampler, reporter,
+		jaeger.TracerOptions.Metrics(metrics),
+		jaeger.TracerOptions.ContribObserver(d),
+	)
+	return t
+}
+
+func TestDebugState(t *testing.T) {
+	is := is.New(t)
+
+	d := newDebugState(0.25, true)
+	sampled := newDebugTracer(d, jaeger.NewConstSampler(true))
+	defer sampled.Close()
+	notSampled := newDebugTracer(d, jaeger.NewConstSampler(false))
+	defer notSampled.Close()
+
+	for i := 0; i < 3; i++ {
+		root := sampled.tracer.StartSpan("get-call")
+		sampled.tracer.StartSpan("load-call", opentracing.ChildOf(root.Context())).Finish()
+		root.Finish()
+	}
+	notSampled.tracer.StartSpan("get-call").Finish()
+
+	// a trace joined by a server span from an upstream service, sampled upstream
+	carrier := opentracing.TextMapCarrier{}
+	upstream := sampled.tracer.StartSpan("upstream")
+	is.NoErr(sampled.tracer.Inject(upstream.Context(), opentracing.TextMap, carrier))
+	upstream.Finish()
+	remote, err := notSampled.tracer.Extract(opentracing.TextMap, carrier)
+	is.NoErr(err)
+	notSampled.tracer.StartSpan("get-call", ext.RPCServerOption(remote)).Finish()
+
+	s := sampled.DebugState()
+	is.Equal(s.SampleRate, 0.25)
+	is.True(s.ReportingEnabled)
+	is.Equal(s.TracesSampled, int64(4))       // the root spans of the sampled tracer
+	is.Equal(s.TracesNotSampled, int64(1))    // the root span of the unsampled tracer
+	is.Equal(s.EffectiveSampleRate, 4.0/5.0)  // sampled share of the traces started here
+	is.Equal(s.TracesJoinedSampled, int64(1)) // the joined trace keeps the upstream decision
+
+	is.Equal(s.Operations["get-call"], OperationSampling{Sampled: 4, NotSampled: 1}) // the joined span follows upstream
+	is.Equal(s.Operations["load-call"], OperationSampling{Sampled: 3})
+}
+
+func TestDebugStateOperationCap(t *testing.T) {
+	is := is.New(t)
+
+	d := newDebugState(1, false)
+	tracer := newDebugTracer(d, jaeger.NewConstSampler(true))
+	defer tracer.Close()
+
+	for i := 0; i < maxDebugOperations+10; i++ {
+		tracer.tracer.StartSpan("get-call-" + strconv.Itoa(i)).Finish()
+	}
+	tracer.tracer.StartSpan("get-call-0").Finish()
+
+	s := d.snapshot()
+	is.Equal(len(s.Operations), maxDebugOperations+1)                       // the capped operations and the other operations
+	is.Equal(s.Operations[otherOperations], OperationSampling{Sampled: 10}) // operations past the cap counted together
+	is.Equal(s.Operations["get-call-0"], OperationSampling{Sampled: 2})     // operations already kept are still counted
+}
+
+func TestDebugHandler(t *testing.T) {
+	is := is.New(t)
+
+	d := newDebugState(0.5, true)
+	tracer := newDebugTracer(d, jaeger.NewConstSampler(true))
+	defer tracer.Close()
+	tracer.tracer.StartSpan("get-call").Finish()
+
+	rec := httptest.NewRecorder()
+	tracer.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/tracing", nil))
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Header().Get("Content-Type"), "application/json")
+
+	var s DebugState
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &s))
+	is.Equal(s, tracer.DebugState()) // the snapshot as JSON
+	is.Equal(s.TracesSampled, int64(1))
+}
@@ -1,6 +1,8 @@
 package tracing
 
 import (
+	"context"
+
 	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
 	"google.golang.org/grpc"
 )
@@ -10,7 +12,28 @@ func (t *Tracer) NewGRPCUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return grpc_opentracing.UnaryServerInterceptor(grpc_opentracing.WithTracer(t.tracer))
 }
 
-// NewGRPCStreamServerInterceptor returns a gRPC stream interceptor wrapped around the internal tracer
+// NewGRPCStreamServerInterceptor returns a gRPC stream interceptor wrapped around the internal tracer.
+// When StreamMessageEvents is set, messages are recorded as events on the span of the stream
 func (t *Tracer) NewGRPCStreamServerInterceptor() grpc.StreamServerInterceptor {
-	return grpc_opentracing.StreamServerInterceptor(grpc_opentracing.WithTracer(t.tracer))
+	traced := grpc_opentracing.StreamServerInterceptor(grpc_opentracing.WithTracer(t.tracer))
+	if t.streamMessageEvents <= 0 {
+		return traced
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return traced(srv, ss, info, messageEventsServerHandler(handler, t.streamMessageEvents))
+	}
+}
+
+// NewGRPCStreamClientInterceptor returns a gRPC stream client interceptor wrapped around the internal tracer.
+// When StreamMessageEvents is set, messages are recorded as events on the span of the stream
+func (t *Tracer) NewGRPCStreamClientInterceptor() grpc.StreamClientInterceptor {
+	traced := grpc_opentracing.StreamClientInterceptor(grpc_opentracing.WithTracer(t.tracer))
+	if t.streamMessageEvents <= 0 {
+		return traced
+	}
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return traced(ctx, desc, cc, method, messageEventsStreamer(streamer, t.streamMessageEvents), opts...)
+	}
 }
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	"github.com/caring/go-packages/v2/pkg/errors"
+	"github.com/opentracing/opentracing-go"
+)
+
+// MarshalSpanContext serializes a span context into bytes so that it can be stored
+// alongside work that is picked up later by another process, such as a job payload,
+// an S3 manifest or a database row.
+func (t *Tracer) MarshalSpanContext(sc opentracing.SpanContext) ([]byte, error) {
+	if sc == nil {
+		return nil, errors.New("cannot marshal a nil span context")
+	}
+
+	var buf bytes.Buffer
+	if err := t.tracer.Inject(sc, opentracing.Binary, &buf); err != nil {
+		return nil, errors.Wrap(err, "unable to marshal span context")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSpanContext restores a span context previously serialized with MarshalSpanContext
+func (t *Tracer) UnmarshalSpanContext(b []byte) (opentracing.SpanContext, error) {
+	if len(b) == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	sc, err := t.tracer.Extract(opentracing.Binary, bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal span context")
+	}
+
+	return sc, nil
+}
+
+// EncodeSpanContext serializes a span context into a base64 string, see MarshalSpanContext
+func (t *Tracer) EncodeSpanContext(sc opentracing.SpanContext) (string, error) {
+	b, err := t.MarshalSpanContext(sc)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// DecodeSpanContext restores a span context previously serialized with EncodeSpanContext
+func (t *Tracer) DecodeSpanContext(s string) (opentracing.SpanContext, error) {
+	if s == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "span context is not valid base64")
+	}
+
+	return t.UnmarshalSpanContext(b)
+}
+
+// StartFollowsFromSpan resumes a trace from a span context encoded with EncodeSpanContext.
+// The new span references the exported span with a follows-from relationship, so a long running
+// job shows up in the same trace as the request that scheduled it without extending that request's duration.
+// If the encoded span context is empty a new root span is started.
+func (t *Tracer) StartFollowsFromSpan(operationName, encoded string, opts ...opentracing.StartSpanOption) (opentracing.Span, error) {
+	if encoded == "" {
+		return t.tracer.StartSpan(operationName, opts...), nil
+	}
+
+	sc, err := t.DecodeSpanContext(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, opentracing.FollowsFrom(sc))
+	return t.tracer.StartSpan(operationName, opts...), nil
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+// newTestTracer returns a Tracer with a jaeger tracer that samples every span, and the reporter recording them.
+// The tracer should be closed once the test is done
+func newTestTracer(opts ...jaeger.TracerOption) (*Tracer, *jaeger.InMemoryReporter) {
+	reporter := jaeger.NewInMemoryReporter()
+	t := NewTracerFrom(nil)
+	t.reporter = reporter
+	t.tracer, t.tracingCloser = jaeger.NewTracer("tracing-test", jaeger.NewConstSampler(true), reporter, opts...)
+	return t, reporter
+}
+
+func TestSpanContextRoundTrip(t *testing.T) {
+	is := is.New(t)
+
+	tracer, _ := newTestTracer()
+	defer tracer.Close()
+	span := tracer.tracer.StartSpan("schedule-job")
+	defer span.Finish()
+	sc := span.Context().(jaeger.SpanContext)
+
+	b, err := tracer.MarshalSpanContext(sc)
+	is.NoErr(err)
+	restored, err := tracer.UnmarshalSpanContext(b)
+	is.NoErr(err)
+	is.Equal(restored.(jaeger.SpanContext).TraceID(), sc.TraceID()) // same trace once unmarshaled
+	is.Equal(restored.(jaeger.SpanContext).SpanID(), sc.SpanID())   // same span once unmarshaled
+
+	encoded, err := tracer.EncodeSpanContext(sc)
+	is.NoErr(err)
+	decoded, err := tracer.DecodeSpanContext(encoded)
+	is.NoErr(err)
+	is.Equal(decoded.(jaeger.SpanContext).TraceID(), sc.TraceID()) // same trace once decoded
+	is.Equal(decoded.(jaeger.SpanContext).SpanID(), sc.SpanID())   // same span once decoded
+}
+
+func TestSpanContextErrors(t *testing.T) {
+	is := is.New(t)
+
+	tracer, _ := newTestTracer()
+	defer tracer.Close()
+
+	_, err := tracer.MarshalSpanContext(nil)
+	is.True(err != nil) // a nil span context can't be marshaled
+
+	_, err = tracer.UnmarshalSpanContext(nil)
+	is.Equal(err, opentracing.ErrSpanContextNotFound) // nothing to unmarshal
+	_, err = tracer.DecodeSpanContext("")
+	is.Equal(err, opentracing.ErrSpanContextNotFound) // nothing to decode
+
+	_, err = tracer.DecodeSpanContext("not base64!")
+	is.True(err != nil) // invalid base64
+	_, err = tracer.UnmarshalSpanContext([]byte("garbage"))
+	is.True(err != nil) // not a span context
+}
+
+func TestStartFollowsFromSpan(t *testing.T) {
+	is := is.New(t)
+
+	tracer, _ := newTestTracer()
+	defer tracer.Close()
+	parent := tracer.tracer.StartSpan("schedule-job")
+	parent.Finish()
+	encoded, err := tracer.EncodeSpanContext(parent.Context())
+	is.NoErr(err)
+
+	span, err := tracer.StartFollowsFromSpan("run-job", encoded)
+	is.NoErr(err)
+	span.Finish()
+	sc := span.(*jaeger.Span).SpanContext()
+	is.Equal(sc.TraceID(), parent.Context().(jaeger.SpanContext).TraceID()) // the job is in the trace that scheduled it
+	refs := span.(*jaeger.Span).References()
+	is.Equal(len(refs), 1)
+	is.Equal(refs[0].Type, opentracing.FollowsFromRef) // follows from the exported span
+	is.Equal(refs[0].ReferencedContext.(jaeger.SpanContext).SpanID(), parent.Context().(jaeger.SpanContext).SpanID())
+
+	root, err := tracer.StartFollowsFromSpan("run-job", "")
+	is.NoErr(err)
+	root.Finish()
+	is.Equal(len(root.(*jaeger.Span).References()), 0) // a root span without an encoded span context
+
+	_, err = tracer.StartFollowsFromSpan("run-job", "not base64!")
+	is.True(err != nil) // the encoded span context is invalid
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/uber/jaeger-client-go"
+)
+
+// The names of the built in reporters that can be listed in Config.Reporters
+const (
+	// LoggingReporter logs every reported span through the tracer's logger
+	LoggingReporter = "logging"
+	// RemoteReporter sends spans to the jaeger agent at the trace destination
+	RemoteReporter = "remote"
+)
+
+// buildReporter builds the reporter spans are sent to, fanning out to each of the configured
+// reporters. The remote reporter is only built when reporting is enabled
+func buildReporter(c *Config, metrics *jaeger.Metrics) (jaeger.Reporter, error) {
+	var reporters []namedReporter
+
+	for _, name := range c.Reporters {
+		switch name {
+		case LoggingReporter:
+			reporters = append(reporters, namedReporter{
+				name:     name,
+				reporter: jaeger.NewLoggingReporter(logging.NewJaegerLogger(c.Logger)),
+			})
+		case RemoteReporter:
+			if *c.DisableReporting {
+				continue
+			}
+
+			transport, err := jaeger.NewUDPTransport(c.TraceDestinationDNS+":"+c.TraceDestinationPort, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			reporters = append(reporters, namedReporter{
+				name: name,
+				reporter: jaeger.NewRemoteReporter(transport,
+					jaeger.ReporterOptions.Metrics(metrics),
+					jaeger.ReporterOptions.Logger(logging.NewJaegerLogger(c.Logger)),
+				),
+			})
+		default:
+			return nil, fmt.Errorf("unknown reporter %q, expected %q or %q", name, LoggingReporter, RemoteReporter)
+		}
+	}
+
+	for i, r := range c.AdditionalReporters {
+		reporters = append(reporters, namedReporter{
+			name:     fmt.Sprintf("additional-%d", i),
+			reporter: r,
+		})
+	}
+
+	switch len(reporters) {
+	case 0:
+		return jaeger.NewNullReporter(), nil
+	case 1:
+		return reporters[0].reporter, nil
+	}
+
+	return &fanOutReporter{
+		logger:    c.Logger,
+		reporters: reporters,
+	}, nil
+}
+
+type namedReporter struct {
+	name     string
+	reporter jaeger.Reporter
+}
+
+// fanOutReporter reports each span to every reporter. A reporter that panics is logged and
+// skipped, so one failing reporter doesn't stop spans reaching the others.
+//
+// Unlike the remote reporter, reporters that send spans synchronously hold up the ones after them,
+// so additional reporters should queue spans and send them in the background.
+type fanOutReporter struct {
+	logger    logging.Logging
+	reporters []namedReporter
+}
+
+// Report implements jaeger.Reporter
+func (r *fanOutReporter) Report(span *jaeger.Span) {
+	for _, nr := range r.reporters {
+		r.isolate(nr.name, "report", func() { nr.reporter.Report(span) })
+	}
+}
+
+// Close implements jaeger.Reporter, closing every reporter even if some of them fail
+func (r *fanOutReporter) Close() {
+	for _, nr := range r.reporters {
+		r.isolate(nr.name, "close", func() { nr.reporter.Close() })
+	}
+}
+
+// isolate runs f, logging and recovering from any panic
+func (r *fanOutReporter) isolate(name, action string, f func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.logger.Error(
+				"tracing reporter failed",
+				logging.String("reporter", name),
+				logging.String("action", action),
+				logging.String("error", fmt.Sprint(p)),
+			)
+		}
+	}()
+
+	f()
+}
+
+// splitList splits a comma separated environment value into its trimmed, non empty parts
+func splitList(s string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/matryer/is"
+	"github.com/uber/jaeger-client-go"
+)
+
+// panickingReporter is a reporter that fails on every call
+type panickingReporter struct {
+	closed bool
+}
+
+func (r *panickingReporter) Report(span *jaeger.Span) {
+	panic("collector unreachable")
+}
+
+func (r *panickingReporter) Close() {
+	r.closed = true
+	panic("collector unreachable")
+}
+
+func TestBuildReporter(t *testing.T) {
+	is := is.New(t)
+
+	logger, _ := logging.NewTestLogger()
+	metrics := jaeger.NewNullMetrics()
+	additional := jaeger.NewInMemoryReporter()
+
+	r, err := buildReporter(&Config{Logger: logger, DisableReporting: &trueVar}, metrics)
+	is.NoErr(err)
+	is.Equal(r, jaeger.NewNullReporter()) // no reporter configured
+
+	r, err = buildReporter(&Config{Logger: logger, DisableReporting: &trueVar, Reporters: []string{RemoteReporter}}, metrics)
+	is.NoErr(err)
+	is.Equal(r, jaeger.NewNullReporter()) // the remote reporter is skipped while reporting is disabled
+
+	r, err = buildReporter(&Config{
+		Logger:              logger,
+		DisableReporting:    &trueVar,
+		Reporters:           []string{RemoteReporter},
+		AdditionalReporters: []jaeger.Reporter{additional},
+	}, metrics)
+	is.NoErr(err)
+	is.Equal(r, additional) // a single reporter isn't fanned out to
+
+	r, err = buildReporter(&Config{
+		Logger:              logger,
+		DisableReporting:    &trueVar,
+		Reporters:           []string{LoggingReporter},
+		AdditionalReporters: []jaeger.Reporter{additional},
+	}, metrics)
+	is.NoErr(err)
+	fanOut, ok := r.(*fanOutReporter)
+	is.True(ok) // several reporters are fanned out to
+	is.Equal(len(fanOut.reporters), 2)
+	is.Equal(fanOut.reporters[0].name, LoggingReporter)
+	is.Equal(fanOut.reporters[1].name, "additional-0")
+
+	_, err = buildReporter(&Config{Logger: logger, DisableReporting: &trueVar, Reporters: []string{"zipkin"}}, metrics)
+	is.True(err != nil) // unknown reporters are rejected
+}
+
+func TestFanOutReporterIsolatesFailures(t *testing.T) {
+	is := is.New(t)
+
+	logger, logs := logging.NewTestLogger()
+	failing := &panickingReporter{}
+	recording := jaeger.NewInMemoryReporter()
+	reporter := &fanOutReporter{
+		logger: logger,
+		reporters: []namedReporter{
+			{name: "failing", reporter: failing},
+			{name: "recording", reporter: recording},
+		},
+	}
+
+	tracer, closer := jaeger.NewTracer("tracing-test", jaeger.NewConstSampler(true), reporter)
+	tracer.StartSpan("get-call").Finish()
+	is.Equal(recording.SpansSubmitted(), 1) // the span reached the reporter after the failing one
+
+	entries := logs.FilterMessage("tracing reporter failed")
+	is.Equal(len(entries), 1) // the failure is logged
+	is.Equal(entries[0].Level, logging.ErrorLevel)
+	is.Equal(entries[0].Fields["reporter"], "failing")
+	is.Equal(entries[0].Fields["action"], "report")
+	is.Equal(entries[0].Fields["error"], "collector unreachable")
+
+	is.NoErr(closer.Close())
+	is.True(failing.closed)                               // every reporter is closed
+	is.Equal(len(logs.FilterField("action", "close")), 1) // the failure to close is logged
+	is.Equal(len(logs.FilterMessage("tracing reporter failed")), 2)
+}
+
+func TestSplitList(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(splitList(" logging, remote ,,"), []string{LoggingReporter, RemoteReporter})
+	is.Equal(splitList(""), []string{})
+}
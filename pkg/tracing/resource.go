@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"runtime/debug"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/uber/jaeger-client-go"
+)
+
+// resourceTags returns the tracer tags describing the process every span comes from. They are detected like the
+// logging package's host metadata, and use the same keys, so traces and logs can be grouped by the same dimensions
+func resourceTags(c *Config) []jaeger.TracerOption {
+	m := logging.DetectHostMetadata()
+
+	tags := map[string]string{
+		"hostname":         m.Hostname,
+		"containerID":      m.ContainerID,
+		"ecsTaskARN":       m.ECSTaskARN,
+		"podName":          m.PodName,
+		"availabilityZone": m.AvailabilityZone,
+		"serviceVersion":   c.ServiceVersion,
+	}
+
+	opts := []jaeger.TracerOption{jaeger.TracerOptions.Tag("pid", m.PID)}
+	for k, v := range tags {
+		if v != "" {
+			opts = append(opts, jaeger.TracerOptions.Tag(k, v))
+		}
+	}
+	return opts
+}
+
+// buildVersion returns the version of the main module the binary was built from, or an empty string
+// when it isn't known, e.g. for binaries built from a working copy
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/uber/jaeger-client-go"
+)
+
+// setenv sets an environment variable for the test, and returns a func that restores it
+func setenv(key, value string) func() {
+	previous, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestResourceTags(t *testing.T) {
+	is := is.New(t)
+
+	ecs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"TaskARN": "arn:aws:ecs:us-east-1:123456789012:task/calls/1", "AvailabilityZone": "us-east-1a"}`))
+	}))
+	defer ecs.Close()
+	defer setenv("ECS_CONTAINER_METADATA_URI_V4", ecs.URL)()
+	defer setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")()
+	defer setenv("POD_NAME", "calls-7d9f")()
+
+	tracer, closer := jaeger.NewTracer("tracing-test", jaeger.NewConstSampler(true), jaeger.NewNullReporter(),
+		resourceTags(&Config{ServiceVersion: "v1.4.2"})...)
+	defer closer.Close()
+
+	tags := map[string]interface{}{}
+	for _, tag := range tracer.(*jaeger.Tracer).Tags() {
+		is.True(tag.Value != "") // tags that weren't detected are left out
+		tags[tag.Key] = tag.Value
+	}
+
+	hostname, _ := os.Hostname()
+	is.Equal(tags["pid"], os.Getpid())
+	is.Equal(tags["hostname"], hostname)
+	is.Equal(tags["ecsTaskARN"], "arn:aws:ecs:us-east-1:123456789012:task/calls/1")
+	is.Equal(tags["availabilityZone"], "us-east-1a")
+	is.Equal(tags["podName"], "calls-7d9f")
+	is.Equal(tags["serviceVersion"], "v1.4.2")
+}
+
+func TestResourceTagsLeaveOutUndetected(t *testing.T) {
+	defer setenv("ECS_CONTAINER_METADATA_URI_V4", "")()
+	defer setenv("KUBERNETES_SERVICE_HOST", "")()
+
+	tracer, closer := jaeger.NewTracer("tracing-test", jaeger.NewConstSampler(true), jaeger.NewNullReporter(),
+		resourceTags(&Config{})...)
+	defer closer.Close()
+
+	for _, tag := range tracer.(*jaeger.Tracer).Tags() {
+		switch tag.Key {
+		case "ecsTaskARN", "availabilityZone", "podName", "serviceVersion":
+			t.Errorf("unexpected tag %s=%v", tag.Key, tag.Value)
+		}
+	}
+}
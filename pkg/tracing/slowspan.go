@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+// slowSpanObserver watches every span, sampled or not, and logs the ones that run
+// past the threshold. This gives visibility of slow operations in the logs even when
+// their trace was never reported.
+type slowSpanObserver struct {
+	logger    logging.Logging
+	threshold time.Duration
+}
+
+func newSlowSpanObserver(logger logging.Logging, threshold time.Duration) *slowSpanObserver {
+	return &slowSpanObserver{
+		logger:    logger,
+		threshold: threshold,
+	}
+}
+
+// OnStartSpan implements jaeger.ContribObserver
+func (o *slowSpanObserver) OnStartSpan(sp opentracing.Span, operationName string, options opentracing.StartSpanOptions) (jaeger.ContribSpanObserver, bool) {
+	start := options.StartTime
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	tags := make(map[string]interface{}, len(options.Tags))
+	for k, v := range options.Tags {
+		tags[k] = v
+	}
+
+	return &slowSpanWatcher{
+		observer:      o,
+		operationName: operationName,
+		start:         start,
+		tags:          tags,
+	}, true
+}
+
+// slowSpanWatcher tracks a single span for the slow span observer
+type slowSpanWatcher struct {
+	observer *slowSpanObserver
+
+	mu            sync.Mutex
+	operationName string
+	start         time.Time
+	tags          map[string]interface{}
+}
+
+// OnSetOperationName implements jaeger.ContribSpanObserver
+func (w *slowSpanWatcher) OnSetOperationName(operationName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.operationName = operationName
+}
+
+// OnSetTag implements jaeger.ContribSpanObserver
+func (w *slowSpanWatcher) OnSetTag(key string, value interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tags[key] = value
+}
+
+// OnFinish implements jaeger.ContribSpanObserver
+func (w *slowSpanWatcher) OnFinish(options opentracing.FinishOptions) {
+	finish := options.FinishTime
+	if finish.IsZero() {
+		finish = time.Now()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	d := finish.Sub(w.start)
+	if d < w.observer.threshold {
+		return
+	}
+
+	w.observer.logger.Debug(
+		"slow span",
+		logging.String("operation", w.operationName),
+		logging.Int64("durationMs", d.Milliseconds()),
+		logging.Any("tags", w.tags),
+	)
+}
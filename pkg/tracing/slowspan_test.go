@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caring/go-packages/v2/pkg/logging"
+	"github.com/matryer/is"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestSlowSpanObserverThreshold(t *testing.T) {
+	is := is.New(t)
+
+	logger, logs := logging.NewTestLogger()
+	o := newSlowSpanObserver(logger, 100*time.Millisecond)
+	start := time.Now()
+
+	finish := func(operationName string, d time.Duration) {
+		w, ok := o.OnStartSpan(nil, operationName, opentracing.StartSpanOptions{
+			StartTime: start,
+			Tags:      opentracing.Tags{"db.table": "calls"},
+		})
+		is.True(ok)
+		w.OnSetTag("db.rows", 3)
+		w.OnFinish(opentracing.FinishOptions{FinishTime: start.Add(d)})
+	}
+
+	finish("fast-query", 99*time.Millisecond)
+	is.Equal(logs.Len(), 0) // spans under the threshold aren't logged
+
+	finish("slow-query", 100*time.Millisecond)
+	finish("slower-query", time.Second)
+	entries := logs.FilterMessage("slow span")
+	is.Equal(len(entries), 2) // spans at or over the threshold are logged
+
+	e := entries[1]
+	is.Equal(e.Level, logging.DebugLevel)
+	is.Equal(e.Fields["operation"], "slower-query")
+	is.Equal(e.Fields["durationMs"], int64(1000))
+	is.Equal(e.Fields["tags"], map[string]interface{}{"db.table": "calls", "db.rows": 3}) // start and later tags
+}
+
+func TestSlowSpanObserverUnsampled(t *testing.T) {
+	is := is.New(t)
+
+	logger, logs := logging.NewTestLogger()
+	tracer, closer := jaeger.NewTracer("tracing-test", jaeger.NewConstSampler(false), jaeger.NewNullReporter(),
+		jaeger.TracerOptions.ContribObserver(newSlowSpanObserver(logger, time.Millisecond)))
+	defer closer.Close()
+
+	span := tracer.StartSpan("load-call")
+	span.SetOperationName("load-call-by-id")
+	time.Sleep(2 * time.Millisecond)
+	span.Finish()
+
+	entries := logs.FilterMessage("slow span")
+	is.Equal(len(entries), 1)                                   // spans are logged whether or not they are sampled
+	is.Equal(entries[0].Fields["operation"], "load-call-by-id") // the name the span finished with
+}
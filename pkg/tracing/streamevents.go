@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc"
+)
+
+// messageEvents records the messages of a stream as events on its span, one for every nth message sent and
+// received, counting from the first. The sequence numbers are updated atomically
+type messageEvents struct {
+	sent     int64
+	received int64
+	span     opentracing.Span
+	every    int64
+}
+
+func newMessageEvents(span opentracing.Span, every int) *messageEvents {
+	return &messageEvents{span: span, every: int64(every)}
+}
+
+func (e *messageEvents) record(event string, seq *int64, m interface{}) {
+	n := atomic.AddInt64(seq, 1)
+	if (n-1)%e.every != 0 {
+		return
+	}
+	e.span.LogFields(
+		log.String("event", event),
+		log.Int64("message.seq", n),
+		log.Int("message.size", messageSize(m)),
+	)
+}
+
+// messageSize returns the encoded size of a protobuf message, or 0 for other messages
+func messageSize(m interface{}) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return 0
+}
+
+// eventServerStream records the messages of a server stream on its span
+type eventServerStream struct {
+	grpc.ServerStream
+	events *messageEvents
+}
+
+func (s *eventServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.events.record("message sent", &s.events.sent, m)
+	}
+	return err
+}
+
+func (s *eventServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.events.record("message received", &s.events.received, m)
+	}
+	return err
+}
+
+// eventClientStream records the messages of a client stream on its span
+type eventClientStream struct {
+	grpc.ClientStream
+	events *messageEvents
+}
+
+func (s *eventClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.events.record("message sent", &s.events.sent, m)
+	}
+	return err
+}
+
+func (s *eventClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.events.record("message received", &s.events.received, m)
+	}
+	return err
+}
+
+// messageEventsServerHandler wraps the handler of a traced stream, so its messages are recorded on the span
+// the tracing interceptor put in the stream's context
+func messageEventsServerHandler(handler grpc.StreamHandler, every int) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		span := opentracing.SpanFromContext(stream.Context())
+		if span == nil {
+			return handler(srv, stream)
+		}
+		return handler(srv, &eventServerStream{stream, newMessageEvents(span, every)})
+	}
+}
+
+// messageEventsStreamer wraps the streamer of a traced stream, so its messages are recorded on the span the
+// tracing interceptor put in the context it is called with
+func messageEventsStreamer(streamer grpc.Streamer, every int) grpc.Streamer {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		span := opentracing.SpanFromContext(ctx)
+		if span == nil {
+			return stream, nil
+		}
+		return &eventClientStream{stream, newMessageEvents(span, every)}, nil
+	}
+}
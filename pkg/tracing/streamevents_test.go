@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/matryer/is"
+	"github.com/uber/jaeger-client-go"
+	"google.golang.org/grpc"
+)
+
+// testServerStream is a server stream that sends nothing anywhere, and receives a message until it is closed
+type testServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	closed bool
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
+
+func (s *testServerStream) SendMsg(m interface{}) error {
+	if s.closed {
+		return errors.New("stream closed")
+	}
+	return nil
+}
+
+func (s *testServerStream) RecvMsg(m interface{}) error {
+	if s.closed {
+		return io.EOF
+	}
+	return nil
+}
+
+// testClientStream is a client stream that sends nothing anywhere, and receives a message until it is closed
+type testClientStream struct {
+	grpc.ClientStream
+	closed bool
+}
+
+func (s *testClientStream) SendMsg(m interface{}) error { return nil }
+
+func (s *testClientStream) RecvMsg(m interface{}) error {
+	if s.closed {
+		return io.EOF
+	}
+	return nil
+}
+
+// messageEventsOf returns the events logged on the span, as the event name and the message sequence number
+func messageEventsOf(span *jaeger.Span) []string {
+	var events []string
+	for _, record := range span.Logs() {
+		var event, seq string
+		for _, f := range record.Fields {
+			switch f.Key() {
+			case "event":
+				event = f.Value().(string)
+			case "message.seq":
+				seq = strconv.FormatInt(f.Value().(int64), 10)
+			}
+		}
+		events = append(events, event+" "+seq)
+	}
+	return events
+}
+
+func TestStreamServerMessageEvents(t *testing.T) {
+	is := is.New(t)
+
+	tracer, reporter := newTestTracer()
+	defer tracer.Close()
+	tracer.streamMessageEvents = 2
+
+	msg := &wrappers.StringValue{Value: "call-42"}
+	ss := &testServerStream{ctx: context.Background()}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		for i := 0; i < 5; i++ {
+			is.NoErr(stream.SendMsg(msg))
+		}
+		for i := 0; i < 3; i++ {
+			is.NoErr(stream.RecvMsg(msg))
+		}
+		ss.closed = true
+		is.True(stream.SendMsg(msg) != nil)   // failed sends aren't recorded
+		is.Equal(stream.RecvMsg(msg), io.EOF) // nor is the end of the stream
+		return nil
+	}
+
+	intercept := tracer.NewGRPCStreamServerInterceptor()
+	is.NoErr(intercept(nil, ss, &grpc.StreamServerInfo{FullMethod: "/calls.v1.Calls/WatchCalls"}, handler))
+
+	spans := reporter.GetSpans()
+	is.Equal(len(spans), 1)
+	span := spans[0].(*jaeger.Span)
+	is.Equal(messageEventsOf(span), []string{
+		"message sent 1", "message sent 3", "message sent 5", // every second message, from the first
+		"message received 1", "message received 3",
+	})
+
+	size := -1
+	for _, f := range span.Logs()[0].Fields {
+		if f.Key() == "message.size" {
+			size = f.Value().(int)
+		}
+	}
+	is.Equal(size, proto.Size(msg)) // the encoded size of the message
+}
+
+func TestStreamClientMessageEvents(t *testing.T) {
+	is := is.New(t)
+
+	tracer, reporter := newTestTracer()
+	defer tracer.Close()
+	tracer.streamMessageEvents = 1
+
+	stream := &testClientStream{}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return stream, nil
+	}
+
+	intercept := tracer.NewGRPCStreamClientInterceptor()
+	cs, err := intercept(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/calls.v1.Calls/WatchCalls", streamer)
+	is.NoErr(err)
+	is.NoErr(cs.SendMsg(&wrappers.StringValue{Value: "call-42"}))
+	is.NoErr(cs.RecvMsg(&wrappers.StringValue{}))
+	is.NoErr(cs.RecvMsg(&wrappers.StringValue{}))
+	stream.closed = true
+	is.Equal(cs.RecvMsg(&wrappers.StringValue{}), io.EOF) // finishes the span
+
+	spans := reporter.GetSpans()
+	is.Equal(len(spans), 1)
+	is.Equal(messageEventsOf(spans[0].(*jaeger.Span)), []string{"message sent 1", "message received 1", "message received 2"})
+}
+
+func TestStreamMessageEventsDisabled(t *testing.T) {
+	is := is.New(t)
+
+	tracer, reporter := newTestTracer()
+	defer tracer.Close()
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		_, wrapped := stream.(*eventServerStream)
+		is.True(!wrapped) // the stream is left as it is
+		return stream.SendMsg(&wrappers.StringValue{})
+	}
+	intercept := tracer.NewGRPCStreamServerInterceptor()
+	is.NoErr(intercept(nil, &testServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/calls.v1.Calls/WatchCalls"}, handler))
+
+	is.Equal(len(reporter.GetSpans()), 1)
+	is.Equal(len(reporter.GetSpans()[0].(*jaeger.Span).Logs()), 0) // no message events
+}
+
+func TestStreamMessageEventsWithoutSpan(t *testing.T) {
+	is := is.New(t)
+
+	handler := messageEventsServerHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		_, wrapped := stream.(*eventServerStream)
+		is.True(!wrapped) // no span to record the messages on
+		return nil
+	}, 1)
+	is.NoErr(handler(nil, &testServerStream{ctx: context.Background()}))
+}
@@ -3,7 +3,6 @@ package tracing
 import (
 	"io"
 
-	"github.com/caring/go-packages/v2/pkg/logging"
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-lib/metrics/prometheus"
@@ -14,14 +13,29 @@ type Tracer struct {
 	tracer        opentracing.Tracer
 	reporter      jaeger.Reporter
 	tracingCloser io.Closer
+	debug         *debugState
+	// record every nth message of a stream as an event on its span, disabled when 0
+	streamMessageEvents int
 }
 
 // Close closes the tracing and reporting objects
 func (t *Tracer) Close() error {
+	if t.reporter == nil {
+		return nil
+	}
 	t.reporter.Close()
 	return t.tracingCloser.Close()
 }
 
+// NewTracerFrom wraps an existing opentracing tracer in a Tracer, e.g. a mock tracer in tests. The
+// tracer is not set as the global tracer, and closing the Tracer does not close it
+func NewTracerFrom(tracer opentracing.Tracer) *Tracer {
+	return &Tracer{
+		tracer: tracer,
+		debug:  newDebugState(0, false),
+	}
+}
+
 // GetInternalTracer returns a pointer to the internal tracer
 func (t *Tracer) GetInternalTracer() *opentracing.Tracer {
 	return &t.tracer
@@ -39,26 +53,17 @@ func NewTracer(config *Config) (*Tracer, error) {
 	factory := prometheus.New()
 	metrics := jaeger.NewMetrics(factory, c.GlobalTags)
 
+	t.debug = newDebugState(c.SampleRate, !*c.DisableReporting)
+	t.debug.instrument(metrics)
+	t.streamMessageEvents = c.StreamMessageEvents
+
 	l := c.Logger
 
-	if !*c.DisableReporting {
-		transport, err := jaeger.NewUDPTransport(c.TraceDestinationDNS+":"+c.TraceDestinationPort, 0)
-		if err != nil {
-			return nil, err
-		}
-
-		// create composite logger to log to the logger and report to the
-		// remote server
-		t.reporter = jaeger.NewCompositeReporter(
-			jaeger.NewLoggingReporter(logging.NewJaegerLogger(l)),
-			jaeger.NewRemoteReporter(transport,
-				jaeger.ReporterOptions.Metrics(metrics),
-				jaeger.ReporterOptions.Logger(logging.NewJaegerLogger(l)),
-			),
-		)
-	} else {
-		// Simple, logging only reporter
-		t.reporter = jaeger.NewLoggingReporter(logging.NewJaegerLogger(l))
+	// fan out to every configured reporter, e.g. logging in staging as well as
+	// reporting to the remote server
+	t.reporter, err = buildReporter(c, metrics)
+	if err != nil {
+		return nil, err
 	}
 
 	// create a sampler for the spans so that we don't report every single span which would be untenable
@@ -67,12 +72,23 @@ func NewTracer(config *Config) (*Tracer, error) {
 		return nil, err
 	}
 
+	opts := []jaeger.TracerOption{
+		jaeger.TracerOptions.Metrics(metrics),
+		jaeger.TracerOptions.ContribObserver(t.debug),
+	}
+	if c.SlowSpanThreshold > 0 {
+		opts = append(opts, jaeger.TracerOptions.ContribObserver(newSlowSpanObserver(l, c.SlowSpanThreshold)))
+	}
+	if *c.ResourceTags {
+		opts = append(opts, resourceTags(c)...)
+	}
+
 	// now make the tracer
 	t.tracer, t.tracingCloser = jaeger.NewTracer(
 		c.ServiceName,
 		sampler,
 		t.reporter,
-		jaeger.TracerOptions.Metrics(metrics),
+		opts...,
 	)
 
 	opentracing.SetGlobalTracer(t.tracer)
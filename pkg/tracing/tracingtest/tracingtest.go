@@ -0,0 +1,203 @@
+// Package tracingtest records the spans created by the code under test in memory, with a jaeger tracer that samples
+// every span, so tests can assert on the spans themselves, their operation names, tags and parents, instead of
+// on the jaeger log output.
+package tracingtest
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/tracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+// serviceName is the service the recorded spans are reported for
+const serviceName = "tracingtest"
+
+// Recorder is a tracer that records every span finished with it
+type Recorder struct {
+	// The tracer to pass to the code under test, e.g. to build its interceptors
+	Tracer *tracing.Tracer
+
+	tracer   opentracing.Tracer
+	reporter *jaeger.InMemoryReporter
+	closer   io.Closer
+}
+
+// New returns a recorder with no spans recorded yet. The tracer is not set as the global tracer,
+// use SetGlobal for code that starts spans from it. The recorder should be closed once the test is done.
+func New() *Recorder {
+	reporter := jaeger.NewInMemoryReporter()
+	tracer, closer := jaeger.NewTracer(serviceName, jaeger.NewConstSampler(true), reporter)
+
+	return &Recorder{
+		Tracer:   tracing.NewTracerFrom(tracer),
+		tracer:   tracer,
+		reporter: reporter,
+		closer:   closer,
+	}
+}
+
+// SetGlobal sets the recorder's tracer as the global tracer, and returns a func that restores the previous one
+func (r *Recorder) SetGlobal() func() {
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(r.tracer)
+	return func() {
+		opentracing.SetGlobalTracer(previous)
+	}
+}
+
+// StartSpan starts a span with the recorder's tracer, e.g. as the parent of the spans of the code under test
+func (r *Recorder) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	return r.tracer.StartSpan(operationName, opts...)
+}
+
+// Spans returns the spans that have finished, in the order they finished
+func (r *Recorder) Spans() []*jaeger.Span {
+	reported := r.reporter.GetSpans()
+	spans := make([]*jaeger.Span, len(reported))
+	for i, s := range reported {
+		spans[i] = s.(*jaeger.Span)
+	}
+	return spans
+}
+
+// SpansNamed returns the spans with the operation name that have finished, in the order they finished
+func (r *Recorder) SpansNamed(operationName string) []*jaeger.Span {
+	var spans []*jaeger.Span
+	for _, s := range r.Spans() {
+		if s.OperationName() == operationName {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// Reset forgets the spans recorded so far
+func (r *Recorder) Reset() {
+	r.reporter.Reset()
+}
+
+// Close closes the recorder's tracer
+func (r *Recorder) Close() error {
+	return r.closer.Close()
+}
+
+// AssertSpan fails the test unless a span with the operation name has finished, carrying every tag in tags
+// with the value given. Values are compared by how they print, so an int matches the int64 it is recorded as,
+// and a span kind matches its name. gRPC spans are named after the full method, e.g. /grpc.health.v1.Health/Check
+func (r *Recorder) AssertSpan(t testing.TB, operationName string, tags map[string]interface{}) *jaeger.Span {
+	t.Helper()
+
+	spans := r.SpansNamed(operationName)
+	if len(spans) == 0 {
+		t.Fatalf("no span named %q has finished, finished: %v", operationName, names(r.Spans()))
+	}
+
+	var mismatch string
+	for _, s := range spans {
+		if mismatch = tagsMismatch(s.Tags(), tags); mismatch == "" {
+			return s
+		}
+	}
+
+	t.Fatalf("no span named %q has the tags %v: %s", operationName, tags, mismatch)
+	return nil
+}
+
+// AssertNoSpan fails the test if a span with the operation name has finished
+func (r *Recorder) AssertNoSpan(t testing.TB, operationName string) {
+	t.Helper()
+
+	if n := len(r.SpansNamed(operationName)); n > 0 {
+		t.Fatalf("%d spans named %q have finished", n, operationName)
+	}
+}
+
+// AssertSpanCount fails the test unless exactly n spans have finished
+func (r *Recorder) AssertSpanCount(t testing.TB, n int) {
+	t.Helper()
+
+	if spans := r.Spans(); len(spans) != n {
+		t.Fatalf("%d spans have finished, want %d: %v", len(spans), n, names(spans))
+	}
+}
+
+// AssertRoot fails the test if the span has a parent
+func (r *Recorder) AssertRoot(t testing.TB, span *jaeger.Span) {
+	t.Helper()
+
+	if parent := span.SpanContext().ParentID(); parent != 0 {
+		t.Fatalf("span %q has the parent %s, want it to be a root span", span.OperationName(), parent)
+	}
+}
+
+// AssertChildOf fails the test unless the child span was started as a child of the parent span
+func (r *Recorder) AssertChildOf(t testing.TB, child, parent *jaeger.Span) {
+	t.Helper()
+	assertReference(t, child, parent, opentracing.ChildOfRef)
+}
+
+// AssertFollowsFrom fails the test unless the span was started as following from the parent span,
+// e.g. a job resumed with tracing.StartFollowsFromSpan
+func (r *Recorder) AssertFollowsFrom(t testing.TB, span, parent *jaeger.Span) {
+	t.Helper()
+	assertReference(t, span, parent, opentracing.FollowsFromRef)
+}
+
+// assertReference fails the test unless the span references the parent with the type of reference given
+func assertReference(t testing.TB, span, parent *jaeger.Span, refType opentracing.SpanReferenceType) {
+	t.Helper()
+
+	sc, pc := span.SpanContext(), parent.SpanContext()
+	if sc.TraceID() != pc.TraceID() {
+		t.Fatalf("span %q is in the trace %s, want the trace %s of %q",
+			span.OperationName(), sc.TraceID(), pc.TraceID(), parent.OperationName())
+	}
+
+	for _, ref := range span.References() {
+		rc, ok := ref.ReferencedContext.(jaeger.SpanContext)
+		if ok && rc.SpanID() == pc.SpanID() {
+			if ref.Type != refType {
+				t.Fatalf("span %q references %q with %s, want %s",
+					span.OperationName(), parent.OperationName(), refName(ref.Type), refName(refType))
+			}
+			return
+		}
+	}
+
+	t.Fatalf("span %q does not reference %q, its parent is %s", span.OperationName(), parent.OperationName(), sc.ParentID())
+}
+
+// tagsMismatch describes the first of the tags that got doesn't carry, or carries with another value,
+// and is empty if it carries them all
+func tagsMismatch(got opentracing.Tags, want map[string]interface{}) string {
+	for k, v := range want {
+		g, ok := got[k]
+		if !ok {
+			return fmt.Sprintf("%s is missing", k)
+		}
+		if !reflect.DeepEqual(g, v) && fmt.Sprint(g) != fmt.Sprint(v) {
+			return fmt.Sprintf("%s is %v (%T), want %v (%T)", k, g, g, v, v)
+		}
+	}
+	return ""
+}
+
+func names(spans []*jaeger.Span) []string {
+	n := make([]string, len(spans))
+	for i, s := range spans {
+		n[i] = s.OperationName()
+	}
+	return n
+}
+
+func refName(t opentracing.SpanReferenceType) string {
+	if t == opentracing.FollowsFromRef {
+		return "follows from"
+	}
+	return "child of"
+}
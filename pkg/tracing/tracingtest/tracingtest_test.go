@@ -0,0 +1,50 @@
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+)
+
+func TestRecorder(t *testing.T) {
+	is := is.New(t)
+
+	r := New()
+	defer r.Close()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		span, _ := opentracing.StartSpanFromContextWithTracer(ctx, r.tracer, "load-call")
+		span.SetTag("db.table", "calls")
+		span.Finish()
+		return nil, nil
+	}
+
+	intercept := r.Tracer.NewGRPCUnaryServerInterceptor()
+	_, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/calls.v1.Calls/GetCall"}, handler)
+	is.NoErr(err)
+
+	r.AssertSpanCount(t, 2)
+	server := r.AssertSpan(t, "/calls.v1.Calls/GetCall", map[string]interface{}{
+		"span.kind": "server",
+	})
+	query := r.AssertSpan(t, "load-call", map[string]interface{}{
+		"db.table": "calls",
+	})
+	r.AssertRoot(t, server)
+	r.AssertChildOf(t, query, server)
+	r.AssertNoSpan(t, "save-call")
+
+	encoded, err := r.Tracer.EncodeSpanContext(server.Context())
+	is.NoErr(err)
+	job, err := r.Tracer.StartFollowsFromSpan("process-job", encoded)
+	is.NoErr(err)
+	job.Finish()
+
+	r.AssertFollowsFrom(t, r.AssertSpan(t, "process-job", nil), server)
+
+	r.Reset()
+	r.AssertSpanCount(t, 0)
+}
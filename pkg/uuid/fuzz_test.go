@@ -0,0 +1,73 @@
+//go:build go1.18
+// +build go1.18
+
+package uuid_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/caring/go-packages/v2/pkg/uuid"
+	"github.com/caring/go-packages/v2/pkg/uuid/uuidtest"
+)
+
+func addSeeds(f *testing.F) {
+	for _, s := range uuidtest.ValidBoundaries() {
+		f.Add(s)
+	}
+	for _, s := range uuidtest.InvalidBoundaries() {
+		f.Add(s)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := uuid.Parse(s)
+		if err != nil || s == "" {
+			return
+		}
+
+		again, err := uuid.Parse(u.String())
+		if err != nil {
+			t.Fatalf("Parse(%q) failed to parse its own string %q: %v", s, u.String(), err)
+		}
+		if again != u {
+			t.Fatalf("Parse(%q) = %v, but its string parses to %v", s, u, again)
+		}
+
+		b, err := uuid.ParseBytes([]byte(s))
+		if err != nil || b != u {
+			t.Fatalf("ParseBytes(%q) = %v, %v, expected %v like Parse", s, b, err, u)
+		}
+	})
+}
+
+func FuzzScan(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		var fromString, fromBytes uuid.UUID
+		errString := fromString.Scan(s)
+		errBytes := fromBytes.Scan([]byte(s))
+		if errString != nil {
+			return
+		}
+		if s != "" && len(s) != 16 && (errBytes != nil || fromBytes != fromString) {
+			t.Fatalf("Scan([]byte(%q)) = %v, %v, expected %v like Scan(string)", s, fromBytes, errBytes, fromString)
+		}
+
+		v, err := fromString.Value()
+		if err != nil {
+			t.Fatalf("Value() of %v failed: %v", fromString, err)
+		}
+		var again uuid.UUID
+		if err := again.Scan(v); err != nil || again != fromString {
+			t.Fatalf("Scan(%v) = %v, %v, expected the value to scan back to %v", v, again, err, fromString)
+		}
+		if !bytes.Equal(again.Bytes(), fromString.Bytes()) {
+			t.Fatalf("Bytes() of %v changed after scanning its value", fromString)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package uuid
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/caring/go-packages/v2/pkg/errors"
+	goouid "github.com/google/uuid"
+)
+
+// MaxLegacyID is the largest int64 ID a LegacyNamespace can map, the low 62 bits of a UUID hold the ID
+const MaxLegacyID = 1<<62 - 1
+
+// legacyVersion is the version of UUIDs mapped from legacy IDs, the custom version 8, so they can never
+// collide with the version 4 and 7 UUIDs generated by New, NewRandom and NewOrdered
+const legacyVersion = 8
+
+// ErrNotLegacyID is returned when a UUID wasn't mapped from a legacy ID in the namespace
+var ErrNotLegacyID = errors.New("uuid was not mapped from a legacy id in this namespace")
+
+// LegacyNamespace deterministically maps the int64 IDs of one kind of entity, e.g. the rows of one table,
+// to UUIDs and back, while primary keys are migrated from ints to UUIDs. The same ID always maps to the same
+// UUID, and IDs in different namespaces map to different UUIDs.
+//
+// The first 64 bits of a mapped UUID are a digest of the namespace name with the version and variant set,
+// and the last 64 the variant followed by the ID.
+type LegacyNamespace struct {
+	name   string
+	prefix [8]byte
+}
+
+// NewLegacyNamespace returns the namespace with the name, usually the name of the table whose keys are being migrated.
+// Names must be unique across the services that share IDs, see CheckLegacyNamespaces.
+func NewLegacyNamespace(name string) LegacyNamespace {
+	sum := sha1.Sum([]byte("caring-legacy-id:" + name))
+
+	ns := LegacyNamespace{name: name}
+	copy(ns.prefix[:], sum[:8])
+	ns.prefix[6] = legacyVersion<<4 | ns.prefix[6]&0x0F
+
+	return ns
+}
+
+// Name returns the name of the namespace
+func (ns LegacyNamespace) Name() string {
+	return ns.name
+}
+
+// FromInt64 returns the UUID the legacy ID maps to. IDs must be between 0 and MaxLegacyID.
+func (ns LegacyNamespace) FromInt64(id int64) (UUID, error) {
+	if id < 0 || id > MaxLegacyID {
+		return UUID{}, errors.Errorf("legacy id %d is out of range, it must be between 0 and %d", id, int64(MaxLegacyID))
+	}
+
+	var uuid goouid.UUID
+	copy(uuid[:8], ns.prefix[:])
+	binary.BigEndian.PutUint64(uuid[8:], uint64(id))
+	uuid[8] |= 0x80 // RFC 4122 variant
+
+	return UUID{UUID: uuid}, nil
+}
+
+// MustFromInt64 returns the UUID the legacy ID maps to, and panics if it is out of range
+func (ns LegacyNamespace) MustFromInt64(id int64) UUID {
+	uuid, err := ns.FromInt64(id)
+	if err != nil {
+		panic(err)
+	}
+	return uuid
+}
+
+// ToInt64 returns the legacy ID the UUID was mapped from, or ErrNotLegacyID if it wasn't mapped from one in
+// this namespace, e.g. because it was generated after the migration
+func (ns LegacyNamespace) ToInt64(uuid UUID) (int64, error) {
+	if !ns.IsLegacy(uuid) {
+		return 0, ErrNotLegacyID
+	}
+
+	return int64(binary.BigEndian.Uint64(uuid.UUID[8:]) &^ (1 << 63)), nil
+}
+
+// IsLegacy reports whether the UUID was mapped from a legacy ID in this namespace
+func (ns LegacyNamespace) IsLegacy(uuid UUID) bool {
+	for i, b := range ns.prefix {
+		if uuid.UUID[i] != b {
+			return false
+		}
+	}
+	// the variant bits, and the top bit of the ID which is never set
+	return uuid.UUID[8]&0xC0 == 0x80
+}
+
+// Parse parses an ID in either form while both are accepted by an API: a decimal legacy ID is mapped to its UUID,
+// anything else is parsed as a UUID. Like Parse, an empty string returns the zero UUID.
+func (ns LegacyNamespace) Parse(s string) (UUID, error) {
+	if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ns.FromInt64(id)
+	}
+	return Parse(s)
+}
+
+// CheckLegacyNamespaces returns an error if any of the namespaces map IDs to the same UUIDs, because two
+// of them have the same name or their names' digests collide. Run it over every namespace a service uses,
+// e.g. in a test, so a collision is caught before any IDs are migrated.
+func CheckLegacyNamespaces(namespaces ...LegacyNamespace) error {
+	seen := make(map[[8]byte]string, len(namespaces))
+	for _, ns := range namespaces {
+		if other, ok := seen[ns.prefix]; ok {
+			return errors.Errorf("legacy namespaces %q and %q map ids to the same uuids", other, ns.name)
+		}
+		seen[ns.prefix] = ns.name
+	}
+	return nil
+}
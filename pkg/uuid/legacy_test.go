@@ -0,0 +1,80 @@
+package uuid
+
+import (
+	"testing"
+
+	goouid "github.com/google/uuid"
+)
+
+func TestLegacyNamespace(t *testing.T) {
+	calls := NewLegacyNamespace("calls")
+	users := NewLegacyNamespace("users")
+
+	for _, id := range []int64{0, 1, 42, 1 << 40, MaxLegacyID} {
+		uuid, err := calls.FromInt64(id)
+		if err != nil {
+			t.Fatalf("FromInt64(%d) failed: %s", id, err)
+		}
+		if v := uuid.Version(); v != legacyVersion {
+			t.Errorf("Legacy UUID %s of version %s", uuid, v)
+		}
+		if uuid.Variant() != goouid.RFC4122 {
+			t.Errorf("Legacy UUID %s is variant %d", uuid, uuid.Variant())
+		}
+		if again := calls.MustFromInt64(id); again != uuid {
+			t.Errorf("FromInt64(%d) is not deterministic, got %s and %s", id, uuid, again)
+		}
+		if other := users.MustFromInt64(id); other == uuid {
+			t.Errorf("FromInt64(%d) is the same in two namespaces", id)
+		}
+
+		back, err := calls.ToInt64(uuid)
+		if err != nil || back != id {
+			t.Errorf("ToInt64(%s) = %d, %v, want %d", uuid, back, err, id)
+		}
+		if _, err := users.ToInt64(uuid); err != ErrNotLegacyID {
+			t.Errorf("ToInt64(%s) in another namespace = %v, want ErrNotLegacyID", uuid, err)
+		}
+	}
+
+	for _, id := range []int64{-1, MaxLegacyID + 1} {
+		if _, err := calls.FromInt64(id); err == nil {
+			t.Errorf("FromInt64(%d) succeeded, want an out of range error", id)
+		}
+	}
+
+	if calls.IsLegacy(New()) || calls.IsLegacy(NewOrdered()) {
+		t.Errorf("Generated UUIDs are reported as legacy")
+	}
+}
+
+func TestLegacyNamespaceParse(t *testing.T) {
+	calls := NewLegacyNamespace("calls")
+
+	uuid, err := calls.Parse("42")
+	if err != nil || uuid != calls.MustFromInt64(42) {
+		t.Errorf("Parse(42) = %s, %v, want the mapped UUID", uuid, err)
+	}
+
+	s := "f47ac10b-58cc-4372-8567-0e02b2c3d479"
+	if uuid, err := calls.Parse(s); err != nil || uuid.String() != s {
+		t.Errorf("Parse(%s) = %s, %v", s, uuid, err)
+	}
+
+	if uuid, err := calls.Parse(""); err != nil || !uuid.IsNil() {
+		t.Errorf("Parse of an empty string = %s, %v, want the zero UUID", uuid, err)
+	}
+
+	if _, err := calls.Parse("not-an-id"); err == nil {
+		t.Errorf("Parse of an invalid ID succeeded")
+	}
+}
+
+func TestCheckLegacyNamespaces(t *testing.T) {
+	if err := CheckLegacyNamespaces(NewLegacyNamespace("calls"), NewLegacyNamespace("users")); err != nil {
+		t.Errorf("CheckLegacyNamespaces of distinct names failed: %s", err)
+	}
+	if err := CheckLegacyNamespaces(NewLegacyNamespace("calls"), NewLegacyNamespace("calls")); err == nil {
+		t.Errorf("CheckLegacyNamespaces of the same name succeeded")
+	}
+}
@@ -0,0 +1,74 @@
+package uuid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	goouid "github.com/google/uuid"
+)
+
+// orderedCounterMax is the largest value of the 12 bit counter held in the rand_a field
+const orderedCounterMax = 0xFFF
+
+// orderedGenerator generates version 7 UUIDs that strictly increase within the process.
+// IDs in the same millisecond are ordered by a counter in the rand_a field, seeded randomly
+// each millisecond with headroom to count up. If the counter runs out, or the clock moves
+// backwards, the timestamp is carried forward from the last ID instead.
+type orderedGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	rand    io.Reader
+	lastMs  int64
+	counter uint16
+}
+
+var ordered = &orderedGenerator{
+	now:  time.Now,
+	rand: rand.Reader,
+}
+
+// NewOrdered returns a version 7 UUID. Its first 48 bits are the unix time in milliseconds,
+// so IDs sort by creation time, and IDs generated by this process are strictly increasing even
+// within the same millisecond. NewOrdered panics if random bytes can't be read, like New.
+func NewOrdered() UUID {
+	return ordered.new()
+}
+
+func (g *orderedGenerator) new() UUID {
+	var uuid goouid.UUID
+	if _, err := io.ReadFull(g.rand, uuid[6:]); err != nil {
+		panic(err)
+	}
+
+	g.mu.Lock()
+	ms := g.now().UnixNano() / int64(time.Millisecond)
+	if ms > g.lastMs {
+		// seed the counter with its top bit clear, so there is room to count up
+		g.counter = binary.BigEndian.Uint16(uuid[6:8]) & (orderedCounterMax >> 1)
+	} else {
+		ms = g.lastMs
+		g.counter++
+		if g.counter > orderedCounterMax {
+			ms++
+			g.counter = 0
+		}
+	}
+	g.lastMs = ms
+	counter := g.counter
+	g.mu.Unlock()
+
+	uuid[0] = byte(ms >> 40)
+	uuid[1] = byte(ms >> 32)
+	uuid[2] = byte(ms >> 24)
+	uuid[3] = byte(ms >> 16)
+	uuid[4] = byte(ms >> 8)
+	uuid[5] = byte(ms)
+	uuid[6] = 0x70 | byte(counter>>8) // version 7
+	uuid[7] = byte(counter)
+	uuid[8] = 0x80 | (uuid[8] & 0x3F) // RFC 4122 variant
+
+	return UUID{UUID: uuid}
+}
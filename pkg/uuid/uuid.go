@@ -2,14 +2,24 @@ package uuid
 
 import (
 	"database/sql"
+	"encoding"
+	"fmt"
+	"os"
+
 	"github.com/caring/go-packages/v2/pkg/errors"
 	goouid "github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 )
 
 type UUID struct {
 	goouid.UUID
 }
 
+var (
+	_ encoding.BinaryMarshaler   = UUID{}
+	_ encoding.BinaryUnmarshaler = &UUID{}
+)
+
 func New() UUID {
 	uuid := goouid.New()
 	return UUID{UUID: uuid}
@@ -49,6 +59,38 @@ func Parse(s string) (UUID, error) {
 	return fromGoogleUuid(uid), nil
 }
 
+// ParseOrError parses s like Parse, but if it isn't a UUID the error names the field it was read from, e.g.
+// "callID is not a valid UUID: invalid UUID length: 3". The error has the InvalidArgument code, so it is returned
+// to gRPC clients as is, the field name and value as fields, and its first part as the message safe to show clients
+func ParseOrError(s, fieldName string) (UUID, error) {
+	uid, err := Parse(s)
+	if err != nil {
+		msg := fmt.Sprintf("%s is not a valid UUID", fieldName)
+		return UUID{}, errors.Build(msg).
+			WithGrpcCode(codes.InvalidArgument).
+			WithSafeMessage(msg).
+			WithField("field", fieldName).
+			WithField("value", s).
+			WithCause(err).
+			Err()
+	}
+	return uid, nil
+}
+
+// MustParseEnv parses the UUID in the environment variable, for config loaded at startup. It panics with an
+// error naming the variable if it is unset or isn't a UUID
+func MustParseEnv(envVar string) UUID {
+	s := os.Getenv(envVar)
+	if s == "" {
+		panic(errors.Errorf("environment variable %s is not set", envVar))
+	}
+	uid, err := Parse(s)
+	if err != nil {
+		panic(errors.Wrapf(err, "environment variable %s is not a valid UUID", envVar))
+	}
+	return uid
+}
+
 // ParseUUIDs is a convenience method to parse multiple strings to UUIDs,
 // if any error is returned then a nil slice is returned. Each empty string is parsed to
 // a 0 value UUID
@@ -99,3 +141,23 @@ func (uuid UUID) Variant() goouid.Variant {
 func (uuid UUID) Version() goouid.Version {
 	return uuid.UUID.Version()
 }
+
+// Bytes returns a copy of the 16 byte representation of uuid.
+func (uuid UUID) Bytes() []byte {
+	b := make([]byte, len(uuid.UUID))
+	copy(b, uuid.UUID[:])
+	return b
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, which gob and msgpack encoders use.
+func (uuid UUID) MarshalBinary() ([]byte, error) {
+	return uuid.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. data must be exactly 16 bytes.
+func (uuid *UUID) UnmarshalBinary(data []byte) error {
+	if err := uuid.UUID.UnmarshalBinary(data); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -1,15 +1,21 @@
 package uuid
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
 	"fmt"
 	goouid "github.com/google/uuid"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 	"unsafe"
 
+	"github.com/caring/go-packages/v2/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
 )
 
 type test struct {
@@ -240,6 +246,33 @@ func TestIsNil(t *testing.T) {
 	assert.False(t, id.IsNil())
 }
 
+func TestParseOrError(t *testing.T) {
+	id, err := ParseOrError("f47ac10b-58cc-0372-8567-0e02b2c3d479", "callID")
+	assert.NoError(t, err)
+	assert.Equal(t, MustParse("f47ac10b-58cc-0372-8567-0e02b2c3d479"), id)
+
+	_, err = ParseOrError("abc", "callID")
+	assert.EqualError(t, err, "rpc error: code = InvalidArgument desc = callID is not a valid UUID: invalid UUID length: 3")
+	assert.Equal(t, codes.InvalidArgument, errors.GrpcCode(err))
+	assert.Equal(t, map[string]interface{}{"field": "callID", "value": "abc"}, errors.Fields(err))
+	msg, ok := errors.SafeMessage(err)
+	assert.True(t, ok)
+	assert.Equal(t, "callID is not a valid UUID", msg)
+}
+
+func TestMustParseEnv(t *testing.T) {
+	defer os.Unsetenv("TEST_ACCOUNT_ID")
+
+	os.Setenv("TEST_ACCOUNT_ID", "f47ac10b-58cc-0372-8567-0e02b2c3d479")
+	assert.Equal(t, MustParse("f47ac10b-58cc-0372-8567-0e02b2c3d479"), MustParseEnv("TEST_ACCOUNT_ID"))
+
+	os.Setenv("TEST_ACCOUNT_ID", "abc")
+	assert.PanicsWithError(t, "environment variable TEST_ACCOUNT_ID is not a valid UUID: invalid UUID length: 3", func() { MustParseEnv("TEST_ACCOUNT_ID") })
+
+	os.Unsetenv("TEST_ACCOUNT_ID")
+	assert.PanicsWithError(t, "environment variable TEST_ACCOUNT_ID is not set", func() { MustParseEnv("TEST_ACCOUNT_ID") })
+}
+
 func TestNullString(t *testing.T) {
 	id := UUID{}
 	assert.True(t, id.IsNil())
@@ -250,6 +283,49 @@ func TestNullString(t *testing.T) {
 	assert.True(t, id.NullString().Valid)
 }
 
+func TestBytes(t *testing.T) {
+	id := MustParse("f47ac10b-58cc-0372-8567-0e02b2c3d479")
+	b := id.Bytes()
+	assert.Equal(t, id.UUID[:], b)
+
+	// mutating the returned slice must not change the uuid
+	b[0] = 0
+	assert.Equal(t, byte(0xf4), id.UUID[0])
+}
+
+func TestBinaryMarshaling(t *testing.T) {
+	id := MustParse("f47ac10b-58cc-0372-8567-0e02b2c3d479")
+
+	data, err := id.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Len(t, data, 16)
+
+	var got UUID
+	assert.NoError(t, got.UnmarshalBinary(data))
+	assert.Equal(t, id, got)
+
+	err = got.UnmarshalBinary(data[:15])
+	assert.Error(t, err, "expected an error unmarshaling a short byte slice")
+}
+
+func TestGobEncoding(t *testing.T) {
+	type cached struct {
+		ID  UUID
+		IDs []UUID
+	}
+	in := cached{
+		ID:  MustParse("f47ac10b-58cc-0372-8567-0e02b2c3d479"),
+		IDs: []UUID{New(), {}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(in))
+
+	var out cached
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(&out))
+	assert.Equal(t, in, out)
+}
+
 func BenchmarkParse(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, err := Parse(asString)
@@ -325,3 +401,44 @@ func BenchmarkUUID_URN(b *testing.B) {
 		}
 	}
 }
+
+func TestNewOrdered(t *testing.T) {
+	prev := NewOrdered()
+	for x := 1; x < 10000; x++ {
+		uuid := NewOrdered()
+		if v := uuid.Version(); v != 7 {
+			t.Errorf("Ordered UUID of version %s", v)
+		}
+		if uuid.Variant() != goouid.RFC4122 {
+			t.Errorf("Ordered UUID is variant %d", uuid.Variant())
+		}
+		if bytes.Compare(prev.Bytes(), uuid.Bytes()) >= 0 {
+			t.Errorf("Ordered UUID %s is not after %s", uuid, prev)
+		}
+		prev = uuid
+	}
+}
+
+func TestNewOrderedSameMillisecond(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	g := &orderedGenerator{
+		now:  func() time.Time { return now },
+		rand: rand.Reader,
+	}
+
+	// more IDs than the counter holds, so the timestamp has to be carried forward
+	prev := g.new()
+	for x := 1; x < 2*orderedCounterMax; x++ {
+		uuid := g.new()
+		if bytes.Compare(prev.Bytes(), uuid.Bytes()) >= 0 {
+			t.Errorf("Ordered UUID %s is not after %s", uuid, prev)
+		}
+		prev = uuid
+	}
+
+	// a clock moving backwards must not break the order either
+	now = now.Add(-time.Second)
+	if uuid := g.new(); bytes.Compare(prev.Bytes(), uuid.Bytes()) >= 0 {
+		t.Errorf("Ordered UUID %s is not after %s", uuid, prev)
+	}
+}
@@ -0,0 +1,151 @@
+// Package uuidtest generates uuid strings for property based and fuzz tests, in every form uuid.Parse accepts
+// and in malformed forms it must reject, so services can test their own parsing and validation of IDs.
+package uuidtest
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// nonHex are characters that aren't hex digits, which make a uuid invalid wherever they replace a digit
+const nonHex = "ghijklmnopqrstuvwxyzGHIJKLMNOPQRSTUVWXYZ!@#$%^&*()_+= "
+
+// Canonical returns a random uuid in the canonical lower case form, e.g. f47ac10b-58cc-4372-a567-0e02b2c3d479.
+// Any version and variant can be returned, not only random v4 uuids
+func Canonical(r *rand.Rand) string {
+	b := make([]byte, 36)
+	for i := range b {
+		switch i {
+		case 8, 13, 18, 23:
+			b[i] = '-'
+		default:
+			b[i] = hexDigits[r.Intn(len(hexDigits))]
+		}
+	}
+	return string(b)
+}
+
+// Valid returns a random uuid in one of the forms uuid.Parse accepts: canonical, upper or mixed case,
+// prefixed with urn:uuid:, wrapped in braces, or 32 hex digits without hyphens
+func Valid(r *rand.Rand) string {
+	s := Canonical(r)
+
+	switch r.Intn(6) {
+	case 0:
+		return strings.ToUpper(s)
+	case 1:
+		return mixedCase(r, s)
+	case 2:
+		return "urn:uuid:" + s
+	case 3:
+		return "{" + s + "}"
+	case 4:
+		return strings.Replace(s, "-", "", -1)
+	}
+	return s
+}
+
+// Invalid returns a random string uuid.Parse rejects, made by breaking a valid uuid: a digit replaced with a
+// character that isn't hex, characters cut off or added, a hyphen replaced, or an unbalanced brace
+func Invalid(r *rand.Rand) string {
+	s := Canonical(r)
+
+	switch r.Intn(5) {
+	case 0:
+		i := digitIndex(r)
+		return s[:i] + string(nonHex[r.Intn(len(nonHex))]) + s[i+1:]
+	case 1:
+		// never cut to empty, which uuid.Parse accepts as the nil uuid
+		return s[:1+r.Intn(len(s)-1)]
+	case 2:
+		return s + string(hexDigits[r.Intn(len(hexDigits))])
+	case 3:
+		hyphens := []int{8, 13, 18, 23}
+		i := hyphens[r.Intn(len(hyphens))]
+		return s[:i] + string(hexDigits[r.Intn(len(hexDigits))]) + s[i+1:]
+	}
+	if r.Intn(2) == 0 {
+		return "{" + s
+	}
+	return s + "}"
+}
+
+// ValidBoundaries returns uuids at the edges of what is valid: the nil and max uuids, every version and variant,
+// and every accepted form of the same uuid
+func ValidBoundaries() []string {
+	b := []string{
+		"00000000-0000-0000-0000-000000000000",
+		"ffffffff-ffff-ffff-ffff-ffffffffffff",
+		"FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF",
+		"urn:uuid:f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		"URN:UUID:f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		"{f47ac10b-58cc-4372-a567-0e02b2c3d479}",
+		"f47ac10b58cc4372a5670e02b2c3d479",
+	}
+	for _, d := range hexDigits {
+		b = append(b, "f47ac10b-58cc-"+string(d)+"372-a567-0e02b2c3d479")
+		b = append(b, "f47ac10b-58cc-4372-"+string(d)+"567-0e02b2c3d479")
+	}
+	return b
+}
+
+// InvalidBoundaries returns strings just outside of what is valid: one character short or long of each form,
+// hyphens out of place, unbalanced braces and prefixes that are almost urn:uuid:
+func InvalidBoundaries() []string {
+	return []string{
+		"f47ac10b-58cc-4372-a567-0e02b2c3d47",
+		"f47ac10b-58cc-4372-a567-0e02b2c3d4790",
+		"f47ac10b58cc4372a5670e02b2c3d47",
+		"f47ac10b58cc4372a5670e02b2c3d4790",
+		"f47ac10b158cc-4372-a567-0e02b2c3d479",
+		"f47ac10b-58cc14372-a567-0e02b2c3d479",
+		"f47ac10b-58cc-4372-a56710e02b2c3d479",
+		"f47ac10-b58cc-4372-a567-0e02b2c3d479",
+		"{f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		"f47ac10b-58cc-4372-a567-0e02b2c3d479}",
+		"urn:uid:f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		"urn:uuid:f47ac10b58cc4372a5670e02b2c3d479",
+		"g47ac10b-58cc-4372-a567-0e02b2c3d479",
+		" f47ac10b-58cc-4372-a567-0e02b2c3d479",
+	}
+}
+
+// ValidString is a uuid in a form uuid.Parse accepts. It implements quick.Generator, so testing/quick can
+// generate it as an argument of a property
+type ValidString string
+
+// Generate implements quick.Generator
+func (ValidString) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(ValidString(Valid(r)))
+}
+
+// InvalidString is a string uuid.Parse rejects. It implements quick.Generator like ValidString
+type InvalidString string
+
+// Generate implements quick.Generator
+func (InvalidString) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(InvalidString(Invalid(r)))
+}
+
+// digitIndex returns the index of a random hex digit in a canonical uuid
+func digitIndex(r *rand.Rand) int {
+	for {
+		i := r.Intn(36)
+		if i != 8 && i != 13 && i != 18 && i != 23 {
+			return i
+		}
+	}
+}
+
+func mixedCase(r *rand.Rand, s string) string {
+	b := []byte(s)
+	for i := range b {
+		if r.Intn(2) == 0 {
+			b[i] = strings.ToUpper(string(b[i]))[0]
+		}
+	}
+	return string(b)
+}
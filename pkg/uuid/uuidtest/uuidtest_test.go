@@ -0,0 +1,51 @@
+package uuidtest
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/caring/go-packages/v2/pkg/uuid"
+)
+
+func TestGenerators(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if s := Valid(r); !parses(s) {
+			t.Errorf("Valid returned %q, which doesn't parse", s)
+		}
+		if s := Invalid(r); parses(s) {
+			t.Errorf("Invalid returned %q, which parses", s)
+		}
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	for _, s := range ValidBoundaries() {
+		if !parses(s) {
+			t.Errorf("valid boundary %q doesn't parse", s)
+		}
+	}
+	for _, s := range InvalidBoundaries() {
+		if parses(s) {
+			t.Errorf("invalid boundary %q parses", s)
+		}
+	}
+}
+
+func TestQuickGenerators(t *testing.T) {
+	valid := func(s ValidString) bool { return parses(string(s)) }
+	if err := quick.Check(valid, nil); err != nil {
+		t.Error(err)
+	}
+
+	invalid := func(s InvalidString) bool { return !parses(string(s)) }
+	if err := quick.Check(invalid, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func parses(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}